@@ -1,30 +1,34 @@
 package network
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
-	"strconv"
 
 	"github.com/Azure/azure-container-networking/cni"
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/cnsclient"
+	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/iptables"
 	"github.com/Azure/azure-container-networking/log"
 	"github.com/Azure/azure-container-networking/network"
 	cniSkel "github.com/containernetworking/cni/pkg/skel"
 	cniTypes "github.com/containernetworking/cni/pkg/types"
 	cniTypesCurr "github.com/containernetworking/cni/pkg/types/current"
+	"github.com/pkg/errors"
 )
 
-const (
-	cnsPort = 10090
-)
+// cnsIPAMClient requests and releases pod IPs from CNS, it is implemented by cnsclient.Client.
+type cnsIPAMClient interface {
+	RequestIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) (*cns.IPConfigResponse, error)
+	ReleaseIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) error
+}
 
 type CNSIPAMInvoker struct {
 	podName      string
 	podNamespace string
-	cnsClient    *cnsclient.CNSClient
+	cnsClient    cnsIPAMClient
 }
 
 type IPv4ResultInfo struct {
@@ -37,9 +41,10 @@ type IPv4ResultInfo struct {
 	hostGateway        string
 }
 
-func NewCNSInvoker(podName, namespace string) (*CNSIPAMInvoker, error) {
-	cnsURL := "http://localhost:" + strconv.Itoa(cnsPort)
-	cnsClient, err := cnsclient.InitCnsClient(cnsURL, defaultRequestTimeout)
+// NewCNSInvoker creates an invoker which requests the pod's IPs from CNS at the url, or at the default
+// CNS url if it is empty.
+func NewCNSInvoker(podName, namespace, cnsURL string) (*CNSIPAMInvoker, error) {
+	cnsClient, err := cnsclient.New(cnsclient.Config{URL: cnsURL})
 
 	return &CNSIPAMInvoker{
 		podName:      podName,
@@ -48,7 +53,7 @@ func NewCNSInvoker(podName, namespace string) (*CNSIPAMInvoker, error) {
 	}, err
 }
 
-//Add uses the requestipconfig API in cns, and returns ipv4 and a nil ipv6 as CNS doesn't support IPv6 yet
+//Add uses the requestipconfig API in cns, and returns ipv4 and, for pods on dual-stack NCs, ipv6 results
 func (invoker *CNSIPAMInvoker) Add(nwCfg *cni.NetworkConfig, args *cniSkel.CmdArgs, hostSubnetPrefix *net.IPNet, options map[string]interface{}) (*cniTypesCurr.Result, *cniTypesCurr.Result, error) {
 	// Parse Pod arguments.
 	podInfo := cns.KubernetesPodInfo{
@@ -67,10 +72,12 @@ func (invoker *CNSIPAMInvoker) Add(nwCfg *cni.NetworkConfig, args *cniSkel.CmdAr
 	}
 
 	log.Printf("Requesting IP for pod %+v using ipconfig %+v", podInfo, ipconfig)
-	response, err := invoker.cnsClient.RequestIPAddress(&ipconfig)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+	defer cancel()
+	response, err := invoker.cnsClient.RequestIPAddress(ctx, &ipconfig)
 	if err != nil {
-		log.Printf("Failed to get IP address from CNS with error %v, response: %v", err, response)
-		return nil, nil, err
+		log.Printf("Failed to get IP address from CNS with error %v", err)
+		return nil, nil, cnsRequestError(err)
 	}
 
 	info := IPv4ResultInfo{
@@ -127,8 +134,50 @@ func (invoker *CNSIPAMInvoker) Add(nwCfg *cni.NetworkConfig, args *cniSkel.CmdAr
 		return nil, nil, err
 	}
 
-	// first result is ipv4, second is ipv6, SWIFT doesn't currently support IPv6
-	return result, nil, nil
+	// CNS only returns an IPv6 address for pods on dual-stack NCs
+	var resultV6 *cniTypesCurr.Result
+	if response.PodIpInfoV6 != nil {
+		resultV6, err = ipv6ResultFromPodIPInfo(response.PodIpInfoV6)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// first result is ipv4, second is ipv6
+	return result, resultV6, nil
+}
+
+// ipv6ResultFromPodIPInfo builds the ipv6 result from the IPv6 address CNS allocated to the pod.
+func ipv6ResultFromPodIPInfo(podIPInfo *cns.PodIpInfo) (*cniTypesCurr.Result, error) {
+	ncgw := net.ParseIP(podIPInfo.NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+	if ncgw == nil {
+		return nil, fmt.Errorf("IPv6 gateway address %v from response is invalid", podIPInfo.NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+	}
+
+	podIPAddress := podIPInfo.PodIPConfig.IPAddress
+	ip, ncipnet, err := net.ParseCIDR(podIPAddress + "/" + fmt.Sprint(podIPInfo.NetworkContainerPrimaryIPConfig.IPSubnet.PrefixLength))
+	if ip == nil {
+		return nil, fmt.Errorf("Unable to parse IPv6 from response: %v with err %v", podIPAddress, err)
+	}
+
+	return &cniTypesCurr.Result{
+		IPs: []*cniTypesCurr.IPConfig{
+			{
+				Version: "6",
+				Address: net.IPNet{
+					IP:   ip,
+					Mask: ncipnet.Mask,
+				},
+				Gateway: ncgw,
+			},
+		},
+		Routes: []*cniTypes.Route{
+			{
+				Dst: network.Ipv6DefaultRouteDstPrefix,
+				GW:  ncgw,
+			},
+		},
+	}, nil
 }
 
 func setHostOptions(nwCfg *cni.NetworkConfig, hostSubnetPrefix *net.IPNet, ncSubnetPrefix *net.IPNet, options map[string]interface{}, info IPv4ResultInfo) error {
@@ -176,7 +225,7 @@ func setHostOptions(nwCfg *cni.NetworkConfig, hostSubnetPrefix *net.IPNet, ncSub
 	return nil
 }
 
-// Delete calls into the releaseipconfiguration API in CNS
+// Delete calls into the releaseipconfiguration API in CNS, which releases both the ipv4 and ipv6 addresses of the pod
 func (invoker *CNSIPAMInvoker) Delete(address *net.IPNet, nwCfg *cni.NetworkConfig, args *cniSkel.CmdArgs, options map[string]interface{}) error {
 	// Parse Pod arguments.
 	podInfo := cns.KubernetesPodInfo{
@@ -201,5 +250,26 @@ func (invoker *CNSIPAMInvoker) Delete(address *net.IPNet, nwCfg *cni.NetworkConf
 		log.Printf("CNS invoker called with empty IP address")
 	}
 
-	return invoker.cnsClient.ReleaseIPAddress(&req)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+	defer cancel()
+	if err = invoker.cnsClient.ReleaseIPAddress(ctx, &req); err != nil {
+		return cnsRequestError(err)
+	}
+	return nil
+}
+
+// cnsRequestError explains why the request to CNS failed, keeping the CNS response code for errors.Is.
+func cnsRequestError(err error) error {
+	switch {
+	case errors.Is(err, types.FailedToAllocateIPConfig):
+		return errors.Wrap(err, "CNS has no IP available for the pod, the node's IP pool may be exhausted or scaling up")
+	case errors.Is(err, types.IPConfigRequestThrottled):
+		return errors.Wrap(err, "CNS throttled the request while it waits for IPs")
+	case errors.Is(err, types.ShuttingDown):
+		return errors.Wrap(err, "CNS is shutting down and no longer allocates IPs")
+	case errors.Is(err, types.UnreachableHost):
+		return errors.Wrap(err, "failed to reach CNS, it may be down or restarting")
+	default:
+		return err
+	}
 }
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cni"
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/cnsclient"
+	"github.com/Azure/azure-container-networking/cns/cnsclient/fake"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/network"
+	cniSkel "github.com/containernetworking/cni/pkg/skel"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ cnsIPAMClient = &cnsclient.Client{}
+	_ cnsIPAMClient = &fake.Client{}
+)
+
+var testPodIPInfo = cns.PodIpInfo{
+	PodIPConfig: cns.IPSubnet{IPAddress: "10.240.0.5", PrefixLength: 16},
+	NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+		IPSubnet:         cns.IPSubnet{IPAddress: "10.240.0.4", PrefixLength: 16},
+		GatewayIPAddress: "10.240.0.1",
+	},
+	HostPrimaryIPInfo: cns.HostIPInfo{Gateway: "10.224.0.1", PrimaryIP: "10.224.0.4", Subnet: "10.224.0.0/16"},
+}
+
+func newTestCNSInvoker(client cnsIPAMClient) (*CNSIPAMInvoker, *cniSkel.CmdArgs) {
+	invoker := &CNSIPAMInvoker{podName: "web", podNamespace: "default", cnsClient: client}
+	args := &cniSkel.CmdArgs{ContainerID: "0123456789abcdef", Netns: "/var/run/netns/web", IfName: "eth0"}
+	return invoker, args
+}
+
+func TestCNSIPAMInvokerAddAndDelete(t *testing.T) {
+	client := fake.NewClient(testPodIPInfo)
+	invoker, args := newTestCNSInvoker(client)
+
+	var hostSubnetPrefix net.IPNet
+	result, resultV6, err := invoker.Add(&cni.NetworkConfig{}, args, &hostSubnetPrefix, map[string]interface{}{})
+	require.NoError(t, err)
+	require.Nil(t, resultV6)
+	require.Len(t, result.IPs, 1)
+	require.Equal(t, "10.240.0.5/16", result.IPs[0].Address.String())
+	require.Equal(t, "10.224.0.0/16", hostSubnetPrefix.String())
+	require.Contains(t, client.Allocated(), GetEndpointID(args))
+
+	require.NoError(t, invoker.Delete(&result.IPs[0].Address, &cni.NetworkConfig{}, args, nil))
+	require.Empty(t, client.Allocated())
+	require.Equal(t, 1, client.Available())
+}
+
+func TestCNSIPAMInvokerAddErrors(t *testing.T) {
+	// no IPs in the pool
+	invoker, args := newTestCNSInvoker(fake.NewClient())
+	_, _, err := invoker.Add(&cni.NetworkConfig{}, args, &net.IPNet{}, map[string]interface{}{})
+	require.True(t, errors.Is(err, types.FailedToAllocateIPConfig))
+	require.Contains(t, err.Error(), "no IP available")
+
+	client := fake.NewClient(testPodIPInfo)
+	client.FailNext(fake.OpRequestIPAddress, &cnsclient.CNSClientError{Code: types.UnreachableHost, Err: errors.New("connection refused")})
+	invoker, args = newTestCNSInvoker(client)
+	_, _, err = invoker.Add(&cni.NetworkConfig{}, args, &net.IPNet{}, map[string]interface{}{})
+	require.True(t, errors.Is(err, types.UnreachableHost))
+	require.Contains(t, err.Error(), "failed to reach CNS")
+	require.Equal(t, 1, client.Available())
+}
+
+func TestIPv6ResultFromPodIPInfo(t *testing.T) {
+	podIPInfo := &cns.PodIpInfo{
+		PodIPConfig: cns.IPSubnet{IPAddress: "fd00::5", PrefixLength: 64},
+		NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+			IPSubnet:         cns.IPSubnet{IPAddress: "fd00::1", PrefixLength: 64},
+			GatewayIPAddress: "fd00::2",
+		},
+	}
+
+	result, err := ipv6ResultFromPodIPInfo(podIPInfo)
+	require.NoError(t, err)
+	require.Len(t, result.IPs, 1)
+	require.Equal(t, "6", result.IPs[0].Version)
+	require.Equal(t, "fd00::5/64", result.IPs[0].Address.String())
+	require.True(t, net.ParseIP("fd00::2").Equal(result.IPs[0].Gateway))
+	require.Len(t, result.Routes, 1)
+	require.Equal(t, network.Ipv6DefaultRouteDstPrefix.String(), result.Routes[0].Dst.String())
+
+	podIPInfo.NetworkContainerPrimaryIPConfig.GatewayIPAddress = ""
+	_, err = ipv6ResultFromPodIPInfo(podIPInfo)
+	require.Error(t, err)
+}
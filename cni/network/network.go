@@ -487,7 +487,7 @@ func (plugin *netPlugin) Add(args *cniSkel.CmdArgs) error {
 
 	switch nwCfg.Ipam.Type {
 	case network.AzureCNS:
-		plugin.ipamInvoker, err = NewCNSInvoker(k8sPodName, k8sNamespace)
+		plugin.ipamInvoker, err = NewCNSInvoker(k8sPodName, k8sNamespace, nwCfg.CNSUrl)
 		if err != nil {
 			log.Printf("[cni-net] Creating network %v, failed with err %v", networkId, err)
 			return err
@@ -692,6 +692,13 @@ func (plugin *netPlugin) Add(args *cniSkel.CmdArgs) error {
 		epInfo.Routes = append(epInfo.Routes, network.RouteInfo{Dst: route.Dst, Gw: route.GW})
 	}
 
+	// CNS returns the ipv6 default route of pods on dual-stack NCs with the ipv6 result
+	if nwCfg.Ipam.Type == network.AzureCNS && resultV6 != nil {
+		for _, route := range resultV6.Routes {
+			epInfo.Routes = append(epInfo.Routes, network.RouteInfo{Dst: route.Dst, Gw: route.GW})
+		}
+	}
+
 	if azIpamResult != nil && azIpamResult.IPs != nil {
 		epInfo.InfraVnetIP = azIpamResult.IPs[0].Address
 	}
@@ -895,7 +902,7 @@ func (plugin *netPlugin) Delete(args *cniSkel.CmdArgs) error {
 
 	switch nwCfg.Ipam.Type {
 	case network.AzureCNS:
-		plugin.ipamInvoker, err = NewCNSInvoker(k8sPodName, k8sNamespace)
+		plugin.ipamInvoker, err = NewCNSInvoker(k8sPodName, k8sNamespace, nwCfg.CNSUrl)
 		if err != nil {
 			log.Printf("[cni-net] Creating network %v failed with err %v.", networkId, err)
 			return err
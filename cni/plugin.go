@@ -152,8 +152,10 @@ func (plugin *Plugin) Errorf(format string, args ...interface{}) *cniTypes.Error
 func (plugin *Plugin) InitializeKeyValueStore(config *common.PluginConfig) error {
 	// Create the key value store.
 	if plugin.Store == nil {
+		// Use the bolt store once the JSON state has been migrated to it.
+		path := platform.CNIRuntimePath + plugin.Name
 		var err error
-		plugin.Store, err = store.NewJsonFileStore(platform.CNIRuntimePath + plugin.Name + ".json")
+		plugin.Store, err = store.NewKeyValueStore(store.DetectType(path), path)
 		if err != nil {
 			log.Printf("[cni] Failed to create store: %v.", err)
 			return err
@@ -140,7 +140,8 @@ func main() {
 	defer tb.Close()
 
 	for {
-		config.Store, err = store.NewJsonFileStore(platform.CNIRuntimePath + pluginName + ".json")
+		storePath := platform.CNIRuntimePath + pluginName
+		config.Store, err = store.NewKeyValueStore(store.DetectType(storePath), storePath)
 		if err != nil {
 			fmt.Printf("[monitor] Failed to create store: %v\n", err)
 			return
@@ -152,8 +153,17 @@ func main() {
 			return
 		}
 
-		if err := nm.Initialize(&config, false); err != nil {
-			log.Printf("[monitor] Failed while initializing network manager %+v", err)
+		// The store is locked while the state is read, like CNI does, which also closes a bolt store again
+		// so that CNI can open it.
+		if err := config.Store.Lock(true); err != nil {
+			log.Printf("[monitor] Failed to lock store: %v", err)
+		} else {
+			if err := nm.Initialize(&config, false); err != nil {
+				log.Printf("[monitor] Failed while initializing network manager %+v", err)
+			}
+			if err := config.Store.Unlock(false); err != nil {
+				log.Printf("[monitor] Failed to unlock store: %v", err)
+			}
 		}
 
 		log.Printf("[monitor] network manager:%+v", nm)
@@ -7,6 +7,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Container Network Service DNC Contract
@@ -26,6 +27,10 @@ const (
 	GetIPAddresses                           = "/debug/getipaddresses"
 	GetPodIPOrchestratorContext              = "/debug/getpodcontext"
 	GetHTTPRestData                          = "/debug/getrestdata"
+	GetIPStateTransitions                    = "/debug/getipstatetransitions"
+	GetLeakedIPs                             = "/debug/getleakedips"
+	GetDebugBundle                           = "/debug/bundle"
+	GetConfigStatus                          = "/debug/configstatus"
 )
 
 // NetworkContainer Prefixes
@@ -92,6 +97,7 @@ type CreateNetworkContainerRequest struct {
 	LocalIPConfiguration       IPConfiguration
 	OrchestratorContext        json.RawMessage
 	IPConfiguration            IPConfiguration
+	IPv6Configuration          IPConfiguration              // Set when the NC also has IPv6 SecondaryIPConfigs.
 	SecondaryIPConfigs         map[string]SecondaryIPConfig // uuid is key
 	MultiTenancyInfo           MultiTenancyInfo
 	CnetAddressSpace           []IPSubnet // To setup SNAT (should include service endpoint vips).
@@ -311,11 +317,15 @@ type GetNetworkContainerStatusRequest struct {
 }
 
 // GetNetworkContainerStatusResponse specifies response of retriving a network container status.
+// Version is the NC version requested by DNC and AzureHostVersion the one NMAgent reported as programmed,
+// -1 until NMAgent reports one. The NC's secondary IPs of versions NMAgent hasn't programmed yet are
+// PendingProgramming.
 type GetNetworkContainerStatusResponse struct {
-	NetworkContainerid string
-	Version            string
-	AzureHostVersion   string
-	Response           Response
+	NetworkContainerid        string
+	Version                   string
+	AzureHostVersion          string
+	PendingProgrammingIPCount int
+	Response                  Response
 }
 
 // GetNetworkContainerRequest specifies the details about the request to retrieve a specifc network container.
@@ -365,15 +375,24 @@ func (i IPConfigRequest) String() string {
 }
 
 // IPConfigResponse is used in CNS IPAM mode as a response to CNI ADD
+// PodIpInfo holds the IPv4 address of the pod and PodIpInfoV6 its IPv6 address
+// when the pod is dual-stack.
 type IPConfigResponse struct {
-	PodIpInfo PodIpInfo
-	Response  Response
+	PodIpInfo   PodIpInfo
+	PodIpInfoV6 *PodIpInfo
+	Response    Response
 }
 
 // GetIPAddressesRequest is used in CNS IPAM mode to get the states of IPConfigs
 // The IPConfigStateFilter is a slice of IP's to fetch from CNS that match those states
+// The other fields narrow the IPs to those matching every one set, empty fields match every IP.
 type GetIPAddressesRequest struct {
 	IPConfigStateFilter []IPConfigState
+	NCID                string
+	PodName             string
+	PodNamespace        string
+	IPCIDR              string        // e.g. 10.0.0.0/24
+	MinTimeInState      time.Duration // matches the IPs which have been in their state for longer than this
 }
 
 // GetIPAddressStateResponse is used in CNS IPAM mode as a response to get IP address state
@@ -394,6 +413,80 @@ type GetPodContextResponse struct {
 	Response   Response
 }
 
+// IPConfigEventType describes the kind of change an IPConfigEvent reports.
+type IPConfigEventType string
+
+const (
+	// IPConfigAdded is sent when an IPConfig is added to the CNS state.
+	IPConfigAdded IPConfigEventType = "Added"
+	// IPConfigModified is sent when an IPConfig changes state or pod.
+	IPConfigModified IPConfigEventType = "Modified"
+	// IPConfigDeleted is sent when an IPConfig is removed from the CNS state.
+	IPConfigDeleted IPConfigEventType = "Deleted"
+)
+
+// IPConfigEvent is a single change to the IPConfigurationStatus of a secondary IP
+// as observed by watchers of the CNS IPAM state.
+type IPConfigEvent struct {
+	Type                  IPConfigEventType
+	IPConfigurationStatus IPConfigurationStatus
+	PreviousState         IPConfigState
+}
+
+// IPStateTransition records a single change to the State of a secondary IP.
+// From is empty when the IP is added to the CNS state and To is empty when it is removed.
+type IPStateTransition struct {
+	Timestamp        time.Time
+	IPConfigID       string
+	IPAddress        string
+	NCID             string
+	PodName          string
+	PodNamespace     string
+	PodInterfaceID   string
+	InfraContainerID string
+	From             IPConfigState
+	To               IPConfigState
+	Cause            string
+}
+
+// GetIPStateTransitionsRequest is used in CNS IPAM mode to query the IP state transition audit trail.
+// Empty fields match every transition.
+type GetIPStateTransitionsRequest struct {
+	IPAddress    string
+	PodName      string
+	PodNamespace string
+	NCID         string
+}
+
+// GetIPStateTransitionsResponse returns the matching IP state transitions, oldest first.
+type GetIPStateTransitionsResponse struct {
+	IPStateTransitions []IPStateTransition
+	Response           Response
+}
+
+// LeakedIP is an Allocated secondary IP which is not held by any pod on the node.
+// Released is set when the leak detector has returned the IP to the pool.
+// PartiallyHeld is set when the IP is still held according to some of the sources of the pods on the node,
+// such IPs are only reported and never released.
+type LeakedIP struct {
+	IPConfigurationStatus IPConfigurationStatus
+	LeakedSince           time.Time
+	Released              bool
+	PartiallyHeld         bool
+}
+
+// GetLeakedIPsResponse returns the leaked IPs found by the most recent leak detection run.
+type GetLeakedIPsResponse struct {
+	LeakedIPs []LeakedIP
+	Response  Response
+}
+
+// GetConfigStatusResponse returns the status of the CNS config file.
+type GetConfigStatusResponse struct {
+	ConfigStatus ConfigStatus
+	Response     Response
+}
+
 // IPAddressState Only used in the GetIPConfig API to return IP's that match a filter
 type IPAddressState struct {
 	IPAddress string
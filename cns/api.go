@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/common"
 	"github.com/Azure/azure-container-networking/cns/types"
@@ -26,6 +27,9 @@ const (
 	GetIPAddressUtilizationPath   = "/network/ip/utilization"
 	GetUnhealthyIPAddressesPath   = "/network/ipaddresses/unhealthy"
 	GetHealthReportPath           = "/network/health"
+	HealthzPath                   = "/healthz"
+	ReadyzPath                    = "/readyz"
+	EventsPath                    = "/events"
 	NumberOfCPUCoresPath          = "/hostcpucores"
 	CreateHostNCApipaEndpointPath = "/network/createhostncapipaendpoint"
 	DeleteHostNCApipaEndpointPath = "/network/deletehostncapipaendpoint"
@@ -37,13 +41,14 @@ const (
 // HTTPService describes the min API interface that every service should have.
 type HTTPService interface {
 	common.ServiceAPI
-	SendNCSnapShotPeriodically(context.Context, int)
+	SendNCSnapShotPeriodically(context.Context, *common.Interval)
 	SetNodeOrchestrator(*SetOrchestratorTypeRequest)
-	SyncNodeStatus(string, string, string, json.RawMessage) (types.ResponseCode, string)
+	SyncNodeStatus(context.Context, string, string, string, json.RawMessage) (types.ResponseCode, string)
 	GetPendingProgramIPConfigs() []IPConfigurationStatus
 	GetAvailableIPConfigs() []IPConfigurationStatus
 	GetAllocatedIPConfigs() []IPConfigurationStatus
 	GetPendingReleaseIPConfigs() []IPConfigurationStatus
+	GetUnallocatedReservedIPCount() int
 	GetPodIPConfigState() map[string]IPConfigurationStatus
 	MarkIPAsPendingRelease(numberToMark int) (map[string]IPConfigurationStatus, error)
 }
@@ -56,6 +61,8 @@ type IPConfigurationStatus struct {
 	IPAddress string
 	State     IPConfigState
 	PodInfo   PodInfo
+	// StateChangedAt is when the IP last changed State, zero if it hasn't changed since CNS started.
+	StateChangedAt time.Time
 }
 
 func (i IPConfigurationStatus) String() string {
@@ -92,6 +99,11 @@ func (i *IPConfigurationStatus) UnmarshalJSON(b []byte) error {
 			return err
 		}
 	}
+	if s, ok := m["StateChangedAt"]; ok {
+		if err := json.Unmarshal(s, &(i.StateChangedAt)); err != nil {
+			return err
+		}
+	}
 	if s, ok := m["PodInfo"]; ok {
 		pi, err := UnmarshalPodInfo(s)
 		if err != nil {
@@ -217,6 +229,7 @@ type IPAMPoolMonitor interface {
 	Start(ctx context.Context, poolMonitorRefreshMilliseconds int) error
 	Update(scalar v1alpha.Scaler, spec v1alpha.NodeNetworkConfigSpec)
 	GetStateSnapshot() IpamPoolMonitorStateSnapshot
+	ReleaseUnallocatedIPs(ctx context.Context) error
 }
 
 // IpamPoolMonitorStateSnapshot struct to expose state values for IPAMPoolMonitor struct
@@ -225,6 +238,8 @@ type IpamPoolMonitorStateSnapshot struct {
 	MaximumFreeIps           int64
 	UpdatingIpsNotInUseCount int
 	CachedNNC                v1alpha.NodeNetworkConfig
+	// LastReconcileTime is when the pool was last reconciled without error, zero if it never was.
+	LastReconcileTime time.Time
 }
 
 // Response describes generic response from CNS.
@@ -233,6 +248,75 @@ type Response struct {
 	Message    string
 }
 
+// HealthCheckResult is the result of one of the checks of a HealthReport.
+type HealthCheckResult struct {
+	Name    string
+	Healthy bool
+	Message string `json:",omitempty"`
+}
+
+// HealthReport describes whether CNS is live, or ready to serve requests, with the result of each check.
+// It is served with status 200 if every check is healthy and 503 otherwise.
+type HealthReport struct {
+	Healthy bool
+	Checks  []HealthCheckResult
+}
+
+// ConfigStatus describes the CNS config file and how much of it is in effect.
+type ConfigStatus struct {
+	Path string
+	// LoadedAt is when the config in effect was read, at startup or by the last reload.
+	LoadedAt time.Time
+	// LastReloadError is why the last change to the config file wasn't applied, empty if it was.
+	LastReloadError string `json:",omitempty"`
+	// RestartRequired lists the fields which were changed since CNS started and are only applied on restart.
+	RestartRequired []string `json:",omitempty"`
+}
+
+// ConfigStatusProvider reports the ConfigStatus of CNS, and the config it last loaded with its secrets redacted.
+type ConfigStatusProvider interface {
+	ConfigStatus() ConfigStatus
+	RedactedConfig() interface{}
+}
+
+// EventType is the kind of change an Event reports.
+type EventType string
+
+const (
+	// NetworkContainerCreated is sent when an NC is added to the CNS state.
+	NetworkContainerCreated EventType = "NetworkContainerCreated"
+	// NetworkContainerUpdated is sent when DNC updates an NC, or NMAgent programs a new version of it.
+	NetworkContainerUpdated EventType = "NetworkContainerUpdated"
+	// NetworkContainerDeleted is sent when an NC is removed from the CNS state.
+	NetworkContainerDeleted EventType = "NetworkContainerDeleted"
+	// IPStateChanged is sent when a secondary IP is added, changes State, or is removed.
+	IPStateChanged EventType = "IPStateChanged"
+	// EventsResync is sent instead of the events a stream missed when it is resumed from an event which
+	// is no longer buffered, or which was sent by a previous CNS process. Consumers re-list the CNS state.
+	EventsResync EventType = "Resync"
+)
+
+// Event is a change to the CNS state, streamed as a server-sent event at EventsPath. IDs increase
+// by one from each event to the next, and restart with each CNS process, so the id field of a streamed
+// event is the ID prefixed by the boot epoch of the CNS process and a dash. A stream is resumed after
+// the event in its Last-Event-ID header.
+type Event struct {
+	ID                uint64
+	Type              EventType
+	Timestamp         time.Time
+	NetworkContainer  *NetworkContainerEvent `json:",omitempty"`
+	IPStateTransition *IPStateTransition     `json:",omitempty"`
+}
+
+// NetworkContainerEvent describes the NC of a NetworkContainerCreated, Updated or Deleted Event.
+type NetworkContainerEvent struct {
+	ID   string
+	Type string
+	// Version is the NC version requested by DNC, and HostVersion the one NMAgent programmed.
+	Version     string
+	HostVersion string
+}
+
 // NumOfCPUCoresResponse describes num of cpu cores present on host.
 type NumOfCPUCoresResponse struct {
 	Response      Response
@@ -0,0 +1,275 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+)
+
+// Version of the v1 API in its OpenAPI document.
+const Version = "1.0.0"
+
+// Schema is the subset of the OpenAPI schema object used by the v1 API.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+// Document is an OpenAPI 3.0 document.
+type Document struct {
+	OpenAPI    string                               `json:"openapi"`
+	Info       Info                                 `json:"info"`
+	Paths      map[string]map[string]*PathOperation `json:"paths"`
+	Components Components                           `json:"components"`
+}
+
+// Info describes the API of a Document.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components are the schemas referenced in a Document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathOperation is an operation in a Document.
+type PathOperation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// RequestBody is the JSON request body of an operation.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a request or response body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+const (
+	contentTypeJSON = "application/json"
+	schemaRefPrefix = "#/components/schemas/"
+)
+
+var (
+	documentOnce sync.Once
+	document     *Document
+)
+
+// OpenAPI returns the OpenAPI document of the v1 API, generated from its Operations.
+func OpenAPI() *Document {
+	documentOnce.Do(func() {
+		document = newDocument(Operations)
+	})
+	return document
+}
+
+func newDocument(operations []Operation) *Document {
+	g := &schemaGenerator{schemas: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: "Azure Container Networking Service", Version: Version},
+		Paths:   map[string]map[string]*PathOperation{},
+	}
+	errorSchema := g.schemaOf(reflect.TypeOf(Error{}))
+
+	for i := range operations {
+		op := &operations[i]
+		pathOp := &PathOperation{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Parameters:  op.Parameters,
+			Responses: map[string]*Response{
+				"default": {Description: "Failure.", Content: jsonContent(errorSchema)},
+			},
+		}
+		if op.RequestBody != nil {
+			pathOp.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.schemaOf(reflect.TypeOf(op.RequestBody)))}
+		}
+		for status, body := range op.Responses {
+			resp := &Response{Description: http.StatusText(status) + "."}
+			if body != nil {
+				resp.Content = jsonContent(g.schemaOf(reflect.TypeOf(body)))
+			}
+			pathOp.Responses[strconv.Itoa(status)] = resp
+		}
+
+		if doc.Paths[op.Path] == nil {
+			doc.Paths[op.Path] = map[string]*PathOperation{}
+		}
+		doc.Paths[op.Path][strings.ToLower(op.Method)] = pathOp
+	}
+
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{contentTypeJSON: {Schema: schema}}
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+	// enums are the values of the string types with a fixed set of values.
+	enums = map[reflect.Type][]string{
+		reflect.TypeOf(cns.IPConfigState("")): ipConfigStates,
+	}
+)
+
+// schemaGenerator generates the schemas of Go types as they are encoded by encoding/json. Named struct
+// types are added to the schemas and referenced.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	switch {
+	case t == rawMessageType:
+		return &Schema{}
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case enums[t] != nil:
+		return &Schema{Type: "string", Enum: enums[t]}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(g.schemaOf(t.Elem()))
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer", Format: intFormat(t)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: intFormat(t), Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// the placeholder ends the recursion of recursive types
+			g.schemas[t.Name()] = &Schema{}
+			*g.schemas[t.Name()] = *g.structSchema(t)
+		}
+		return &Schema{Ref: schemaRefPrefix + t.Name()}
+	}
+	return &Schema{}
+}
+
+// structSchema returns the schema of the struct's fields. The fields of embedded structs are inlined,
+// and the openapi tag of a field marks it as required or sets its format, e.g. `openapi:"required,format=ip"`.
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(field.Type)
+			for n, p := range embedded.Properties {
+				s.Properties[n] = p
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := g.schemaOf(field.Type)
+		for _, option := range strings.Split(field.Tag.Get("openapi"), ",") {
+			switch {
+			case option == "required":
+				s.Required = append(s.Required, name)
+			case strings.HasPrefix(option, "format="):
+				format := *fieldSchema
+				format.Format = strings.TrimPrefix(option, "format=")
+				fieldSchema = &format
+			}
+		}
+		s.Properties[name] = fieldSchema
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// jsonFieldName returns the name of the field in its json tag, and false if it isn't encoded.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" && !field.Anonymous {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	return strings.Split(tag, ",")[0], true
+}
+
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AllOf: []*Schema{s}, Nullable: true}
+	}
+	n := *s
+	n.Nullable = true
+	return &n
+}
+
+func intFormat(t reflect.Type) string {
+	if t.Bits() == 64 || t.Kind() == reflect.Int || t.Kind() == reflect.Uint {
+		return "int64"
+	}
+	return "int32"
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package v1
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDescribesEveryOperation(t *testing.T) {
+	doc := OpenAPI()
+	ids := map[string]bool{}
+	for _, op := range Operations {
+		require.False(t, ids[op.ID], "duplicate operation %s", op.ID)
+		ids[op.ID] = true
+
+		pathOp := doc.Paths[op.Path][strings.ToLower(op.Method)]
+		require.NotNil(t, pathOp, "operation %s is not in the document", op.ID)
+		assert.Equal(t, op.ID, pathOp.OperationID)
+		assert.Contains(t, pathOp.Responses, "default")
+		assert.Len(t, pathOp.Responses, len(op.Responses)+1)
+		assert.Equal(t, op.RequestBody != nil, pathOp.RequestBody != nil)
+	}
+
+	// every referenced schema is a component
+	encoded, err := json.Marshal(doc)
+	require.NoError(t, err)
+	for _, ref := range strings.Split(string(encoded), `"$ref":"`)[1:] {
+		name := strings.TrimPrefix(ref[:strings.Index(ref, `"`)], schemaRefPrefix)
+		assert.Contains(t, doc.Components.Schemas, name)
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	type embedded struct {
+		Embedded string
+	}
+	type resource struct {
+		embedded
+		Name     string `json:"name" openapi:"required"`
+		Address  string `json:",omitempty" openapi:"format=ip"`
+		Count    uint8
+		Children []resource
+		Parent   *resource
+		Labels   map[string]string
+		Raw      json.RawMessage
+		Ignored  string `json:"-"`
+		private  string //nolint:unused,structcheck // unexported fields aren't encoded
+	}
+
+	g := &schemaGenerator{schemas: map[string]*Schema{}}
+	assert.Equal(t, &Schema{Ref: schemaRefPrefix + "resource"}, g.schemaOf(reflect.TypeOf(resource{})))
+
+	s := g.schemas["resource"]
+	require.NotNil(t, s)
+	assert.Equal(t, []string{"name"}, s.Required)
+	assert.ElementsMatch(t, []string{"Embedded", "name", "Address", "Count", "Children", "Parent", "Labels", "Raw"}, keys(s.Properties))
+	assert.Equal(t, "ip", s.Properties["Address"].Format)
+	assert.Equal(t, float(0), s.Properties["Count"].Minimum)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: schemaRefPrefix + "resource"}, Nullable: true}, s.Properties["Children"])
+	assert.Equal(t, &Schema{AllOf: []*Schema{{Ref: schemaRefPrefix + "resource"}}, Nullable: true}, s.Properties["Parent"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}, Nullable: true}, s.Properties["Labels"])
+	assert.Equal(t, &Schema{}, s.Properties["Raw"])
+}
+
+func keys(m map[string]*Schema) []string {
+	var k []string
+	for name := range m {
+		k = append(k, name)
+	}
+	return k
+}
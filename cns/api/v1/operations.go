@@ -0,0 +1,155 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns"
+)
+
+// Parameter is a path or query parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Operation is an operation of the v1 API. The OpenAPI document is generated from the operations,
+// and the requests to them are validated against it.
+type Operation struct {
+	ID      string
+	Method  string
+	Path    string
+	Summary string
+	// Mutating operations change the CNS state, and are only served to authorized peers on a unix socket.
+	Mutating   bool
+	Parameters []Parameter
+	// RequestBody is a value of the type of the request body, nil if the operation has none.
+	RequestBody interface{}
+	// Responses are a value of the type of the response body by status, nil if the response has no body.
+	// Failures are described by an Error.
+	Responses map[int]interface{}
+}
+
+var (
+	idParameter        = Parameter{Name: "id", In: "path", Required: true, Description: "ID of the network container.", Schema: &Schema{Type: "string"}}
+	namespaceParameter = Parameter{Name: "namespace", In: "path", Required: true, Description: "Namespace of the pod.", Schema: &Schema{Type: "string"}}
+	nameParameter      = Parameter{Name: "name", In: "path", Required: true, Description: "Name of the pod.", Schema: &Schema{Type: "string"}}
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+// Operations of the v1 API.
+var Operations = []Operation{
+	{
+		ID:        "getOpenAPIDocument",
+		Method:    http.MethodGet,
+		Path:      OpenAPIPath,
+		Summary:   "Get the OpenAPI document of the v1 API.",
+		Responses: map[int]interface{}{http.StatusOK: map[string]interface{}{}},
+	},
+	{
+		ID:        "listNetworkContainers",
+		Method:    http.MethodGet,
+		Path:      NCsPath,
+		Summary:   "List the network containers programmed on the node.",
+		Responses: map[int]interface{}{http.StatusOK: NetworkContainerList{}},
+	},
+	{
+		ID:         "getNetworkContainer",
+		Method:     http.MethodGet,
+		Path:       NCPath,
+		Summary:    "Get a network container, with the version NMAgent programmed and its IP counts.",
+		Parameters: []Parameter{idParameter},
+		Responses:  map[int]interface{}{http.StatusOK: NetworkContainer{}},
+	},
+	{
+		ID:          "putNetworkContainer",
+		Method:      http.MethodPut,
+		Path:        NCPath,
+		Summary:     "Create or update a network container. The NetworkContainerid of the request defaults to the id in the path.",
+		Mutating:    true,
+		Parameters:  []Parameter{idParameter},
+		RequestBody: cns.CreateNetworkContainerRequest{},
+		Responses: map[int]interface{}{
+			http.StatusOK:      NetworkContainer{},
+			http.StatusCreated: NetworkContainer{},
+		},
+	},
+	{
+		ID:         "deleteNetworkContainer",
+		Method:     http.MethodDelete,
+		Path:       NCPath,
+		Summary:    "Delete a network container.",
+		Mutating:   true,
+		Parameters: []Parameter{idParameter},
+		Responses:  map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		ID:      "listIPAddresses",
+		Method:  http.MethodGet,
+		Path:    IPsPath,
+		Summary: "List a page of the secondary IPs of the network containers, ordered by ID.",
+		Parameters: []Parameter{
+			{
+				Name:        "state",
+				In:          "query",
+				Description: "List only the IPs in any of the states.",
+				Schema:      &Schema{Type: "array", Items: &Schema{Type: "string", Enum: ipConfigStates}},
+			},
+			{
+				Name:        "limit",
+				In:          "query",
+				Description: "Maximum number of IPs in the page.",
+				Schema:      &Schema{Type: "integer", Minimum: float(1), Maximum: float(MaxIPsLimit), Default: DefaultIPsLimit},
+			},
+			{
+				Name:        "continue",
+				In:          "query",
+				Description: "Continue token of the previous page.",
+				Schema:      &Schema{Type: "string"},
+			},
+		},
+		Responses: map[int]interface{}{http.StatusOK: IPAddressList{}},
+	},
+	{
+		ID:         "listPodIPAddresses",
+		Method:     http.MethodGet,
+		Path:       PodIPsPath,
+		Summary:    "List the IPs allocated to a pod.",
+		Parameters: []Parameter{namespaceParameter, nameParameter},
+		Responses:  map[int]interface{}{http.StatusOK: IPAddressList{}},
+	},
+	{
+		ID:          "requestPodIPAddresses",
+		Method:      http.MethodPost,
+		Path:        PodIPsPath,
+		Summary:     "Allocate IPs to a network interface of a pod, or get the ones it already holds.",
+		Mutating:    true,
+		Parameters:  []Parameter{namespaceParameter, nameParameter},
+		RequestBody: PodIPsRequest{},
+		Responses:   map[int]interface{}{http.StatusOK: PodIPs{}},
+	},
+	{
+		ID:       "releasePodIPAddresses",
+		Method:   http.MethodDelete,
+		Path:     PodIPsPath,
+		Summary:  "Release the IPs held by a network interface of a pod. Releasing IPs the pod doesn't hold succeeds.",
+		Mutating: true,
+		Parameters: []Parameter{
+			namespaceParameter,
+			nameParameter,
+			{Name: "interfaceID", In: "query", Required: true, Description: "ID of the network interface of the pod.", Schema: &Schema{Type: "string"}},
+			{Name: "infraContainerID", In: "query", Description: "ID of the infra container of the pod.", Schema: &Schema{Type: "string"}},
+		},
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+}
+
+var ipConfigStates = []string{string(cns.Allocated), string(cns.Available), string(cns.PendingProgramming), string(cns.PendingRelease)}
@@ -0,0 +1,83 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// Package v1 is the contract of the resource-oriented v1 REST API of CNS: its paths, resources and
+// the OpenAPI document describing them, which CNS serves and validates requests against.
+// The action-style API under cns.V2Prefix is still served for compatibility.
+package v1
+
+import (
+	"github.com/Azure/azure-container-networking/cns"
+)
+
+// Paths of the v1 API. Path parameters are in braces.
+const (
+	Prefix          = "/v1"
+	OpenAPIPath     = Prefix + "/openapi.json"
+	NCsPath         = Prefix + "/ncs"
+	NCPath          = Prefix + "/ncs/{id}"
+	IPsPath         = Prefix + "/ips"
+	PodIPsPath      = Prefix + "/pods/{namespace}/{name}/ips"
+	DefaultIPsLimit = 250
+	MaxIPsLimit     = 1000
+)
+
+// NetworkContainer is a network container programmed on the node.
+type NetworkContainer struct {
+	ID   string
+	Type string
+	// Version is the NC version requested by DNC.
+	Version string
+	// ProgrammedVersion is the NC version NMAgent reported as programmed, -1 until NMAgent reports one.
+	// The IPs of later versions are PendingProgramming.
+	ProgrammedVersion string
+	// IPCounts is the count of the NC's secondary IPs in each state.
+	IPCounts map[cns.IPConfigState]int
+}
+
+// NetworkContainerList is the list of the network containers programmed on the node.
+type NetworkContainerList struct {
+	Items []NetworkContainer
+}
+
+// IPAddress is a secondary IP of a network container.
+type IPAddress struct {
+	ID        string
+	NCID      string
+	IPAddress string
+	State     cns.IPConfigState
+	// Pod is the pod the IP is allocated to, if it is Allocated.
+	Pod *Pod `json:",omitempty"`
+}
+
+// Pod identifies the pod, and its network interface, an IP is allocated to.
+type Pod struct {
+	Name             string
+	Namespace        string
+	InterfaceID      string
+	InfraContainerID string
+}
+
+// IPAddressList is a page of IP addresses ordered by ID. If Continue is set, the next page is listed
+// by passing it as the continue parameter.
+type IPAddressList struct {
+	Items    []IPAddress
+	Continue string `json:",omitempty"`
+}
+
+// PodIPsRequest requests IPs for the network interface of a pod.
+type PodIPsRequest struct {
+	InterfaceID      string `openapi:"required"`
+	InfraContainerID string
+	// DesiredIPAddress is allocated to the pod if it is set, instead of any Available IP.
+	DesiredIPAddress string `json:",omitempty" openapi:"format=ip"`
+}
+
+// PodIPs are the IPs allocated to the network interface of a pod, IPv4 first.
+type PodIPs struct {
+	IPs []cns.PodIpInfo
+}
+
+// Error is the body of the responses to failed requests. ReturnCode is the CNS types.ResponseCode
+// of the failure.
+type Error = cns.Response
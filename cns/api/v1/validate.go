@@ -0,0 +1,219 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package v1
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidRequest is wrapped by the errors of requests which don't match the OpenAPI document.
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ValidateRequest validates the parameters and body of the request to the operation against the
+// OpenAPI document. pathParams are the values of the path parameters of the request.
+// The request body is read and replaced, so that it can be decoded once it is valid.
+func ValidateRequest(op *Operation, r *http.Request, pathParams map[string]string) error {
+	doc := OpenAPI()
+
+	query := r.URL.Query()
+	declared := map[string]bool{}
+	for i := range op.Parameters {
+		param := &op.Parameters[i]
+		var values []string
+		switch param.In {
+		case "path":
+			if v, ok := pathParams[param.Name]; ok && v != "" {
+				values = []string{v}
+			}
+		case "query":
+			declared[param.Name] = true
+			values = query[param.Name]
+		}
+		if err := doc.validateParameter(param, values); err != nil {
+			return err
+		}
+	}
+	for name := range query {
+		if !declared[name] {
+			return errors.Wrapf(ErrInvalidRequest, "unknown query parameter %q", name)
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	if r.Body == nil {
+		return errors.Wrap(ErrInvalidRequest, "request body is required")
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read request body")
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return errors.Wrapf(ErrInvalidRequest, "request body is not JSON: %v", err)
+	}
+	schema := doc.Paths[op.Path][strings.ToLower(op.Method)].RequestBody.Content[contentTypeJSON].Schema
+	return doc.validate(schema, value, "body")
+}
+
+// validateParameter validates the values of the parameter in the request. Array parameters may have
+// any number of values, others at most one.
+func (doc *Document) validateParameter(param *Parameter, values []string) error {
+	at := param.In + " parameter " + param.Name
+	if len(values) == 0 {
+		if param.Required {
+			return errors.Wrapf(ErrInvalidRequest, "%s is required", at)
+		}
+		return nil
+	}
+
+	schema := param.Schema
+	if schema.Type != "array" {
+		if len(values) > 1 {
+			return errors.Wrapf(ErrInvalidRequest, "%s must have a single value", at)
+		}
+	} else {
+		schema = schema.Items
+	}
+	for _, v := range values {
+		var value interface{} = v
+		if schema.Type == "integer" || schema.Type == "number" {
+			value = json.Number(v)
+		}
+		if err := doc.validate(schema, value, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate validates the value decoded from JSON against the schema. at locates the value in the request.
+func (doc *Document) validate(schema *Schema, value interface{}, at string) error {
+	if schema.Ref != "" {
+		return doc.validate(doc.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)], value, at)
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return errors.Wrapf(ErrInvalidRequest, "%s must not be null", at)
+	}
+	for _, s := range schema.AllOf {
+		if err := doc.validate(s, value, at); err != nil {
+			return err
+		}
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return errors.Wrapf(ErrInvalidRequest, "%s %s", at, fmt.Sprintf(format, args...))
+	}
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid("must be an object")
+		}
+		for _, name := range schema.Required {
+			if v, ok := obj[name]; !ok || v == "" {
+				return invalid("must have %s", name)
+			}
+		}
+		for name, v := range obj {
+			propSchema, ok := schema.Properties[name]
+			if !ok {
+				propSchema = schema.AdditionalProperties
+			}
+			if propSchema == nil {
+				continue
+			}
+			if err := doc.validate(propSchema, v, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return invalid("must be an array")
+		}
+		for i, v := range arr {
+			if err := doc.validate(schema.Items, v, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			return invalid("must be one of %s", strings.Join(schema.Enum, ", "))
+		}
+		if err := validateFormat(schema.Format, s); err != nil {
+			return invalid("must be %s: %v", schema.Format, err)
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return invalid("must be a %s", schema.Type)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return invalid("must be a %s", schema.Type)
+		}
+		if _, err := n.Int64(); schema.Type == "integer" && err != nil {
+			return invalid("must be an integer")
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return invalid("must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			return invalid("must be at most %v", *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid("must be a boolean")
+		}
+	}
+	return nil
+}
+
+func validateFormat(format, s string) error {
+	switch format {
+	case "ip":
+		if s != "" && net.ParseIP(s) == nil {
+			return errors.New("not an IP address")
+		}
+	case "byte":
+		_, err := base64.StdEncoding.DecodeString(s)
+		return err //nolint:wrapcheck
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err //nolint:wrapcheck
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
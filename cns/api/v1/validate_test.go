@@ -0,0 +1,88 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package v1
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func operation(t *testing.T, id string) *Operation {
+	for i := range Operations {
+		if Operations[i].ID == id {
+			return &Operations[i]
+		}
+	}
+	t.Fatalf("no operation %s", id)
+	return nil
+}
+
+func TestValidateRequest(t *testing.T) {
+	podParams := map[string]string{"namespace": "ns", "name": "pod"}
+	tests := []struct {
+		name    string
+		op      string
+		target  string
+		body    string
+		params  map[string]string
+		wantErr bool
+	}{
+		{name: "list without parameters", op: "listIPAddresses", target: IPsPath},
+		{name: "list with parameters", op: "listIPAddresses", target: IPsPath + "?state=Available&state=Allocated&limit=1000&continue=abc"},
+		{name: "limit under the minimum", op: "listIPAddresses", target: IPsPath + "?limit=0", wantErr: true},
+		{name: "limit over the maximum", op: "listIPAddresses", target: IPsPath + "?limit=1001", wantErr: true},
+		{name: "limit is not an integer", op: "listIPAddresses", target: IPsPath + "?limit=1.5", wantErr: true},
+		{name: "limit with multiple values", op: "listIPAddresses", target: IPsPath + "?limit=1&limit=2", wantErr: true},
+		{name: "unknown state", op: "listIPAddresses", target: IPsPath + "?state=Leaked", wantErr: true},
+		{name: "unknown query parameter", op: "listIPAddresses", target: IPsPath + "?page=1", wantErr: true},
+		{name: "missing path parameter", op: "getNetworkContainer", target: "/v1/ncs/", params: map[string]string{}, wantErr: true},
+		{name: "request", op: "requestPodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, body: `{"InterfaceID":"eth0","DesiredIPAddress":"10.0.0.4"}`},
+		{name: "request without body", op: "requestPodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, wantErr: true},
+		{name: "request body is not JSON", op: "requestPodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, body: `{`, wantErr: true},
+		{name: "request body is not an object", op: "requestPodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, body: `[]`, wantErr: true},
+		{name: "request without required field", op: "requestPodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, body: `{"InfraContainerID":"abc"}`, wantErr: true},
+		{name: "request with invalid IP", op: "requestPodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, body: `{"InterfaceID":"eth0","DesiredIPAddress":"10.0.0"}`, wantErr: true},
+		{name: "request with mistyped field", op: "requestPodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, body: `{"InterfaceID":1}`, wantErr: true},
+		{name: "nc", op: "putNetworkContainer", target: "/v1/ncs/nc", params: map[string]string{"id": "nc"}, body: `{"Version":"1","SecondaryIPConfigs":{"id":{"IPAddress":"10.0.0.4","NCVersion":1}}}`},
+		{name: "nc with mistyped nested field", op: "putNetworkContainer", target: "/v1/ncs/nc", params: map[string]string{"id": "nc"}, body: `{"SecondaryIPConfigs":{"id":{"NCVersion":"1"}}}`, wantErr: true},
+		{name: "release", op: "releasePodIPAddresses", target: "/v1/pods/ns/pod/ips?interfaceID=eth0", params: podParams},
+		{name: "release without interface ID", op: "releasePodIPAddresses", target: "/v1/pods/ns/pod/ips", params: podParams, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			op := operation(t, tt.op)
+			r := httptest.NewRequest(op.Method, tt.target, nil)
+			if tt.body != "" {
+				r = httptest.NewRequest(op.Method, tt.target, strings.NewReader(tt.body))
+			}
+			err := ValidateRequest(op, r, tt.params)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, ErrInvalidRequest), "unexpected error %v", err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.body != "" {
+				// the body is restored to be decoded by the handler
+				body, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.body, string(body))
+			}
+		})
+	}
+}
+
+func TestValidateRequestWithoutBody(t *testing.T) {
+	op := operation(t, "requestPodIPAddresses")
+	r := &http.Request{Method: op.Method, URL: httptest.NewRequest(op.Method, "/v1/pods/ns/pod/ips", nil).URL}
+	assert.True(t, errors.Is(ValidateRequest(op, r, map[string]string{"namespace": "ns", "name": "pod"}), ErrInvalidRequest))
+}
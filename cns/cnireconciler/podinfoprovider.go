@@ -27,20 +27,41 @@ func newCNIPodInfoProvider(exec exec.Interface) (cns.PodInfoByIPProvider, error)
 	}), nil
 }
 
+// NewCNIPodInfoRefresher returns an implementation of cns.PodInfoByIPProvider
+// that execs out to the CNI on every call, so that the PodInfo map follows
+// the endpoints added and removed by the CNI since it was created.
+func NewCNIPodInfoRefresher() cns.PodInfoByIPProvider {
+	return newCNIPodInfoRefresher(exec.New())
+}
+
+func newCNIPodInfoRefresher(exec exec.Interface) cns.PodInfoByIPProvider {
+	return cns.PodInfoByIPProviderFunc(func() (map[string]cns.PodInfo, error) {
+		provider, err := newCNIPodInfoProvider(exec)
+		if err != nil {
+			return nil, err
+		}
+		return provider.PodInfoByIP()
+	})
+}
+
 // cniStateToPodInfoByIP converts an AzureCNIState dumped from a CNI exec
-// into a PodInfo map, using the first endpoint IP as the key in the map.
+// into a PodInfo map, using each endpoint IP as a key in the map so that
+// dual-stack endpoints are found by both their IPv4 and IPv6 address.
 func cniStateToPodInfoByIP(state *api.AzureCNIState) (map[string]cns.PodInfo, error) {
 	podInfoByIP := map[string]cns.PodInfo{}
 	for _, endpoint := range state.ContainerInterfaces {
-		if _, ok := podInfoByIP[endpoint.IPAddresses[0].IP.String()]; ok {
-			return nil, errors.Wrap(cns.ErrDuplicateIP, endpoint.IPAddresses[0].IP.String())
-		}
-		podInfoByIP[endpoint.IPAddresses[0].IP.String()] = cns.NewPodInfo(
+		podInfo := cns.NewPodInfo(
 			endpoint.ContainerID,
 			endpoint.PodEndpointId,
 			endpoint.PodName,
 			endpoint.PodNamespace,
 		)
+		for _, ipAddress := range endpoint.IPAddresses {
+			if _, ok := podInfoByIP[ipAddress.IP.String()]; ok {
+				return nil, errors.Wrap(cns.ErrDuplicateIP, ipAddress.IP.String())
+			}
+			podInfoByIP[ipAddress.IP.String()] = podInfo
+		}
 	}
 	return podInfoByIP, nil
 }
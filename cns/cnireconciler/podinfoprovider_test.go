@@ -59,3 +59,22 @@ func TestNewCNIPodInfoProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCNIPodInfoRefresher(t *testing.T) {
+	exec := testutils.GetFakeExecWithScripts([]testutils.TestCmd{
+		{Cmd: []string{"/opt/cni/bin/azure-vnet"}, Stdout: `{}`},
+		{Cmd: []string{"/opt/cni/bin/azure-vnet"}, Stdout: `{"ContainerInterfaces":{"6e688597-eth0":{"PodName":"tunnelfront-5d96f9b987-65xbn","PodNamespace":"kube-system","PodEndpointID":"6e688597-eth0","ContainerID":"6e688597eafb97c83c84e402cc72b299bfb8aeb02021e4c99307a037352c0bed","IPAddresses":[{"IP":"10.241.0.13","Mask":"//8AAA=="}]}}}`},
+	})
+	refresher := newCNIPodInfoRefresher(exec)
+
+	// the CNI is exec'd again on every call.
+	podInfoByIP, err := refresher.PodInfoByIP()
+	assert.NoError(t, err)
+	assert.Empty(t, podInfoByIP)
+
+	podInfoByIP, err = refresher.PodInfoByIP()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]cns.PodInfo{
+		"10.241.0.13": cns.NewPodInfo("6e688597eafb97c83c84e402cc72b299bfb8aeb02021e4c99307a037352c0bed", "6e688597-eth0", "tunnelfront-5d96f9b987-65xbn", "kube-system"),
+	}, podInfoByIP)
+}
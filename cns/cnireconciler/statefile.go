@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/store"
 )
 
 // WriteObjectToCNIStatefile checks for a file at the CNI statefile path,
@@ -17,9 +18,16 @@ import (
 // path, but the statefile wasn't written by CNI yet. Kubelet will stub an
 // empty file on the host filesystem, crashing older CNI because it doesn't know
 // how to handle empty statefiles.
+//
+// Once the CNI state has been migrated to a bolt store, CNI no longer reads the
+// JSON statefile and bolt initializes empty files itself, so nothing is written.
 func WriteObjectToCNIStatefile() error {
-	filename := "/var/run/azure-vnet.json"
-	return writeObjectToFile(filename)
+	path := "/var/run/azure-vnet"
+	if store.DetectType(path) == store.Bolt {
+		logger.Printf("CNI state is kept in the bolt store %s", path+store.Bolt.FileExtension())
+		return nil
+	}
+	return writeObjectToFile(path + store.JSONFile.FileExtension())
 }
 
 func writeObjectToFile(filename string) error {
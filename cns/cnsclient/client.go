@@ -0,0 +1,363 @@
+package cnsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+)
+
+// RetryOptions sets how often and how quickly a request to CNS which fails with a temporary error is retried.
+type RetryOptions struct {
+	// Attempts is the maximum number of times a request is sent.
+	Attempts int
+	// InitialBackoff is the wait before the first retry, it doubles on each retry up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryOptions are used when the client config has no retry options.
+var DefaultRetryOptions = RetryOptions{
+	Attempts:       3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// Config of a CNS client.
+type Config struct {
+	// URL of the CNS API, defaults to the unix socket at DefaultSocketPath if it exists and to
+	// http://localhost:10090 otherwise. A unix:// url dials the CNS unix socket at the url path.
+	URL string
+	// RequestTimeout bounds each attempt of a request, the context bounds all of them.
+	RequestTimeout time.Duration
+	Retry          RetryOptions
+}
+
+// Client is a client of the CNS API. Requests which fail with a temporary error are retried
+// until they succeed, the retry attempts are exhausted or the context is done.
+// Errors are *CNSClientError, which errors.Is matches against the types.ResponseCode CNS responded with.
+type Client struct {
+	connectionURL string
+	httpc         *http.Client
+	retry         RetryOptions
+}
+
+// New creates a CNS client with the config.
+func New(config Config) (*Client, error) {
+	cnsURL := config.URL
+	if cnsURL == "" {
+		cnsURL = defaultURL(DefaultSocketPath)
+	}
+	if config.Retry.Attempts <= 0 {
+		config.Retry = DefaultRetryOptions
+	}
+
+	httpc := &http.Client{
+		Timeout: config.RequestTimeout,
+	}
+
+	u, err := url.Parse(cnsURL)
+	if err != nil {
+		return nil, &CNSClientError{types.InvalidParameter, errors.Wrapf(err, "failed to parse CNS url %s", cnsURL)}
+	}
+
+	if u.Scheme == "unix" {
+		socketPath := u.Host + u.Path
+		dialer := net.Dialer{}
+		httpc.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		}
+		// the host is ignored when dialing the socket, but is required by the http.Client.
+		cnsURL = unixSocketHostURL
+	}
+
+	return &Client{
+		connectionURL: cnsURL,
+		httpc:         httpc,
+		retry:         config.Retry,
+	}, nil
+}
+
+// defaultURL returns the url of the unix socket if it exists and the url of the CNS TCP listener otherwise.
+func defaultURL(socketPath string) string {
+	if _, err := os.Stat(socketPath); err == nil {
+		return "unix://" + socketPath
+	}
+	return defaultCnsURL
+}
+
+// GetNetworkConfiguration gets the network container configuration of the orchestrator context.
+func (c *Client) GetNetworkConfiguration(ctx context.Context, orchestratorContext []byte) (*cns.GetNetworkContainerResponse, error) {
+	payload := &cns.GetNetworkContainerRequest{
+		OrchestratorContext: orchestratorContext,
+	}
+
+	var resp cns.GetNetworkContainerResponse
+	if err := c.do(ctx, http.MethodPost, cns.GetNetworkContainerByOrchestratorContext, payload, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetNetworkContainerStatus gets the NC version requested by DNC, the one NMAgent reported as programmed
+// and the count of the NC's IPs pending programming. When CNS has no such NC the error matches types.UnknownContainerID.
+func (c *Client) GetNetworkContainerStatus(ctx context.Context, networkContainerID string) (*cns.GetNetworkContainerStatusResponse, error) {
+	payload := &cns.GetNetworkContainerStatusRequest{
+		NetworkContainerid: networkContainerID,
+	}
+
+	var resp cns.GetNetworkContainerStatusResponse
+	if err := c.do(ctx, http.MethodPost, cns.GetNetworkContainerStatus, payload, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateHostNCApipaEndpoint creates an endpoint in APIPA network for host container connectivity.
+func (c *Client) CreateHostNCApipaEndpoint(ctx context.Context, networkContainerID string) (string, error) {
+	payload := &cns.CreateHostNCApipaEndpointRequest{
+		NetworkContainerID: networkContainerID,
+	}
+
+	var resp cns.CreateHostNCApipaEndpointResponse
+	if err := c.do(ctx, http.MethodPost, cns.CreateHostNCApipaEndpointPath, payload, &resp); err != nil {
+		return "", err
+	}
+	return resp.EndpointID, nil
+}
+
+// DeleteHostNCApipaEndpoint deletes the endpoint in APIPA network created for host container connectivity.
+func (c *Client) DeleteHostNCApipaEndpoint(ctx context.Context, networkContainerID string) error {
+	payload := &cns.DeleteHostNCApipaEndpointRequest{
+		NetworkContainerID: networkContainerID,
+	}
+
+	var resp cns.DeleteHostNCApipaEndpointResponse
+	return c.do(ctx, http.MethodPost, cns.DeleteHostNCApipaEndpointPath, payload, &resp)
+}
+
+// RequestIPAddress requests an IP for the pod from CNS. If the request fails, whatever CNS
+// may have allocated to the pod is released.
+// When the pool has no IP for the pod the error matches types.FailedToAllocateIPConfig.
+func (c *Client) RequestIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	var resp cns.IPConfigResponse
+	if err := c.do(ctx, http.MethodPost, cns.RequestIPConfig, ipconfig, &resp); err != nil {
+		if ctx.Err() == nil {
+			if releaseErr := c.ReleaseIPAddress(ctx, ipconfig); releaseErr != nil {
+				log.Errorf("failed to release IP address [%v] after failed add [%v]", releaseErr, err)
+			}
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ReleaseIPAddress releases the IPs held by the pod, releasing IPs the pod doesn't hold succeeds.
+func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) error {
+	log.Printf("Releasing ipconfig %+v", ipconfig)
+
+	var resp cns.Response
+	return c.do(ctx, http.MethodPost, cns.ReleaseIPConfig, ipconfig, &resp)
+}
+
+// GetIPAddressesMatchingStates gets the IPs in any of the states, or none if no state is given.
+func (c *Client) GetIPAddressesMatchingStates(ctx context.Context, stateFilter ...cns.IPConfigState) ([]cns.IPConfigurationStatus, error) {
+	if len(stateFilter) == 0 {
+		return nil, nil
+	}
+
+	return c.GetIPAddresses(ctx, cns.GetIPAddressesRequest{IPConfigStateFilter: stateFilter})
+}
+
+// GetIPAddresses gets the IPs in any of the states of the request which match its other filters,
+// none if no state is given.
+func (c *Client) GetIPAddresses(ctx context.Context, req cns.GetIPAddressesRequest) ([]cns.IPConfigurationStatus, error) {
+	var resp cns.GetIPAddressStatusResponse
+	if err := c.do(ctx, http.MethodPost, cns.GetIPAddresses, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.IPConfigurationStatus, nil
+}
+
+// GetIPStateTransitions returns the IP state transitions recorded by CNS which match the request, oldest first.
+func (c *Client) GetIPStateTransitions(ctx context.Context, req cns.GetIPStateTransitionsRequest) ([]cns.IPStateTransition, error) {
+	var resp cns.GetIPStateTransitionsResponse
+	if err := c.do(ctx, http.MethodPost, cns.GetIPStateTransitions, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.IPStateTransitions, nil
+}
+
+// GetLeakedIPs gets the IPs CNS detected as leaked.
+func (c *Client) GetLeakedIPs(ctx context.Context) ([]cns.LeakedIP, error) {
+	var resp cns.GetLeakedIPsResponse
+	if err := c.do(ctx, http.MethodGet, cns.GetLeakedIPs, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.LeakedIPs, nil
+}
+
+// GetConfigStatus gets the status of the CNS config file and the changes to it which need a restart.
+func (c *Client) GetConfigStatus(ctx context.Context) (cns.ConfigStatus, error) {
+	var resp cns.GetConfigStatusResponse
+	if err := c.do(ctx, http.MethodGet, cns.GetConfigStatus, nil, &resp); err != nil {
+		return cns.ConfigStatus{}, err
+	}
+	return resp.ConfigStatus, nil
+}
+
+// GetPodOrchestratorContext gets the IP config ID held by each pod interface.
+func (c *Client) GetPodOrchestratorContext(ctx context.Context) (map[string]string, error) {
+	var resp cns.GetPodContextResponse
+	if err := c.do(ctx, http.MethodGet, cns.GetPodIPOrchestratorContext, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.PodContext, nil
+}
+
+// GetHTTPServiceData gets all public in-memory struct details for debugging purpose
+func (c *Client) GetHTTPServiceData(ctx context.Context) (*restserver.GetHTTPServiceDataResponse, error) {
+	var resp restserver.GetHTTPServiceDataResponse
+	if err := c.do(ctx, http.MethodGet, cns.GetHTTPRestData, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetDebugBundle writes the tar.gz of the CNS diagnostics to w. It isn't retried, since part of the
+// bundle may have been written when it fails.
+func (c *Client) GetDebugBundle(ctx context.Context, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.connectionURL+cns.GetDebugBundle, nil)
+	if err != nil {
+		return &CNSClientError{types.UnexpectedError, errors.Wrap(err, "failed to create request")}
+	}
+	res, err := c.httpc.Do(req)
+	if err != nil {
+		return &CNSClientError{types.UnreachableHost, errors.Wrapf(err, "GET %s failed", cns.GetDebugBundle)}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return &CNSClientError{types.UnexpectedError, &HTTPStatusError{Path: cns.GetDebugBundle, StatusCode: res.StatusCode}}
+	}
+	if _, err = io.Copy(w, res.Body); err != nil {
+		return &CNSClientError{types.UnexpectedError, errors.Wrapf(err, "failed to read %s response", cns.GetDebugBundle)}
+	}
+	return nil
+}
+
+// GetReadiness returns whether CNS is ready to serve requests, with the result of each readiness check.
+func (c *Client) GetReadiness(ctx context.Context) (*cns.HealthReport, error) {
+	var report cns.HealthReport
+	// CNS responds 503 with the report when it isn't ready
+	if err := c.do(ctx, http.MethodGet, cns.ReadyzPath, nil, &report, http.StatusServiceUnavailable); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// do sends the request to the path and decodes the response into out, retrying while it fails with
+// a temporary error. Statuses other than 200 are errors unless they are in okStatus.
+func (c *Client) do(ctx context.Context, method, path string, payload, out interface{}, okStatus ...int) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return &CNSClientError{types.UnexpectedError, errors.Wrapf(err, "failed to encode %s request", path)}
+		}
+	}
+
+	backoff := c.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.send(ctx, method, path, body, out, okStatus)
+		if err == nil {
+			return nil
+		}
+		if !err.Temporary() || ctx.Err() != nil || attempt >= c.retry.Attempts {
+			log.Errorf("[Azure CNSClient] %s %s failed after %d attempts: %v", method, path, attempt, err)
+			return err
+		}
+
+		log.Printf("[Azure CNSClient] %s %s attempt %d failed, retrying in %s: %v", method, path, attempt, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return &CNSClientError{err.Code, errors.Wrapf(ctx.Err(), "%s cancelled after %d attempts, last error: %v", path, attempt, err.Err)}
+		}
+
+		if backoff *= 2; backoff > c.retry.MaxBackoff {
+			backoff = c.retry.MaxBackoff
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out interface{}, okStatus []int) *CNSClientError {
+	req, err := http.NewRequestWithContext(ctx, method, c.connectionURL+path, bytes.NewReader(body))
+	if err != nil {
+		return &CNSClientError{types.UnexpectedError, errors.Wrap(err, "failed to create request")}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentTypeJSON)
+	}
+
+	res, err := c.httpc.Do(req)
+	if err != nil {
+		// the request failing because the context is done says nothing of whether CNS is reachable
+		code := types.UnreachableHost
+		if ctx.Err() != nil {
+			code = types.UnexpectedError
+		}
+		return &CNSClientError{code, errors.Wrapf(err, "%s %s failed", method, path)}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && !containsStatus(okStatus, res.StatusCode) {
+		return &CNSClientError{types.UnexpectedError, &HTTPStatusError{Path: path, StatusCode: res.StatusCode}}
+	}
+
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return &CNSClientError{types.UnreachableHost, errors.Wrapf(err, "failed to read %s response", path)}
+	}
+	if err = json.Unmarshal(respBody, out); err != nil {
+		return &CNSClientError{types.UnexpectedError, errors.Wrapf(err, "failed to decode %s response", path)}
+	}
+
+	// the response code is either at the top level of the response, or in its Response field.
+	var codes struct {
+		ReturnCode types.ResponseCode
+		Message    string
+		Response   *cns.Response
+	}
+	_ = json.Unmarshal(respBody, &codes)
+	if codes.Response != nil {
+		codes.ReturnCode, codes.Message = codes.Response.ReturnCode, codes.Response.Message
+	}
+	if codes.ReturnCode != types.Success {
+		return &CNSClientError{codes.ReturnCode, errors.New(codes.Message)}
+	}
+	return nil
+}
+
+func containsStatus(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
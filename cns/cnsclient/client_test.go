@@ -0,0 +1,147 @@
+package cnsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryOptions = RetryOptions{Attempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+// newTestServer returns a server which responds to each request with the next of the responses,
+// repeating the last one, and the number of requests it received.
+func newTestServer(t *testing.T, responses ...func(w http.ResponseWriter)) (*Client, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&requests, 1)) - 1
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		responses[i](w)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := New(Config{URL: server.URL, Retry: testRetryOptions})
+	require.NoError(t, err)
+	return client, &requests
+}
+
+func respondStatus(status int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(status)
+	}
+}
+
+func respondCode(code types.ResponseCode) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		_ = json.NewEncoder(w).Encode(cns.IPConfigResponse{
+			PodIpInfo: cns.PodIpInfo{PodIPConfig: cns.IPSubnet{IPAddress: "10.0.0.5", PrefixLength: 24}},
+			Response:  cns.Response{ReturnCode: code, Message: code.String()},
+		})
+	}
+}
+
+func TestClientRetriesTemporaryFailures(t *testing.T) {
+	client, requests := newTestServer(t,
+		respondStatus(http.StatusServiceUnavailable),
+		respondCode(types.IPConfigRequestThrottled),
+		respondCode(types.Success))
+
+	resp, err := client.RequestIPAddress(context.Background(), &cns.IPConfigRequest{PodInterfaceID: "pod-eth0"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", resp.PodIpInfo.PodIPConfig.IPAddress)
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestClientReturnsTypedErrors(t *testing.T) {
+	// the IP request isn't retried, and the release after it succeeds
+	client, requests := newTestServer(t, respondCode(types.FailedToAllocateIPConfig), respondCode(types.Success))
+
+	_, err := client.RequestIPAddress(context.Background(), &cns.IPConfigRequest{PodInterfaceID: "pod-eth0"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, types.FailedToAllocateIPConfig))
+	assert.False(t, errors.Is(err, types.UnreachableHost))
+	assert.Equal(t, types.FailedToAllocateIPConfig, ResponseCode(err))
+	assert.Contains(t, err.Error(), "FailedToAllocateIpConfig")
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+
+	// the last temporary failure is returned once the attempts are exhausted
+	client, requests = newTestServer(t, respondStatus(http.StatusInternalServerError))
+	err = client.ReleaseIPAddress(context.Background(), &cns.IPConfigRequest{PodInterfaceID: "pod-eth0"})
+	statusErr := &HTTPStatusError{}
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+	assert.Equal(t, int32(testRetryOptions.Attempts), atomic.LoadInt32(requests))
+}
+
+func TestClientUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client, err := New(Config{URL: server.URL, Retry: testRetryOptions})
+	require.NoError(t, err)
+
+	_, err = client.GetPodOrchestratorContext(context.Background())
+	assert.True(t, errors.Is(err, types.UnreachableHost))
+	assert.True(t, IsTemporary(err))
+}
+
+func TestClientStopsRetryingWhenContextDone(t *testing.T) {
+	client, requests := newTestServer(t, respondStatus(http.StatusServiceUnavailable))
+	client.retry = RetryOptions{Attempts: 10, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetLeakedIPs(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestClientGetReadinessWhenNotReady(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(cns.HealthReport{Checks: []cns.HealthCheckResult{{Name: "state-restored"}}})
+	})
+
+	report, err := client.GetReadiness(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Healthy)
+	assert.Len(t, report.Checks, 1)
+}
+
+func TestClientGetDebugBundle(t *testing.T) {
+	client, _ := newTestServer(t, func(w http.ResponseWriter) {
+		_, _ = w.Write([]byte("bundle"))
+	})
+	var buf bytes.Buffer
+	require.NoError(t, client.GetDebugBundle(context.Background(), &buf))
+	assert.Equal(t, "bundle", buf.String())
+
+	client, requests := newTestServer(t, respondStatus(http.StatusServiceUnavailable))
+	err := client.GetDebugBundle(context.Background(), &buf)
+	var statusErr *HTTPStatusError
+	assert.True(t, errors.As(err, &statusErr), "got %v", err)
+	assert.Equal(t, int32(1), *requests)
+}
+
+func TestDefaultURLPrefersTheSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "cns.sock")
+	assert.Equal(t, defaultCnsURL, defaultURL(socketPath))
+
+	require.NoError(t, ioutil.WriteFile(socketPath, nil, 0o600))
+	assert.Equal(t, "unix://"+socketPath, defaultURL(socketPath))
+}
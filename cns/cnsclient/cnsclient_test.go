@@ -277,11 +277,38 @@ func TestCNSClientRequestAndRelease(t *testing.T) {
 
 	t.Log(ipaddresses)
 
+	// the allocated IP is reported as leaked when no pod on the node holds it
+	noPods := cns.PodInfoByIPProviderFunc(func() (map[string]cns.PodInfo, error) {
+		return map[string]cns.PodInfo{}, nil
+	})
+	if _, err = svc.DetectIPLeaks(restserver.IPLeakDetectorConfig{}, noPods); err != nil {
+		t.Fatalf("Detect IP leaks failed %+v", err)
+	}
+
+	leakedIPs, err := cnsClient.GetLeakedIPs()
+	if err != nil {
+		t.Fatalf("Get leaked IPs failed %+v", err)
+	}
+
+	if len(leakedIPs) != 1 || leakedIPs[0].IPConfigurationStatus.IPAddress != desiredIpAddress || leakedIPs[0].Released {
+		t.Fatalf("Leaked IPs do not match expected, leaked IPs: %+v", leakedIPs)
+	}
+
 	// release requested IP address, expect success
 	err = cnsClient.ReleaseIPAddress(&cns.IPConfigRequest{DesiredIPAddress: ipaddresses[0].IPAddress, OrchestratorContext: orchestratorContext})
 	if err != nil {
 		t.Fatalf("Expected to not fail when releasing IP reservation found with context: %+v", err)
 	}
+
+	// the allocation and release are recorded in the audit trail for the pod
+	transitions, err := cnsClient.GetIPStateTransitions(cns.GetIPStateTransitionsRequest{PodName: podName, PodNamespace: podNamespace})
+	if err != nil {
+		t.Fatalf("Get IP state transitions failed %+v", err)
+	}
+
+	if len(transitions) != 2 || transitions[0].To != cns.Allocated || transitions[1].To != cns.Available {
+		t.Fatalf("IP state transitions do not match expected, transitions: %+v", transitions)
+	}
 }
 
 func TestCNSClientPodContextApi(t *testing.T) {
@@ -391,3 +418,28 @@ func TestCNSClientDebugAPI(t *testing.T) {
 	t.Logf("PodIPConfigState: %+v", inmemory.HTTPRestServiceData.PodIPConfigState)
 	t.Logf("IPAMPoolMonitor: %+v", inmemory.HTTPRestServiceData.IPAMPoolMonitor)
 }
+
+func TestCNSClientGetReadiness(t *testing.T) {
+	cnsClient, _ := InitCnsClient("", 2*time.Second)
+
+	// the test service has no store and its pool monitor has never reconciled, so CNS isn't ready
+	report, err := cnsClient.GetReadiness()
+	if err != nil {
+		t.Fatalf("GetReadiness failed with %+v", err)
+	}
+
+	if report.Healthy {
+		t.Fatalf("Expected CNS not to be ready, %+v", report)
+	}
+
+	checks := map[string]bool{}
+	for _, check := range report.Checks {
+		checks[check.Name] = check.Healthy
+	}
+	if healthy, ok := checks["state-restored"]; !ok || !healthy {
+		t.Errorf("Expected the state to be restored, %+v", report)
+	}
+	if healthy, ok := checks["ipam-pool-monitor"]; !ok || healthy {
+		t.Errorf("Expected the pool monitor not to have reconciled, %+v", report)
+	}
+}
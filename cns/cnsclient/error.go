@@ -3,6 +3,7 @@ package cnsclient
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/Azure/azure-container-networking/cns/types"
 )
@@ -17,6 +18,61 @@ func (e *CNSClientError) Error() string {
 	return fmt.Sprintf("[Azure CNSClient] Code: %d , Error: %v", e.Code, e.Err)
 }
 
+// Unwrap returns the underlying error.
+func (e *CNSClientError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is the code of the error, so callers can test the code
+// with errors.Is(err, types.FailedToAllocateIPConfig).
+func (e *CNSClientError) Is(target error) bool {
+	code, ok := target.(types.ResponseCode)
+	return ok && code == e.Code
+}
+
+// Temporary returns true if the request may succeed when retried: CNS could not be reached,
+// responded with a 5xx or 429 status, throttled the request, or was shutting down.
+func (e *CNSClientError) Temporary() bool {
+	if e.Code == types.UnreachableHost || e.Code == types.IPConfigRequestThrottled || e.Code == types.ShuttingDown {
+		return true
+	}
+	statusErr := &HTTPStatusError{}
+	return errors.As(e.Err, &statusErr) && statusErr.Temporary()
+}
+
+// HTTPStatusError is the error of a CNSClientError when CNS responds with an unexpected HTTP status.
+type HTTPStatusError struct {
+	Path       string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s invalid http status code: %d", e.Path, e.StatusCode)
+}
+
+// Temporary returns true if the status is 5xx or 429.
+func (e *HTTPStatusError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// ResponseCode returns the code of the error, Success if it is nil or UnexpectedError if it is not a CNSClientError.
+func ResponseCode(err error) types.ResponseCode {
+	if err == nil {
+		return types.Success
+	}
+	e := &CNSClientError{}
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return types.UnexpectedError
+}
+
+// IsTemporary returns true if the error is a CNSClientError for a request which may succeed when retried.
+func IsTemporary(err error) bool {
+	e := &CNSClientError{}
+	return errors.As(err, &e) && e.Temporary()
+}
+
 // IsNotFound tests if the provided error is of type CNSClientError and then
 // further tests if the error code is of type UnknowContainerID
 func IsNotFound(err error) bool {
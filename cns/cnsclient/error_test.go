@@ -44,3 +44,23 @@ func TestIsNotFound(t *testing.T) {
 		})
 	}
 }
+
+func TestCNSClientErrorIs(t *testing.T) {
+	err := errors.Wrap(&CNSClientError{Code: types.FailedToAllocateIPConfig, Err: errors.New("no IPs")}, "add failed")
+
+	if !errors.Is(err, types.FailedToAllocateIPConfig) {
+		t.Errorf("errors.Is(%v, FailedToAllocateIPConfig) = false, want true", err)
+	}
+	if errors.Is(err, types.UnreachableHost) {
+		t.Errorf("errors.Is(%v, UnreachableHost) = true, want false", err)
+	}
+	if got := ResponseCode(err); got != types.FailedToAllocateIPConfig {
+		t.Errorf("ResponseCode() = %v, want %v", got, types.FailedToAllocateIPConfig)
+	}
+	if IsTemporary(err) {
+		t.Errorf("IsTemporary(%v) = true, want false", err)
+	}
+	if !IsTemporary(&CNSClientError{Code: types.UnexpectedError, Err: &HTTPStatusError{StatusCode: 503}}) {
+		t.Errorf("IsTemporary() = false for a 503 status, want true")
+	}
+}
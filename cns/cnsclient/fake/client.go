@@ -0,0 +1,165 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// Package fake has an in-memory fake of the CNS client for the tests of its callers, such as the CNI.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/cnsclient"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+// Operations of the fake client, whose next calls can be set to fail with FailNext.
+const (
+	OpRequestIPAddress        = "RequestIPAddress"
+	OpReleaseIPAddress        = "ReleaseIPAddress"
+	OpGetNetworkConfiguration = "GetNetworkConfiguration"
+)
+
+// Client is a fake of cnsclient.Client which allocates the IPs of its pool to pods by pod interface ID,
+// and fails with the same errors as the CNS client does, e.g. types.FailedToAllocateIPConfig when the pool is exhausted.
+type Client struct {
+	sync.Mutex
+	available      []cns.PodIpInfo
+	allocated      map[string]cns.PodIpInfo
+	networkConfigs map[string]*cns.GetNetworkContainerResponse
+	failures       map[string][]error
+	calls          map[string]int
+}
+
+// NewClient returns a fake client with a pool of the IPs.
+func NewClient(pool ...cns.PodIpInfo) *Client {
+	return &Client{
+		available:      append([]cns.PodIpInfo{}, pool...),
+		allocated:      map[string]cns.PodIpInfo{},
+		networkConfigs: map[string]*cns.GetNetworkContainerResponse{},
+		failures:       map[string][]error{},
+		calls:          map[string]int{},
+	}
+}
+
+// FailNext sets the next calls of the operation to fail with the errors, one call per error.
+func (c *Client) FailNext(op string, errs ...error) {
+	c.Lock()
+	defer c.Unlock()
+	c.failures[op] = append(c.failures[op], errs...)
+}
+
+// SetNetworkConfiguration sets the network configuration returned for the pod.
+func (c *Client) SetNetworkConfiguration(podName, podNamespace string, config *cns.GetNetworkContainerResponse) {
+	c.Lock()
+	defer c.Unlock()
+	c.networkConfigs[podNamespace+"/"+podName] = config
+}
+
+// Allocated returns the IPs allocated by pod interface ID.
+func (c *Client) Allocated() map[string]cns.PodIpInfo {
+	c.Lock()
+	defer c.Unlock()
+	allocated := make(map[string]cns.PodIpInfo, len(c.allocated))
+	for id, podIPInfo := range c.allocated {
+		allocated[id] = podIPInfo
+	}
+	return allocated
+}
+
+// Available returns the number of IPs left in the pool.
+func (c *Client) Available() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.available)
+}
+
+// Calls returns the number of times the operation was called.
+func (c *Client) Calls(op string) int {
+	c.Lock()
+	defer c.Unlock()
+	return c.calls[op]
+}
+
+// RequestIPAddress allocates an IP from the pool to the pod interface, or returns the one it already holds.
+// The desired IP is allocated if it is set.
+func (c *Client) RequestIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.call(ctx, OpRequestIPAddress); err != nil {
+		return nil, err
+	}
+
+	if podIPInfo, ok := c.allocated[ipconfig.PodInterfaceID]; ok {
+		return &cns.IPConfigResponse{PodIpInfo: podIPInfo}, nil
+	}
+
+	for i := range c.available {
+		if ipconfig.DesiredIPAddress != "" && c.available[i].PodIPConfig.IPAddress != ipconfig.DesiredIPAddress {
+			continue
+		}
+		podIPInfo := c.available[i]
+		c.available = append(c.available[:i], c.available[i+1:]...)
+		c.allocated[ipconfig.PodInterfaceID] = podIPInfo
+		return &cns.IPConfigResponse{PodIpInfo: podIPInfo}, nil
+	}
+
+	return nil, &cnsclient.CNSClientError{
+		Code: types.FailedToAllocateIPConfig,
+		Err:  errors.Errorf("AllocateIPConfig failed: no IP available for %s", ipconfig),
+	}
+}
+
+// ReleaseIPAddress returns the IP held by the pod interface to the pool, releasing an IP the pod doesn't hold succeeds.
+func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) error {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.call(ctx, OpReleaseIPAddress); err != nil {
+		return err
+	}
+
+	if podIPInfo, ok := c.allocated[ipconfig.PodInterfaceID]; ok {
+		delete(c.allocated, ipconfig.PodInterfaceID)
+		c.available = append(c.available, podIPInfo)
+	}
+	return nil
+}
+
+// GetNetworkConfiguration returns the network configuration set for the pod of the orchestrator context,
+// or an error for which cnsclient.IsNotFound is true if none is.
+func (c *Client) GetNetworkConfiguration(ctx context.Context, orchestratorContext []byte) (*cns.GetNetworkContainerResponse, error) {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.call(ctx, OpGetNetworkConfiguration); err != nil {
+		return nil, err
+	}
+
+	var podInfo cns.KubernetesPodInfo
+	if err := json.Unmarshal(orchestratorContext, &podInfo); err != nil {
+		return nil, &cnsclient.CNSClientError{Code: types.UnsupportedOrchestratorContext, Err: err}
+	}
+
+	config, ok := c.networkConfigs[podInfo.PodNamespace+"/"+podInfo.PodName]
+	if !ok {
+		return nil, &cnsclient.CNSClientError{
+			Code: types.UnknownContainerID,
+			Err:  errors.Errorf("no network container for pod %s/%s", podInfo.PodNamespace, podInfo.PodName),
+		}
+	}
+	return config, nil
+}
+
+// call records the call of the operation, and returns the error it should fail with.
+func (c *Client) call(ctx context.Context, op string) error {
+	c.calls[op]++
+	if err := ctx.Err(); err != nil {
+		return &cnsclient.CNSClientError{Code: types.UnexpectedError, Err: err}
+	}
+	if failures := c.failures[op]; len(failures) > 0 {
+		c.failures[op] = failures[1:]
+		return failures[0]
+	}
+	return nil
+}
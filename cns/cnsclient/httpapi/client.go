@@ -4,13 +4,14 @@ import (
 	"fmt"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/cnsclient"
 	"github.com/Azure/azure-container-networking/cns/restserver"
-	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/pkg/errors"
 )
 
-// Client implements APIClient interface. Used to update CNS state
+// Client implements APIClient interface. Used to update CNS state.
+// Its errors are *cnsclient.CNSClientError with the code CNS returned.
 type Client struct {
 	RestService *restserver.HTTPRestService
 }
@@ -20,7 +21,10 @@ func (client *Client) CreateOrUpdateNC(ncRequest cns.CreateNetworkContainerReque
 	returnCode := client.RestService.CreateOrUpdateNetworkContainerInternal(ncRequest)
 
 	if returnCode != 0 {
-		return fmt.Errorf("Failed to Create NC request: %+v, errorCode: %d", ncRequest, returnCode)
+		return &cnsclient.CNSClientError{
+			Code: returnCode,
+			Err:  fmt.Errorf("Failed to Create NC request: %+v, errorCode: %d", ncRequest, returnCode),
+		}
 	}
 
 	return nil
@@ -36,7 +40,10 @@ func (client *Client) ReconcileNCState(ncRequest *cns.CreateNetworkContainerRequ
 	returnCode := client.RestService.ReconcileNCState(ncRequest, podInfoByIP, scalar, spec)
 
 	if returnCode != 0 {
-		return fmt.Errorf("Failed to Reconcile ncState: ncRequest %+v, podInfoMap: %+v, errorCode: %d", *ncRequest, podInfoByIP, returnCode)
+		return &cnsclient.CNSClientError{
+			Code: returnCode,
+			Err:  fmt.Errorf("Failed to Reconcile ncState: ncRequest %+v, podInfoMap: %+v, errorCode: %d", *ncRequest, podInfoByIP, returnCode),
+		}
 	}
 
 	return nil
@@ -45,10 +52,10 @@ func (client *Client) ReconcileNCState(ncRequest *cns.CreateNetworkContainerRequ
 func (client *Client) GetNC(req cns.GetNetworkContainerRequest) (cns.GetNetworkContainerResponse, error) {
 	resp, returnCode := client.RestService.GetNetworkContainerInternal(req)
 	if returnCode != 0 {
-		if returnCode == types.UnknownContainerID {
-			return resp, errors.New(returnCode.String())
+		return resp, &cnsclient.CNSClientError{
+			Code: returnCode,
+			Err:  errors.Errorf("failed to get NC, request: %+v, errorCode: %d", req, returnCode),
 		}
-		return resp, errors.Errorf("failed to get NC, request: %+v, errorCode: %d", req, returnCode)
 	}
 
 	return resp, nil
@@ -57,7 +64,10 @@ func (client *Client) GetNC(req cns.GetNetworkContainerRequest) (cns.GetNetworkC
 func (client *Client) DeleteNC(req cns.DeleteNetworkContainerRequest) error {
 	returnCode := client.RestService.DeleteNetworkContainerInternal(req)
 	if returnCode != 0 {
-		return fmt.Errorf("Failed to delete NC, request: %+v, errorCode: %d", req, returnCode)
+		return &cnsclient.CNSClientError{
+			Code: returnCode,
+			Err:  fmt.Errorf("Failed to delete NC, request: %+v, errorCode: %d", req, returnCode),
+		}
 	}
 
 	return nil
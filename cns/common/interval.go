@@ -0,0 +1,65 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Interval is a time.Duration which can be changed, e.g. by a config reload, while loops wait on it.
+type Interval struct {
+	sync.Mutex
+	d time.Duration
+	// changed is closed and replaced when the duration is changed, to wake the loops waiting on it
+	changed chan struct{}
+}
+
+// NewInterval creates an Interval of the duration d.
+func NewInterval(d time.Duration) *Interval {
+	return &Interval{d: d, changed: make(chan struct{})}
+}
+
+// Get returns the current duration of the interval.
+func (i *Interval) Get() time.Duration {
+	d, _ := i.get()
+	return d
+}
+
+func (i *Interval) get() (time.Duration, <-chan struct{}) {
+	i.Lock()
+	defer i.Unlock()
+	return i.d, i.changed
+}
+
+// Set changes the duration of the interval.
+func (i *Interval) Set(d time.Duration) {
+	i.Lock()
+	defer i.Unlock()
+	if d == i.d {
+		return
+	}
+	i.d = d
+	close(i.changed)
+	i.changed = make(chan struct{})
+}
+
+// Tick calls f each time the interval elapses until the ctx is cancelled. When the interval is changed,
+// the wait for the next call restarts with the new duration.
+func (i *Interval) Tick(ctx context.Context, f func()) {
+	for {
+		d, changed := i.get()
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+			f()
+		case <-changed:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
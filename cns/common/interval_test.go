@@ -0,0 +1,45 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalTickRestartsWhenChanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interval := NewInterval(time.Hour)
+	ticks := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		interval.Tick(ctx, func() {
+			select {
+			case ticks <- struct{}{}:
+			default:
+			}
+		})
+		close(done)
+	}()
+
+	interval.Set(time.Millisecond)
+	assert.Equal(t, time.Millisecond, interval.Get())
+	select {
+	case <-ticks:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the changed interval didn't tick")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Tick didn't return when the ctx was cancelled")
+	}
+}
@@ -5,6 +5,7 @@ package common
 
 import (
 	"errors"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns/logger"
 	acn "github.com/Azure/azure-container-networking/common"
@@ -33,13 +34,61 @@ type ServiceAPI interface {
 
 // ServiceConfig specifies common configuration.
 type ServiceConfig struct {
-	Name        string
-	Version     string
-	Listener    *acn.Listener
-	ErrChan     chan<- error
-	Store       store.KeyValueStore
-	ChannelMode string
-	TlsSettings tls.TlsSettings
+	Name         string
+	Version      string
+	Listener     *acn.Listener
+	ErrChan      chan<- error
+	Store        store.KeyValueStore
+	ChannelMode  string
+	TlsSettings  tls.TlsSettings
+	GRPCSettings GRPCSettings
+	// PeerAuthorization restricts the mutating APIs served on a unix socket listener.
+	PeerAuthorization PeerAuthorizationSettings
+	// IPRequestQueue bounds the IP requests waiting for an IP while the pool has none.
+	IPRequestQueue IPRequestQueueSettings
+	// NamespaceIPQuotas limit the IPs allocated to the pods of each namespace, and reserve IPs for them.
+	NamespaceIPQuotas []NamespaceIPQuota
+	// AllocatePendingProgrammingIPs allows IPs to be allocated to pods before NMAgent reports
+	// the NC version which includes them as programmed.
+	AllocatePendingProgrammingIPs bool
+	// RedactedCNSConfig is the CNS config with its secrets redacted, included in debug bundles as JSON.
+	RedactedCNSConfig interface{}
+}
+
+// GRPCSettings configures the gRPC listener for the CNS IPAM APIs.
+type GRPCSettings struct {
+	Enable    bool
+	IPAddress string
+	Port      uint16
+	// Socket is the path of a unix socket to listen on instead of IPAddress:Port.
+	Socket string
+}
+
+// PeerAuthorizationSettings lists the callers allowed to use the mutating IPAM and
+// NC APIs when CNS is listening on a unix socket. A caller is allowed if its UID
+// or the path of its executable is listed. If both lists are empty all callers are allowed.
+// Callers whose credentials can't be read, such as every caller on windows, are rejected.
+// If UnixSocketOnly is set, the mutating APIs are rejected on the TCP listeners.
+type PeerAuthorizationSettings struct {
+	AllowedUIDs     []uint32
+	AllowedBinaries []string
+	UnixSocketOnly  bool
+}
+
+// IPRequestQueueSettings bounds the queue of IP requests waiting for an Available IP.
+// Requests are not queued if MaxLength is 0.
+type IPRequestQueueSettings struct {
+	MaxLength int
+	MaxWait   time.Duration
+}
+
+// NamespaceIPQuota bounds the IPs allocated to the pods of a namespace. At most MaxIPs are allocated
+// to them at once, unless MaxIPs is 0, and ReservedIPs are kept for them which pods of other
+// namespaces can't be allocated. IPs of either family count towards the quota.
+type NamespaceIPQuota struct {
+	Namespace   string
+	MaxIPs      int
+	ReservedIPs int
 }
 
 // NewService creates a new Service object.
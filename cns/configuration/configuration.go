@@ -3,25 +3,42 @@ package configuration
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/store"
 )
 
 const (
 	defaultConfigName = "cns_config.json"
+	// redacted replaces the secrets of a redacted config.
+	redacted = "[REDACTED]"
 )
 
 type CNSConfig struct {
 	ChannelMode                 string
+	ContainerRuntimeSettings    ContainerRuntimeSettings
+	GRPCSettings                GRPCSettings
+	IPLeakDetectionSettings     IPLeakDetectionSettings
+	IPQuotaSettings             IPQuotaSettings
+	IPRequestQueueSettings      IPRequestQueueSettings
 	InitializeFromCNI           bool
+	LogLevel                    string
 	ManagedSettings             ManagedSettings
 	MetricsBindAddress          string
+	NCProgrammingSettings       NCProgrammingSettings
+	PeerAuthorizationSettings   PeerAuthorizationSettings
+	ShutdownSettings            ShutdownSettings
+	StoreGenerations            int
+	StoreType                   store.Type
 	SyncHostNCTimeoutMs         time.Duration
 	SyncHostNCVersionIntervalMs time.Duration
 	TLSCertificatePath          string
@@ -58,6 +75,81 @@ type TelemetrySettings struct {
 	SnapshotIntervalInMins int
 }
 
+type GRPCSettings struct {
+	// Flag to serve the CNS IPAM APIs over gRPC.
+	Enable bool
+	// Address the gRPC listener binds to.
+	IPAddress string
+	// Port the gRPC listener binds to.
+	Port uint16
+	// Path of the unix socket the gRPC listener binds to instead of IPAddress and Port, so that its
+	// mutating APIs can be authorized by the PeerAuthorizationSettings.
+	Socket string
+}
+
+type IPLeakDetectionSettings struct {
+	// Flag to periodically detect Allocated IPs not held by any pod on the node.
+	Enable bool
+	// Interval between leak detection runs.
+	IntervalInSecs int
+	// Flag to release leaked IPs once they have been leaked for the grace period.
+	Release bool
+	// How long an IP must be continuously leaked before it is released.
+	GracePeriodInSecs int
+	// Flag to log the leaked IPs which would be released instead of releasing them.
+	DryRun bool
+}
+
+type IPQuotaSettings struct {
+	// Quotas of the pod namespaces which are limited, or have IPs reserved for them.
+	Namespaces []NamespaceIPQuota
+}
+
+type NamespaceIPQuota struct {
+	// Namespace of the pods the quota applies to.
+	Namespace string
+	// Max number of IPs allocated to the pods of the namespace at once, unlimited if 0.
+	MaxIPs int
+	// Number of IPs kept for the pods of the namespace, which pods of other namespaces can't be allocated.
+	ReservedIPs int
+}
+
+type IPRequestQueueSettings struct {
+	// Flag to queue IP requests while no IPs are available instead of failing them.
+	Enable bool
+	// Max number of IP requests waiting for an IP.
+	MaxLength int
+	// Max time an IP request waits for an IP.
+	MaxWaitInSecs int
+}
+
+type NCProgrammingSettings struct {
+	// Flag to allocate IPs to pods before NMAgent reports the NC version which includes them as programmed.
+	AllocatePendingProgrammingIPs bool
+}
+
+type PeerAuthorizationSettings struct {
+	// UIDs of the processes allowed to call the mutating APIs on the unix socket.
+	AllowedUIDs []uint32
+	// Executable paths of the processes allowed to call the mutating APIs on the unix socket.
+	AllowedBinaries []string
+	// Flag to reject the mutating APIs on the TCP listeners, HTTP and gRPC, so that they are only served
+	// on the unix sockets.
+	UnixSocketOnly bool
+}
+
+type ShutdownSettings struct {
+	// Max time CNS waits on SIGTERM for the IP requests in flight, and to release the IPs of a deleted node.
+	TimeoutInSecs int
+}
+
+type ContainerRuntimeSettings struct {
+	// Runtime CNS creates networks in and attaches network containers to the sandboxes of: docker or cri.
+	Type containerruntime.Type
+	// Endpoint of the CRI runtime, such as unix:///run/containerd/containerd.sock. Defaults to containerd.
+	Endpoint string
+}
+
 type ManagedSettings struct {
 	PrivateEndpoint           string
 	InfrastructureNetworkID   string
@@ -65,21 +157,30 @@ type ManagedSettings struct {
 	NodeSyncIntervalInSeconds int
 }
 
-// This functions reads cns config file and save it in a structure
-func ReadConfig() (CNSConfig, error) {
-	var cnsConfig CNSConfig
-
+// ConfigPath returns the path of the cns config file, from CNS_CONFIGURATION_PATH or next to the executable.
+func ConfigPath() (string, error) {
 	// Check if env set for config path otherwise use default path
 	configpath, found := os.LookupEnv("CNS_CONFIGURATION_PATH")
 	if !found {
 		dir, err := common.GetExecutableDirectory()
 		if err != nil {
 			logger.Errorf("[Configuration] Failed to find exe dir:%v", err)
-			return cnsConfig, err
+			return "", err
 		}
 
 		configpath = filepath.Join(dir, defaultConfigName)
 	}
+	return configpath, nil
+}
+
+// This functions reads cns config file and save it in a structure
+func ReadConfig() (CNSConfig, error) {
+	var cnsConfig CNSConfig
+
+	configpath, err := ConfigPath()
+	if err != nil {
+		return cnsConfig, err
+	}
 
 	logger.Printf("[Configuration] Config path:%s", configpath)
 
@@ -127,16 +228,190 @@ func setManagedSettingDefaults(managedSettings *ManagedSettings) {
 	}
 }
 
+// set gRPC setting defaults
+func setGRPCSettingDefaults(grpcSettings *GRPCSettings) {
+	if grpcSettings.IPAddress == "" {
+		grpcSettings.IPAddress = "localhost"
+	}
+
+	if grpcSettings.Port == 0 {
+		grpcSettings.Port = 10092
+	}
+}
+
+// set IP leak detection setting defaults
+func setIPLeakDetectionSettingDefaults(ipLeakDetectionSettings *IPLeakDetectionSettings) {
+	if ipLeakDetectionSettings.IntervalInSecs == 0 {
+		ipLeakDetectionSettings.IntervalInSecs = 60
+	}
+
+	if ipLeakDetectionSettings.GracePeriodInSecs == 0 {
+		ipLeakDetectionSettings.GracePeriodInSecs = 300
+	}
+}
+
+// set IP request queue setting defaults
+func setIPRequestQueueSettingDefaults(ipRequestQueueSettings *IPRequestQueueSettings) {
+	if ipRequestQueueSettings.MaxLength == 0 {
+		ipRequestQueueSettings.MaxLength = 250
+	}
+
+	if ipRequestQueueSettings.MaxWaitInSecs == 0 {
+		// shorter than the CNI's request timeout, so the CNI gets the throttled response
+		ipRequestQueueSettings.MaxWaitInSecs = 10
+	}
+}
+
+// set shutdown setting defaults
+func setShutdownSettingDefaults(shutdownSettings *ShutdownSettings) {
+	if shutdownSettings.TimeoutInSecs == 0 {
+		// shorter than the default termination grace period of pods, so CNS isn't killed while shutting down
+		shutdownSettings.TimeoutInSecs = 20
+	}
+}
+
 // SetCNSConfigDefaults set default values of CNS config if not specified
 func SetCNSConfigDefaults(config *CNSConfig) {
 	setTelemetrySettingDefaults(&config.TelemetrySettings)
 	setManagedSettingDefaults(&config.ManagedSettings)
+	setGRPCSettingDefaults(&config.GRPCSettings)
+	setIPLeakDetectionSettingDefaults(&config.IPLeakDetectionSettings)
+	setIPRequestQueueSettingDefaults(&config.IPRequestQueueSettings)
+	setShutdownSettingDefaults(&config.ShutdownSettings)
 	if config.ChannelMode == "" {
 		config.ChannelMode = cns.Direct
 	}
 	if config.MetricsBindAddress == "" {
 		config.MetricsBindAddress = ":9090"
 	}
-	config.SyncHostNCVersionIntervalMs = 1000
-	config.SyncHostNCTimeoutMs = 500
+	if config.StoreType == "" {
+		config.StoreType = store.JSONFile
+	}
+	if config.StoreGenerations == 0 {
+		config.StoreGenerations = 3
+	}
+	if config.ContainerRuntimeSettings.Type == "" {
+		config.ContainerRuntimeSettings.Type = containerruntime.Docker
+	}
+	if config.SyncHostNCVersionIntervalMs == 0 {
+		config.SyncHostNCVersionIntervalMs = 1000
+	}
+	if config.SyncHostNCTimeoutMs == 0 {
+		config.SyncHostNCTimeoutMs = 500
+	}
+}
+
+// Validate returns an error if a field of the config, with its defaults set, has an invalid value.
+func (c *CNSConfig) Validate() error {
+	if invalid := c.invalidFields(); len(invalid) > 0 {
+		return invalid[0].err
+	}
+	return nil
+}
+
+// ResetInvalidFields resets the fields of the config, with its defaults set, which have an invalid value to their
+// defaults, and returns the errors of the invalid values.
+func (c *CNSConfig) ResetInvalidFields() []error {
+	invalid := c.invalidFields()
+	errs := make([]error, 0, len(invalid))
+	for _, field := range invalid {
+		field.reset()
+		errs = append(errs, field.err)
+	}
+	SetCNSConfigDefaults(c)
+	return errs
+}
+
+// invalidField is a field of the config with an invalid value, reset clears it so its default is set again.
+type invalidField struct {
+	err   error
+	reset func()
+}
+
+// invalidFields returns the fields of the config, with its defaults set, which have an invalid value.
+func (c *CNSConfig) invalidFields() []invalidField {
+	var invalid []invalidField
+	if c.SyncHostNCVersionIntervalMs <= 0 {
+		invalid = append(invalid, invalidField{
+			err:   fmt.Errorf("SyncHostNCVersionIntervalMs must be positive, got %d", c.SyncHostNCVersionIntervalMs), //nolint:goerr113
+			reset: func() { c.SyncHostNCVersionIntervalMs = 0 },
+		})
+	}
+	if c.SyncHostNCTimeoutMs <= 0 {
+		invalid = append(invalid, invalidField{
+			err:   fmt.Errorf("SyncHostNCTimeoutMs must be positive, got %d", c.SyncHostNCTimeoutMs), //nolint:goerr113
+			reset: func() { c.SyncHostNCTimeoutMs = 0 },
+		})
+	}
+	for _, interval := range []struct {
+		name  string
+		value *int
+	}{
+		{"IPLeakDetectionSettings.IntervalInSecs", &c.IPLeakDetectionSettings.IntervalInSecs},
+		{"IPRequestQueueSettings.MaxWaitInSecs", &c.IPRequestQueueSettings.MaxWaitInSecs},
+		{"ManagedSettings.NodeSyncIntervalInSeconds", &c.ManagedSettings.NodeSyncIntervalInSeconds},
+		{"ShutdownSettings.TimeoutInSecs", &c.ShutdownSettings.TimeoutInSecs},
+		{"TelemetrySettings.HeartBeatIntervalInMins", &c.TelemetrySettings.HeartBeatIntervalInMins},
+		{"TelemetrySettings.SnapshotIntervalInMins", &c.TelemetrySettings.SnapshotIntervalInMins},
+		{"TelemetrySettings.TelemetryBatchIntervalInSecs", &c.TelemetrySettings.TelemetryBatchIntervalInSecs},
+	} {
+		if value := interval.value; *value <= 0 {
+			invalid = append(invalid, invalidField{
+				err:   fmt.Errorf("%s must be positive, got %d", interval.name, *value), //nolint:goerr113
+				reset: func() { *value = 0 },
+			})
+		}
+	}
+	if c.StoreGenerations < 0 {
+		invalid = append(invalid, invalidField{
+			err:   fmt.Errorf("StoreGenerations must not be negative, got %d", c.StoreGenerations), //nolint:goerr113
+			reset: func() { c.StoreGenerations = 0 },
+		})
+	}
+	if _, err := c.GetLogLevel(log.LevelInfo); err != nil {
+		invalid = append(invalid, invalidField{
+			err:   err,
+			reset: func() { c.LogLevel = "" },
+		})
+	}
+	switch c.ContainerRuntimeSettings.Type {
+	case containerruntime.Docker, containerruntime.CRI:
+	default:
+		invalid = append(invalid, invalidField{
+			err: fmt.Errorf("ContainerRuntimeSettings.Type must be %s or %s, got %q", //nolint:goerr113
+				containerruntime.Docker, containerruntime.CRI, c.ContainerRuntimeSettings.Type),
+			reset: func() { c.ContainerRuntimeSettings.Type = "" },
+		})
+	}
+	return invalid
+}
+
+// GetLogLevel returns the log level of the config, or cmdlineLevel if the config doesn't set one.
+func (c *CNSConfig) GetLogLevel(cmdlineLevel int) (int, error) {
+	switch c.LogLevel {
+	case "":
+		return cmdlineLevel, nil
+	case common.OptLogLevelInfo:
+		return log.LevelInfo, nil
+	case common.OptLogLevelDebug:
+		return log.LevelDebug, nil
+	default:
+		return cmdlineLevel, fmt.Errorf("LogLevel must be %q or %q, got %q", common.OptLogLevelInfo, common.OptLogLevelDebug, c.LogLevel) //nolint:goerr113
+	}
+}
+
+// Redacted returns a copy of the config with its secrets replaced, to be shared in diagnostics.
+func (c CNSConfig) Redacted() CNSConfig {
+	for _, secret := range []*string{
+		&c.ManagedSettings.PrivateEndpoint,
+		&c.ManagedSettings.InfrastructureNetworkID,
+		&c.ManagedSettings.NodeID,
+		&c.TLSCertificatePath,
+		&c.TLSSubjectName,
+	} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+	return c
 }
@@ -0,0 +1,181 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+// liveFields are the fields of the config which are applied when the config file is reloaded.
+// The other fields are only applied when CNS is restarted.
+var liveFields = map[string]bool{
+	"LogLevel":                                 true,
+	"SyncHostNCTimeoutMs":                      true,
+	"SyncHostNCVersionIntervalMs":              true,
+	"TelemetrySettings.DisableEvent":           true,
+	"TelemetrySettings.DisableMetric":          true,
+	"TelemetrySettings.DisableTrace":           true,
+	"TelemetrySettings.SnapshotIntervalInMins": true,
+}
+
+// Watcher reloads the cns config file when it changes, and applies the live fields of the new config.
+type Watcher struct {
+	sync.Mutex
+	path  string
+	apply func(CNSConfig)
+	// startup is the config CNS was started with, which the fields needing a restart are compared to
+	startup CNSConfig
+	// current is the config in effect, which the live fields are compared to
+	current CNSConfig
+	// content of the config file when it was last read, nil if it couldn't be
+	content []byte
+	status  cns.ConfigStatus
+}
+
+// NewWatcher creates a Watcher of the config file at path, which CNS was started with the config of.
+// apply is called with each valid new config, to apply its live fields.
+func NewWatcher(path string, config CNSConfig, apply func(CNSConfig)) *Watcher {
+	w := &Watcher{
+		path:    path,
+		apply:   apply,
+		startup: config,
+		current: config,
+		status:  cns.ConfigStatus{Path: path, LoadedAt: time.Now()},
+	}
+	if content, err := ioutil.ReadFile(path); err == nil {
+		w.content = content
+	}
+	return w
+}
+
+// ConfigStatus returns the status of the config file and its reloads.
+func (w *Watcher) ConfigStatus() cns.ConfigStatus {
+	w.Lock()
+	defer w.Unlock()
+	status := w.status
+	status.RestartRequired = append([]string(nil), w.status.RestartRequired...)
+	return status
+}
+
+// RedactedConfig returns the config last loaded from the config file, with its secrets redacted.
+func (w *Watcher) RedactedConfig() interface{} {
+	w.Lock()
+	defer w.Unlock()
+	return w.current.Redacted()
+}
+
+// WatchPeriodically reloads the config file every interval until the ctx is cancelled.
+func (w *Watcher) WatchPeriodically(ctx context.Context, interval time.Duration) {
+	logger.Printf("[Configuration] Watching config file %s for changes every %v", w.path, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.Reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Reload reads the config file and, if it changed and is valid, applies its live fields.
+// Invalid configs are not applied, and are reported in the ConfigStatus until the file is fixed.
+func (w *Watcher) Reload() {
+	w.Lock()
+	content, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		w.content = nil
+		w.failUnlocked(fmt.Errorf("failed to read config file: %w", err))
+		w.Unlock()
+		return
+	}
+	if w.content != nil && bytes.Equal(content, w.content) {
+		w.Unlock()
+		return
+	}
+	w.content = content
+
+	config, err := parseConfig(content)
+	if err != nil {
+		w.failUnlocked(err)
+		w.Unlock()
+		return
+	}
+
+	live, _ := partitionFields(diffFields("", reflect.ValueOf(w.current), reflect.ValueOf(config), nil))
+	_, restartRequired := partitionFields(diffFields("", reflect.ValueOf(w.startup), reflect.ValueOf(config), nil))
+	w.current = config
+	w.status.LoadedAt = time.Now()
+	w.status.LastReloadError = ""
+	w.status.RestartRequired = restartRequired
+	w.Unlock()
+
+	logger.Printf("[Configuration] Reloaded config file %s, applying changed fields %v", w.path, live)
+	if len(restartRequired) > 0 {
+		logger.Printf("[Configuration] Fields %v of the config file differ from the ones CNS was started with and need a restart to be applied",
+			restartRequired)
+	}
+	w.apply(config)
+}
+
+// failUnlocked records why the config file couldn't be reloaded, logging it only once in a row.
+func (w *Watcher) failUnlocked(err error) {
+	if w.status.LastReloadError != err.Error() {
+		logger.Errorf("[Configuration] Failed to reload config file %s, keeping the current config: %v", w.path, err)
+	}
+	w.status.LastReloadError = err.Error()
+}
+
+// parseConfig returns the config in content, with its defaults set, or an error if it is invalid.
+func parseConfig(content []byte) (CNSConfig, error) {
+	var config CNSConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	SetCNSConfigDefaults(&config)
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// diffFields appends the paths of the fields which differ between the structs a and b to diff.
+func diffFields(prefix string, a, b reflect.Value, diff []string) []string {
+	for i := 0; i < a.NumField(); i++ {
+		name := prefix + a.Type().Field(i).Name
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Kind() == reflect.Struct {
+			diff = diffFields(name+".", fa, fb, diff)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			diff = append(diff, name)
+		}
+	}
+	return diff
+}
+
+// partitionFields splits the field paths into the live ones and the ones which need a restart.
+func partitionFields(fields []string) (live, restartRequired []string) {
+	for _, field := range fields {
+		if liveFields[field] {
+			live = append(live, field)
+		} else {
+			restartRequired = append(restartRequired, field)
+		}
+	}
+	return live, restartRequired
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("testlogs", 0, log.TargetStderr, "")
+	os.Exit(m.Run())
+}
+
+func TestValidate(t *testing.T) {
+	var config CNSConfig
+	SetCNSConfigDefaults(&config)
+	require.NoError(t, config.Validate())
+	assert.Equal(t, time.Duration(1000), config.SyncHostNCVersionIntervalMs)
+
+	config.LogLevel = "debug"
+	require.NoError(t, config.Validate())
+	level, _ := config.GetLogLevel(log.LevelInfo)
+	assert.Equal(t, log.LevelDebug, level)
+
+	config.LogLevel = "verbose"
+	assert.Error(t, config.Validate())
+
+	config.LogLevel = ""
+	config.TelemetrySettings.SnapshotIntervalInMins = -1
+	assert.Error(t, config.Validate())
+
+	config.TelemetrySettings.SnapshotIntervalInMins = 60
+	config.ContainerRuntimeSettings.Type = "rkt"
+	assert.Error(t, config.Validate())
+}
+
+func TestResetInvalidFields(t *testing.T) {
+	var config CNSConfig
+	SetCNSConfigDefaults(&config)
+	assert.Empty(t, config.ResetInvalidFields())
+
+	config.ChannelMode = "CRD"
+	config.LogLevel = "verbose"
+	config.SyncHostNCTimeoutMs = -1
+	config.TelemetrySettings.SnapshotIntervalInMins = -1
+	config.ContainerRuntimeSettings.Type = "rkt"
+	assert.Len(t, config.ResetInvalidFields(), 4)
+	require.NoError(t, config.Validate())
+
+	assert.Equal(t, "CRD", config.ChannelMode)
+	assert.Equal(t, "", config.LogLevel)
+	assert.Equal(t, time.Duration(500), config.SyncHostNCTimeoutMs)
+	assert.Equal(t, 60, config.TelemetrySettings.SnapshotIntervalInMins)
+	assert.Equal(t, containerruntime.Docker, config.ContainerRuntimeSettings.Type)
+}
+
+func TestWatcherReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), defaultConfigName)
+	writeConfig := func(content string) {
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+	}
+	writeConfig(`{"ChannelMode": "CRD"}`)
+	startup, err := parseConfig([]byte(`{"ChannelMode": "CRD"}`))
+	require.NoError(t, err)
+
+	var applied []CNSConfig
+	w := NewWatcher(path, startup, func(c CNSConfig) { applied = append(applied, c) })
+
+	// an unchanged file isn't applied again
+	w.Reload()
+	assert.Empty(t, applied)
+
+	writeConfig(`{"ChannelMode": "Managed", "SyncHostNCVersionIntervalMs": 5000, "LogLevel": "debug"}`)
+	w.Reload()
+	require.Len(t, applied, 1)
+	assert.Equal(t, time.Duration(5000), applied[0].SyncHostNCVersionIntervalMs)
+	status := w.ConfigStatus()
+	assert.Equal(t, path, status.Path)
+	assert.Empty(t, status.LastReloadError)
+	assert.Equal(t, []string{"ChannelMode"}, status.RestartRequired)
+
+	// invalid configs are reported and not applied
+	writeConfig(`{"ChannelMode": "Managed", "LogLevel": "verbose"}`)
+	w.Reload()
+	assert.Len(t, applied, 1)
+	assert.Contains(t, w.ConfigStatus().LastReloadError, "LogLevel")
+
+	writeConfig(`{`)
+	w.Reload()
+	assert.Len(t, applied, 1)
+	assert.Contains(t, w.ConfigStatus().LastReloadError, "failed to parse")
+
+	// reverting the fields which need a restart clears them from the status
+	writeConfig(`{"ChannelMode": "CRD"}`)
+	w.Reload()
+	require.Len(t, applied, 2)
+	status = w.ConfigStatus()
+	assert.Empty(t, status.LastReloadError)
+	assert.Empty(t, status.RestartRequired)
+
+	// the redacted config is the reloaded one
+	writeConfig(`{"ChannelMode": "CRD", "ManagedSettings": {"PrivateEndpoint": "https://dnc.example.com"}}`)
+	w.Reload()
+	redactedConfig, ok := w.RedactedConfig().(CNSConfig)
+	require.True(t, ok)
+	assert.Equal(t, redacted, redactedConfig.ManagedSettings.PrivateEndpoint)
+}
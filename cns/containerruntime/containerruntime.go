@@ -0,0 +1,54 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// Package containerruntime abstracts the container runtime of the node, which CNS creates networks in
+// and attaches network containers to the sandboxes of.
+package containerruntime
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Azure/azure-container-networking/cns/imdsclient"
+)
+
+// Type is the kind of container runtime CNS talks to.
+type Type string
+
+const (
+	// Docker talks to the Docker daemon over its HTTP API.
+	Docker Type = "docker"
+	// CRI talks to a CRI runtime, such as containerd, over the CRI gRPC API.
+	CRI Type = "cri"
+)
+
+var (
+	// ErrNotSupported is returned for the operations the container runtime doesn't support.
+	ErrNotSupported = errors.New("not supported by the container runtime")
+	// ErrSandboxNotFound is returned by Sandbox when the runtime has no such container, e.g. once it was deleted.
+	ErrSandboxNotFound = errors.New("sandbox not found")
+)
+
+// NoNetNS is the network namespace passed to the CNI plugin for sandboxes without one,
+// such as the ones of deleted containers.
+const NoNetNS = "none"
+
+// Sandbox is the sandbox of a container, which holds its network namespace.
+type Sandbox struct {
+	// ID of the sandbox, which the CNI plugin keys its endpoints by.
+	ID string
+	// NetNS is the network namespace of the sandbox, as passed to the CNI plugin.
+	NetNS string
+}
+
+// Client is a client of the container runtime of the node.
+type Client interface {
+	// NetworkExists returns nil if the runtime has the network.
+	NetworkExists(networkName string) error
+	// CreateNetwork creates a network on the subnet of the NIC.
+	CreateNetwork(networkName string, nicInfo *imdsclient.InterfaceInfo, options map[string]interface{}) error
+	// DeleteNetwork deletes the network.
+	DeleteNetwork(networkName string) error
+	// Sandbox returns the sandbox of the container, which may be the container itself.
+	Sandbox(ctx context.Context, containerID string) (Sandbox, error)
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// Package criclient is a client of CRI container runtimes, such as containerd, for the nodes without Docker.
+package criclient
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
+	"github.com/Azure/azure-container-networking/cns/imdsclient"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// sandboxInfoKey is the key of the verbose PodSandboxStatus info in which containerd returns the OCI
+// runtime spec of the sandbox.
+const sandboxInfoKey = "info"
+
+var _ containerruntime.Client = (*Client)(nil)
+
+// sandboxInfo describes the fields CNS reads from the verbose sandbox info of containerd.
+type sandboxInfo struct {
+	RuntimeSpec struct {
+		Linux *struct {
+			Namespaces []struct {
+				Type string `json:"type"`
+				Path string `json:"path"`
+			} `json:"namespaces"`
+		} `json:"linux"`
+		Windows *struct {
+			Network *struct {
+				NetworkNamespace string `json:"networkNamespace"`
+			} `json:"network"`
+		} `json:"windows"`
+	} `json:"runtimeSpec"`
+}
+
+// Client talks to a CRI runtime over its gRPC API.
+type Client struct {
+	conn    *grpc.ClientConn
+	runtime runtimeapi.RuntimeServiceClient
+}
+
+// NewClient dials the CRI runtime at the endpoint, such as unix:///run/containerd/containerd.sock, and
+// returns a Client for it. The opts are applied after the ones dialing the endpoint.
+func NewClient(ctx context.Context, endpoint string, opts ...grpc.DialOption) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse CRI endpoint %s", endpoint)
+	}
+	if u.Scheme != endpointScheme {
+		return nil, errors.Errorf("CRI endpoint %s must have the %s scheme", endpoint, endpointScheme)
+	}
+	path := u.Path
+	opts = append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return dial(ctx, path)
+		}),
+	}, opts...)
+	conn, err := grpc.DialContext(ctx, path, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial CRI runtime at %s", endpoint)
+	}
+	return &Client{
+		conn:    conn,
+		runtime: runtimeapi.NewRuntimeServiceClient(conn),
+	}, nil
+}
+
+// Close closes the connection to the CRI runtime.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// NetworkExists fails since CRI runtimes have no networks, the CNI plugin creates them.
+func (c *Client) NetworkExists(networkName string) error {
+	return errors.Wrapf(containerruntime.ErrNotSupported, "CRI runtimes have no network %s", networkName)
+}
+
+// CreateNetwork fails since CRI runtimes have no networks, the CNI plugin creates them.
+func (c *Client) CreateNetwork(networkName string, _ *imdsclient.InterfaceInfo, _ map[string]interface{}) error {
+	return errors.Wrapf(containerruntime.ErrNotSupported, "failed to create network %s", networkName)
+}
+
+// DeleteNetwork fails since CRI runtimes have no networks, the CNI plugin creates them.
+func (c *Client) DeleteNetwork(networkName string) error {
+	return errors.Wrapf(containerruntime.ErrNotSupported, "failed to delete network %s", networkName)
+}
+
+// Sandbox returns the pod sandbox of the container, or the sandbox itself if the containerID is the ID
+// of a pod sandbox.
+func (c *Client) Sandbox(ctx context.Context, containerID string) (containerruntime.Sandbox, error) {
+	sandboxID := containerID
+	containers, err := c.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{
+		Filter: &runtimeapi.ContainerFilter{Id: containerID},
+	})
+	if err != nil {
+		return containerruntime.Sandbox{}, errors.Wrapf(err, "failed to list CRI container %s", containerID)
+	}
+	if len(containers.Containers) > 0 {
+		sandboxID = containers.Containers[0].PodSandboxId
+	}
+
+	resp, err := c.runtime.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{
+		PodSandboxId: sandboxID,
+		Verbose:      true,
+	})
+	if status.Code(err) == codes.NotFound {
+		return containerruntime.Sandbox{}, errors.Wrapf(containerruntime.ErrSandboxNotFound, "CRI container or pod sandbox %s", containerID)
+	}
+	if err != nil {
+		return containerruntime.Sandbox{}, errors.Wrapf(err, "failed to get the status of CRI pod sandbox %s", sandboxID)
+	}
+
+	netNS, err := sandboxNetNS(resp.Info)
+	if err != nil {
+		return containerruntime.Sandbox{}, errors.Wrapf(err, "failed to get the network namespace of CRI pod sandbox %s", sandboxID)
+	}
+	return containerruntime.Sandbox{
+		ID:    resp.Status.Id,
+		NetNS: netNS,
+	}, nil
+}
+
+// sandboxNetNS returns the network namespace of a sandbox from its verbose info: the namespace path on
+// linux, or the HCN namespace ID on windows.
+func sandboxNetNS(info map[string]string) (string, error) {
+	raw, ok := info[sandboxInfoKey]
+	if !ok {
+		return "", errors.New("the runtime returned no sandbox info")
+	}
+	var si sandboxInfo
+	if err := json.Unmarshal([]byte(raw), &si); err != nil {
+		return "", errors.Wrap(err, "failed to parse the sandbox info")
+	}
+	if linux := si.RuntimeSpec.Linux; linux != nil {
+		for _, ns := range linux.Namespaces {
+			if ns.Type == "network" && ns.Path != "" {
+				return ns.Path, nil
+			}
+		}
+	}
+	if windows := si.RuntimeSpec.Windows; windows != nil && windows.Network != nil && windows.Network.NetworkNamespace != "" {
+		return windows.Network.NetworkNamespace, nil
+	}
+	return "", errors.New("the sandbox has no network namespace of its own")
+}
@@ -0,0 +1,22 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// +build linux
+
+package criclient
+
+import (
+	"context"
+	"net"
+)
+
+const (
+	// DefaultEndpoint is the endpoint of containerd.
+	DefaultEndpoint = "unix:///run/containerd/containerd.sock"
+	endpointScheme  = "unix"
+)
+
+func dial(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", path)
+}
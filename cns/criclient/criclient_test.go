@@ -0,0 +1,111 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package criclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// fakeRuntimeService is a CRI runtime with the given containers and the verbose info of its sandboxes.
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	containers      []*runtimeapi.Container
+	sandboxInfoByID map[string]string
+}
+
+func (f *fakeRuntimeService) ListContainers(_ context.Context, req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	resp := &runtimeapi.ListContainersResponse{}
+	for _, c := range f.containers {
+		if req.Filter == nil || req.Filter.Id == "" || req.Filter.Id == c.Id {
+			resp.Containers = append(resp.Containers, c)
+		}
+	}
+	return resp, nil
+}
+
+func (f *fakeRuntimeService) PodSandboxStatus(_ context.Context, req *runtimeapi.PodSandboxStatusRequest) (*runtimeapi.PodSandboxStatusResponse, error) {
+	info, ok := f.sandboxInfoByID[req.PodSandboxId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "pod sandbox %s not found", req.PodSandboxId)
+	}
+	resp := &runtimeapi.PodSandboxStatusResponse{
+		Status: &runtimeapi.PodSandboxStatus{Id: req.PodSandboxId},
+	}
+	if req.Verbose {
+		resp.Info = map[string]string{sandboxInfoKey: info}
+	}
+	return resp, nil
+}
+
+func startFakeCRIServer(t *testing.T, runtime *fakeRuntimeService) *Client {
+	l := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(server, runtime)
+	go func() {
+		_ = server.Serve(l)
+	}()
+	t.Cleanup(server.Stop)
+
+	client, err := NewClient(context.Background(), DefaultEndpoint,
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestSandbox(t *testing.T) {
+	client := startFakeCRIServer(t, &fakeRuntimeService{
+		containers: []*runtimeapi.Container{
+			{Id: "app", PodSandboxId: "linuxpod"},
+		},
+		sandboxInfoByID: map[string]string{
+			"linuxpod":   `{"runtimeSpec":{"linux":{"namespaces":[{"type":"pid"},{"type":"network","path":"/var/run/netns/cni-1234"}]}}}`,
+			"windowspod": `{"runtimeSpec":{"windows":{"network":{"networkNamespace":"9e0a1d5c-7a5b-4ac3-8a7e-2b7d1f8c0e11"}}}}`,
+			"hostpod":    `{"runtimeSpec":{"linux":{"namespaces":[{"type":"pid"}]}}}`,
+		},
+	})
+	ctx := context.Background()
+
+	sandbox, err := client.Sandbox(ctx, "app")
+	require.NoError(t, err)
+	assert.Equal(t, containerruntime.Sandbox{ID: "linuxpod", NetNS: "/var/run/netns/cni-1234"}, sandbox)
+
+	sandbox, err = client.Sandbox(ctx, "linuxpod")
+	require.NoError(t, err)
+	assert.Equal(t, containerruntime.Sandbox{ID: "linuxpod", NetNS: "/var/run/netns/cni-1234"}, sandbox)
+
+	sandbox, err = client.Sandbox(ctx, "windowspod")
+	require.NoError(t, err)
+	assert.Equal(t, containerruntime.Sandbox{ID: "windowspod", NetNS: "9e0a1d5c-7a5b-4ac3-8a7e-2b7d1f8c0e11"}, sandbox)
+
+	_, err = client.Sandbox(ctx, "hostpod")
+	assert.Error(t, err)
+
+	_, err = client.Sandbox(ctx, "missing")
+	assert.True(t, errors.Is(err, containerruntime.ErrSandboxNotFound))
+}
+
+func TestNetworksNotSupported(t *testing.T) {
+	client := startFakeCRIServer(t, &fakeRuntimeService{})
+	assert.True(t, errors.Is(client.NetworkExists("azure"), containerruntime.ErrNotSupported))
+	assert.True(t, errors.Is(client.CreateNetwork("azure", nil, nil), containerruntime.ErrNotSupported))
+	assert.True(t, errors.Is(client.DeleteNetwork("azure"), containerruntime.ErrNotSupported))
+}
+
+func TestNewClientRejectsOtherSchemes(t *testing.T) {
+	_, err := NewClient(context.Background(), "tcp://localhost:2375")
+	assert.Error(t, err)
+}
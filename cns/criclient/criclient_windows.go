@@ -0,0 +1,23 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// +build windows
+
+package criclient
+
+import (
+	"context"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+const (
+	// DefaultEndpoint is the endpoint of containerd.
+	DefaultEndpoint = "npipe:////./pipe/containerd-containerd"
+	endpointScheme  = "npipe"
+)
+
+func dial(ctx context.Context, path string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, path)
+}
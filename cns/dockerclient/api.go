@@ -6,6 +6,7 @@ package dockerclient
 const (
 	createNetworkPath  = "/networks/create"
 	inspectNetworkPath = "/networks/"
+	containersPath     = "/containers/"
 
 	OptDisableSnat = "DisableSNAT"
 )
@@ -30,6 +31,14 @@ type NetworkConfiguration struct {
 	Options  map[string]interface{}
 }
 
+// ContainerInspect describes the fields CNS reads from docker container inspect.
+type ContainerInspect struct {
+	ID    string `json:"Id"`
+	State struct {
+		Pid int
+	}
+}
+
 // DockerErrorResponse defines the error response retunred by docker.
 type DockerErrorResponse struct {
 	message string
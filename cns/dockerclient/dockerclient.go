@@ -5,10 +5,12 @@ package dockerclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/imdsclient"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/common"
@@ -22,6 +24,8 @@ const (
 	bridgeMode                 = "bridge"
 )
 
+var _ containerruntime.Client = (*DockerClient)(nil)
+
 // DockerClient specifies a client to connect to docker.
 type DockerClient struct {
 	connectionURL string
@@ -196,3 +200,38 @@ func (dockerClient *DockerClient) DeleteNetwork(networkName string) error {
 	return fmt.Errorf("[Azure CNS] Unknown return code from docker delete network %v: ret = %d",
 		networkName, res.StatusCode)
 }
+
+// Sandbox returns the container itself as its sandbox, with the network namespace of its process.
+func (dockerClient *DockerClient) Sandbox(ctx context.Context, containerID string) (containerruntime.Sandbox, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dockerClient.connectionURL+containersPath+containerID+"/json", nil)
+	if err != nil {
+		return containerruntime.Sandbox{}, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Errorf("[Azure CNS] Error received from http Get for docker container inspect %v %v", containerID, err.Error())
+		return containerruntime.Sandbox{}, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return containerruntime.Sandbox{}, fmt.Errorf("[Azure CNS] Docker container %v: %w", containerID, containerruntime.ErrSandboxNotFound)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return containerruntime.Sandbox{}, fmt.Errorf("[Azure CNS] Unknown return code from docker container inspect %v: ret = %d",
+			containerID, res.StatusCode)
+	}
+
+	var container ContainerInspect
+	if err = json.NewDecoder(res.Body).Decode(&container); err != nil {
+		return containerruntime.Sandbox{}, err
+	}
+
+	return containerruntime.Sandbox{
+		ID:    containerID,
+		NetNS: netNS(container.State.Pid),
+	}, nil
+}
@@ -5,6 +5,13 @@
 
 package dockerclient
 
+import "fmt"
+
 const (
 	defaultNetworkPlugin = "azure-vnet"
 )
+
+// netNS returns the network namespace of the container process.
+func netNS(pid int) string {
+	return fmt.Sprintf("/proc/%d/ns/net", pid)
+}
@@ -5,6 +5,13 @@
 
 package dockerclient
 
+import "github.com/Azure/azure-container-networking/cns/containerruntime"
+
 const (
 	defaultNetworkPlugin = "l2tunnel"
 )
+
+// netNS returns the network namespace of docker containers, which the CNI plugin finds itself on windows.
+func netNS(int) string {
+	return containerruntime.NoNetNS
+}
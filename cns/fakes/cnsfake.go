@@ -170,6 +170,8 @@ var _ cns.HTTPService = (*HTTPServiceFake)(nil)
 type HTTPServiceFake struct {
 	IPStateManager IPStateManager
 	PoolMonitor    cns.IPAMPoolMonitor
+	// UnallocatedReservedIPCount is the count of IPs reserved for namespaces which are not allocated to their pods.
+	UnallocatedReservedIPCount int
 }
 
 func NewHTTPServiceFake() *HTTPServiceFake {
@@ -206,11 +208,11 @@ func (fake *HTTPServiceFake) SetNumberOfAllocatedIPs(desiredAllocatedIPCount int
 	return nil
 }
 
-func (fake *HTTPServiceFake) SendNCSnapShotPeriodically(context.Context, int) {}
+func (fake *HTTPServiceFake) SendNCSnapShotPeriodically(context.Context, *common.Interval) {}
 
 func (fake *HTTPServiceFake) SetNodeOrchestrator(*cns.SetOrchestratorTypeRequest) {}
 
-func (fake *HTTPServiceFake) SyncNodeStatus(string, string, string, json.RawMessage) (types.ResponseCode, string) {
+func (fake *HTTPServiceFake) SyncNodeStatus(context.Context, string, string, string, json.RawMessage) (types.ResponseCode, string) {
 	return 0, ""
 }
 
@@ -249,6 +251,10 @@ func (fake *HTTPServiceFake) GetPendingReleaseIPConfigs() []cns.IPConfigurationS
 	return ipconfigs
 }
 
+func (fake *HTTPServiceFake) GetUnallocatedReservedIPCount() int {
+	return fake.UnallocatedReservedIPCount
+}
+
 // Return union of all state maps
 func (fake *HTTPServiceFake) GetPodIPConfigState() map[string]cns.IPConfigurationStatus {
 	ipconfigs := make(map[string]cns.IPConfigurationStatus)
@@ -2,6 +2,7 @@ package fakes
 
 import (
 	"context"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
@@ -12,6 +13,9 @@ type IPAMPoolMonitorFake struct {
 	FakeMaximumIps       int
 	FakeIpsNotInUseCount int
 	FakecachedNNC        v1alpha.NodeNetworkConfig
+	FakeLastReconcile    time.Time
+	// ReleasedUnallocatedIPs is set when ReleaseUnallocatedIPs is called
+	ReleasedUnallocatedIPs bool
 }
 
 func (ipm *IPAMPoolMonitorFake) Start(ctx context.Context, poolMonitorRefreshMilliseconds int) error {
@@ -24,11 +28,17 @@ func (ipm *IPAMPoolMonitorFake) Reconcile() error {
 	return nil
 }
 
+func (ipm *IPAMPoolMonitorFake) ReleaseUnallocatedIPs(context.Context) error {
+	ipm.ReleasedUnallocatedIPs = true
+	return nil
+}
+
 func (ipm *IPAMPoolMonitorFake) GetStateSnapshot() cns.IpamPoolMonitorStateSnapshot {
 	return cns.IpamPoolMonitorStateSnapshot{
 		MinimumFreeIps:           int64(ipm.FakeMinimumIps),
 		MaximumFreeIps:           int64(ipm.FakeMaximumIps),
 		UpdatingIpsNotInUseCount: ipm.FakeIpsNotInUseCount,
 		CachedNNC:                ipm.FakecachedNNC,
+		LastReconcileTime:        ipm.FakeLastReconcile,
 	}
 }
@@ -3,6 +3,13 @@
 
 package fakes
 
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns/nmagentclient"
+)
+
 // NMAgentClientTest can be used to query to VM Host info.
 type NMAgentClientTest struct {
 }
@@ -12,11 +19,36 @@ func NewFakeNMAgentClient() *NMAgentClientTest {
 	return &NMAgentClientTest{}
 }
 
+// JoinNetwork is mock implementation to join a network.
+func (fake *NMAgentClientTest) JoinNetwork(context.Context, string, string) (*nmagentclient.Response, error) {
+	return &nmagentclient.Response{StatusCode: http.StatusOK}, nil
+}
+
+// PublishNetworkContainer is mock implementation to publish a network container.
+func (fake *NMAgentClientTest) PublishNetworkContainer(context.Context, string, string, []byte) (*nmagentclient.Response, error) {
+	return &nmagentclient.Response{StatusCode: http.StatusOK}, nil
+}
+
+// UnpublishNetworkContainer is mock implementation to unpublish a network container.
+func (fake *NMAgentClientTest) UnpublishNetworkContainer(context.Context, string, string) (*nmagentclient.Response, error) {
+	return &nmagentclient.Response{StatusCode: http.StatusOK}, nil
+}
+
+// GetNetworkContainerVersion is mock implementation to return version 0 of the network container.
+func (fake *NMAgentClientTest) GetNetworkContainerVersion(_ context.Context, ncID, _ string) (*nmagentclient.NMANetworkContainerResponse, error) {
+	return &nmagentclient.NMANetworkContainerResponse{ResponseCode: "200", NetworkContainerID: ncID, Version: "0"}, nil
+}
+
+// GetNmAgentSupportedApis is mock implementation to return no supported apis.
+func (fake *NMAgentClientTest) GetNmAgentSupportedApis(context.Context, string) ([]string, error) {
+	return []string{}, nil
+}
+
 // GetNcVersionListWithOutToken is mock implementation to return nc version list.
-func (nmagentclient *NMAgentClientTest) GetNcVersionListWithOutToken(ncNeedUpdateList []string) map[string]int {
+func (fake *NMAgentClientTest) GetNcVersionListWithOutToken(_ context.Context, ncNeedUpdateList []string) (map[string]int, error) {
 	ncVersionList := make(map[string]int)
 	for _, ncID := range ncNeedUpdateList {
 		ncVersionList[ncID] = 0
 	}
-	return ncVersionList
+	return ncVersionList, nil
 }
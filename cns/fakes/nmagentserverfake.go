@@ -0,0 +1,235 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package fakes
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns/nmagentclient"
+	"github.com/Azure/azure-container-networking/common"
+)
+
+// URLs of the fake NMAgent in the form DNC hands them to CNS.
+const (
+	joinNetworkURLFmt               = "http://%s/joinedVirtualNetworks/%s/api-version/1"
+	publishNetworkContainerURLFmt   = "http://%s/interfaces/%s/networkContainers/%s/authenticationToken/%s/api-version/1"
+	unpublishNetworkContainerURLFmt = publishNetworkContainerURLFmt + "/method/DELETE"
+)
+
+// NMAgentServerFake is a fake NMAgent served over HTTP. It serves the join network, publish and
+// unpublish NC, NC version, NC version list and supported APIs endpoints at the wireserver URLs, or
+// at the URL paths for the same types, and the next responses of each operation can be set to fail.
+type NMAgentServerFake struct {
+	*httptest.Server
+	mu             sync.Mutex
+	joinedNetworks map[string]bool
+	// NC ID is key, the NC version programmed
+	ncVersions map[string]string
+	// NC ID is key, the body of the publish request
+	publishedNCs  map[string][]byte
+	supportedApis []string
+	// operation is key, the statuses of its next responses
+	failures map[string][]int
+	// operation is key, the IDs of its requests in order
+	requestIDs map[string][]string
+}
+
+// NewNMAgentServerFake starts a fake NMAgent, which is closed with Close.
+func NewNMAgentServerFake() *NMAgentServerFake {
+	fake := &NMAgentServerFake{
+		joinedNetworks: map[string]bool{},
+		ncVersions:     map[string]string{},
+		publishedNCs:   map[string][]byte{},
+		failures:       map[string][]int{},
+		requestIDs:     map[string][]string{},
+	}
+	fake.Server = httptest.NewServer(fake)
+	return fake
+}
+
+// Host returns the host:port of the fake, to use in place of the wireserver IP.
+func (fake *NMAgentServerFake) Host() string {
+	u, _ := url.Parse(fake.URL)
+	return u.Host
+}
+
+// JoinNetworkURL returns the URL to join the network.
+func (fake *NMAgentServerFake) JoinNetworkURL(networkID string) string {
+	return fmt.Sprintf(joinNetworkURLFmt, fake.Host(), networkID)
+}
+
+// PublishNetworkContainerURL returns the URL to publish the NC.
+func (fake *NMAgentServerFake) PublishNetworkContainerURL(interfaceID, ncID, authToken string) string {
+	return fmt.Sprintf(publishNetworkContainerURLFmt, fake.Host(), interfaceID, ncID, authToken)
+}
+
+// UnpublishNetworkContainerURL returns the URL to unpublish the NC.
+func (fake *NMAgentServerFake) UnpublishNetworkContainerURL(interfaceID, ncID, authToken string) string {
+	return fmt.Sprintf(unpublishNetworkContainerURLFmt, fake.Host(), interfaceID, ncID, authToken)
+}
+
+// FailNext fails the next requests of the operation, one with each status.
+func (fake *NMAgentServerFake) FailNext(op string, statusCodes ...int) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	fake.failures[op] = append(fake.failures[op], statusCodes...)
+}
+
+// SetNCVersion sets the version of the NC NMAgent has programmed.
+func (fake *NMAgentServerFake) SetNCVersion(ncID, version string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	fake.ncVersions[ncID] = version
+}
+
+// RemoveNC forgets the NC as if it was unpublished.
+func (fake *NMAgentServerFake) RemoveNC(ncID string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	delete(fake.publishedNCs, ncID)
+	delete(fake.ncVersions, ncID)
+}
+
+// SetSupportedApis sets the APIs NMAgent supports.
+func (fake *NMAgentServerFake) SetSupportedApis(supportedApis ...string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	fake.supportedApis = supportedApis
+}
+
+// IsNetworkJoined returns true if the network was joined.
+func (fake *NMAgentServerFake) IsNetworkJoined(networkID string) bool {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	return fake.joinedNetworks[networkID]
+}
+
+// PublishedNC returns the body the NC was published with, and false if it isn't published.
+func (fake *NMAgentServerFake) PublishedNC(ncID string) ([]byte, bool) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	body, ok := fake.publishedNCs[ncID]
+	return body, ok
+}
+
+// RequestIDs returns the IDs of the requests of the operation in order, including the failed ones.
+func (fake *NMAgentServerFake) RequestIDs(op string) []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	return append([]string{}, fake.requestIDs[op]...)
+}
+
+func (fake *NMAgentServerFake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	op, method, id := route(r)
+	if op == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != method {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	fake.requestIDs[op] = append(fake.requestIDs[op], r.Header.Get(nmagentclient.RequestIDHeader))
+	if failures := fake.failures[op]; len(failures) > 0 {
+		fake.failures[op] = failures[1:]
+		w.WriteHeader(failures[0])
+		return
+	}
+
+	switch op {
+	case nmagentclient.OpJoinNetwork:
+		fake.joinedNetworks[id] = true
+		writeJSON(w, map[string]string{"httpStatusCode": "200"})
+
+	case nmagentclient.OpPublishNetworkContainer:
+		fake.publishedNCs[id] = body
+		if _, ok := fake.ncVersions[id]; !ok {
+			fake.ncVersions[id] = "0"
+		}
+		writeJSON(w, map[string]string{"httpStatusCode": "200"})
+
+	case nmagentclient.OpUnpublishNetworkContainer:
+		delete(fake.publishedNCs, id)
+		delete(fake.ncVersions, id)
+		writeJSON(w, map[string]string{"httpStatusCode": "200"})
+
+	case nmagentclient.OpGetNetworkContainerVersion:
+		version, ok := fake.ncVersions[id]
+		if !ok {
+			writeJSON(w, nmagentclient.NMANetworkContainerResponse{ResponseCode: "404", NetworkContainerID: id})
+			return
+		}
+		writeJSON(w, nmagentclient.NMANetworkContainerResponse{ResponseCode: "200", NetworkContainerID: id, Version: version})
+
+	case nmagentclient.OpGetNcVersionListWithOutToken:
+		list := nmagentclient.NMANetworkContainerListResponse{ResponseCode: "200", Containers: []nmagentclient.ContainerInfo{}}
+		for ncID, version := range fake.ncVersions {
+			list.Containers = append(list.Containers, nmagentclient.ContainerInfo{NetworkContainerID: ncID, Version: version})
+		}
+		writeJSON(w, list)
+
+	case nmagentclient.OpGetNmAgentSupportedApis:
+		output, _ := xml.Marshal(nmagentclient.NMAgentSupportedApisResponseXML{SupportedApis: fake.supportedApis})
+		w.Header().Set(common.ContentType, "application/xml")
+		_, _ = w.Write(output)
+	}
+}
+
+// route returns the operation of the request, its method, and the network or NC ID it is for.
+func route(r *http.Request) (op, method, id string) {
+	target := r.URL.Query().Get("type")
+	if target == "" {
+		target = strings.TrimPrefix(r.URL.Path, "/")
+	}
+	segments := strings.Split(strings.TrimPrefix(target, "NetworkManagement/"), "/")
+
+	switch {
+	case segments[0] == "GetSupportedApis":
+		return nmagentclient.OpGetNmAgentSupportedApis, http.MethodGet, ""
+	case segments[0] == "joinedVirtualNetworks" && len(segments) > 1:
+		return nmagentclient.OpJoinNetwork, http.MethodPost, segments[1]
+	case segments[0] == "interfaces" && len(segments) > 1 && segments[1] == "api-version":
+		return nmagentclient.OpGetNcVersionListWithOutToken, http.MethodGet, ""
+	case segments[0] == "interfaces" && len(segments) > 4 && segments[2] == "networkContainers":
+		switch {
+		case segments[4] == "version":
+			return nmagentclient.OpGetNetworkContainerVersion, http.MethodGet, segments[3]
+		case strings.HasSuffix(target, "/method/DELETE"):
+			return nmagentclient.OpUnpublishNetworkContainer, http.MethodPost, segments[3]
+		default:
+			return nmagentclient.OpPublishNetworkContainer, http.MethodPost, segments[3]
+		}
+	}
+	return "", "", ""
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set(common.ContentType, common.JsonContent)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -1,6 +1,11 @@
 package filter
 
-import "github.com/Azure/azure-container-networking/cns"
+import (
+	"net"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+)
 
 type IPConfigStatePredicate func(ipconfig cns.IPConfigurationStatus) bool
 
@@ -67,3 +72,73 @@ func PredicatesForStates(states ...cns.IPConfigState) []IPConfigStatePredicate {
 	}
 	return predicates
 }
+
+// NCID returns a predicate matching the IPs of the NC.
+func NCID(ncID string) IPConfigStatePredicate {
+	return func(ipconfig cns.IPConfigurationStatus) bool {
+		return ipconfig.NCID == ncID
+	}
+}
+
+// PodNamespace returns a predicate matching the IPs of the pods in the namespace.
+func PodNamespace(namespace string) IPConfigStatePredicate {
+	return func(ipconfig cns.IPConfigurationStatus) bool {
+		return ipconfig.PodInfo != nil && ipconfig.PodInfo.Namespace() == namespace
+	}
+}
+
+// PodName returns a predicate matching the IPs of the pods with the name, in any namespace.
+func PodName(name string) IPConfigStatePredicate {
+	return func(ipconfig cns.IPConfigurationStatus) bool {
+		return ipconfig.PodInfo != nil && ipconfig.PodInfo.Name() == name
+	}
+}
+
+// IPInCIDR returns a predicate matching the IPs in the CIDR.
+func IPInCIDR(cidr *net.IPNet) IPConfigStatePredicate {
+	return func(ipconfig cns.IPConfigurationStatus) bool {
+		ip := net.ParseIP(ipconfig.IPAddress)
+		return ip != nil && cidr.Contains(ip)
+	}
+}
+
+// StateChangedBefore returns a predicate matching the IPs which have been in their State since before t.
+// IPs which don't know when they changed State don't match.
+func StateChangedBefore(t time.Time) IPConfigStatePredicate {
+	return func(ipconfig cns.IPConfigurationStatus) bool {
+		return !ipconfig.StateChangedAt.IsZero() && ipconfig.StateChangedAt.Before(t)
+	}
+}
+
+// Any returns a predicate matching the IPs matched by any of the predicates, none if there are no predicates.
+func Any(predicates ...IPConfigStatePredicate) IPConfigStatePredicate {
+	return func(ipconfig cns.IPConfigurationStatus) bool {
+		return matchesAnyIPConfigState(ipconfig, predicates...)
+	}
+}
+
+// All returns a predicate matching the IPs matched by every predicate, all if there are no predicates.
+func All(predicates ...IPConfigStatePredicate) IPConfigStatePredicate {
+	return func(ipconfig cns.IPConfigurationStatus) bool {
+		for _, p := range predicates {
+			if !p(ipconfig) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchAllIPConfigStates filters the passed IPConfigurationStatus map
+// according to the passed predicates and returns the values matching every one.
+func MatchAllIPConfigStates(in map[string]cns.IPConfigurationStatus, predicates ...IPConfigStatePredicate) []cns.IPConfigurationStatus {
+	out := []cns.IPConfigurationStatus{}
+
+	matchesAll := All(predicates...)
+	for _, v := range in {
+		if matchesAll(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
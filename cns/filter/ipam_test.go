@@ -1,8 +1,10 @@
 package filter
 
 import (
+	"net"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/stretchr/testify/assert"
@@ -66,3 +68,66 @@ func TestMatchAnyIPConfigState(t *testing.T) {
 		assert.Equal(t, expected, filtered)
 	}
 }
+
+func TestPredicates(t *testing.T) {
+	now := time.Now()
+	ipconfig := cns.IPConfigurationStatus{
+		ID:             "allocated",
+		NCID:           "nc1",
+		IPAddress:      "10.0.0.4",
+		State:          cns.Allocated,
+		PodInfo:        cns.NewPodInfo("infra", "web-eth0", "web", "default"),
+		StateChangedAt: now.Add(-time.Hour),
+	}
+	_, cidr, _ := net.ParseCIDR("10.0.0.0/30")
+	_, otherCIDR, _ := net.ParseCIDR("10.0.0.4/30")
+
+	tests := []struct {
+		name      string
+		predicate IPConfigStatePredicate
+		want      bool
+	}{
+		{name: "NC ID", predicate: NCID("nc1"), want: true},
+		{name: "other NC ID", predicate: NCID("nc2"), want: false},
+		{name: "pod namespace", predicate: PodNamespace("default"), want: true},
+		{name: "other pod namespace", predicate: PodNamespace("kube-system"), want: false},
+		{name: "pod name", predicate: PodName("web"), want: true},
+		{name: "other pod name", predicate: PodName("db"), want: false},
+		{name: "in CIDR", predicate: IPInCIDR(otherCIDR), want: true},
+		{name: "not in CIDR", predicate: IPInCIDR(cidr), want: false},
+		{name: "state changed before", predicate: StateChangedBefore(now.Add(-time.Minute)), want: true},
+		{name: "state changed after", predicate: StateChangedBefore(now.Add(-2 * time.Hour)), want: false},
+		{name: "all", predicate: All(NCID("nc1"), StateAllocated), want: true},
+		{name: "not all", predicate: All(NCID("nc1"), StateAvailable), want: false},
+		{name: "all of none", predicate: All(), want: true},
+		{name: "any", predicate: Any(StateAvailable, StateAllocated), want: true},
+		{name: "not any", predicate: Any(StateAvailable, StatePendingRelease), want: false},
+		{name: "any of none", predicate: Any(), want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.predicate(ipconfig))
+		})
+	}
+
+	// the pod predicates don't match IPs without a pod, nor the time predicate IPs which never changed State
+	available := cns.IPConfigurationStatus{NCID: "nc1", IPAddress: "10.0.0.1", State: cns.Available}
+	assert.False(t, PodNamespace("")(available))
+	assert.False(t, PodName("")(available))
+	assert.False(t, StateChangedBefore(now)(available))
+}
+
+func TestMatchAllIPConfigStates(t *testing.T) {
+	m := map[string]cns.IPConfigurationStatus{}
+	for i := range testStatuses {
+		status := testStatuses[i].Status
+		status.NCID = "nc" + strconv.Itoa(i%2)
+		m[strconv.Itoa(i)] = status
+	}
+
+	assert.Len(t, MatchAllIPConfigStates(m), len(testStatuses))
+	assert.ElementsMatch(t, []cns.IPConfigurationStatus{m["0"], m["2"]}, MatchAllIPConfigStates(m, NCID("nc0")))
+	assert.Equal(t, []cns.IPConfigurationStatus{m["0"]}, MatchAllIPConfigStates(m, NCID("nc0"), StateAllocated))
+	assert.Empty(t, MatchAllIPConfigStates(m, NCID("nc1"), StateAllocated))
+}
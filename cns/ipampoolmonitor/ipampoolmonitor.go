@@ -24,6 +24,7 @@ type CNSIPAMPoolMonitor struct {
 	rc                       singletenantcontroller.RequestController
 	scalarUnits              v1alpha.Scaler
 	updatingIpsNotInUseCount int
+	lastReconcileTime        time.Time
 }
 
 func NewCNSIPAMPoolMonitor(httpService cns.HTTPService, rc singletenantcontroller.RequestController) *CNSIPAMPoolMonitor {
@@ -52,20 +53,41 @@ func (pm *CNSIPAMPoolMonitor) Start(ctx context.Context, poolMonitorRefreshMilli
 	}
 }
 
+// Reconcile scales the pool to keep the free IP count within the limits, and records when it succeeded.
 func (pm *CNSIPAMPoolMonitor) Reconcile(ctx context.Context) error {
+	if err := pm.reconcile(ctx); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	pm.lastReconcileTime = time.Now()
+	pm.mu.Unlock()
+	return nil
+}
+
+func (pm *CNSIPAMPoolMonitor) reconcile(ctx context.Context) error {
 	cnsPodIPConfigCount := len(pm.httpService.GetPodIPConfigState())
-	pendingProgramCount := len(pm.httpService.GetPendingProgramIPConfigs()) // TODO: add pending program count to real cns
+	pendingProgramCount := len(pm.httpService.GetPendingProgramIPConfigs())
 	allocatedPodIPCount := len(pm.httpService.GetAllocatedIPConfigs())
 	pendingReleaseIPCount := len(pm.httpService.GetPendingReleaseIPConfigs())
 	availableIPConfigCount := len(pm.httpService.GetAvailableIPConfigs()) // TODO: add pending allocation count to real cns
+	reservedIPConfigCount := pm.httpService.GetUnallocatedReservedIPCount()
+
+	// the request controller updates the limits and cached spec concurrently, so they are read under the lock
+	pm.mu.Lock()
 	requestedIPConfigCount := pm.cachedNNC.Spec.RequestedIPCount
-	unallocatedIPConfigCount := cnsPodIPConfigCount - allocatedPodIPCount
-	freeIPConfigCount := requestedIPConfigCount - int64(allocatedPodIPCount)
+	ipsNotInUseCount := len(pm.cachedNNC.Spec.IPsNotInUse)
+	minimumFreeIps, maximumFreeIps := pm.MinimumFreeIps, pm.MaximumFreeIps
 	batchSize := pm.getBatchSize() // Use getters in case customer changes batchsize manually
 	maxIPCount := pm.getMaxIPCount()
+	pm.mu.Unlock()
+
+	unallocatedIPConfigCount := cnsPodIPConfigCount - allocatedPodIPCount
+	// IPs reserved for namespaces can't be allocated to the pods of others, so they aren't free
+	freeIPConfigCount := requestedIPConfigCount - int64(allocatedPodIPCount) - int64(reservedIPConfigCount)
 
-	msg := fmt.Sprintf("[ipam-pool-monitor] Pool Size: %v, Goal Size: %v, BatchSize: %v, MaxIPCount: %v, MinFree: %v, MaxFree:%v, Allocated: %v, Available: %v, Pending Release: %v, Free: %v, Pending Program: %v",
-		cnsPodIPConfigCount, pm.cachedNNC.Spec.RequestedIPCount, batchSize, maxIPCount, pm.MinimumFreeIps, pm.MaximumFreeIps, allocatedPodIPCount, availableIPConfigCount, pendingReleaseIPCount, freeIPConfigCount, pendingProgramCount)
+	msg := fmt.Sprintf("[ipam-pool-monitor] Pool Size: %v, Goal Size: %v, BatchSize: %v, MaxIPCount: %v, MinFree: %v, MaxFree:%v, Allocated: %v, Available: %v, Pending Release: %v, Free: %v, Pending Program: %v, Reserved: %v",
+		cnsPodIPConfigCount, requestedIPConfigCount, batchSize, maxIPCount, minimumFreeIps, maximumFreeIps, allocatedPodIPCount, availableIPConfigCount, pendingReleaseIPCount, freeIPConfigCount, pendingProgramCount, reservedIPConfigCount)
 
 	ipamAllocatedIPCount.Set(float64(allocatedPodIPCount))
 	ipamAvailableIPCount.Set(float64(availableIPConfigCount))
@@ -76,12 +98,13 @@ func (pm *CNSIPAMPoolMonitor) Reconcile(ctx context.Context) error {
 	ipamPendingProgramIPCount.Set(float64(pendingProgramCount))
 	ipamPendingReleaseIPCount.Set(float64(pendingReleaseIPCount))
 	ipamRequestedIPConfigCount.Set(float64(requestedIPConfigCount))
+	ipamReservedIPCount.Set(float64(reservedIPConfigCount))
 	ipamUnallocatedIPCount.Set(float64(unallocatedIPConfigCount))
 
 	switch {
 	// pod count is increasing
-	case freeIPConfigCount < pm.MinimumFreeIps:
-		if pm.cachedNNC.Spec.RequestedIPCount == maxIPCount {
+	case freeIPConfigCount < minimumFreeIps:
+		if requestedIPConfigCount == maxIPCount {
 			// If we're already at the maxIPCount, don't try to increase
 			return nil
 		}
@@ -90,13 +113,13 @@ func (pm *CNSIPAMPoolMonitor) Reconcile(ctx context.Context) error {
 		return pm.increasePoolSize(ctx)
 
 	// pod count is decreasing
-	case freeIPConfigCount >= pm.MaximumFreeIps:
+	case freeIPConfigCount >= maximumFreeIps:
 		logger.Printf("[ipam-pool-monitor] Decreasing pool size...%s ", msg)
 		return pm.decreasePoolSize(ctx, pendingReleaseIPCount)
 
 	// CRD has reconciled CNS state, and target spec is now the same size as the state
 	// free to remove the IP's from the CRD
-	case len(pm.cachedNNC.Spec.IPsNotInUse) != pendingReleaseIPCount:
+	case ipsNotInUseCount != pendingReleaseIPCount:
 		logger.Printf("[ipam-pool-monitor] Removing Pending Release IP's from CRD...%s ", msg)
 		return pm.cleanPendingRelease(ctx)
 
@@ -245,6 +268,33 @@ func (pm *CNSIPAMPoolMonitor) cleanPendingRelease(ctx context.Context) error {
 	return nil
 }
 
+// ReleaseUnallocatedIPs marks every IP which isn't allocated to a pod as PendingRelease, and scales the
+// requested IP count down to the allocated IPs, so DNC can reclaim the IPs of a node which is being deleted.
+// The pool must not be reconciled afterwards, as that would scale it back up.
+func (pm *CNSIPAMPoolMonitor) ReleaseUnallocatedIPs(ctx context.Context) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	unallocatedIPCount := len(pm.httpService.GetAvailableIPConfigs()) + len(pm.httpService.GetPendingProgramIPConfigs())
+	pendingIPAddresses, err := pm.httpService.MarkIPAsPendingRelease(unallocatedIPCount)
+	if err != nil {
+		return err
+	}
+
+	tempNNCSpec := pm.createNNCSpecForCRD()
+	tempNNCSpec.RequestedIPCount = int64(len(pm.httpService.GetAllocatedIPConfigs()))
+	logger.Printf("[ipam-pool-monitor] Releasing unallocated IPs, newly marked PendingRelease: %d, Requested IP Count: %v, ToBeDeleted Count: %v",
+		len(pendingIPAddresses), tempNNCSpec.RequestedIPCount, len(tempNNCSpec.IPsNotInUse))
+
+	if err := pm.rc.UpdateCRDSpec(ctx, tempNNCSpec); err != nil {
+		return err
+	}
+
+	pm.cachedNNC.Spec = tempNNCSpec
+	pm.updatingIpsNotInUseCount = 0
+	return nil
+}
+
 // createNNCSpecForCRD translates CNS's map of IPs to be released and requested IP count into an NNC Spec.
 func (pm *CNSIPAMPoolMonitor) createNNCSpecForCRD() v1alpha.NodeNetworkConfigSpec {
 	var spec v1alpha.NodeNetworkConfigSpec
@@ -272,6 +322,7 @@ func (pm *CNSIPAMPoolMonitor) Update(scalar v1alpha.Scaler, spec v1alpha.NodeNet
 	pm.MaximumFreeIps = int64(float64(pm.getBatchSize()) * (float64(pm.scalarUnits.ReleaseThresholdPercent) / 100))
 
 	pm.cachedNNC.Spec = spec
+	pm.cachedNNC.Status.Scaler = scalar
 
 	// if the nnc has conveged, observe the pool scaling latency (if any)
 	allocatedIPs := len(pm.httpService.GetPodIPConfigState()) - len(pm.httpService.GetPendingReleaseIPConfigs())
@@ -309,5 +360,6 @@ func (pm *CNSIPAMPoolMonitor) GetStateSnapshot() cns.IpamPoolMonitorStateSnapsho
 		MaximumFreeIps:           pm.MaximumFreeIps,
 		UpdatingIpsNotInUseCount: pm.updatingIpsNotInUseCount,
 		CachedNNC:                pm.cachedNNC,
+		LastReconcileTime:        pm.lastReconcileTime,
 	}
 }
@@ -270,6 +270,50 @@ func TestPoolIncreasePastNodeLimit(t *testing.T) {
 	}
 }
 
+func TestPoolSizeIncreaseForReservedIPs(t *testing.T) {
+	var (
+		batchSize               = 10
+		initialIPConfigCount    = 10
+		requestThresholdPercent = 30
+		releaseThresholdPercent = 150
+		maxPodIPCount           = int64(30)
+	)
+
+	fakecns, _, poolmonitor := initFakes(t,
+		batchSize,
+		initialIPConfigCount,
+		requestThresholdPercent,
+		releaseThresholdPercent,
+		maxPodIPCount)
+
+	// 5 of the 10 IPs are free, more than the minimum of 3
+	err := fakecns.SetNumberOfAllocatedIPs(5)
+	if err != nil {
+		t.Fatalf("Failed to allocate test ipconfigs with err: %v", err)
+	}
+
+	err = poolmonitor.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to reconcile pool monitor with err: %v", err)
+	}
+
+	if poolmonitor.cachedNNC.Spec.RequestedIPCount != int64(initialIPConfigCount) {
+		t.Fatalf("Pool monitor target IP count (%v) should not change while enough IPs are free", poolmonitor.cachedNNC.Spec.RequestedIPCount)
+	}
+
+	// 3 of the free IPs are reserved for a namespace, so only 2 are free for the other pods
+	fakecns.UnallocatedReservedIPCount = 3
+	err = poolmonitor.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to reconcile pool monitor with err: %v", err)
+	}
+
+	if poolmonitor.cachedNNC.Spec.RequestedIPCount != int64(initialIPConfigCount+batchSize) {
+		t.Fatalf("Pool monitor target IP count (%v) should increase by a batch when reserved IPs leave too few free, expected %v",
+			poolmonitor.cachedNNC.Spec.RequestedIPCount, initialIPConfigCount+batchSize)
+	}
+}
+
 func TestPoolIncreaseBatchSizeGreaterThanMaxPodIPCount(t *testing.T) {
 	var (
 		batchSize               = 50
@@ -763,3 +807,40 @@ func ReconcileAndValidate(ctx context.Context,
 			len(poolmonitor.cachedNNC.Spec.IPsNotInUse))
 	}
 }
+
+func TestReleaseUnallocatedIPs(t *testing.T) {
+	var (
+		batchSize               = 10
+		initialIPConfigCount    = 20
+		requestThresholdPercent = 30
+		releaseThresholdPercent = 150
+		maxPodIPCount           = int64(30)
+	)
+
+	fakecns, _, poolmonitor := initFakes(t, batchSize, initialIPConfigCount,
+		requestThresholdPercent, releaseThresholdPercent, maxPodIPCount)
+
+	err := fakecns.SetNumberOfAllocatedIPs(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = poolmonitor.ReleaseUnallocatedIPs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if poolmonitor.cachedNNC.Spec.RequestedIPCount != 5 {
+		t.Fatalf("Expected requested IP count to be scaled down to the allocated IPs, expected %v, actual %v",
+			5, poolmonitor.cachedNNC.Spec.RequestedIPCount)
+	}
+
+	if len(poolmonitor.cachedNNC.Spec.IPsNotInUse) != initialIPConfigCount-5 {
+		t.Fatalf("Expected every unallocated IP to be not in use, expected %v, actual %v",
+			initialIPConfigCount-5, len(poolmonitor.cachedNNC.Spec.IPsNotInUse))
+	}
+
+	if len(fakecns.GetAvailableIPConfigs()) != 0 {
+		t.Fatalf("Expected no Available IPs, got %d", len(fakecns.GetAvailableIPConfigs()))
+	}
+}
@@ -60,6 +60,12 @@ var (
 			Help: "Requested IP count.",
 		},
 	)
+	ipamReservedIPCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ipam_reserved_ips",
+			Help: "IP count reserved for pod namespaces and not allocated to their pods.",
+		},
+	)
 	ipamUnallocatedIPCount = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "ipam_unallocated_ips",
@@ -78,5 +84,6 @@ func init() {
 		ipamMaxIPCount,
 		ipamPendingProgramIPCount,
 		ipamPendingReleaseIPCount,
+		ipamReservedIPCount,
 	)
 }
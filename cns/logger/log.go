@@ -52,6 +52,18 @@ func InitAI(aiConfig aitelemetry.AIConfig, disableTraceLogging, disableMetricLog
 	Log.DisableEventLogging = disableEventLogging
 }
 
+// SetLogLevel sets the level of the messages written to the CNS log.
+func SetLogLevel(logLevel int) {
+	Log.logger.SetLevel(logLevel)
+}
+
+// SetTelemetryToggles sets which kinds of telemetry are not sent to AI.
+func SetTelemetryToggles(disableTraceLogging, disableMetricLogging, disableEventLogging bool) {
+	Log.DisableMetricLogging = disableMetricLogging
+	Log.DisableTraceLogging = disableTraceLogging
+	Log.DisableEventLogging = disableEventLogging
+}
+
 // Close CNS and AI telemetry handle
 func Close() {
 	Log.logger.Close()
@@ -64,6 +76,11 @@ func SetTargetLogDirectory(target int, dir string) error {
 	return Log.logger.SetTargetLogDirectory(target, dir)
 }
 
+// LogFileName returns the full name of the active CNS log file.
+func LogFileName() string {
+	return Log.logger.GetLogFileName()
+}
+
 // Set context details for logs and metrics
 func SetContextDetails(orchestrator string, nodeID string) {
 	Printf("SetContext details called with: %v orchestrator nodeID %v", orchestrator, nodeID)
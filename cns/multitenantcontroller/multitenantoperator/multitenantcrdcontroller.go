@@ -81,6 +81,7 @@ func New(restService *restserver.HTTPRestService, kubeconfig *rest.Config) (*req
 	// Create multiTenantCrdReconciler
 	reconciler := &multiTenantCrdReconciler{
 		KubeClient: mgr.GetClient(),
+		NodeReader: mgr.GetAPIReader(),
 		NodeName:   nodeName,
 		CNSClient:  httpClient,
 	}
@@ -6,16 +6,23 @@ import (
 	"net"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/cnsclient"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/types"
 	ncapi "github.com/Azure/azure-container-networking/crd/multitenantnetworkcontainer/api/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -28,16 +35,52 @@ const (
 	NCStateSucceeded = "Succeeded"
 	// NCStateTerminated indicates the NC has been terminated by CNS.
 	NCStateTerminated = "Terminated"
+
+	// NCFinalizer keeps a MultiTenantNetworkContainer from being removed until CNS has deleted its NC.
+	NCFinalizer = "networking.azure.com/cns-network-container"
+
+	// orphanedFinalizerCheckInterval is how often a deleted NC of another node is checked for the deletion of its node.
+	orphanedFinalizerCheckInterval = 5 * time.Minute
+)
+
+// Reasons of the MultiTenantNetworkContainer status conditions.
+const (
+	ReasonProgrammed             = "NetworkContainerProgrammed"
+	ReasonNotProgrammed          = "NetworkContainerNotProgrammed"
+	ReasonTerminated             = "NetworkContainerTerminated"
+	ReasonReconciled             = "Reconciled"
+	ReasonMissingMultiTenantInfo = "MissingMultiTenantInfo"
+	ReasonInvalidIPSubnet        = "InvalidIPSubnet"
+	ReasonCNSRequestFailed       = "CNSRequestFailed"
 )
 
+// permanentCNSErrors are the codes of CNS NC requests which fail the same way when retried,
+// the requests failing with any other code are requeued.
+var permanentCNSErrors = []types.ResponseCode{
+	types.InvalidParameter,
+	types.InvalidRequest,
+	types.InvalidPrimaryIPConfig,
+	types.InvalidSecondaryIPConfig,
+	types.MalformedSubnet,
+	types.EmptyOrchestratorContext,
+	types.UnsupportedOrchestratorContext,
+	types.UnsupportedOrchestratorType,
+	types.UnsupportedNetworkContainerType,
+	types.UnsupportedNCVersion,
+	types.PrimaryCANotSame,
+}
+
 // multiTenantCrdReconciler reconciles multi-tenant network containers.
 type multiTenantCrdReconciler struct {
 	KubeClient client.Client
+	// NodeReader reads the nodes from the apiserver, so that CNS doesn't cache every node of the cluster.
+	NodeReader client.Reader
 	NodeName   string
 	CNSClient  cnsclient.APIClient
 }
 
 // Reconcile is called on multi-tenant CRD status changes.
+// NCs which fail to be persisted in CNS with a temporary error are requeued with the controller's backoff.
 func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	logger.Printf("Reconcling MultiTenantNetworkContainer %v", request.NamespacedName.String())
 
@@ -52,39 +95,33 @@ func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconc
 		return ctrl.Result{}, err
 	}
 
-	if !nc.ObjectMeta.DeletionTimestamp.IsZero() {
-		// Do nothing if the NC has already in Terminated state.
-		if nc.Status.State == NCStateTerminated {
-			logger.Printf("MultiTenantNetworkContainer %s already terminated, skip reconciling", request.NamespacedName.String())
-			return ctrl.Result{}, nil
-		}
-
-		// Remove the deleted network container from CNS.
-		err := r.CNSClient.DeleteNC(cns.DeleteNetworkContainerRequest{
-			NetworkContainerid: nc.Spec.UUID,
-		})
-		if err != nil {
-			logger.Errorf("Failed to delete NC %s (UUID: %s) from CNS: %v", request.NamespacedName.String(), nc.Spec.UUID, err)
-			return ctrl.Result{}, err
-		}
+	// NCs of other nodes are only reconciled to release the ones left behind by a node which is gone.
+	if !r.equalNode(&nc) {
+		return r.releaseOrphanedFinalizer(ctx, &nc)
+	}
 
-		// Update NC state to Terminated.
-		nc.Status.State = NCStateTerminated
-		if err := r.KubeClient.Status().Update(ctx, &nc); err != nil {
-			logger.Errorf("Failed to update network container state for %s (UUID: %s): %v", request.NamespacedName.String(), nc.Spec.UUID, err)
-			return ctrl.Result{}, err
-		}
+	// the status is only written when the reconcile changes it, as writing it triggers another reconcile
+	original := nc.Status.DeepCopy()
 
-		logger.Printf("NC has been terminated for %s (UUID: %s)", request.NamespacedName.String(), nc.Spec.UUID)
-		return ctrl.Result{}, nil
+	if !nc.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, &nc, original)
 	}
 
 	// Do nothing if the network container hasn't been initialized yet from control plane.
-	if nc.Status.State != NCStateInitialized {
+	if nc.Status.State != NCStateInitialized && nc.Status.State != NCStateSucceeded {
 		logger.Printf("MultiTenantNetworkContainer %s hasn't initialized yet, skip reconciling", request.NamespacedName.String())
 		return ctrl.Result{}, nil
 	}
 
+	// Hold the NC until it is removed from CNS.
+	if !controllerutil.ContainsFinalizer(&nc, NCFinalizer) {
+		controllerutil.AddFinalizer(&nc, NCFinalizer)
+		if err := r.KubeClient.Update(ctx, &nc); err != nil {
+			logger.Errorf("Failed to add finalizer to network container %s: %v", request.NamespacedName.String(), err)
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Parse KubernetesPodInfo as orchestratorContext.
 	podInfo := cns.KubernetesPodInfo{
 		PodName:      nc.Name,
@@ -103,24 +140,28 @@ func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconc
 	})
 	if err == nil {
 		logger.Printf("NC %s (UUID: %s) has already been created in CNS", request.NamespacedName.String(), nc.Spec.UUID)
-		return ctrl.Result{}, nil
-	} else if !strings.EqualFold(err.Error(), types.UnknownContainerID.String()) {
-		logger.Errorf("Failed to fetch NC %s (UUID: %s) from CNS: %v", request.NamespacedName.String(), nc.Spec.UUID, err)
-		return ctrl.Result{}, err
+		return r.setProgrammed(ctx, &nc, original)
+	} else if !errors.Is(err, types.UnknownContainerID) {
+		return r.setFailed(ctx, &nc, original, ReasonCNSRequestFailed, errors.Wrap(err, "failed to fetch NC from CNS"), isTemporary(err))
 	}
 
+	meta.SetStatusCondition(&nc.Status.Conditions, metav1.Condition{
+		Type:    ncapi.ConditionProgrammed,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonNotProgrammed,
+		Message: "CNS has not persisted the NC",
+	})
+
 	// Check that the MultiTenantInfo is set
 	if reflect.DeepEqual(ncapi.MultiTenantInfo{}, nc.Status.MultiTenantInfo) {
-		logger.Errorf("expected NC status multitenant info to not be empty for object %s", request.NamespacedName)
 		// There is no reason to requeue since we will reconcile this object when the multitenant info is added
-		return ctrl.Result{}, nil
+		return r.setFailed(ctx, &nc, original, ReasonMissingMultiTenantInfo, errors.New("expected NC status multitenant info to not be empty"), false)
 	}
 
 	// Persist NC states into CNS.
 	_, ipNet, err := net.ParseCIDR(nc.Status.IPSubnet)
 	if err != nil {
-		logger.Errorf("Failed to parse IPSubnet %s for NC %s: %v", nc.Status.IPSubnet, nc.Spec.UUID, err)
-		return ctrl.Result{}, err
+		return r.setFailed(ctx, &nc, original, ReasonInvalidIPSubnet, errors.Wrapf(err, "failed to parse IPSubnet %s", nc.Status.IPSubnet), false)
 	}
 	prefixLength, _ := ipNet.Mask.Size()
 	networkContainerRequest := cns.CreateNetworkContainerRequest{
@@ -143,19 +184,158 @@ func (r *multiTenantCrdReconciler) Reconcile(ctx context.Context, request reconc
 	}
 	logger.Printf("CreateOrUpdateNC with networkContainerRequest: %#v", networkContainerRequest)
 	if err = r.CNSClient.CreateOrUpdateNC(networkContainerRequest); err != nil {
-		logger.Errorf("Failed to persist state for NC %s (UUID: %s) to CNS: %v", request.NamespacedName.String(), nc.Spec.UUID, err)
+		return r.setFailed(ctx, &nc, original, ReasonCNSRequestFailed, errors.Wrap(err, "failed to persist NC to CNS"), isTemporary(err))
+	}
+
+	logger.Printf("Reconciled NC %s (UUID: %s)", request.NamespacedName.String(), nc.Spec.UUID)
+	return r.setProgrammed(ctx, &nc, original)
+}
+
+// reconcileDelete deletes the NC from CNS, marks it Terminated and then releases it by removing the finalizer.
+func (r *multiTenantCrdReconciler) reconcileDelete(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer, original *ncapi.MultiTenantNetworkContainerStatus) (reconcile.Result, error) {
+	hasFinalizer := controllerutil.ContainsFinalizer(nc, NCFinalizer)
+
+	// Do nothing if the NC has already been terminated and released.
+	if nc.Status.State == NCStateTerminated && !hasFinalizer {
+		logger.Printf("MultiTenantNetworkContainer %s/%s already terminated, skip reconciling", nc.Namespace, nc.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if nc.Status.State != NCStateTerminated {
+		// Remove the deleted network container from CNS, which succeeds if CNS doesn't have it.
+		err := r.CNSClient.DeleteNC(cns.DeleteNetworkContainerRequest{
+			NetworkContainerid: nc.Spec.UUID,
+		})
+		if err != nil {
+			// the NC must be removed from CNS before it is released, so the deletion is retried whatever the error
+			return r.setFailed(ctx, nc, original, ReasonCNSRequestFailed, errors.Wrap(err, "failed to delete NC from CNS"), true)
+		}
+
+		// Update NC state to Terminated.
+		nc.Status.State = NCStateTerminated
+		r.setConditions(nc, metav1.ConditionFalse, ReasonTerminated, "CNS has deleted the NC")
+		meta.SetStatusCondition(&nc.Status.Conditions, metav1.Condition{
+			Type:               ncapi.ConditionFailed,
+			Status:             metav1.ConditionFalse,
+			Reason:             ReasonTerminated,
+			ObservedGeneration: nc.Generation,
+		})
+		if err := r.updateStatus(ctx, nc, original); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Printf("NC has been terminated for %s/%s (UUID: %s)", nc.Namespace, nc.Name, nc.Spec.UUID)
+	}
+
+	if hasFinalizer {
+		controllerutil.RemoveFinalizer(nc, NCFinalizer)
+		if err := r.KubeClient.Update(ctx, nc); err != nil {
+			logger.Errorf("Failed to remove finalizer from network container %s/%s: %v", nc.Namespace, nc.Name, err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// releaseOrphanedFinalizer removes the finalizer of a deleted NC of another node once its node has been deleted,
+// as the CNS of that node can no longer release it. While the node exists the NC is left to its CNS, which may
+// only be down for a while and must delete the NC from its state first.
+func (r *multiTenantCrdReconciler) releaseOrphanedFinalizer(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer) (reconcile.Result, error) {
+	if nc.ObjectMeta.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(nc, NCFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	var node corev1.Node
+	err := r.NodeReader.Get(ctx, client.ObjectKey{Name: nc.Spec.Node}, &node)
+	if err == nil {
+		// the deletion of the node doesn't trigger a reconcile of its NCs, so the node is checked again later.
+		return ctrl.Result{RequeueAfter: orphanedFinalizerCheckInterval}, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		logger.Errorf("Failed to fetch node %s of network container %s/%s: %v", nc.Spec.Node, nc.Namespace, nc.Name, err)
 		return ctrl.Result{}, err
 	}
 
-	// Update NC state to Succeeded.
-	nc.Status.State = NCStateSucceeded
-	if err := r.KubeClient.Status().Update(ctx, &nc); err != nil {
-		logger.Errorf("Failed to update network container state for %s (UUID: %s): %v", request.NamespacedName.String(), nc.Spec.UUID, err)
+	logger.Printf("Node %s of MultiTenantNetworkContainer %s/%s not found, releasing it", nc.Spec.Node, nc.Namespace, nc.Name)
+	controllerutil.RemoveFinalizer(nc, NCFinalizer)
+	if err := r.KubeClient.Update(ctx, nc); err != nil {
+		logger.Errorf("Failed to remove finalizer from network container %s/%s: %v", nc.Namespace, nc.Name, err)
 		return ctrl.Result{}, err
 	}
+	return ctrl.Result{}, nil
+}
 
-	logger.Printf("Reconciled NC %s (UUID: %s)", request.NamespacedName.String(), nc.Spec.UUID)
-	return reconcile.Result{}, nil
+// setProgrammed marks the NC Succeeded, with the Programmed and Ready conditions true.
+func (r *multiTenantCrdReconciler) setProgrammed(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer, original *ncapi.MultiTenantNetworkContainerStatus) (reconcile.Result, error) {
+	nc.Status.State = NCStateSucceeded
+	r.setConditions(nc, metav1.ConditionTrue, ReasonProgrammed, "CNS has persisted the NC")
+	meta.SetStatusCondition(&nc.Status.Conditions, metav1.Condition{
+		Type:               ncapi.ConditionFailed,
+		Status:             metav1.ConditionFalse,
+		Reason:             ReasonReconciled,
+		ObservedGeneration: nc.Generation,
+	})
+	return ctrl.Result{}, r.updateStatus(ctx, nc, original)
+}
+
+// setFailed records the reconcile failure in the Failed and Ready conditions, and requeues the NC if the error is temporary.
+func (r *multiTenantCrdReconciler) setFailed(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer, original *ncapi.MultiTenantNetworkContainerStatus, reason string, err error, requeue bool) (reconcile.Result, error) {
+	logger.Errorf("Failed to reconcile NC %s/%s (UUID: %s), requeue: %t: %v", nc.Namespace, nc.Name, nc.Spec.UUID, requeue, err)
+
+	meta.SetStatusCondition(&nc.Status.Conditions, metav1.Condition{
+		Type:               ncapi.ConditionFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: nc.Generation,
+	})
+	meta.SetStatusCondition(&nc.Status.Conditions, metav1.Condition{
+		Type:               ncapi.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: nc.Generation,
+	})
+	if updateErr := r.updateStatus(ctx, nc, original); updateErr != nil {
+		return ctrl.Result{}, updateErr
+	}
+	return ctrl.Result{Requeue: requeue}, nil
+}
+
+// setConditions sets the Programmed and Ready conditions to the status.
+func (r *multiTenantCrdReconciler) setConditions(nc *ncapi.MultiTenantNetworkContainer, status metav1.ConditionStatus, reason, message string) {
+	for _, conditionType := range []string{ncapi.ConditionProgrammed, ncapi.ConditionReady} {
+		meta.SetStatusCondition(&nc.Status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: nc.Generation,
+		})
+	}
+}
+
+// updateStatus writes the status of the NC for its generation, if it differs from the original status.
+func (r *multiTenantCrdReconciler) updateStatus(ctx context.Context, nc *ncapi.MultiTenantNetworkContainer, original *ncapi.MultiTenantNetworkContainerStatus) error {
+	nc.Status.ObservedGeneration = nc.Generation
+	if equality.Semantic.DeepEqual(original, &nc.Status) {
+		return nil
+	}
+	if err := r.KubeClient.Status().Update(ctx, nc); err != nil {
+		logger.Errorf("Failed to update network container state for %s/%s (UUID: %s): %v", nc.Namespace, nc.Name, nc.Spec.UUID, err)
+		return err
+	}
+	return nil
+}
+
+// isTemporary returns true if the CNS request may succeed when retried.
+func isTemporary(err error) bool {
+	for _, code := range permanentCNSErrors {
+		if errors.Is(err, code) {
+			return false
+		}
+	}
+	return true
 }
 
 // SetupWithManager Sets up the reconciler with a new manager, filtering using NodeNetworkConfigFilter
@@ -166,16 +346,17 @@ func (r *multiTenantCrdReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
+// predicate passes the NCs of the node, and the deleted NCs of any node which are still held by the finalizer.
 func (r *multiTenantCrdReconciler) predicate() predicate.Predicate {
 	return predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
-			return r.equalNode(e.Object)
+			return r.equalNode(e.Object) || isFinalizing(e.Object)
 		},
 		GenericFunc: func(e event.GenericEvent) bool {
-			return r.equalNode(e.Object)
+			return r.equalNode(e.Object) || isFinalizing(e.Object)
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			return r.equalNode(e.ObjectNew)
+			return r.equalNode(e.ObjectNew) || isFinalizing(e.ObjectNew)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
 			return r.equalNode(e.Object)
@@ -183,6 +364,11 @@ func (r *multiTenantCrdReconciler) predicate() predicate.Predicate {
 	}
 }
 
+// isFinalizing returns true if the NC has been deleted and is still held by the finalizer.
+func isFinalizing(o client.Object) bool {
+	return !o.GetDeletionTimestamp().IsZero() && controllerutil.ContainsFinalizer(o, NCFinalizer)
+}
+
 func (r *multiTenantCrdReconciler) equalNode(o runtime.Object) bool {
 	nc, ok := o.(*ncapi.MultiTenantNetworkContainer)
 	if ok {
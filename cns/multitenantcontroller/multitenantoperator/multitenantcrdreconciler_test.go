@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/cnsclient"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/multitenantcontroller/mockclients"
 	cnstypes "github.com/Azure/azure-container-networking/cns/types"
@@ -13,13 +15,51 @@ import (
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-var errContainerIDNotFound = errors.New(cnstypes.UnknownContainerID.String())
+const mockNodeName = "mockNodeName"
+
+var errContainerIDNotFound = &cnsclient.CNSClientError{
+	Code: cnstypes.UnknownContainerID,
+	Err:  errors.New(cnstypes.UnknownContainerID.String()),
+}
+
+// expectUpdate expects the object to be updated once, and saves the update to the NC.
+func expectUpdate(kubeClient *mockclients.MockClient, updated *ncapi.MultiTenantNetworkContainer) {
+	kubeClient.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+			*updated = *obj.(*ncapi.MultiTenantNetworkContainer).DeepCopy()
+			return nil
+		})
+}
+
+// expectStatusUpdate expects the status to be updated once, and saves the update to the NC.
+func expectStatusUpdate(mockCtl *gomock.Controller, kubeClient *mockclients.MockClient, updated *ncapi.MultiTenantNetworkContainer) {
+	statusWriter := mockclients.NewMockStatusWriter(mockCtl)
+	statusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+			*updated = *obj.(*ncapi.MultiTenantNetworkContainer).DeepCopy()
+			return nil
+		})
+	kubeClient.EXPECT().Status().Return(statusWriter)
+}
+
+func expectCondition(nc *ncapi.MultiTenantNetworkContainer, conditionType string, status metav1.ConditionStatus, reason string) {
+	condition := meta.FindStatusCondition(nc.Status.Conditions, conditionType)
+	Expect(condition).NotTo(BeNil(), "condition %s", conditionType)
+	Expect(condition.Status).To(Equal(status), "condition %s", conditionType)
+	Expect(condition.Reason).To(Equal(reason), "condition %s", conditionType)
+	Expect(condition.ObservedGeneration).To(Equal(nc.Generation), "condition %s", conditionType)
+}
 
 var _ = Describe("multiTenantCrdReconciler", func() {
 	var kubeClient *mockclients.MockClient
@@ -27,7 +67,6 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 	var mockCtl *gomock.Controller
 	var reconciler *multiTenantCrdReconciler
 	const uuidValue = "uuid"
-	mockNodeName := "mockNodeName"
 	namespacedName := types.NamespacedName{
 		Namespace: "test",
 		Name:      "test",
@@ -44,6 +83,7 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 		cnsClient = mockclients.NewMockAPIClient(mockCtl)
 		reconciler = &multiTenantCrdReconciler{
 			KubeClient: kubeClient,
+			NodeReader: kubeClient,
 			NodeName:   mockNodeName,
 			CNSClient:  cnsClient,
 		}
@@ -78,9 +118,13 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 		})
 
 		It("Should succeed when the NC is in Terminated state", func() {
+			now := metav1.Now()
 			var nc ncapi.MultiTenantNetworkContainer = ncapi.MultiTenantNetworkContainer{
 				ObjectMeta: metav1.ObjectMeta{
-					DeletionTimestamp: &metav1.Time{},
+					DeletionTimestamp: &now,
+				},
+				Spec: ncapi.MultiTenantNetworkContainerSpec{
+					Node: mockNodeName,
 				},
 				Status: ncapi.MultiTenantNetworkContainerStatus{
 					State: "Terminated",
@@ -95,6 +139,9 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 
 		It("Should succeed when the NC is not in Initialized state", func() {
 			var nc ncapi.MultiTenantNetworkContainer = ncapi.MultiTenantNetworkContainer{
+				Spec: ncapi.MultiTenantNetworkContainerSpec{
+					Node: mockNodeName,
+				},
 				Status: ncapi.MultiTenantNetworkContainerStatus{
 					State: "Pending",
 				},
@@ -115,6 +162,7 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				},
 				Spec: ncapi.MultiTenantNetworkContainerSpec{
 					UUID: uuid,
+					Node: mockNodeName,
 				},
 				Status: ncapi.MultiTenantNetworkContainerStatus{
 					State: "Initialized",
@@ -128,7 +176,10 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 			orchestratorContext, err := json.Marshal(podInfo)
 			Expect(err).To(BeNil())
 
+			var withFinalizer, updated ncapi.MultiTenantNetworkContainer
 			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			expectUpdate(kubeClient, &withFinalizer)
+			expectStatusUpdate(mockCtl, kubeClient, &updated)
 			cnsClient.EXPECT().GetNC(cns.GetNetworkContainerRequest{
 				NetworkContainerid:  uuid,
 				OrchestratorContext: orchestratorContext,
@@ -137,6 +188,19 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				NamespacedName: namespacedName,
 			})
 			Expect(err).To(BeNil())
+			Expect(controllerutil.ContainsFinalizer(&withFinalizer, NCFinalizer)).To(BeTrue())
+			Expect(updated.Status.State).To(Equal(NCStateSucceeded))
+			expectCondition(&updated, ncapi.ConditionProgrammed, metav1.ConditionTrue, ReasonProgrammed)
+			expectCondition(&updated, ncapi.ConditionReady, metav1.ConditionTrue, ReasonProgrammed)
+			expectCondition(&updated, ncapi.ConditionFailed, metav1.ConditionFalse, ReasonReconciled)
+
+			// reconciling the programmed NC again changes nothing
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, updated)
+			cnsClient.EXPECT().GetNC(gomock.Any()).Return(cns.GetNetworkContainerResponse{}, nil)
+			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
 		})
 
 		It("Should fail when the NC subnet isn't in correct format", func() {
@@ -148,6 +212,7 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				},
 				Spec: ncapi.MultiTenantNetworkContainerSpec{
 					UUID: uuid,
+					Node: mockNodeName,
 				},
 				Status: ncapi.MultiTenantNetworkContainerStatus{
 					State:    "Initialized",
@@ -162,16 +227,24 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 			orchestratorContext, err := json.Marshal(podInfo)
 			Expect(err).To(BeNil())
 
+			var updated ncapi.MultiTenantNetworkContainer
 			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			expectUpdate(kubeClient, &ncapi.MultiTenantNetworkContainer{})
+			expectStatusUpdate(mockCtl, kubeClient, &updated)
 			cnsClient.EXPECT().GetNC(cns.GetNetworkContainerRequest{
 				NetworkContainerid:  uuid,
 				OrchestratorContext: orchestratorContext,
 			}).Return(cns.GetNetworkContainerResponse{}, errContainerIDNotFound)
-			_, err = reconciler.Reconcile(context.TODO(), reconcile.Request{
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
 				NamespacedName: namespacedName,
 			})
-			Expect(err).NotTo(BeNil())
-			Expect(err.Error()).To(ContainSubstring("invalid CIDR address"))
+			// the NC is reconciled again when its status is fixed, not requeued
+			Expect(err).To(BeNil())
+			Expect(result.Requeue).To(BeFalse())
+			expectCondition(&updated, ncapi.ConditionFailed, metav1.ConditionTrue, ReasonInvalidIPSubnet)
+			expectCondition(&updated, ncapi.ConditionReady, metav1.ConditionFalse, ReasonInvalidIPSubnet)
+			expectCondition(&updated, ncapi.ConditionProgrammed, metav1.ConditionFalse, ReasonNotProgrammed)
+			Expect(meta.FindStatusCondition(updated.Status.Conditions, ncapi.ConditionFailed).Message).To(ContainSubstring("invalid CIDR address"))
 		})
 
 		It("Should succeed when the NC subnet is in correct format", func() {
@@ -183,6 +256,7 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				},
 				Spec: ncapi.MultiTenantNetworkContainerSpec{
 					UUID: uuid,
+					Node: mockNodeName,
 				},
 				Status: ncapi.MultiTenantNetworkContainerStatus{
 					State:    "Initialized",
@@ -217,10 +291,10 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				},
 			}
 
+			var updated ncapi.MultiTenantNetworkContainer
 			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
-			statusWriter := mockclients.NewMockStatusWriter(mockCtl)
-			statusWriter.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
-			kubeClient.EXPECT().Status().Return(statusWriter)
+			expectUpdate(kubeClient, &ncapi.MultiTenantNetworkContainer{})
+			expectStatusUpdate(mockCtl, kubeClient, &updated)
 			cnsClient.EXPECT().GetNC(cns.GetNetworkContainerRequest{
 				NetworkContainerid:  uuid,
 				OrchestratorContext: orchestratorContext,
@@ -230,6 +304,180 @@ var _ = Describe("multiTenantCrdReconciler", func() {
 				NamespacedName: namespacedName,
 			})
 			Expect(err).To(BeNil())
+			Expect(updated.Status.State).To(Equal(NCStateSucceeded))
+			Expect(updated.Status.ObservedGeneration).To(Equal(nc.Generation))
+			expectCondition(&updated, ncapi.ConditionProgrammed, metav1.ConditionTrue, ReasonProgrammed)
+			expectCondition(&updated, ncapi.ConditionReady, metav1.ConditionTrue, ReasonProgrammed)
+		})
+
+		It("Should requeue when CNS fails to persist the NC with a temporary error", func() {
+			nc := initializedNC(namespacedName)
+
+			var updated ncapi.MultiTenantNetworkContainer
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			expectStatusUpdate(mockCtl, kubeClient, &updated)
+			cnsClient.EXPECT().GetNC(gomock.Any()).Return(cns.GetNetworkContainerResponse{}, errContainerIDNotFound)
+			cnsClient.EXPECT().CreateOrUpdateNC(gomock.Any()).Return(&cnsclient.CNSClientError{
+				Code: cnstypes.NmAgentSupportedApisError,
+				Err:  errors.New("nmagent unavailable"),
+			})
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
+			Expect(result.Requeue).To(BeTrue())
+			expectCondition(&updated, ncapi.ConditionFailed, metav1.ConditionTrue, ReasonCNSRequestFailed)
+			expectCondition(&updated, ncapi.ConditionReady, metav1.ConditionFalse, ReasonCNSRequestFailed)
+		})
+
+		It("Should not requeue when CNS rejects the NC", func() {
+			nc := initializedNC(namespacedName)
+
+			var updated ncapi.MultiTenantNetworkContainer
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			expectStatusUpdate(mockCtl, kubeClient, &updated)
+			cnsClient.EXPECT().GetNC(gomock.Any()).Return(cns.GetNetworkContainerResponse{}, errContainerIDNotFound)
+			cnsClient.EXPECT().CreateOrUpdateNC(gomock.Any()).Return(&cnsclient.CNSClientError{
+				Code: cnstypes.InvalidPrimaryIPConfig,
+				Err:  errors.New("invalid primary IP"),
+			})
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
+			Expect(result.Requeue).To(BeFalse())
+			expectCondition(&updated, ncapi.ConditionFailed, metav1.ConditionTrue, ReasonCNSRequestFailed)
+		})
+
+		It("Should delete the NC from CNS before releasing it", func() {
+			nc := initializedNC(namespacedName)
+			nc.Status.State = NCStateSucceeded
+			now := metav1.Now()
+			nc.DeletionTimestamp = &now
+
+			var updated, released ncapi.MultiTenantNetworkContainer
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			deleted := cnsClient.EXPECT().DeleteNC(cns.DeleteNetworkContainerRequest{NetworkContainerid: nc.Spec.UUID}).Return(nil)
+			expectStatusUpdate(mockCtl, kubeClient, &updated)
+			kubeClient.EXPECT().Update(gomock.Any(), gomock.Any()).After(deleted).DoAndReturn(
+				func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+					released = *obj.(*ncapi.MultiTenantNetworkContainer).DeepCopy()
+					return nil
+				})
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
+			Expect(updated.Status.State).To(Equal(NCStateTerminated))
+			expectCondition(&updated, ncapi.ConditionProgrammed, metav1.ConditionFalse, ReasonTerminated)
+			expectCondition(&updated, ncapi.ConditionReady, metav1.ConditionFalse, ReasonTerminated)
+			Expect(controllerutil.ContainsFinalizer(&released, NCFinalizer)).To(BeFalse())
+		})
+
+		It("Should keep the NC and requeue when CNS fails to delete it", func() {
+			nc := initializedNC(namespacedName)
+			nc.Status.State = NCStateSucceeded
+			now := metav1.Now()
+			nc.DeletionTimestamp = &now
+
+			var updated ncapi.MultiTenantNetworkContainer
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			cnsClient.EXPECT().DeleteNC(gomock.Any()).Return(errors.New("failed to save state"))
+			expectStatusUpdate(mockCtl, kubeClient, &updated)
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
+			Expect(result.Requeue).To(BeTrue())
+			Expect(updated.Status.State).To(Equal(NCStateSucceeded))
+			Expect(controllerutil.ContainsFinalizer(&updated, NCFinalizer)).To(BeTrue())
+			expectCondition(&updated, ncapi.ConditionFailed, metav1.ConditionTrue, ReasonCNSRequestFailed)
+		})
+	})
+
+	Context("orphaned finalizer", func() {
+		const otherNodeName = "otherNodeName"
+		nodeKey := client.ObjectKey{Name: otherNodeName}
+
+		deletedNC := func(deleted time.Time) ncapi.MultiTenantNetworkContainer {
+			nc := initializedNC(namespacedName)
+			nc.Spec.Node = otherNodeName
+			nc.Status.State = NCStateSucceeded
+			deletionTimestamp := metav1.NewTime(deleted)
+			nc.DeletionTimestamp = &deletionTimestamp
+			return nc
+		}
+
+		It("Should release the NC of a deleted node", func() {
+			nc := deletedNC(time.Now())
+			var released ncapi.MultiTenantNetworkContainer
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			kubeClient.EXPECT().Get(gomock.Any(), nodeKey, gomock.AssignableToTypeOf(&corev1.Node{})).
+				Return(apierrors.NewNotFound(corev1.Resource("nodes"), otherNodeName))
+			expectUpdate(kubeClient, &released)
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(reconcile.Result{}))
+			Expect(controllerutil.ContainsFinalizer(&released, NCFinalizer)).To(BeFalse())
+			Expect(released.Status.State).To(Equal(NCStateSucceeded))
+		})
+
+		It("Should leave the NC to its node while the node exists", func() {
+			// however long the CNS of the node has been down.
+			nc := deletedNC(time.Now().Add(-24 * time.Hour))
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			kubeClient.EXPECT().Get(gomock.Any(), nodeKey, gomock.AssignableToTypeOf(&corev1.Node{})).Return(nil)
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
+			Expect(result.RequeueAfter).To(Equal(orphanedFinalizerCheckInterval))
+		})
+
+		It("Should ignore the NCs of other nodes which aren't deleted", func() {
+			nc := initializedNC(namespacedName)
+			nc.Spec.Node = otherNodeName
+			kubeClient.EXPECT().Get(gomock.Any(), namespacedName, gomock.Any()).SetArg(2, nc)
+			result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: namespacedName,
+			})
+			Expect(err).To(BeNil())
+			Expect(result).To(Equal(reconcile.Result{}))
+		})
+
+		It("Should pass the deleted NCs of other nodes held by the finalizer", func() {
+			nc := deletedNC(time.Now())
+			p := reconciler.predicate()
+			Expect(p.Update(event.UpdateEvent{ObjectOld: &nc, ObjectNew: &nc})).To(BeTrue())
+
+			controllerutil.RemoveFinalizer(&nc, NCFinalizer)
+			Expect(p.Update(event.UpdateEvent{ObjectOld: &nc, ObjectNew: &nc})).To(BeFalse())
 		})
 	})
 })
+
+// initializedNC returns an NC initialized by DNC and held by the finalizer.
+func initializedNC(namespacedName types.NamespacedName) ncapi.MultiTenantNetworkContainer {
+	return ncapi.MultiTenantNetworkContainer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       namespacedName.Name,
+			Namespace:  namespacedName.Namespace,
+			Generation: 2,
+			Finalizers: []string{NCFinalizer},
+		},
+		Spec: ncapi.MultiTenantNetworkContainerSpec{
+			UUID: "uuid",
+			Node: mockNodeName,
+		},
+		Status: ncapi.MultiTenantNetworkContainerStatus{
+			State:    NCStateInitialized,
+			IPSubnet: "1.2.3.0/24",
+			MultiTenantInfo: ncapi.MultiTenantInfo{
+				EncapType: "Vlan",
+				ID:        1,
+			},
+		},
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"os/exec"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/containernetworking/cni/libcni"
 	"github.com/containernetworking/cni/pkg/invoke"
@@ -203,18 +204,18 @@ func execPlugin(rt *libcni.RuntimeConf, netconf []byte, operation, path string)
 	}
 }
 
-// Attach - attaches network container to network.
-func (cn *NetworkContainers) Attach(podInfo cns.PodInfo, dockerContainerid string, netPluginConfig *NetPluginConfiguration) error {
+// Attach - attaches network container to the network of the sandbox.
+func (cn *NetworkContainers) Attach(podInfo cns.PodInfo, sandbox containerruntime.Sandbox, netPluginConfig *NetPluginConfiguration) error {
 	logger.Printf("[Azure CNS] NetworkContainers.Attach called")
-	err := configureNetworkContainerNetworking(cniAdd, podInfo.Name(), podInfo.Namespace(), dockerContainerid, netPluginConfig)
+	err := configureNetworkContainerNetworking(cniAdd, podInfo.Name(), podInfo.Namespace(), sandbox, netPluginConfig)
 	logger.Printf("[Azure CNS] NetworkContainers.Attach finished")
 	return err
 }
 
-// Detach - detaches network container from network.
-func (cn *NetworkContainers) Detach(podInfo cns.PodInfo, dockerContainerid string, netPluginConfig *NetPluginConfiguration) error {
+// Detach - detaches network container from the network of the sandbox.
+func (cn *NetworkContainers) Detach(podInfo cns.PodInfo, sandbox containerruntime.Sandbox, netPluginConfig *NetPluginConfiguration) error {
 	logger.Printf("[Azure CNS] NetworkContainers.Detach called")
-	err := configureNetworkContainerNetworking(cniDelete, podInfo.Name(), podInfo.Namespace(), dockerContainerid, netPluginConfig)
+	err := configureNetworkContainerNetworking(cniDelete, podInfo.Name(), podInfo.Namespace(), sandbox, netPluginConfig)
 	logger.Printf("[Azure CNS] NetworkContainers.Detach finished")
 	return err
 }
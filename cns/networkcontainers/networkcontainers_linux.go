@@ -9,6 +9,7 @@ import (
 	"os"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/containernetworking/cni/libcni"
 )
@@ -85,7 +86,7 @@ func deleteInterface(networkContainerID string) error {
 	return nil
 }
 
-func configureNetworkContainerNetworking(operation, podName, podNamespace, dockerContainerid string, netPluginConfig *NetPluginConfiguration) (err error) {
+func configureNetworkContainerNetworking(operation, podName, podNamespace string, sandbox containerruntime.Sandbox, netPluginConfig *NetPluginConfiguration) (err error) {
 	return fmt.Errorf("[Azure CNS] Operation is not supported in linux.")
 }
 
@@ -15,6 +15,7 @@ import (
 	"sync"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
@@ -218,10 +219,10 @@ func deleteInterface(interfaceName string) error {
 	return err
 }
 
-func configureNetworkContainerNetworking(operation, podName, podNamespace, dockerContainerid string, netPluginConfig *NetPluginConfiguration) (err error) {
+func configureNetworkContainerNetworking(operation, podName, podNamespace string, sandbox containerruntime.Sandbox, netPluginConfig *NetPluginConfiguration) (err error) {
 	cniRtConf := &libcni.RuntimeConf{
-		ContainerID: dockerContainerid,
-		NetNS:       "none",
+		ContainerID: sandbox.ID,
+		NetNS:       sandbox.NetNS,
 		IfName:      "eth0",
 		Args: [][2]string{
 			{k8sPodNamespaceStr, podNamespace},
@@ -237,7 +238,7 @@ func configureNetworkContainerNetworking(operation, podName, podNamespace, docke
 	logger.Printf("[Azure CNS] network configuration info %v", string(netConfig))
 
 	if err = execPlugin(cniRtConf, netConfig, operation, netPluginConfig.path); err != nil {
-		logger.Printf("[Azure CNS] Failed to invoke CNI with %s operation on docker container %s with error %v", operation, dockerContainerid, err)
+		logger.Printf("[Azure CNS] Failed to invoke CNI with %s operation on sandbox %s with error %v", operation, sandbox.ID, err)
 	}
 
 	return err
@@ -0,0 +1,34 @@
+package nmagentclient
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// StatusError is returned when NMAgent responds with a status other than 200.
+type StatusError struct {
+	Op         string
+	StatusCode int
+	RequestID  string
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("NMAgent %s failed with status %d, request ID %s", e.Op, e.StatusCode, e.RequestID)
+}
+
+// Temporary returns true if the request may succeed when retried.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// StatusCode returns the status NMAgent responded to the request with, or 0 if it didn't respond.
+func StatusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
@@ -2,16 +2,20 @@ package nmagentclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/common"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -19,6 +23,19 @@ const (
 	GetNmAgentSupportedApiURLFmt       = "http://%s/machine/plugins/?comp=nmagent&type=GetSupportedApis"
 	GetNetworkContainerVersionURLFmt   = "http://%s/machine/plugins/?comp=nmagent&type=NetworkManagement/interfaces/%s/networkContainers/%s/version/authenticationToken/%s/api-version/1"
 	GetNcVersionListWithOutTokenURLFmt = "http://%s/machine/plugins/?comp=nmagent&type=NetworkManagement/interfaces/api-version/%s"
+
+	// RequestIDHeader carries the ID of a request to NMAgent, which is the same for each of its retries.
+	RequestIDHeader = "x-ms-client-request-id"
+)
+
+// NMAgent operations, as reported in StatusError.
+const (
+	OpJoinNetwork                  = "JoinNetwork"
+	OpPublishNetworkContainer      = "PublishNetworkContainer"
+	OpUnpublishNetworkContainer    = "UnpublishNetworkContainer"
+	OpGetNetworkContainerVersion   = "GetNetworkContainerVersion"
+	OpGetNmAgentSupportedApis      = "GetNmAgentSupportedApis"
+	OpGetNcVersionListWithOutToken = "GetNcVersionListWithOutToken"
 )
 
 //WireServerIP - wire server ip
@@ -46,139 +63,163 @@ type NMANetworkContainerListResponse struct {
 	Containers   []ContainerInfo `json:"networkContainers"`
 }
 
+// Response is the status and body of an NMAgent response.
+type Response struct {
+	StatusCode int
+	Body       []byte
+	RequestID  string
+}
+
+func (response *Response) String() string {
+	return fmt.Sprintf("{StatusCode:%d RequestID:%s Body:%s}", response.StatusCode, response.RequestID, response.Body)
+}
+
+// RetryOptions sets how often and how quickly a failed request to NMAgent is retried.
+// GETs which fail to connect, or with a 5xx or 429 status, are retried. Other requests, such as joining a network
+// or publishing an NC, aren't idempotent, so they are only retried if they were never sent or NMAgent throttled them.
+type RetryOptions struct {
+	// Attempts is the maximum number of times a request is sent.
+	Attempts int
+	// InitialBackoff is the wait before the first retry, it doubles on each retry up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryOptions are used when the client config has no retry options.
+var DefaultRetryOptions = RetryOptions{
+	Attempts:       3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// Config of an NMAgent client.
+type Config struct {
+	// NCVersionListURL is the URL of the NC version list, defaults to the wireserver's.
+	NCVersionListURL string
+	// HTTPClient defaults to the common HTTP client.
+	HTTPClient *http.Client
+	Retry      RetryOptions
+}
+
 // NMAgentClient is client to handle queries to nmagent
 type NMAgentClient struct {
 	connectionURL string
+	httpClient    *http.Client
+	retry         RetryOptions
 }
 
 // NMAgentClientInterface has interface that nmagent client will handle
 type NMAgentClientInterface interface {
-	GetNcVersionListWithOutToken(ncNeedUpdateList []string) map[string]int
+	JoinNetwork(ctx context.Context, networkID, joinNetworkURL string) (*Response, error)
+	PublishNetworkContainer(ctx context.Context, networkContainerID, createNetworkContainerURL string, requestBodyData []byte) (*Response, error)
+	UnpublishNetworkContainer(ctx context.Context, networkContainerID, deleteNetworkContainerURL string) (*Response, error)
+	GetNetworkContainerVersion(ctx context.Context, networkContainerID, getNetworkContainerVersionURL string) (*NMANetworkContainerResponse, error)
+	GetNmAgentSupportedApis(ctx context.Context, getNmAgentSupportedApisURL string) ([]string, error)
+	GetNcVersionListWithOutToken(ctx context.Context, ncNeedUpdateList []string) (map[string]int, error)
 }
 
 // NewNMAgentClient create a new nmagent client.
 func NewNMAgentClient(url string) (*NMAgentClient, error) {
-	if url == "" {
-		url = fmt.Sprintf(GetNcVersionListWithOutTokenURLFmt, WireserverIP, getNcVersionListWithOutTokenURLVersion)
+	return New(Config{NCVersionListURL: url}), nil
+}
+
+// New creates an NMAgent client with the config.
+func New(config Config) *NMAgentClient {
+	if config.NCVersionListURL == "" {
+		config.NCVersionListURL = fmt.Sprintf(GetNcVersionListWithOutTokenURLFmt, WireserverIP, getNcVersionListWithOutTokenURLVersion)
+	}
+	if config.Retry.Attempts <= 0 {
+		config.Retry = DefaultRetryOptions
 	}
 	return &NMAgentClient{
-		connectionURL: url,
-	}, nil
+		connectionURL: config.NCVersionListURL,
+		httpClient:    config.HTTPClient,
+		retry:         config.Retry,
+	}
 }
 
 // JoinNetwork joins the given network
-func JoinNetwork(
-	networkID string,
-	joinNetworkURL string) (*http.Response, error) {
+func (nmagentclient *NMAgentClient) JoinNetwork(ctx context.Context, networkID, joinNetworkURL string) (*Response, error) {
 	logger.Printf("[NMAgentClient] JoinNetwork: %s", networkID)
 
 	// Empty body is required as wireserver cannot handle a post without the body.
-	var body bytes.Buffer
-	json.NewEncoder(&body).Encode("")
-	response, err := common.GetHttpClient().Post(joinNetworkURL, "application/json", &body)
-
-	if err == nil && response.StatusCode == http.StatusOK {
-		defer response.Body.Close()
-	}
+	response, err := nmagentclient.do(ctx, OpJoinNetwork, http.MethodPost, joinNetworkURL, emptyBody())
 
 	logger.Printf("[NMAgentClient][Response] Join network: %s. Response: %+v. Error: %v",
 		networkID, response, err)
-
 	return response, err
 }
 
 // PublishNetworkContainer publishes given network container
-func PublishNetworkContainer(
+func (nmagentclient *NMAgentClient) PublishNetworkContainer(
+	ctx context.Context,
 	networkContainerID string,
 	createNetworkContainerURL string,
-	requestBodyData []byte) (*http.Response, error) {
+	requestBodyData []byte) (*Response, error) {
 	logger.Printf("[NMAgentClient] PublishNetworkContainer NC: %s", networkContainerID)
 
-	requestBody := bytes.NewBuffer(requestBodyData)
-	response, err := common.GetHttpClient().Post(createNetworkContainerURL, "application/json", requestBody)
+	response, err := nmagentclient.do(ctx, OpPublishNetworkContainer, http.MethodPost, createNetworkContainerURL, requestBodyData)
 
 	logger.Printf("[NMAgentClient][Response] Publish NC: %s. Response: %+v. Error: %v",
 		networkContainerID, response, err)
-
 	return response, err
 }
 
 // UnpublishNetworkContainer unpublishes given network container
-func UnpublishNetworkContainer(
+func (nmagentclient *NMAgentClient) UnpublishNetworkContainer(
+	ctx context.Context,
 	networkContainerID string,
-	deleteNetworkContainerURL string) (*http.Response, error) {
+	deleteNetworkContainerURL string) (*Response, error) {
 	logger.Printf("[NMAgentClient] UnpublishNetworkContainer NC: %s", networkContainerID)
 
 	// Empty body is required as wireserver cannot handle a post without the body.
-	var body bytes.Buffer
-	json.NewEncoder(&body).Encode("")
-	response, err := common.GetHttpClient().Post(deleteNetworkContainerURL, "application/json", &body)
+	response, err := nmagentclient.do(ctx, OpUnpublishNetworkContainer, http.MethodPost, deleteNetworkContainerURL, emptyBody())
 
 	logger.Printf("[NMAgentClient][Response] Unpublish NC: %s. Response: %+v. Error: %v",
 		networkContainerID, response, err)
-
 	return response, err
 }
 
 // GetNetworkContainerVersion :- Retrieves NC version from NMAgent
-func GetNetworkContainerVersion(
+func (nmagentclient *NMAgentClient) GetNetworkContainerVersion(
+	ctx context.Context,
 	networkContainerID,
-	getNetworkContainerVersionURL string) (*http.Response, error) {
+	getNetworkContainerVersionURL string) (*NMANetworkContainerResponse, error) {
 	logger.Printf("[NMAgentClient] GetNetworkContainerVersion NC: %s", networkContainerID)
 
-	response, err := common.GetHttpClient().Get(getNetworkContainerVersionURL)
-
+	response, err := nmagentclient.do(ctx, OpGetNetworkContainerVersion, http.MethodGet, getNetworkContainerVersionURL, nil)
 	logger.Printf("[NMAgentClient][Response] GetNetworkContainerVersion NC: %s. Response: %+v. Error: %v",
 		networkContainerID, response, err)
-	return response, err
+	if err != nil {
+		return nil, err
+	}
+
+	var versionResponse NMANetworkContainerResponse
+	if err = json.Unmarshal(response.Body, &versionResponse); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode NC version response, request ID %s", response.RequestID)
+	}
+	return &versionResponse, nil
 }
 
 // GetNmAgentSupportedApis :- Retrieves Supported Apis from NMAgent
-func GetNmAgentSupportedApis(
-	httpc *http.Client,
-	getNmAgentSupportedApisURL string) ([]string, error) {
-	var (
-		returnErr error
-	)
-
+func (nmagentclient *NMAgentClient) GetNmAgentSupportedApis(ctx context.Context, getNmAgentSupportedApisURL string) ([]string, error) {
 	if getNmAgentSupportedApisURL == "" {
 		getNmAgentSupportedApisURL = fmt.Sprintf(
 			GetNmAgentSupportedApiURLFmt, WireserverIP)
 	}
 
-	response, err := httpc.Get(getNmAgentSupportedApisURL)
+	response, err := nmagentclient.do(ctx, OpGetNmAgentSupportedApis, http.MethodGet, getNmAgentSupportedApisURL, nil)
 	if err != nil {
-		returnErr = fmt.Errorf(
-			"Failed to retrieve Supported Apis from NMAgent with error %v",
-			err.Error())
-		logger.Errorf("[Azure-CNS] %s", returnErr)
-		return nil, returnErr
-	}
-	if response == nil {
-		returnErr = fmt.Errorf(
-			"Response from getNmAgentSupportedApis call is <nil>")
-		logger.Errorf("[Azure-CNS] %s", returnErr)
-		return nil, returnErr
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		returnErr = fmt.Errorf(
-			"Failed to retrieve Supported Apis from NMAgent with StatusCode: %d",
-			response.StatusCode)
-		logger.Errorf("[Azure-CNS] %s", returnErr)
-		return nil, returnErr
+		err = errors.Wrap(err, "Failed to retrieve Supported Apis from NMAgent")
+		logger.Errorf("[Azure-CNS] %s", err)
+		return nil, err
 	}
 
 	var xmlDoc NMAgentSupportedApisResponseXML
-	decoder := xml.NewDecoder(response.Body)
-	err = decoder.Decode(&xmlDoc)
-	if err != nil {
-		returnErr = fmt.Errorf(
-			"Failed to decode XML response of Supported Apis from NMAgent with error %v",
-			err.Error())
-		logger.Errorf("[Azure-CNS] %s", returnErr)
-		return nil, returnErr
+	if err = xml.Unmarshal(response.Body, &xmlDoc); err != nil {
+		err = errors.Wrapf(err, "Failed to decode XML response of Supported Apis from NMAgent, request ID %s", response.RequestID)
+		logger.Errorf("[Azure-CNS] %s", err)
+		return nil, err
 	}
 
 	logger.Printf("[NMAgentClient][Response] GetNmAgentSupportedApis. Response: %+v.", response)
@@ -186,30 +227,27 @@ func GetNmAgentSupportedApis(
 }
 
 // GetNcVersionListWithOutToken query nmagent for programmed container version.
-func (nmagentclient *NMAgentClient) GetNcVersionListWithOutToken(ncNeedUpdateList []string) map[string]int {
-	ncVersionList := make(map[string]int)
+func (nmagentclient *NMAgentClient) GetNcVersionListWithOutToken(ctx context.Context, ncNeedUpdateList []string) (map[string]int, error) {
 	now := time.Now()
-	response, err := http.Get(nmagentclient.connectionURL)
+	response, err := nmagentclient.do(ctx, OpGetNcVersionListWithOutToken, http.MethodGet, nmagentclient.connectionURL, nil)
 	latency := time.Since(now)
 	logger.Printf("[NMAgentClient][Response] GetNcVersionListWithOutToken response: %+v, latency is %d", response, latency.Milliseconds())
-
-	if response.StatusCode != http.StatusOK {
-		logger.Printf("[NMAgentClient][Response] GetNcVersionListWithOutToken failed with %d, err is %v", response.StatusCode, err)
-		return nil
+	if err != nil {
+		return nil, err
 	}
 
 	var nmaNcListResponse NMANetworkContainerListResponse
-	rBytes, _ := ioutil.ReadAll(response.Body)
-	if err := json.Unmarshal(rBytes, &nmaNcListResponse); err != nil {
-		logger.Printf("[NMAgentClient][Response] GetNcVersionListWithOutToken unmarshal failed with %s", err)
-		return nil
+	if err = json.Unmarshal(response.Body, &nmaNcListResponse); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode NC version list response, request ID %s", response.RequestID)
 	}
-	logger.Printf("NMAgent NC List Response is %s", nmaNcListResponse)
+	logger.Printf("NMAgent NC List Response is %+v", nmaNcListResponse)
 
 	var receivedNcVersionListInMap = make(map[string]string)
 	for _, containers := range nmaNcListResponse.Containers {
 		receivedNcVersionListInMap[containers.NetworkContainerID] = containers.Version
 	}
+
+	ncVersionList := make(map[string]int)
 	for _, ncID := range ncNeedUpdateList {
 		if version, ok := receivedNcVersionListInMap[ncID]; ok {
 			if versionInInt, err := strconv.Atoi(version); err != nil {
@@ -220,5 +258,93 @@ func (nmagentclient *NMAgentClient) GetNcVersionListWithOutToken(ncNeedUpdateLis
 			}
 		}
 	}
-	return ncVersionList
+	return ncVersionList, nil
+}
+
+// do sends the request and retries it while it fails with a temporary error. The last response
+// received is returned with the error, which is a *StatusError if NMAgent responded with a status other than 200.
+func (nmagentclient *NMAgentClient) do(ctx context.Context, op, method, url string, body []byte) (*Response, error) {
+	httpClient := nmagentclient.httpClient
+	if httpClient == nil {
+		httpClient = common.GetHttpClient()
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	requestID := uuid.New().String()
+	backoff := nmagentclient.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		response, err := send(ctx, httpClient, method, url, body, requestID)
+		if err == nil && response.StatusCode != http.StatusOK {
+			err = &StatusError{Op: op, StatusCode: response.StatusCode, RequestID: requestID, Body: response.Body}
+		}
+		if err == nil || !isRetriable(ctx, method, err) || attempt >= nmagentclient.retry.Attempts {
+			return response, err
+		}
+
+		logger.Printf("[NMAgentClient] %s attempt %d with request ID %s failed, retrying in %s: %v", op, attempt, requestID, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return response, errors.Wrapf(ctx.Err(), "%s with request ID %s cancelled after %d attempts, last error: %v", op, requestID, attempt, err)
+		}
+
+		if backoff *= 2; backoff > nmagentclient.retry.MaxBackoff {
+			backoff = nmagentclient.retry.MaxBackoff
+		}
+	}
+}
+
+func send(ctx context.Context, httpClient *http.Client, method, url string, body []byte, requestID string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set(RequestIDHeader, requestID)
+	if body != nil {
+		req.Header.Set(common.ContentType, common.JsonContent)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "request ID %s failed", requestID)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read response to request ID %s", requestID)
+	}
+	return &Response{StatusCode: resp.StatusCode, Body: respBody, RequestID: requestID}, nil
+}
+
+// isRetriable returns true if the request failed with a temporary error which it is safe to retry the request
+// with, and the context is not done.
+func isRetriable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if method != http.MethodGet {
+			// NMAgent may have applied the request before failing, unless it throttled it
+			return statusErr.StatusCode == http.StatusTooManyRequests
+		}
+		return statusErr.Temporary()
+	}
+	if method != http.MethodGet {
+		// the request may have been applied unless the connection failed before it was sent
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	return true
+}
+
+// emptyBody returns the JSON encoding of an empty string.
+func emptyBody() []byte {
+	var body bytes.Buffer
+	_ = json.NewEncoder(&body).Encode("")
+	return body.Bytes()
 }
@@ -0,0 +1,184 @@
+package nmagentclient_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/nmagentclient"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	logger.InitLogger("testlogs", 0, 0, "./")
+}
+
+func newClient(server *fakes.NMAgentServerFake, attempts int) *nmagentclient.NMAgentClient {
+	return nmagentclient.New(nmagentclient.Config{
+		NCVersionListURL: fmt.Sprintf(nmagentclient.GetNcVersionListWithOutTokenURLFmt, server.Host(), "2"),
+		Retry:            nmagentclient.RetryOptions{Attempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+}
+
+func versionURL(server *fakes.NMAgentServerFake, ncID string) string {
+	return fmt.Sprintf(nmagentclient.GetNetworkContainerVersionURLFmt, server.Host(), "intf", ncID, "token")
+}
+
+func TestRetriesTemporaryFailures(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	server.SetNCVersion("nc1", "3")
+	server.FailNext(nmagentclient.OpGetNetworkContainerVersion, http.StatusInternalServerError, http.StatusTooManyRequests)
+
+	resp, err := newClient(server, 3).GetNetworkContainerVersion(context.Background(), "nc1", versionURL(server, "nc1"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", resp.Version)
+
+	requestIDs := server.RequestIDs(nmagentclient.OpGetNetworkContainerVersion)
+	require.Len(t, requestIDs, 3)
+	assert.NotEmpty(t, requestIDs[0])
+	assert.Equal(t, requestIDs[0], requestIDs[1])
+	assert.Equal(t, requestIDs[0], requestIDs[2])
+}
+
+func TestReturnsStatusErrorAfterAttempts(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	server.FailNext(nmagentclient.OpJoinNetwork, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+
+	resp, err := newClient(server, 2).JoinNetwork(context.Background(), "vnet1", server.JoinNetworkURL("vnet1"))
+	require.Error(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, nmagentclient.StatusCode(err))
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Len(t, server.RequestIDs(nmagentclient.OpJoinNetwork), 2)
+	assert.False(t, server.IsNetworkJoined("vnet1"))
+}
+
+func TestDoesNotRetryNonIdempotentRequestsWhichMayHaveBeenApplied(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	server.FailNext(nmagentclient.OpPublishNetworkContainer, http.StatusInternalServerError)
+
+	url := server.PublishNetworkContainerURL("intf", "nc1", "token")
+	_, err := newClient(server, 3).PublishNetworkContainer(context.Background(), "nc1", url, []byte("{}"))
+	require.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, nmagentclient.StatusCode(err))
+	assert.Len(t, server.RequestIDs(nmagentclient.OpPublishNetworkContainer), 1)
+}
+
+func TestRetriesNonIdempotentRequestsWhichWereNotSent(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	url := server.JoinNetworkURL("vnet1")
+	server.Close()
+
+	var attempts int32
+	client := nmagentclient.New(nmagentclient.Config{
+		HTTPClient: &http.Client{Transport: countingTransport{attempts: &attempts}},
+		Retry:      nmagentclient.RetryOptions{Attempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	_, err := client.JoinNetwork(context.Background(), "vnet1", url)
+	require.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+// countingTransport counts the requests sent through the default transport.
+type countingTransport struct {
+	attempts *int32
+}
+
+func (c countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(c.attempts, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	server.FailNext(nmagentclient.OpPublishNetworkContainer, http.StatusBadRequest)
+
+	url := server.PublishNetworkContainerURL("intf", "nc1", "token")
+	_, err := newClient(server, 3).PublishNetworkContainer(context.Background(), "nc1", url, []byte("{}"))
+	require.Error(t, err)
+
+	var statusErr *nmagentclient.StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, nmagentclient.OpPublishNetworkContainer, statusErr.Op)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.False(t, statusErr.Temporary())
+	assert.Equal(t, server.RequestIDs(nmagentclient.OpPublishNetworkContainer), []string{statusErr.RequestID})
+}
+
+func TestStopsRetryingWhenContextIsDone(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	server.FailNext(nmagentclient.OpGetNcVersionListWithOutToken, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	client := nmagentclient.New(nmagentclient.Config{
+		NCVersionListURL: fmt.Sprintf(nmagentclient.GetNcVersionListWithOutTokenURLFmt, server.Host(), "2"),
+		Retry:            nmagentclient.RetryOptions{Attempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Minute},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetNcVersionListWithOutToken(ctx, []string{"nc1"})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Len(t, server.RequestIDs(nmagentclient.OpGetNcVersionListWithOutToken), 1)
+}
+
+func TestPublishAndUnpublishNetworkContainer(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	client := newClient(server, 1)
+
+	resp, err := client.JoinNetwork(context.Background(), "vnet1", server.JoinNetworkURL("vnet1"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, server.IsNetworkJoined("vnet1"))
+
+	_, err = client.PublishNetworkContainer(context.Background(), "nc1", server.PublishNetworkContainerURL("intf", "nc1", "token"), []byte(`{"a":1}`))
+	require.NoError(t, err)
+	body, ok := server.PublishedNC("nc1")
+	require.True(t, ok)
+	assert.Equal(t, `{"a":1}`, string(body))
+
+	_, err = client.UnpublishNetworkContainer(context.Background(), "nc1", server.UnpublishNetworkContainerURL("intf", "nc1", "token"))
+	require.NoError(t, err)
+	_, ok = server.PublishedNC("nc1")
+	assert.False(t, ok)
+
+	version, err := client.GetNetworkContainerVersion(context.Background(), "nc1", versionURL(server, "nc1"))
+	require.NoError(t, err)
+	assert.Equal(t, "404", version.ResponseCode)
+}
+
+func TestGetNcVersionListWithOutToken(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	server.SetNCVersion("nc1", "1")
+	server.SetNCVersion("nc2", "2")
+	server.SetNCVersion("nc3", "not-a-version")
+
+	versions, err := newClient(server, 1).GetNcVersionListWithOutToken(context.Background(), []string{"nc1", "nc3", "nc4"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"nc1": 1}, versions)
+}
+
+func TestGetNmAgentSupportedApis(t *testing.T) {
+	server := fakes.NewNMAgentServerFake()
+	defer server.Close()
+	server.SetSupportedApis("NetworkManagementDNSSupport", "NetworkManagement")
+
+	apis, err := newClient(server, 1).GetNmAgentSupportedApis(context.Background(),
+		fmt.Sprintf(nmagentclient.GetNmAgentSupportedApiURLFmt, server.Host()))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"NetworkManagementDNSSupport", "NetworkManagement"}, apis)
+}
@@ -4,8 +4,8 @@
 package restserver
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"regexp"
@@ -13,11 +13,11 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/hnsclient"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/nmagentclient"
 	"github.com/Azure/azure-container-networking/cns/types"
-	"github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/platform"
 )
 
@@ -72,12 +72,15 @@ func (service *HTTPRestService) createNetwork(w http.ResponseWriter, r *http.Req
 		} else {
 			switch r.Method {
 			case "POST":
-				dc := service.dockerClient
+				dc := service.containerRuntime
 				rt := service.routingTable
 				err = dc.NetworkExists(req.NetworkName)
 
-				// Network does not exist.
-				if err != nil {
+				if errors.Is(err, containerruntime.ErrNotSupported) {
+					returnMessage = fmt.Sprintf("[Azure CNS] Error. CreateNetwork failed %v.", err.Error())
+					returnCode = types.UnsupportedEnvironment
+				} else if err != nil {
+					// Network does not exist.
 					switch service.state.NetworkType {
 					case "Underlay":
 						switch service.state.Location {
@@ -173,11 +176,14 @@ func (service *HTTPRestService) deleteNetwork(w http.ResponseWriter, r *http.Req
 
 	switch r.Method {
 	case "POST":
-		dc := service.dockerClient
+		dc := service.containerRuntime
 		err := dc.NetworkExists(req.NetworkName)
 
-		// Network does exist
-		if err == nil {
+		if errors.Is(err, containerruntime.ErrNotSupported) {
+			returnMessage = fmt.Sprintf("[Azure CNS] Error. DeleteNetwork failed %v.", err.Error())
+			returnCode = types.UnsupportedEnvironment
+		} else if err == nil {
+			// Network does exist
 			logger.Printf("[Azure CNS] Deleting network with name %v.", req.NetworkName)
 			err := dc.DeleteNetwork(req.NetworkName)
 			if err != nil {
@@ -779,36 +785,7 @@ func (service *HTTPRestService) createOrUpdateNetworkContainer(w http.ResponseWr
 	var returnMessage string
 	switch r.Method {
 	case "POST":
-		if req.NetworkContainerType == cns.WebApps {
-			// try to get the saved nc state if it exists
-			existing, ok := service.getNetworkContainerDetails(req.NetworkContainerid)
-
-			// create/update nc only if it doesn't exist or it exists and the requested version is different from the saved version
-			if !ok || (ok && existing.VMVersion != req.Version) {
-				nc := service.networkContainer
-				if err = nc.Create(req); err != nil {
-					returnMessage = fmt.Sprintf("[Azure CNS] Error. CreateOrUpdateNetworkContainer failed %v", err.Error())
-					returnCode = types.UnexpectedError
-					break
-				}
-			}
-		} else if req.NetworkContainerType == cns.AzureContainerInstance {
-			// try to get the saved nc state if it exists
-			existing, ok := service.getNetworkContainerDetails(req.NetworkContainerid)
-
-			// create/update nc only if it doesn't exist or it exists and the requested version is different from the saved version
-			if ok && existing.VMVersion != req.Version {
-				nc := service.networkContainer
-				netPluginConfig := service.getNetPluginDetails()
-				if err = nc.Update(req, netPluginConfig); err != nil {
-					returnMessage = fmt.Sprintf("[Azure CNS] Error. CreateOrUpdateNetworkContainer failed %v", err.Error())
-					returnCode = types.UnexpectedError
-					break
-				}
-			}
-		}
-
-		returnCode, returnMessage = service.saveNetworkContainerGoalState(req)
+		returnCode, returnMessage = service.createOrUpdateNetworkContainerFromRequest(req)
 
 	default:
 		returnMessage = "[Azure CNS] Error. CreateOrUpdateNetworkContainer did not receive a POST."
@@ -825,12 +802,51 @@ func (service *HTTPRestService) createOrUpdateNetworkContainer(w http.ResponseWr
 
 	// If the NC was created successfully, log NC snapshot.
 	if returnCode == types.Success {
-		logNCSnapshot(req)
+		service.logNCSnapshot(req)
 	}
 
 	logger.Response(service.Name, reserveResp, resp.ReturnCode, err)
 }
 
+// createOrUpdateNetworkContainerFromRequest creates or updates the NC of the request on the host,
+// if its type needs it, and saves its goal state.
+func (service *HTTPRestService) createOrUpdateNetworkContainerFromRequest(req cns.CreateNetworkContainerRequest) (types.ResponseCode, string) {
+	if code, message := service.createOrUpdateNetworkContainerOnHost(req); code != types.Success {
+		return code, message
+	}
+	return service.saveNetworkContainerGoalState(req)
+}
+
+// createOrUpdateNetworkContainerOnHost creates or updates the NC of the request on the host, if its type needs it.
+func (service *HTTPRestService) createOrUpdateNetworkContainerOnHost(req cns.CreateNetworkContainerRequest) (types.ResponseCode, string) {
+	if req.NetworkContainerType == cns.WebApps {
+		// try to get the saved nc state if it exists
+		existing, ok := service.getNetworkContainerDetails(req.NetworkContainerid)
+
+		// create/update nc only if it doesn't exist or it exists and the requested version is different from the saved version
+		if !ok || (ok && existing.VMVersion != req.Version) {
+			nc := service.networkContainer
+			if err := nc.Create(req); err != nil {
+				return types.UnexpectedError, fmt.Sprintf("[Azure CNS] Error. CreateOrUpdateNetworkContainer failed %v", err.Error())
+			}
+		}
+	} else if req.NetworkContainerType == cns.AzureContainerInstance {
+		// try to get the saved nc state if it exists
+		existing, ok := service.getNetworkContainerDetails(req.NetworkContainerid)
+
+		// create/update nc only if it doesn't exist or it exists and the requested version is different from the saved version
+		if ok && existing.VMVersion != req.Version {
+			nc := service.networkContainer
+			netPluginConfig := service.getNetPluginDetails()
+			if err := nc.Update(req, netPluginConfig); err != nil {
+				return types.UnexpectedError, fmt.Sprintf("[Azure CNS] Error. CreateOrUpdateNetworkContainer failed %v", err.Error())
+			}
+		}
+	}
+
+	return types.Success, ""
+}
+
 func (service *HTTPRestService) getNetworkContainerByID(w http.ResponseWriter, r *http.Request) {
 	logger.Printf("[Azure CNS] getNetworkContainerByID")
 
@@ -873,12 +889,48 @@ func (service *HTTPRestService) getNetworkContainerByOrchestratorContext(w http.
 		return
 	}
 
-	getNetworkContainerResponse := service.getNetworkContainerResponse(req)
+	getNetworkContainerResponse := service.getNetworkContainerResponse(r.Context(), req)
 	returnCode := getNetworkContainerResponse.Response.ReturnCode
 	err = service.Listener.Encode(w, &getNetworkContainerResponse)
 	logger.Response(service.Name, getNetworkContainerResponse, returnCode, err)
 }
 
+// getNetworkContainerStatus returns the NC version requested by DNC, the one NMAgent reported as programmed,
+// and how many of the NC's IPs are waiting for NMAgent to program them.
+func (service *HTTPRestService) getNetworkContainerStatus(w http.ResponseWriter, r *http.Request) {
+	logger.Printf("[Azure CNS] getNetworkContainerStatus")
+
+	var req cns.GetNetworkContainerStatusRequest
+	err := service.Listener.Decode(w, r, &req)
+	logger.Request(service.Name, &req, err)
+	if err != nil {
+		return
+	}
+
+	resp := cns.GetNetworkContainerStatusResponse{NetworkContainerid: req.NetworkContainerid}
+
+	service.RLock()
+	ncStatus, exists := service.state.ContainerStatus[req.NetworkContainerid]
+	if exists {
+		resp.Version = ncStatus.CreateNetworkContainerRequest.Version
+		resp.AzureHostVersion = ncStatus.HostVersion
+		for _, ipConfig := range service.PodIPConfigState {
+			if ipConfig.NCID == req.NetworkContainerid && ipConfig.State == cns.PendingProgramming {
+				resp.PendingProgrammingIPCount++
+			}
+		}
+	}
+	service.RUnlock()
+
+	if !exists {
+		resp.Response.ReturnCode = types.UnknownContainerID
+		resp.Response.Message = fmt.Sprintf("[Azure CNS] Error. Network container %s not found", req.NetworkContainerid)
+	}
+
+	err = service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp, resp.Response.ReturnCode, err)
+}
+
 func (service *HTTPRestService) deleteNetworkContainer(w http.ResponseWriter, r *http.Request) {
 	logger.Printf("[Azure CNS] deleteNetworkContainer")
 
@@ -899,42 +951,9 @@ func (service *HTTPRestService) deleteNetworkContainer(w http.ResponseWriter, r
 
 	switch r.Method {
 	case "POST":
-		var containerStatus containerstatus
-		var ok bool
-
-		containerStatus, ok = service.getNetworkContainerDetails(req.NetworkContainerid)
-
-		if !ok {
-			logger.Printf("Not able to retrieve network container details for this container id %v", req.NetworkContainerid)
-			break
-		}
-
-		if containerStatus.CreateNetworkContainerRequest.NetworkContainerType == cns.WebApps {
-			nc := service.networkContainer
-			if err := nc.Delete(req.NetworkContainerid); err != nil {
-				returnMessage = fmt.Sprintf("[Azure CNS] Error. DeleteNetworkContainer failed %v", err.Error())
-				returnCode = types.UnexpectedError
-				break
-			}
-		}
-
-		service.Lock()
-		defer service.Unlock()
-
-		if service.state.ContainerStatus != nil {
-			delete(service.state.ContainerStatus, req.NetworkContainerid)
+		if req.NetworkContainerid != "" {
+			_, returnCode, returnMessage = service.deleteNetworkContainerByID(req.NetworkContainerid)
 		}
-
-		if service.state.ContainerIDByOrchestratorContext != nil {
-			for orchestratorContext, networkContainerID := range service.state.ContainerIDByOrchestratorContext {
-				if networkContainerID == req.NetworkContainerid {
-					delete(service.state.ContainerIDByOrchestratorContext, orchestratorContext)
-					break
-				}
-			}
-		}
-
-		service.saveState()
 	default:
 		returnMessage = "[Azure CNS] Error. DeleteNetworkContainer did not receive a POST."
 		returnCode = types.InvalidParameter
@@ -950,6 +969,44 @@ func (service *HTTPRestService) deleteNetworkContainer(w http.ResponseWriter, r
 	logger.Response(service.Name, reserveResp, resp.ReturnCode, err)
 }
 
+// deleteNetworkContainerByID deletes the NC from the host, if its type needs it, and its saved state.
+// It returns false if CNS has no such NC.
+func (service *HTTPRestService) deleteNetworkContainerByID(networkContainerID string) (bool, types.ResponseCode, string) {
+	containerStatus, ok := service.getNetworkContainerDetails(networkContainerID)
+	if !ok {
+		logger.Printf("Not able to retrieve network container details for this container id %v", networkContainerID)
+		return false, types.Success, ""
+	}
+
+	if containerStatus.CreateNetworkContainerRequest.NetworkContainerType == cns.WebApps {
+		nc := service.networkContainer
+		if err := nc.Delete(networkContainerID); err != nil {
+			return true, types.UnexpectedError, fmt.Sprintf("[Azure CNS] Error. DeleteNetworkContainer failed %v", err.Error())
+		}
+	}
+
+	service.Lock()
+	defer service.Unlock()
+
+	if service.state.ContainerStatus != nil {
+		delete(service.state.ContainerStatus, networkContainerID)
+		deleteNCVersions(networkContainerID)
+		service.publishNCEvent(cns.NetworkContainerDeleted, containerStatus)
+	}
+
+	if service.state.ContainerIDByOrchestratorContext != nil {
+		for orchestratorContext, ncID := range service.state.ContainerIDByOrchestratorContext {
+			if ncID == networkContainerID {
+				delete(service.state.ContainerIDByOrchestratorContext, orchestratorContext)
+				break
+			}
+		}
+	}
+
+	service.saveState()
+	return true, types.Success, ""
+}
+
 func (service *HTTPRestService) getInterfaceForContainer(w http.ResponseWriter, r *http.Request) {
 	logger.Printf("[Azure CNS] getInterfaceForContainer")
 
@@ -1014,7 +1071,7 @@ func (service *HTTPRestService) attachNetworkContainerToNetwork(w http.ResponseW
 		return
 	}
 
-	resp := service.attachOrDetachHelper(req, attach, r.Method)
+	resp := service.attachOrDetachHelper(r.Context(), req, attach, r.Method)
 	attachResp := &cns.AttachContainerToNetworkResponse{Response: resp}
 	err = service.Listener.Encode(w, &attachResp)
 	logger.Response(service.Name, attachResp, resp.ReturnCode, err)
@@ -1030,7 +1087,7 @@ func (service *HTTPRestService) detachNetworkContainerFromNetwork(w http.Respons
 		return
 	}
 
-	resp := service.attachOrDetachHelper(req, detach, r.Method)
+	resp := service.attachOrDetachHelper(r.Context(), req, detach, r.Method)
 	detachResp := &cns.DetachContainerFromNetworkResponse{Response: resp}
 	err = service.Listener.Encode(w, &detachResp)
 	logger.Response(service.Name, detachResp, resp.ReturnCode, err)
@@ -1096,7 +1153,7 @@ func (service *HTTPRestService) publishNetworkContainer(w http.ResponseWriter, r
 		req                 cns.PublishNetworkContainerRequest
 		returnCode          types.ResponseCode
 		returnMessage       string
-		publishResponse     *http.Response
+		publishResponse     *nmagentclient.Response
 		publishStatusCode   int
 		publishResponseBody []byte
 		publishError        error
@@ -1123,7 +1180,7 @@ func (service *HTTPRestService) publishNetworkContainer(w http.ResponseWriter, r
 	switch r.Method {
 	case "POST":
 		// Join the network
-		publishResponse, publishError, err = service.joinNetwork(req.NetworkID, req.JoinNetworkURL)
+		publishResponse, publishError, err = service.joinNetwork(r.Context(), req.NetworkID, req.JoinNetworkURL)
 		if err == nil {
 			isNetworkJoined = true
 		} else {
@@ -1133,11 +1190,12 @@ func (service *HTTPRestService) publishNetworkContainer(w http.ResponseWriter, r
 
 		if isNetworkJoined {
 			// Publish Network Container
-			publishResponse, publishError = nmagentclient.PublishNetworkContainer(
+			publishResponse, publishError = service.nmagentClient.PublishNetworkContainer(
+				r.Context(),
 				req.NetworkContainerID,
 				req.CreateNetworkContainerURL,
 				req.CreateNetworkContainerRequestBody)
-			if publishError != nil || publishResponse.StatusCode != http.StatusOK {
+			if publishError != nil {
 				returnMessage = fmt.Sprintf("Failed to publish Network Container: %s", req.NetworkContainerID)
 				returnCode = types.NetworkContainerPublishFailed
 				logger.Errorf("[Azure-CNS] %s", returnMessage)
@@ -1172,16 +1230,7 @@ func (service *HTTPRestService) publishNetworkContainer(w http.ResponseWriter, r
 
 	if publishResponse != nil {
 		publishStatusCode = publishResponse.StatusCode
-
-		var errParse error
-		publishResponseBody, errParse = ioutil.ReadAll(publishResponse.Body)
-		if errParse != nil {
-			returnMessage = fmt.Sprintf("Failed to parse the publish body. Error: %v", errParse)
-			returnCode = types.UnexpectedError
-			logger.Errorf("[Azure-CNS] %s", returnMessage)
-		}
-
-		publishResponse.Body.Close()
+		publishResponseBody = publishResponse.Body
 	}
 
 	response := cns.PublishNetworkContainerResponse{
@@ -1207,7 +1256,7 @@ func (service *HTTPRestService) unpublishNetworkContainer(w http.ResponseWriter,
 		req                   cns.UnpublishNetworkContainerRequest
 		returnCode            types.ResponseCode
 		returnMessage         string
-		unpublishResponse     *http.Response
+		unpublishResponse     *nmagentclient.Response
 		unpublishStatusCode   int
 		unpublishResponseBody []byte
 		unpublishError        error
@@ -1236,7 +1285,7 @@ func (service *HTTPRestService) unpublishNetworkContainer(w http.ResponseWriter,
 		// Join Network if not joined already
 		isNetworkJoined = service.isNetworkJoined(req.NetworkID)
 		if !isNetworkJoined {
-			unpublishResponse, unpublishError, err = service.joinNetwork(req.NetworkID, req.JoinNetworkURL)
+			unpublishResponse, unpublishError, err = service.joinNetwork(r.Context(), req.NetworkID, req.JoinNetworkURL)
 			if err == nil {
 				isNetworkJoined = true
 			} else {
@@ -1247,26 +1296,16 @@ func (service *HTTPRestService) unpublishNetworkContainer(w http.ResponseWriter,
 
 		if isNetworkJoined {
 			// Unpublish Network Container
-			unpublishResponse, unpublishError = nmagentclient.UnpublishNetworkContainer(
+			unpublishResponse, unpublishError = service.nmagentClient.UnpublishNetworkContainer(
+				r.Context(),
 				req.NetworkContainerID,
 				req.DeleteNetworkContainerURL)
-			if unpublishError != nil || unpublishResponse.StatusCode != http.StatusOK {
+			if unpublishError != nil {
 				returnMessage = fmt.Sprintf("Failed to unpublish Network Container: %s", req.NetworkContainerID)
 				returnCode = types.NetworkContainerUnpublishFailed
 				logger.Errorf("[Azure-CNS] %s", returnMessage)
 			}
 
-			if unpublishResponse != nil {
-				var errParse error
-				unpublishResponseBody, errParse = ioutil.ReadAll(unpublishResponse.Body)
-				if errParse != nil {
-					returnMessage = fmt.Sprintf("Failed to parse the unpublish body. Error: %v", errParse)
-					returnCode = types.UnexpectedError
-					logger.Errorf("[Azure-CNS] %s", returnMessage)
-				}
-
-				unpublishResponse.Body.Close()
-			}
 		}
 
 		// Remove the NC version URL entry added during publish
@@ -1282,6 +1321,7 @@ func (service *HTTPRestService) unpublishNetworkContainer(w http.ResponseWriter,
 
 	if unpublishResponse != nil {
 		unpublishStatusCode = unpublishResponse.StatusCode
+		unpublishResponseBody = unpublishResponse.Body
 	}
 
 	response := cns.UnpublishNetworkContainerResponse{
@@ -1415,7 +1455,7 @@ func (service *HTTPRestService) nmAgentSupportedApisHandler(w http.ResponseWrite
 
 	switch r.Method {
 	case http.MethodPost:
-		supportedApis, retErr = nmagentclient.GetNmAgentSupportedApis(common.GetHttpClient(),
+		supportedApis, retErr = service.nmagentClient.GetNmAgentSupportedApis(r.Context(),
 			req.GetNmAgentSupportedApisURL)
 		if retErr != nil {
 			returnCode = types.NmAgentSupportedApisError
@@ -12,8 +12,10 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/store"
 
@@ -58,6 +60,7 @@ var (
 	service           cns.HTTPService
 	svc               *HTTPRestService
 	mux               *http.ServeMux
+	nmagentServer     *fakes.NMAgentServerFake
 	hostQueryResponse = xmlDocument{
 		XMLName: xml.Name{Local: "Interfaces"},
 		Interface: []Interface{{
@@ -101,42 +104,22 @@ func getInterfaceInfo(w http.ResponseWriter, r *http.Request) {
 	w.Write(output)
 }
 
-func nmagentHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set(acncommon.ContentType, acncommon.JsonContent)
-
-	if strings.Contains(r.RequestURI, "nc-nma-success") {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"httpStatusCode":"200","networkContainerId":"nc-nma-success","version":"0"}`))
-	}
-
-	if strings.Contains(r.RequestURI, "nc-nma-fail-version-mismatch") {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"httpStatusCode":"200","networkContainerId":"nc-nma-fail-version-mismatch","version":"0"}`))
-	}
-
-	if strings.Contains(r.RequestURI, "nc-nma-fail-500") {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"httpStatusCode":"200","networkContainerId":"nc-nma-fail-500","version":"0"}`))
-	}
-
-	if strings.Contains(r.RequestURI, "nc-nma-fail-unavailable") {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"httpStatusCode":"401","networkContainerId":"nc-nma-fail-unavailable","version":"0"}`))
-	}
-}
-
 // Wraps the test run with service setup and teardown.
 func TestMain(m *testing.M) {
 	var err error
 	logger.InitLogger("testlogs", 0, 0, "./")
 
+	// Setup fake nmagent
+	nmagentServer = fakes.NewNMAgentServerFake()
+	nmagentclient.WireserverIP = nmagentServer.Host()
+
 	// Create the service.
 	if err = startService(); err != nil {
 		fmt.Printf("Failed to start CNS Service. Error: %v", err)
 		os.Exit(1)
 	}
 
-	// Setup mock nmagent server
+	// Setup mock host server
 	u, err := url.Parse("tcp://" + nmagentEndpoint)
 	if err != nil {
 		fmt.Println(err.Error())
@@ -148,8 +131,6 @@ func TestMain(m *testing.M) {
 	}
 
 	nmAgentServer.AddHandler("/getInterface", getInterfaceInfo)
-	nmAgentServer.AddHandler("/", nmagentHandler)
-	nmagentclient.WireserverIP = nmagentEndpoint
 
 	err = nmAgentServer.Start(make(chan error, 1))
 	if err != nil {
@@ -163,6 +144,7 @@ func TestMain(m *testing.M) {
 	// Cleanup.
 	service.Stop()
 	nmAgentServer.Stop()
+	nmagentServer.Close()
 
 	os.Exit(exitCode)
 }
@@ -470,6 +452,7 @@ func TestGetNetworkContainerVersionStatus(t *testing.T) {
 	}
 
 	createNC(t, params)
+	nmagentServer.FailNext(nmagentclient.OpGetNetworkContainerVersion, http.StatusInternalServerError, http.StatusInternalServerError)
 
 	if err := getNetworkContainerByContext(t, params); err != nil {
 		t.Errorf("TestGetNetworkContainerVersionStatus failed")
@@ -481,7 +464,7 @@ func TestGetNetworkContainerVersionStatus(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Testing the path where NMAgent response status code is 200 but embedded response is 401
+	// Testing the path where NMAgent response status code is 200 but embedded response is 404
 	params = createOrUpdateNetworkContainerParams{
 		ncID:         "nc-nma-fail-unavailable",
 		ncIP:         "11.0.0.5",
@@ -493,6 +476,7 @@ func TestGetNetworkContainerVersionStatus(t *testing.T) {
 	}
 
 	createNC(t, params)
+	nmagentServer.RemoveNC(params.ncID)
 
 	if err := getNetworkContainerByContextExpectedError(t, params); err != nil {
 		t.Errorf("TestGetNetworkContainerVersionStatus failed")
@@ -529,9 +513,8 @@ func publishNCViaCNS(t *testing.T,
 		resp cns.PublishNetworkContainerResponse
 	)
 
-	joinNetworkURL := "http://" + nmagentEndpoint + "/dummyVnetURL"
-	createNetworkContainerURL := "http://" + nmagentEndpoint +
-		"/interfaces/dummyIntf/networkContainers/dummyNCURL/authenticationToken/dummyT/api-version"
+	joinNetworkURL := nmagentServer.JoinNetworkURL(networkID)
+	createNetworkContainerURL := nmagentServer.PublishNetworkContainerURL("dummyIntf", networkContainerID, "dummyT")
 
 	publishNCRequest := &cns.PublishNetworkContainerRequest{
 		NetworkID:                         networkID,
@@ -559,6 +542,66 @@ func publishNCViaCNS(t *testing.T,
 	fmt.Printf("PublishNetworkContainer succeded with response %+v, raw:%+v\n", resp, w.Body)
 }
 
+func TestPublishNCViaCNSRetriesNMAgentFailures(t *testing.T) {
+	fmt.Println("Test: publishNetworkContainer retries throttled NMAgent requests")
+	nmagentServer.FailNext(nmagentclient.OpJoinNetwork, http.StatusTooManyRequests)
+	nmagentServer.FailNext(nmagentclient.OpPublishNetworkContainer, http.StatusTooManyRequests)
+
+	publishNCViaCNS(t, "vnetRetry", "ncRetry")
+
+	if !nmagentServer.IsNetworkJoined("vnetRetry") {
+		t.Fatal("expected network vnetRetry to be joined")
+	}
+	if _, ok := nmagentServer.PublishedNC("ncRetry"); !ok {
+		t.Fatal("expected NC ncRetry to be published")
+	}
+	requestIDs := nmagentServer.RequestIDs(nmagentclient.OpPublishNetworkContainer)
+	if n := len(requestIDs); n < 2 || requestIDs[n-1] != requestIDs[n-2] {
+		t.Fatalf("expected the publish retry to reuse the request ID, got %v", requestIDs)
+	}
+}
+
+func TestPublishNCViaCNSFailure(t *testing.T) {
+	fmt.Println("Test: publishNetworkContainer fails")
+
+	var (
+		body bytes.Buffer
+		resp cns.PublishNetworkContainerResponse
+	)
+
+	nmagentServer.FailNext(nmagentclient.OpPublishNetworkContainer, http.StatusBadRequest)
+
+	publishNCRequest := &cns.PublishNetworkContainerRequest{
+		NetworkID:                         "vnetFail",
+		NetworkContainerID:                "ncFail",
+		JoinNetworkURL:                    nmagentServer.JoinNetworkURL("vnetFail"),
+		CreateNetworkContainerURL:         nmagentServer.PublishNetworkContainerURL("dummyIntf", "ncFail", "dummyT"),
+		CreateNetworkContainerRequestBody: make([]byte, 0),
+	}
+
+	json.NewEncoder(&body).Encode(publishNCRequest)
+	req, err := http.NewRequest(http.MethodPost, cns.PublishNetworkContainer, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if err = decodeResponse(w, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Response.ReturnCode != types.NetworkContainerPublishFailed {
+		t.Fatalf("expected return code %v, got response %+v", types.NetworkContainerPublishFailed, resp)
+	}
+	if resp.PublishStatusCode != http.StatusBadRequest {
+		t.Fatalf("expected publish status code %d, got %d", http.StatusBadRequest, resp.PublishStatusCode)
+	}
+	if _, ok := nmagentServer.PublishedNC("ncFail"); ok {
+		t.Fatal("expected NC ncFail not to be published")
+	}
+}
+
 func TestExtractHost(t *testing.T) {
 	joinURL := "http://127.0.0.1:9001/joinedVirtualNetworks/c9b8e695-2de1-11eb-bf54-000d3af666c8/api-version/1"
 
@@ -579,8 +622,8 @@ func TestUnpublishNCViaCNS(t *testing.T) {
 
 	networkID := "vnet1"
 	networkContainerID := "ethWebApp"
-	joinNetworkURL := "http://" + nmagentEndpoint + "/dummyVnetURL"
-	deleteNetworkContainerURL := "http://" + nmagentEndpoint + "/networkContainers/dummyNCURL"
+	joinNetworkURL := nmagentServer.JoinNetworkURL(networkID)
+	deleteNetworkContainerURL := nmagentServer.UnpublishNetworkContainerURL("dummyIntf", networkContainerID, "dummyT")
 
 	unpublishNCRequest := &cns.UnpublishNetworkContainerRequest{
 		NetworkID:                 networkID,
@@ -914,7 +957,11 @@ func startService() error {
 		return err
 	}
 
-	service, err = NewHTTPRestService(&config, fakes.NewFakeImdsClient(), fakes.NewFakeNMAgentClient())
+	nmaclient := nmagentclient.New(nmagentclient.Config{
+		NCVersionListURL: fmt.Sprintf(nmagentclient.GetNcVersionListWithOutTokenURLFmt, nmagentServer.Host(), "2"),
+		Retry:            nmagentclient.RetryOptions{Attempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	service, err = NewHTTPRestService(&config, fakes.NewFakeImdsClient(), nmaclient)
 	if err != nil {
 		return err
 	}
@@ -928,7 +975,13 @@ func startService() error {
 	svc.IPAMPoolMonitor = &fakes.IPAMPoolMonitorFake{}
 
 	if service != nil {
-		// Create empty azure-cns.json. CNS should start successfully by deleting this file
+		// Create empty azure-cns.json. CNS should start successfully by deleting this file.
+		// The generations and IP state transitions left by an earlier run are removed, else they would be restored over it.
+		generations, _ := filepath.Glob(cnsJsonFileName + ".*")
+		for _, generation := range generations {
+			os.Remove(generation)
+		}
+		os.Remove(strings.TrimSuffix(cnsJsonFileName, filepath.Ext(cnsJsonFileName)) + ipStateTransitionsFileSuffix)
 		file, _ := os.Create(cnsJsonFileName)
 		file.Close()
 
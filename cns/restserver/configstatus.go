@@ -0,0 +1,42 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+)
+
+// SetConfigStatusProvider sets what reports the status of the CNS config file at GetConfigStatus.
+// It must be called before the service is started.
+func (service *HTTPRestService) SetConfigStatusProvider(provider cns.ConfigStatusProvider) {
+	service.configStatus = provider
+}
+
+// getConfigStatus returns the status of the CNS config file, or an error if CNS doesn't reload it.
+func (service *HTTPRestService) getConfigStatus() (cns.ConfigStatus, error) {
+	if service.configStatus == nil {
+		return cns.ConfigStatus{}, fmt.Errorf("CNS doesn't watch its config file") //nolint:goerr113
+	}
+	return service.configStatus.ConfigStatus(), nil
+}
+
+// Handles requests for the status of the CNS config file: when it was loaded, why its last change wasn't
+// applied, and which of its changes need a restart.
+func (service *HTTPRestService) getConfigStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var resp cns.GetConfigStatusResponse
+	status, err := service.getConfigStatus()
+	if err != nil {
+		resp.Response = cns.Response{ReturnCode: types.UnexpectedError, Message: err.Error()}
+	} else {
+		resp.ConfigStatus = status
+	}
+
+	err = service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp.Response, resp.Response.ReturnCode, err)
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConfigStatusProvider cns.ConfigStatus
+
+func (f fakeConfigStatusProvider) ConfigStatus() cns.ConfigStatus {
+	return cns.ConfigStatus(f)
+}
+
+func (f fakeConfigStatusProvider) RedactedConfig() interface{} {
+	return nil
+}
+
+func TestGetConfigStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	svc.getConfigStatusHandler(w, httptest.NewRequest(http.MethodGet, cns.GetConfigStatus, nil))
+	var resp cns.GetConfigStatusResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, types.UnexpectedError, resp.Response.ReturnCode)
+
+	svc.SetConfigStatusProvider(fakeConfigStatusProvider{
+		Path:            "/etc/azure-cns/cns_config.json",
+		RestartRequired: []string{"ChannelMode"},
+	})
+	defer svc.SetConfigStatusProvider(nil)
+
+	w = httptest.NewRecorder()
+	svc.getConfigStatusHandler(w, httptest.NewRequest(http.MethodGet, cns.GetConfigStatus, nil))
+	resp = cns.GetConfigStatusResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, types.Success, resp.Response.ReturnCode)
+	assert.Equal(t, "/etc/azure-cns/cns_config.json", resp.ConfigStatus.Path)
+	assert.Equal(t, []string{"ChannelMode"}, resp.ConfigStatus.RestartRequired)
+}
@@ -1,5 +1,7 @@
 package restserver
 
+import "time"
+
 const (
 	// Key against which CNS state is persisted.
 	storeKey = "ContainerNetworkService"
@@ -8,4 +10,9 @@ const (
 	// Rest service state identifier for named lock
 	stateJoinedNetworks = "JoinedNetworks"
 	dncApiVersion       = "?api-version=2018-03-01"
+	// Timeout of the NMAgent request made to check whether an NC version is programmed.
+	ncVersionCheckTimeout = 5 * time.Second
 )
+
+// Key the NC statuses are persisted against in transactional stores, which keep every NC under a key of its own.
+const containerStatusStoreKey = storeKey + "/ContainerStatus"
@@ -0,0 +1,82 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
+	"github.com/Azure/azure-container-networking/cns/imdsclient"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// networklessRuntime is a container runtime without networks, like the CRI runtimes.
+type networklessRuntime struct{}
+
+func (networklessRuntime) NetworkExists(string) error {
+	return containerruntime.ErrNotSupported
+}
+
+func (networklessRuntime) CreateNetwork(string, *imdsclient.InterfaceInfo, map[string]interface{}) error {
+	return containerruntime.ErrNotSupported
+}
+
+func (networklessRuntime) DeleteNetwork(string) error {
+	return containerruntime.ErrNotSupported
+}
+
+func (networklessRuntime) Sandbox(_ context.Context, containerID string) (containerruntime.Sandbox, error) {
+	return containerruntime.Sandbox{ID: containerID}, nil
+}
+
+func TestNetworksUnsupportedByContainerRuntime(t *testing.T) {
+	setEnv(t)
+	runtime := svc.containerRuntime
+	svc.SetContainerRuntimeClient(networklessRuntime{})
+	defer svc.SetContainerRuntimeClient(runtime)
+
+	for _, path := range []string{cns.CreateNetworkPath, cns.DeleteNetworkPath} {
+		var body bytes.Buffer
+		require.NoError(t, json.NewEncoder(&body).Encode(cns.CreateNetworkRequest{NetworkName: "azurenet"}))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, &body))
+
+		var resp cns.Response
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, types.UnsupportedEnvironment, resp.ReturnCode, path)
+	}
+}
+
+// deletedContainerRuntime is a container runtime whose containers were all deleted.
+type deletedContainerRuntime struct {
+	networklessRuntime
+}
+
+func (deletedContainerRuntime) Sandbox(_ context.Context, containerID string) (containerruntime.Sandbox, error) {
+	return containerruntime.Sandbox{}, errors.Wrapf(containerruntime.ErrSandboxNotFound, "container %s", containerID)
+}
+
+func TestSandboxOfDeletedContainer(t *testing.T) {
+	setEnv(t)
+	runtime := svc.containerRuntime
+	svc.SetContainerRuntimeClient(deletedContainerRuntime{})
+	defer svc.SetContainerRuntimeClient(runtime)
+
+	// the endpoint of a deleted container is still detached
+	sandbox, err := svc.sandbox(context.Background(), "deleted", detach)
+	require.NoError(t, err)
+	assert.Equal(t, containerruntime.Sandbox{ID: "deleted", NetNS: containerruntime.NoNetNS}, sandbox)
+
+	_, err = svc.sandbox(context.Background(), "deleted", attach)
+	assert.True(t, errors.Is(err, containerruntime.ErrSandboxNotFound))
+}
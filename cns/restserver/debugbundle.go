@@ -0,0 +1,216 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// debugBundleErrorsFile lists the diagnostics which couldn't be collected into a debug bundle.
+	debugBundleErrorsFile = "errors.txt"
+	// redactedSecret replaces the secrets of the state in debug bundles.
+	redactedSecret = "[REDACTED]"
+)
+
+// debugBundleFile is a file of a debug bundle, whose contents are collected when the bundle is written.
+type debugBundleFile struct {
+	name    string
+	collect func() ([]byte, error)
+}
+
+// debugBundleFiles returns the files of a debug bundle, in the order they are written.
+func (service *HTTPRestService) debugBundleFiles() []debugBundleFile {
+	files := []debugBundleFile{
+		{name: "httprestservicedata.json", collect: service.collectHTTPRestServiceData},
+		{name: "httprestservicestate.json", collect: service.collectHTTPRestServiceState},
+		{name: "ipampoolmonitor.json", collect: service.collectIPAMPoolMonitorState},
+		{name: "nodenetworkconfig.yaml", collect: service.collectNodeNetworkConfig},
+		{name: "cns_config.json", collect: service.collectCNSConfig},
+		{name: "cns_config_status.json", collect: service.collectConfigStatus},
+		{name: "goroutines.txt", collect: collectGoroutines},
+	}
+	for _, logFile := range debugBundleLogFiles() {
+		files = append(files, debugBundleFile{name: "logs/" + filepath.Base(logFile), collect: readFile(logFile)})
+	}
+	return files
+}
+
+// collectHTTPRestServiceData returns the in-memory IPAM state, marshalled while it is locked.
+func (service *HTTPRestService) collectHTTPRestServiceData() ([]byte, error) {
+	service.RLock()
+	defer service.RUnlock()
+	data := HTTPRestServiceData{
+		PodIPIDByPodInterfaceKey:   service.PodIPIDByPodInterfaceKey,
+		PodIPv6IDByPodInterfaceKey: service.PodIPv6IDByPodInterfaceKey,
+		PodIPConfigState:           service.PodIPConfigState,
+	}
+	if service.IPAMPoolMonitor != nil {
+		data.IPAMPoolMonitor = service.IPAMPoolMonitor.GetStateSnapshot()
+	}
+	return json.MarshalIndent(data, "", "  ")
+}
+
+// collectHTTPRestServiceState returns the state CNS persists, marshalled while it is locked, with the
+// authorization tokens of the NCs redacted.
+func (service *HTTPRestService) collectHTTPRestServiceState() ([]byte, error) {
+	service.RLock()
+	defer service.RUnlock()
+	state := *service.state
+	state.ContainerStatus = make(map[string]containerstatus, len(service.state.ContainerStatus))
+	for id, status := range service.state.ContainerStatus {
+		if status.CreateNetworkContainerRequest.AuthorizationToken != "" {
+			status.CreateNetworkContainerRequest.AuthorizationToken = redactedSecret
+		}
+		state.ContainerStatus[id] = status
+	}
+	return json.MarshalIndent(&state, "", "  ")
+}
+
+func (service *HTTPRestService) collectIPAMPoolMonitorState() ([]byte, error) {
+	if service.IPAMPoolMonitor == nil {
+		return nil, fmt.Errorf("no IPAM pool monitor is running") //nolint:goerr113
+	}
+	return json.MarshalIndent(service.IPAMPoolMonitor.GetStateSnapshot(), "", "  ")
+}
+
+// collectNodeNetworkConfig returns the NodeNetworkConfig cached by the pool monitor as YAML, as kubectl shows it.
+func (service *HTTPRestService) collectNodeNetworkConfig() ([]byte, error) {
+	if service.IPAMPoolMonitor == nil {
+		return nil, fmt.Errorf("no IPAM pool monitor is running") //nolint:goerr113
+	}
+	nnc := service.IPAMPoolMonitor.GetStateSnapshot().CachedNNC
+	return yaml.Marshal(&nnc)
+}
+
+// collectCNSConfig returns the redacted config CNS last loaded, which is reloaded with the config file if
+// CNS watches it.
+func (service *HTTPRestService) collectCNSConfig() ([]byte, error) {
+	config := service.redactedCNSConfig
+	if service.configStatus != nil {
+		config = service.configStatus.RedactedConfig()
+	}
+	if config == nil {
+		return nil, fmt.Errorf("CNS was started without a config") //nolint:goerr113
+	}
+	return json.MarshalIndent(config, "", "  ")
+}
+
+func (service *HTTPRestService) collectConfigStatus() ([]byte, error) {
+	status, err := service.getConfigStatus()
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(status, "", "  ")
+}
+
+// collectGoroutines returns the stacks of every goroutine, in the format of an unrecovered panic.
+func collectGoroutines() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil { //nolint:gomnd // 2 is the panic format
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func readFile(name string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		return ioutil.ReadFile(name)
+	}
+}
+
+// debugBundleLogFiles returns the active CNS log file and the rotated ones, newest first.
+func debugBundleLogFiles() []string {
+	if logger.Log == nil {
+		return nil
+	}
+	active := logger.LogFileName()
+	rotated, _ := filepath.Glob(active + ".*")
+	sort.Slice(rotated, func(i, j int) bool {
+		// azure-cns.log.2 is newer than azure-cns.log.10
+		return len(rotated[i]) < len(rotated[j]) || (len(rotated[i]) == len(rotated[j]) && rotated[i] < rotated[j])
+	})
+	return append([]string{active}, rotated...)
+}
+
+// writeDebugBundle writes the debug bundle as a tar.gz. Diagnostics which can't be collected are listed
+// in the errors file of the bundle, so that the rest are still collected.
+func (service *HTTPRestService) writeDebugBundle(w *tar.Writer, now time.Time) error {
+	var collectErrors []string
+	for _, file := range service.debugBundleFiles() {
+		contents, err := file.collect()
+		if err != nil {
+			collectErrors = append(collectErrors, fmt.Sprintf("%s: %v", file.name, err))
+			continue
+		}
+		if err := writeTarFile(w, file.name, contents, now); err != nil {
+			return err
+		}
+	}
+	if len(collectErrors) > 0 {
+		return writeTarFile(w, debugBundleErrorsFile, []byte(strings.Join(collectErrors, "\n")+"\n"), now)
+	}
+	return nil
+}
+
+func writeTarFile(w *tar.Writer, name string, contents []byte, modTime time.Time) error {
+	if err := w.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644, //nolint:gomnd // rw-r--r--
+		Size:    int64(len(contents)),
+		ModTime: modTime,
+	}); err != nil {
+		return err //nolint:wrapcheck
+	}
+	_, err := w.Write(contents)
+	return err //nolint:wrapcheck
+}
+
+// debugBundleHandler responds with a tar.gz of the CNS diagnostics: its in-memory state, the pool monitor
+// state and cached NodeNetworkConfig, the redacted persisted state, the log files, the redacted config and
+// a goroutine dump.
+func (service *HTTPRestService) debugBundleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = service.Listener.Encode(w, &cns.Response{ReturnCode: types.UnsupportedVerb, Message: "[Azure CNS] Debug bundles are only served to GET requests"})
+		return
+	}
+
+	now := time.Now().UTC()
+	name := fmt.Sprintf("azure-cns-debug-%s.tar.gz", now.Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := service.writeDebugBundle(tw, now)
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		// the response has started, so the client sees a truncated bundle
+		logger.Errorf("[Azure CNS] Failed to write debug bundle to %s: %v", r.RemoteAddr, err)
+		return
+	}
+	logger.Printf("[Azure CNS] Wrote debug bundle %s to %s", name, r.RemoteAddr)
+}
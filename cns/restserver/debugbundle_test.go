@@ -0,0 +1,104 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readDebugBundle returns the contents of the files of the tar.gz by name.
+func readDebugBundle(t *testing.T, w *httptest.ResponseRecorder) map[string][]byte {
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		contents, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = contents
+	}
+	return files
+}
+
+func TestDebugBundle(t *testing.T) {
+	var cnsConfig configuration.CNSConfig
+	cnsConfig.ManagedSettings.PrivateEndpoint = "https://secret.example.com"
+	svc.redactedCNSConfig = cnsConfig.Redacted()
+	defer func() { svc.redactedCNSConfig = nil }()
+
+	const ncID = "debugbundle-nc"
+	svc.Lock()
+	svc.state.ContainerStatus[ncID] = containerstatus{
+		ID:                            ncID,
+		CreateNetworkContainerRequest: cns.CreateNetworkContainerRequest{NetworkContainerid: ncID, AuthorizationToken: "secret-token"},
+	}
+	svc.Unlock()
+	defer func() {
+		svc.Lock()
+		delete(svc.state.ContainerStatus, ncID)
+		svc.Unlock()
+	}()
+
+	w := httptest.NewRecorder()
+	svc.debugBundleHandler(w, httptest.NewRequest(http.MethodGet, "/debug/bundle", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "azure-cns-debug-")
+
+	files := readDebugBundle(t, w)
+	for _, name := range []string{"httprestservicedata.json", "httprestservicestate.json", "ipampoolmonitor.json", "nodenetworkconfig.yaml", "cns_config.json", "goroutines.txt"} {
+		assert.Contains(t, files, name)
+	}
+	assert.Contains(t, string(files["goroutines.txt"]), "TestDebugBundle")
+
+	var data HTTPRestServiceData
+	require.NoError(t, json.Unmarshal(files["httprestservicedata.json"], &data))
+	assert.Len(t, data.PodIPConfigState, len(svc.PodIPConfigState))
+
+	var redacted configuration.CNSConfig
+	require.NoError(t, json.Unmarshal(files["cns_config.json"], &redacted))
+	assert.Equal(t, "[REDACTED]", redacted.ManagedSettings.PrivateEndpoint)
+	assert.NotContains(t, string(files["cns_config.json"]), "secret.example.com")
+
+	var state httpRestServiceState
+	require.NoError(t, json.Unmarshal(files["httprestservicestate.json"], &state))
+	require.Contains(t, state.ContainerStatus, ncID)
+	assert.Equal(t, redactedSecret, state.ContainerStatus[ncID].CreateNetworkContainerRequest.AuthorizationToken)
+	assert.NotContains(t, string(files["httprestservicestate.json"]), "secret-token")
+	svc.RLock()
+	assert.Equal(t, "secret-token", svc.state.ContainerStatus[ncID].CreateNetworkContainerRequest.AuthorizationToken)
+	svc.RUnlock()
+}
+
+func TestDebugBundleListsUncollectedDiagnostics(t *testing.T) {
+	w := httptest.NewRecorder()
+	svc.debugBundleHandler(w, httptest.NewRequest(http.MethodGet, "/debug/bundle", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	files := readDebugBundle(t, w)
+	assert.NotContains(t, files, "cns_config.json")
+	assert.Contains(t, string(files[debugBundleErrorsFile]), "cns_config.json: CNS was started without a config")
+}
+
+func TestDebugBundleRejectsPost(t *testing.T) {
+	w := httptest.NewRecorder()
+	svc.debugBundleHandler(w, httptest.NewRequest(http.MethodPost, "/debug/bundle", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// eventsKeepAliveInterval is how often an idle stream is sent a comment, so that proxies and
+	// clients don't time it out.
+	eventsKeepAliveInterval = 15 * time.Second
+	// lastEventIDHeader is the header an event stream is resumed from, as sent by EventSource clients.
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// bootEpoch prefixes the IDs of the streamed events, since the event IDs restart with each CNS process.
+var bootEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// publishNCEvent adds a change to the NC to the events of the CNS state.
+func (service *HTTPRestService) publishNCEvent(eventType cns.EventType, ncStatus containerstatus) {
+	service.ipStateTransitions.append(cns.Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		NetworkContainer: &cns.NetworkContainerEvent{
+			ID:          ncStatus.ID,
+			Type:        ncStatus.CreateNetworkContainerRequest.NetworkContainerType,
+			Version:     ncStatus.CreateNetworkContainerRequest.Version,
+			HostVersion: ncStatus.HostVersion,
+		},
+	})
+}
+
+// eventsHandler streams the events of the CNS state as server-sent events, until the client disconnects
+// or CNS stops. A stream starts with the next event, or after the event in the Last-Event-ID header or
+// lastEventID query parameter if either is set.
+func (service *HTTPRestService) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_ = service.Listener.Encode(w, &cns.Response{ReturnCode: types.UnsupportedVerb, Message: "[Azure CNS] Events are only streamed to GET requests"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = service.Listener.Encode(w, &cns.Response{ReturnCode: types.UnexpectedError, Message: "[Azure CNS] Events can't be streamed on this connection"})
+		return
+	}
+
+	events := &service.ipStateTransitions
+	lastID, resync := events.last(), false
+	if resumeFrom := firstNonEmpty(r.Header.Get(lastEventIDHeader), r.URL.Query().Get("lastEventID")); resumeFrom != "" {
+		epoch, id, err := parseEventID(resumeFrom)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = service.Listener.Encode(w, &cns.Response{ReturnCode: types.InvalidParameter, Message: fmt.Sprintf("[Azure CNS] Invalid last event ID %s", resumeFrom)})
+			return
+		}
+		// the events after an event of a previous CNS process are unknown
+		lastID, resync = id, epoch != bootEpoch
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	logger.Printf("[Azure CNS] Streaming events after %d to %s", lastID, r.RemoteAddr)
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		missed, ok, updated := events.since(lastID)
+		if !ok || resync {
+			// the events the stream missed are unknown, so the client resyncs from the current state
+			logger.Printf("[Azure CNS] Events after %d are unknown, resyncing %s", lastID, r.RemoteAddr)
+			lastID, resync = events.last(), false
+			missed = []cns.Event{{ID: lastID, Type: cns.EventsResync, Timestamp: time.Now()}}
+		}
+		for i := range missed {
+			if err := writeEvent(w, &missed[i]); err != nil {
+				logger.Errorf("[Azure CNS] Failed to stream event %d to %s: %v", missed[i].ID, r.RemoteAddr, err)
+				return
+			}
+			lastID = missed[i].ID
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-updated:
+			if events.isClosed() {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseEventID parses the boot epoch and event ID of a streamed event ID.
+func parseEventID(s string) (string, uint64, error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return "", 0, errors.Errorf("event ID %s has no boot epoch", s)
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	return parts[0], id, errors.Wrapf(err, "invalid event ID %s", s)
+}
+
+// writeEvent writes the event in the server-sent event format, with the ID prefixed by the boot epoch.
+func writeEvent(w http.ResponseWriter, event *cns.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err //nolint:wrapcheck // marshalling the event can't fail
+	}
+	_, err = fmt.Fprintf(w, "id: %s-%d\nevent: %s\ndata: %s\n\n", bootEpoch, event.ID, event.Type, data)
+	return err //nolint:wrapcheck
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPStateTransitionLogSince(t *testing.T) {
+	var l ipStateTransitionLog
+	events, ok, updated := l.since(0)
+	assert.True(t, ok)
+	assert.Empty(t, events)
+
+	l.record(cns.IPStateTransition{IPConfigID: "0"})
+	select {
+	case <-updated:
+	default:
+		t.Fatal("Expected recording a change to notify the streams waiting on the log")
+	}
+	l.append(cns.Event{Type: cns.NetworkContainerCreated, NetworkContainer: &cns.NetworkContainerEvent{ID: "nc"}})
+	events, ok, _ = l.since(0)
+	require.True(t, ok)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(1), events[0].ID)
+	assert.Equal(t, cns.IPStateChanged, events[0].Type)
+	assert.Equal(t, uint64(2), events[1].ID)
+	assert.Len(t, l.list(nil), 1, "only the IP state transitions are listed")
+
+	// an ID later than the last event
+	_, ok, _ = l.since(3)
+	assert.False(t, ok)
+
+	for i := 0; i < ipStateTransitionsCapacity; i++ {
+		l.record(cns.IPStateTransition{})
+	}
+	// events 1 and 2 were overwritten, so a stream which has only seen event 1 missed one
+	_, ok, _ = l.since(1)
+	assert.False(t, ok)
+	events, ok, _ = l.since(2)
+	require.True(t, ok)
+	require.Len(t, events, ipStateTransitionsCapacity)
+	assert.Equal(t, uint64(3), events[0].ID)
+	assert.Equal(t, uint64(ipStateTransitionsCapacity+2), events[ipStateTransitionsCapacity-1].ID)
+
+	_, _, updated = l.since(l.last())
+	l.close()
+	select {
+	case <-updated:
+	default:
+		t.Fatal("Expected closing the log to end the streams waiting on it")
+	}
+	assert.True(t, l.isClosed())
+}
+
+// eventStream reads the server-sent events of a stream.
+type eventStream struct {
+	t      *testing.T
+	resp   *http.Response
+	reader *bufio.Reader
+}
+
+// streamEvents starts streaming the events of svc, after lastEventID if it is set.
+func streamEvents(t *testing.T, lastEventID string) *eventStream {
+	server := httptest.NewServer(newHandlerFuncWithMetrics(cns.EventsPath, svc.eventsHandler))
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+cns.EventsPath, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set(lastEventIDHeader, lastEventID)
+	}
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return &eventStream{t: t, resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+// next returns the next event of the stream, checking that its id and event fields match its data.
+func (s *eventStream) next() cns.Event {
+	fields := map[string]string{}
+	done := make(chan error, 1)
+	go func() {
+		for {
+			line, err := s.reader.ReadString('\n')
+			if err != nil {
+				done <- err
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" && len(fields) > 0 {
+				done <- nil
+				return
+			}
+			if field := strings.SplitN(line, ": ", 2); len(field) == 2 && field[0] != "" {
+				fields[field[0]] = field[1]
+			}
+		}
+	}()
+	select {
+	case err := <-done:
+		require.NoError(s.t, err)
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("Timed out waiting for an event")
+	}
+
+	var event cns.Event
+	require.NoError(s.t, json.Unmarshal([]byte(fields["data"]), &event))
+	assert.Equal(s.t, eventID(bootEpoch, event.ID), fields["id"])
+	assert.Equal(s.t, string(event.Type), fields["event"])
+	return event
+}
+
+func eventID(epoch string, id uint64) string {
+	return epoch + "-" + strconv.FormatUint(id, 10)
+}
+
+// nextOfType skips the events of the stream until the next one of the type.
+func (s *eventStream) nextOfType(eventType cns.EventType) cns.Event {
+	for {
+		if event := s.next(); event.Type == eventType {
+			return event
+		}
+	}
+}
+
+func TestEventsStreamNCAndIPChanges(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+
+	stream := streamEvents(t, "")
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{uuid.New().String(): newSecondaryIPConfig("10.0.0.30", 0)}
+	req := createNCReqInternal(t, secondaryIPConfigs, "testEventsNc", "0")
+
+	ipEvent := stream.next()
+	require.Equal(t, cns.IPStateChanged, ipEvent.Type)
+	assert.Equal(t, "10.0.0.30", ipEvent.IPStateTransition.IPAddress)
+	assert.Equal(t, cns.IPConfigState(""), ipEvent.IPStateTransition.From)
+	ncEvent := stream.next()
+	require.Equal(t, cns.NetworkContainerCreated, ncEvent.Type)
+	assert.Equal(t, ipEvent.ID+1, ncEvent.ID)
+	assert.Equal(t, &cns.NetworkContainerEvent{ID: req.NetworkContainerid, Type: req.NetworkContainerType, Version: "0", HostVersion: "-1"}, ncEvent.NetworkContainer)
+
+	// NMAgent programs the NC, so its IP becomes Available
+	nmagentServer.SetNCVersion(req.NetworkContainerid, "0")
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+	available := stream.next()
+	require.Equal(t, cns.IPStateChanged, available.Type)
+	assert.Equal(t, cns.Available, available.IPStateTransition.To)
+	programmed := stream.next()
+	require.Equal(t, cns.NetworkContainerUpdated, programmed.Type)
+	assert.Equal(t, "0", programmed.NetworkContainer.HostVersion)
+
+	_, err := svc.AllocateAnyAvailableIPConfigs(testPod1Info)
+	require.NoError(t, err)
+	allocated := stream.next()
+	require.Equal(t, cns.IPStateChanged, allocated.Type)
+	assert.Equal(t, cns.Allocated, allocated.IPStateTransition.To)
+	assert.Equal(t, testPod1Info.Name(), allocated.IPStateTransition.PodName)
+
+	require.Equal(t, 0, int(svc.DeleteNetworkContainerInternal(cns.DeleteNetworkContainerRequest{NetworkContainerid: req.NetworkContainerid})))
+	deleted := stream.nextOfType(cns.NetworkContainerDeleted)
+	assert.Equal(t, req.NetworkContainerid, deleted.NetworkContainer.ID)
+
+	// a stream resumed from an event replays the events after it
+	resumed := streamEvents(t, eventID(bootEpoch, ipEvent.ID))
+	assert.Equal(t, ncEvent, resumed.next())
+	assert.Equal(t, available, resumed.next())
+	assert.Equal(t, programmed, resumed.next())
+	assert.Equal(t, allocated, resumed.next())
+}
+
+func TestEventsStreamResyncsWhenResumedFromUnknownEvent(t *testing.T) {
+	stream := streamEvents(t, eventID(bootEpoch, svc.ipStateTransitions.last()+100))
+	event := stream.next()
+	assert.Equal(t, cns.EventsResync, event.Type)
+	assert.Equal(t, svc.ipStateTransitions.last(), event.ID)
+}
+
+func TestEventsStreamResyncsWhenResumedFromPreviousProcess(t *testing.T) {
+	stream := streamEvents(t, eventID("previous", svc.ipStateTransitions.last()))
+	event := stream.next()
+	assert.Equal(t, cns.EventsResync, event.Type)
+	assert.Equal(t, svc.ipStateTransitions.last(), event.ID)
+}
+
+func TestEventsStreamRejectsInvalidLastEventID(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, cns.EventsPath+"?lastEventID=abc", nil)
+	svc.eventsHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// an ID without a boot epoch
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, cns.EventsPath+"?lastEventID=12", nil)
+	svc.eventsHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
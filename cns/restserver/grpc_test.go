@@ -0,0 +1,198 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/rpc"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startTestGRPCServer(t *testing.T, svc *HTTPRestService) *rpc.Client {
+	l := bufconn.Listen(1024 * 1024)
+	server := rpc.NewServer(svc, rpc.WithPeerAuthorization(svc.authorizeRequest)...)
+	go func() {
+		_ = server.Serve(l)
+	}()
+	t.Cleanup(server.Stop)
+
+	client, err := rpc.NewClient(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func nextEvent(t *testing.T, events <-chan cns.IPConfigEvent) cns.IPConfigEvent {
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "watch closed unexpectedly")
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for IPConfigEvent")
+	}
+	return cns.IPConfigEvent{}
+}
+
+func TestGRPCRequestReleaseAndWatch(t *testing.T) {
+	svc := getTestService()
+	state := NewPodState(testIP1, 24, testPod1GUID, testNCID, cns.Available, 0)
+	require.NoError(t, UpdatePodIpConfigState(t, svc, map[string]cns.IPConfigurationStatus{state.ID: state}))
+
+	client := startTestGRPCServer(t, svc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, _, err := client.WatchIPConfigs(ctx)
+	require.NoError(t, err)
+
+	// the current state is sent first.
+	event := nextEvent(t, events)
+	assert.Equal(t, cns.IPConfigAdded, event.Type)
+	assert.Equal(t, testIP1, event.IPConfigurationStatus.IPAddress)
+	assert.Equal(t, cns.Available, event.IPConfigurationStatus.State)
+
+	orchestratorContext, _ := testPod1Info.OrchestratorContext()
+	req := &cns.IPConfigRequest{
+		PodInterfaceID:      testPod1Info.InterfaceID(),
+		InfraContainerID:    testPod1Info.InfraContainerID(),
+		OrchestratorContext: orchestratorContext,
+	}
+	resp, err := client.RequestIPAddress(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, testIP1, resp.PodIpInfo.PodIPConfig.IPAddress)
+	assert.Equal(t, primaryIp, resp.PodIpInfo.NetworkContainerPrimaryIPConfig.IPSubnet.IPAddress)
+	assert.Equal(t, dnsservers, resp.PodIpInfo.NetworkContainerPrimaryIPConfig.DNSServers)
+	assert.Nil(t, resp.PodIpInfoV6)
+
+	event = nextEvent(t, events)
+	assert.Equal(t, cns.IPConfigModified, event.Type)
+	assert.Equal(t, cns.Allocated, event.IPConfigurationStatus.State)
+	assert.Equal(t, cns.Available, event.PreviousState)
+	assert.Equal(t, testPod1Info.Key(), event.IPConfigurationStatus.PodInfo.Key())
+
+	allocated, err := client.GetIPAddressesMatchingStates(ctx, cns.Allocated)
+	require.NoError(t, err)
+	require.Len(t, allocated, 1)
+	assert.Equal(t, testIP1, allocated[0].IPAddress)
+
+	// the pool is now empty, so another pod can't get an IP.
+	orchestratorContext, _ = testPod2Info.OrchestratorContext()
+	_, err = client.RequestIPAddress(ctx, &cns.IPConfigRequest{
+		PodInterfaceID:      testPod2Info.InterfaceID(),
+		InfraContainerID:    testPod2Info.InfraContainerID(),
+		OrchestratorContext: orchestratorContext,
+	})
+	var rpcErr *rpc.Error
+	require.True(t, errors.As(err, &rpcErr), "expected rpc.Error, got %v", err)
+	assert.Equal(t, types.FailedToAllocateIPConfig, rpcErr.Code)
+
+	require.NoError(t, client.ReleaseIPAddress(ctx, req))
+	event = nextEvent(t, events)
+	assert.Equal(t, cns.IPConfigModified, event.Type)
+	assert.Equal(t, cns.Available, event.IPConfigurationStatus.State)
+	assert.Equal(t, cns.Allocated, event.PreviousState)
+}
+
+func TestGRPCRequestIPConfigDualStack(t *testing.T) {
+	svc := newDualStackTestService(t, map[string]string{testIPv6GUID1: testIPv6IP1})
+	client := startTestGRPCServer(t, svc)
+
+	req := newTestIPConfigRequest(t, testPod1Info, testIP1)
+	resp, err := client.RequestIPAddress(context.Background(), &req)
+	require.NoError(t, err)
+	assert.Equal(t, testIP1, resp.PodIpInfo.PodIPConfig.IPAddress)
+	require.NotNil(t, resp.PodIpInfoV6)
+	assert.Equal(t, testIPv6IP1, resp.PodIpInfoV6.PodIPConfig.IPAddress)
+	assert.Equal(t, testIPv6Gateway, resp.PodIpInfoV6.NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+}
+
+func TestGRPCWatchStateFilter(t *testing.T) {
+	svc := getTestService()
+	state1 := NewPodState(testIP1, 24, testPod1GUID, testNCID, cns.Available, 0)
+	state2 := NewPodState(testIP2, 24, testPod2GUID, testNCID, cns.Available, 0)
+	require.NoError(t, UpdatePodIpConfigState(t, svc, map[string]cns.IPConfigurationStatus{
+		state1.ID: state1,
+		state2.ID: state2,
+	}))
+
+	client := startTestGRPCServer(t, svc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, _, err := client.WatchIPConfigs(ctx, cns.Allocated, cns.PendingRelease)
+	require.NoError(t, err)
+
+	// nothing is Allocated or PendingRelease yet, so once the server is watching
+	// the first event is the change.
+	require.Eventually(t, func() bool {
+		svc.ipConfigWatchers.Lock()
+		defer svc.ipConfigWatchers.Unlock()
+		return len(svc.ipConfigWatchers.watchers) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = svc.MarkIPAsPendingRelease(1)
+	require.NoError(t, err)
+
+	event := nextEvent(t, events)
+	assert.Equal(t, cns.IPConfigModified, event.Type)
+	assert.Equal(t, cns.PendingRelease, event.IPConfigurationStatus.State)
+	assert.Equal(t, cns.Available, event.PreviousState)
+}
+
+func TestGRPCRejectsUnspecifiedStateFilter(t *testing.T) {
+	svc := getTestService()
+	state := NewPodState(testIP1, 24, testPod1GUID, testNCID, cns.Available, 0)
+	require.NoError(t, UpdatePodIpConfigState(t, svc, map[string]cns.IPConfigurationStatus{state.ID: state}))
+
+	client := startTestGRPCServer(t, svc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a state unknown to the client is sent as IP_CONFIG_STATE_UNSPECIFIED, which mustn't widen the filter to every state.
+	_, err := client.GetIPAddressesMatchingStates(ctx, cns.IPConfigState("Unknown"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	events, errs, err := client.WatchIPConfigs(ctx, cns.IPConfigState("Unknown"))
+	require.NoError(t, err)
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Equal(t, codes.InvalidArgument, status.Code(<-errs))
+}
+
+func TestGRPCUnixSocketOnly(t *testing.T) {
+	svc := getTestService()
+	svc.peerAuthorization = common.PeerAuthorizationSettings{UnixSocketOnly: true}
+	defer func() { svc.peerAuthorization = common.PeerAuthorizationSettings{} }()
+
+	client := startTestGRPCServer(t, svc)
+	ctx := context.Background()
+
+	orchestratorContext, _ := testPod1Info.OrchestratorContext()
+	_, err := client.RequestIPAddress(ctx, &cns.IPConfigRequest{
+		PodInterfaceID:      testPod1Info.InterfaceID(),
+		InfraContainerID:    testPod1Info.InfraContainerID(),
+		OrchestratorContext: orchestratorContext,
+	})
+	var rpcErr *rpc.Error
+	require.True(t, errors.As(err, &rpcErr), "expected rpc.Error, got %v", err)
+	assert.Equal(t, types.UnauthorizedPeer, rpcErr.Code)
+
+	// the read-only methods are still served
+	_, err = client.GetIPAddressesMatchingStates(ctx, cns.Allocated)
+	assert.NoError(t, err)
+}
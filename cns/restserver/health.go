@@ -0,0 +1,195 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/pkg/errors"
+)
+
+// Names of the health checks reported by /healthz and /readyz.
+const (
+	healthCheckServiceLock     = "service-lock"
+	healthCheckStateRestored   = "state-restored"
+	healthCheckStore           = "store"
+	healthCheckIPAMPoolMonitor = "ipam-pool-monitor"
+)
+
+// serviceLockTimeout is how long an acquisition of the service lock may be pending before the liveness check
+// reports CNS as deadlocked. It is well above the time the lock is held for under normal contention.
+const serviceLockTimeout = 30 * time.Second
+
+// serviceLockProbeWait is how long the liveness check waits for a pending acquisition of the service lock to
+// complete before it answers from the time the acquisition has been pending for.
+const serviceLockProbeWait = 100 * time.Millisecond
+
+// serviceLockProbe tracks the one pending acquisition of the service lock shared by all the liveness checks,
+// so that probes made while the lock is held don't each leave a goroutine waiting for it.
+type serviceLockProbe struct {
+	sync.Mutex
+	// acquired is closed once the pending acquisition has got the lock.
+	acquired chan struct{}
+	since    time.Time
+}
+
+// healthCheck is a named check which returns an error if CNS is not healthy.
+type healthCheck struct {
+	name  string
+	check func() error
+}
+
+// AddReadinessCheck adds a check which must pass for CNS to be reported ready by /readyz.
+func (service *HTTPRestService) AddReadinessCheck(name string, check func() error) {
+	service.healthChecksLock.Lock()
+	defer service.healthChecksLock.Unlock()
+
+	service.readinessChecks = append(service.readinessChecks, healthCheck{name: name, check: check})
+}
+
+// livenessChecks returns the checks which must pass for CNS to be reported live by /healthz.
+func (service *HTTPRestService) livenessChecks() []healthCheck {
+	return []healthCheck{
+		{name: healthCheckServiceLock, check: service.checkServiceLock},
+	}
+}
+
+// allReadinessChecks returns the built-in readiness checks followed by the added ones.
+func (service *HTTPRestService) allReadinessChecks() []healthCheck {
+	checks := []healthCheck{
+		{name: healthCheckStateRestored, check: service.checkStateRestored},
+		{name: healthCheckStore, check: service.checkStoreWritable},
+	}
+	if service.IPAMPoolMonitor != nil {
+		checks = append(checks, healthCheck{name: healthCheckIPAMPoolMonitor, check: service.checkIPAMPoolMonitorReconciled})
+	}
+
+	service.healthChecksLock.Lock()
+	defer service.healthChecksLock.Unlock()
+	return append(checks, service.readinessChecks...)
+}
+
+// checkServiceLock returns an error if an acquisition of the service lock has been pending for longer than
+// serviceLockTimeout. It starts an acquisition only if the previous one has completed.
+func (service *HTTPRestService) checkServiceLock() error {
+	probe := &service.serviceLockProbe
+	probe.Lock()
+	defer probe.Unlock()
+
+	if probe.acquired == nil || isClosed(probe.acquired) {
+		acquired := make(chan struct{})
+		probe.acquired, probe.since = acquired, time.Now()
+		go func() {
+			service.RLock()
+			service.RUnlock()
+			close(acquired)
+		}()
+	}
+
+	select {
+	case <-probe.acquired:
+		return nil
+	case <-time.After(serviceLockProbeWait):
+	}
+
+	if pending := time.Since(probe.since); pending > serviceLockTimeout {
+		return errors.Errorf("service lock not acquired for %s", pending.Round(time.Second))
+	}
+	return nil
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkStateRestored returns an error if the state has not been restored from the store yet.
+func (service *HTTPRestService) checkStateRestored() error {
+	service.RLock()
+	defer service.RUnlock()
+
+	if !service.stateRestored {
+		return errors.New("state not restored")
+	}
+	return nil
+}
+
+// checkStoreWritable returns an error if a file can't be written next to the store.
+func (service *HTTPRestService) checkStoreWritable() error {
+	if service.store == nil {
+		return errors.New("no store")
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(service.store.GetFileName()), ".readyz-")
+	if err != nil {
+		return errors.Wrap(err, "store directory is not writable")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write([]byte("ok")); err != nil {
+		f.Close()
+		return errors.Wrap(err, "store directory is not writable")
+	}
+	return errors.Wrap(f.Close(), "store directory is not writable")
+}
+
+// checkIPAMPoolMonitorReconciled returns an error if the IPAM pool monitor has never reconciled the pool.
+func (service *HTTPRestService) checkIPAMPoolMonitorReconciled() error {
+	lastReconcileTime := service.IPAMPoolMonitor.GetStateSnapshot().LastReconcileTime
+	if lastReconcileTime.IsZero() {
+		return errors.New("pool not reconciled yet")
+	}
+	return nil
+}
+
+// runHealthChecks runs the checks and returns the report of their results.
+func runHealthChecks(checks []healthCheck) cns.HealthReport {
+	report := cns.HealthReport{Healthy: true, Checks: make([]cns.HealthCheckResult, 0, len(checks))}
+	for _, c := range checks {
+		result := cns.HealthCheckResult{Name: c.name, Healthy: true}
+		if err := c.check(); err != nil {
+			result.Healthy = false
+			result.Message = err.Error()
+			report.Healthy = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+// writeHealthReport encodes the report with status 200 if it is healthy and 503 otherwise.
+func (service *HTTPRestService) writeHealthReport(w http.ResponseWriter, report cns.HealthReport) {
+	// the status is written before the body, so the content type set by Encode would be too late
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if report.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		logger.Printf("[Azure CNS] Unhealthy report %+v", report)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := service.Listener.Encode(w, &report); err != nil {
+		logger.Errorf("[Azure CNS] Failed to encode health report, err:%v", err)
+	}
+}
+
+// healthzHandler reports whether CNS is live.
+func (service *HTTPRestService) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	service.writeHealthReport(w, runHealthChecks(service.livenessChecks()))
+}
+
+// readyzHandler reports whether CNS is ready to serve requests.
+func (service *HTTPRestService) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	service.writeHealthReport(w, runHealthChecks(service.allReadinessChecks()))
+}
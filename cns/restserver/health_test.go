@@ -0,0 +1,133 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestHealthReport(t *testing.T, handler http.HandlerFunc, path string) (int, cns.HealthReport) {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report cns.HealthReport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	return w.Code, report
+}
+
+func healthCheckResult(report cns.HealthReport, name string) (cns.HealthCheckResult, bool) {
+	for _, result := range report.Checks {
+		if result.Name == name {
+			return result, true
+		}
+	}
+	return cns.HealthCheckResult{}, false
+}
+
+func TestReadyzWaitsForPoolMonitor(t *testing.T) {
+	restartService()
+	poolMonitor := &fakes.IPAMPoolMonitorFake{}
+	previous := svc.IPAMPoolMonitor
+	svc.IPAMPoolMonitor = poolMonitor
+	defer func() { svc.IPAMPoolMonitor = previous }()
+
+	code, report := requestHealthReport(t, svc.readyzHandler, cns.ReadyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, report.Healthy)
+	result, ok := healthCheckResult(report, healthCheckIPAMPoolMonitor)
+	require.True(t, ok)
+	assert.False(t, result.Healthy)
+	assert.NotEmpty(t, result.Message)
+
+	poolMonitor.FakeLastReconcile = time.Now()
+	code, report = requestHealthReport(t, svc.readyzHandler, cns.ReadyzPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Healthy)
+	for _, name := range []string{healthCheckStateRestored, healthCheckStore, healthCheckIPAMPoolMonitor} {
+		result, ok := healthCheckResult(report, name)
+		assert.True(t, ok, name)
+		assert.True(t, result.Healthy, name)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	code, report := requestHealthReport(t, svc.healthzHandler, cns.HealthzPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Healthy)
+	_, ok := healthCheckResult(report, healthCheckServiceLock)
+	assert.True(t, ok)
+}
+
+func TestReadinessChecks(t *testing.T) {
+	kvs, err := store.NewJsonFileStore(filepath.Join(t.TempDir(), "azure-cns.json"))
+	require.NoError(t, err)
+	s := &HTTPRestService{store: kvs}
+
+	report := runHealthChecks(s.allReadinessChecks())
+	assert.False(t, report.Healthy)
+	result, _ := healthCheckResult(report, healthCheckStateRestored)
+	assert.False(t, result.Healthy)
+	result, _ = healthCheckResult(report, healthCheckStore)
+	assert.True(t, result.Healthy)
+	_, ok := healthCheckResult(report, healthCheckIPAMPoolMonitor)
+	assert.False(t, ok, "the pool monitor is only checked if there is one")
+
+	s.stateRestored = true
+	s.AddReadinessCheck("added", func() error { return errors.New("not yet") })
+	report = runHealthChecks(s.allReadinessChecks())
+	assert.False(t, report.Healthy)
+	result, ok = healthCheckResult(report, "added")
+	require.True(t, ok)
+	assert.Equal(t, cns.HealthCheckResult{Name: "added", Message: "not yet"}, result)
+}
+
+func TestCheckStoreWritable(t *testing.T) {
+	s := &HTTPRestService{}
+	assert.Error(t, s.checkStoreWritable())
+
+	kvs, err := store.NewJsonFileStore(filepath.Join(t.TempDir(), "missing", "azure-cns.json"))
+	require.NoError(t, err)
+	s.store = kvs
+	assert.Error(t, s.checkStoreWritable())
+}
+
+func TestCheckServiceLock(t *testing.T) {
+	s := &HTTPRestService{}
+	require.NoError(t, s.checkServiceLock())
+
+	s.Lock()
+	assert.NoError(t, s.checkServiceLock(), "the lock may be held for less than the timeout")
+	pending := s.serviceLockProbe.acquired
+	assert.NoError(t, s.checkServiceLock())
+	assert.Equal(t, pending, s.serviceLockProbe.acquired, "a pending acquisition is shared by the checks")
+
+	s.serviceLockProbe.since = time.Now().Add(-serviceLockTimeout - time.Second)
+	assert.Error(t, s.checkServiceLock())
+	s.Unlock()
+	<-pending
+	assert.NoError(t, s.checkServiceLock())
+}
+
+func TestLegacyHealthReport(t *testing.T) {
+	w := httptest.NewRecorder()
+	svc.getHealthReport(w, httptest.NewRequest(http.MethodGet, cns.GetHealthReportPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp cns.Response
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, types.Success, resp.ReturnCode)
+}
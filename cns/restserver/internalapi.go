@@ -45,7 +45,7 @@ func (service *HTTPRestService) SetNodeOrchestrator(r *cns.SetOrchestratorTypeRe
 
 // SyncNodeStatus :- Retrieve the latest node state from DNC & returns the first occurence of returnCode and error with respect to contextFromCNI
 func (service *HTTPRestService) SyncNodeStatus(
-	dncEP, infraVnet, nodeID string, contextFromCNI json.RawMessage) (returnCode types.ResponseCode, errStr string) {
+	ctx context.Context, dncEP, infraVnet, nodeID string, contextFromCNI json.RawMessage) (returnCode types.ResponseCode, errStr string) {
 	logger.Printf("[Azure CNS] SyncNodeStatus")
 	var (
 		resp             *http.Response
@@ -56,7 +56,7 @@ func (service *HTTPRestService) SyncNodeStatus(
 
 	// try to retrieve NodeInfoResponse from mDNC
 	url := fmt.Sprintf(common.SyncNodeNetworkContainersURLFmt, dncEP, infraVnet, nodeID, dncApiVersion)
-	req, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	resp, err := httpc.Do(req)
 	if err == nil {
 		if resp.StatusCode == http.StatusOK {
@@ -107,7 +107,7 @@ func (service *HTTPRestService) SyncNodeStatus(
 		)
 
 		ncVersionURLs.Store(nc.NetworkContainerid, versionURL)
-		waitingForUpdate, _, _ := service.isNCWaitingForUpdate(nc.Version, nc.NetworkContainerid)
+		waitingForUpdate, _, _ := service.isNCWaitingForUpdate(ctx, nc.Version, nc.NetworkContainerid)
 
 		body, _ = json.Marshal(nc)
 		req, _ = http.NewRequest(http.MethodPost, "", bytes.NewBuffer(body))
@@ -175,37 +175,32 @@ func (service *HTTPRestService) SyncHostNCVersion(ctx context.Context, channelMo
 	service.RUnlock()
 	if len(hostVersionNeedUpdateNcList) > 0 {
 		logger.Printf("Updating version of the following NC IDs: %v", hostVersionNeedUpdateNcList)
-		ncVersionChannel := make(chan map[string]int)
-		ctxWithTimeout, _ := context.WithTimeout(ctx, syncHostNCTimeoutMilliSec*time.Millisecond)
-		go func() {
-			ncVersionChannel <- service.nmagentClient.GetNcVersionListWithOutToken(hostVersionNeedUpdateNcList)
-			close(ncVersionChannel)
-		}()
-		select {
-		case newHostNCVersionList := <-ncVersionChannel:
-			if newHostNCVersionList == nil {
-				logger.Errorf("Can't get vfp programmed NC version list from url without token")
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, syncHostNCTimeoutMilliSec*time.Millisecond)
+		defer cancel()
+		newHostNCVersionList, err := service.nmagentClient.GetNcVersionListWithOutToken(ctxWithTimeout, hostVersionNeedUpdateNcList)
+		if err != nil {
+			logger.Errorf("Can't get vfp programmed NC version list from url without token, err:%v", err)
+			return
+		}
+
+		service.Lock()
+		for ncID, newHostNCVersion := range newHostNCVersionList {
+			// Check whether it exist in service state and get the related nc info
+			if ncInfo, exist := service.state.ContainerStatus[ncID]; !exist {
+				logger.Errorf("Can't find NC with ID %s in service state, stop updating this host NC version", ncID)
 			} else {
-				service.Lock()
-				for ncID, newHostNCVersion := range newHostNCVersionList {
-					// Check whether it exist in service state and get the related nc info
-					if ncInfo, exist := service.state.ContainerStatus[ncID]; !exist {
-						logger.Errorf("Can't find NC with ID %s in service state, stop updating this host NC version", ncID)
-					} else {
-						if channelMode == cns.CRD {
-							service.MarkIpsAsAvailableUntransacted(ncInfo.ID, newHostNCVersion)
-						}
-						oldHostNCVersion := ncInfo.HostVersion
-						ncInfo.HostVersion = strconv.Itoa(newHostNCVersion)
-						service.state.ContainerStatus[ncID] = ncInfo
-						logger.Printf("Updated NC %s host version from %s to %s", ncID, oldHostNCVersion, ncInfo.HostVersion)
-					}
+				if channelMode == cns.CRD {
+					service.MarkIpsAsAvailableUntransacted(ncInfo.ID, newHostNCVersion)
 				}
-				service.Unlock()
+				oldHostNCVersion := ncInfo.HostVersion
+				ncInfo.HostVersion = strconv.Itoa(newHostNCVersion)
+				service.state.ContainerStatus[ncID] = ncInfo
+				observeNCVersions(ncID, ncInfo.CreateNetworkContainerRequest.Version, ncInfo.HostVersion)
+				service.publishNCEvent(cns.NetworkContainerUpdated, ncInfo)
+				logger.Printf("Updated NC %s host version from %s to %s", ncID, oldHostNCVersion, ncInfo.HostVersion)
 			}
-		case <-ctxWithTimeout.Done():
-			logger.Errorf("Timeout when getting vfp programmed NC version list from url without token")
 		}
+		service.Unlock()
 	}
 }
 
@@ -227,30 +222,42 @@ func (service *HTTPRestService) ReconcileNCState(
 	}
 	service.IPAMPoolMonitor.Update(scalar, spec)
 
-	// now parse the secondaryIP list, if it exists in PodInfo list, then allocate that ip
+	// now parse the secondaryIP list, if it exists in PodInfo list, then allocate that ip.
+	// The IPv4 and IPv6 addresses of a dual-stack pod are allocated together.
+	podInfoByKey := map[string]cns.PodInfo{}
+	desiredIPAddressesByKey := map[string][]string{}
 	for _, secIpConfig := range ncRequest.SecondaryIPConfigs {
 		if podInfo, exists := podInfoByIP[secIpConfig.IPAddress]; exists {
 			logger.Printf("SecondaryIP %+v is allocated to Pod. %+v, ncId: %s", secIpConfig, podInfo, ncRequest.NetworkContainerid)
+			podInfoByKey[podInfo.Key()] = podInfo
+			desiredIPAddressesByKey[podInfo.Key()] = append(desiredIPAddressesByKey[podInfo.Key()], secIpConfig.IPAddress)
+		} else {
+			logger.Printf("SecondaryIP %+v is not allocated. ncId: %s", secIpConfig, ncRequest.NetworkContainerid)
+		}
+	}
 
-			jsonContext, err := podInfo.OrchestratorContext()
-			if err != nil {
-				logger.Errorf("Failed to marshal KubernetesPodInfo, error: %v", err)
-				return types.UnexpectedError
-			}
+	for key, podInfo := range podInfoByKey {
+		jsonContext, err := podInfo.OrchestratorContext()
+		if err != nil {
+			logger.Errorf("Failed to marshal KubernetesPodInfo, error: %v", err)
+			return types.UnexpectedError
+		}
 
-			ipconfigRequest := cns.IPConfigRequest{
-				DesiredIPAddress:    secIpConfig.IPAddress,
-				OrchestratorContext: jsonContext,
-				PodInterfaceID:      podInfo.InterfaceID(),
-				InfraContainerID:    podInfo.InfraContainerID(),
-			}
+		ipconfigRequest := cns.IPConfigRequest{
+			OrchestratorContext: jsonContext,
+			PodInterfaceID:      podInfo.InterfaceID(),
+			InfraContainerID:    podInfo.InfraContainerID(),
+		}
 
-			if _, err := requestIPConfigHelper(service, ipconfigRequest); err != nil {
-				logger.Errorf("AllocateIPConfig failed for SecondaryIP %+v, podInfo %+v, ncId %s, error: %v", secIpConfig, podInfo, ncRequest.NetworkContainerid, err)
-				return types.FailedToAllocateIPConfig
-			}
-		} else {
-			logger.Printf("SecondaryIP %+v is not allocated. ncId: %s", secIpConfig, ncRequest.NetworkContainerid)
+		requestPodInfo, err := cns.NewPodInfoFromIPConfigRequest(ipconfigRequest)
+		if err != nil {
+			logger.Errorf("AllocateIPConfig failed for SecondaryIPs %v, podInfo %+v, ncId %s, error: %v", desiredIPAddressesByKey[key], podInfo, ncRequest.NetworkContainerid, err)
+			return types.FailedToAllocateIPConfig
+		}
+
+		if _, err := service.reconcilePodIPConfigs(requestPodInfo, desiredIPAddressesByKey[key]...); err != nil {
+			logger.Errorf("AllocateIPConfig failed for SecondaryIPs %v, podInfo %+v, ncId %s, error: %v", desiredIPAddressesByKey[key], podInfo, ncRequest.NetworkContainerid, err)
+			return types.FailedToAllocateIPConfig
 		}
 	}
 
@@ -267,7 +274,7 @@ func (service *HTTPRestService) ReconcileNCState(
 func (service *HTTPRestService) GetNetworkContainerInternal(
 	req cns.GetNetworkContainerRequest,
 ) (cns.GetNetworkContainerResponse, types.ResponseCode) {
-	getNetworkContainerResponse := service.getNetworkContainerResponse(req)
+	getNetworkContainerResponse := service.getNetworkContainerResponse(context.Background(), req)
 	returnCode := getNetworkContainerResponse.Response.ReturnCode
 	return getNetworkContainerResponse, returnCode
 }
@@ -276,7 +283,7 @@ func (service *HTTPRestService) GetNetworkContainerInternal(
 func (service *HTTPRestService) DeleteNetworkContainerInternal(
 	req cns.DeleteNetworkContainerRequest,
 ) types.ResponseCode {
-	_, exist := service.getNetworkContainerDetails(req.NetworkContainerid)
+	ncStatus, exist := service.getNetworkContainerDetails(req.NetworkContainerid)
 	if !exist {
 		logger.Printf("network container for id %v doesn't exist", req.NetworkContainerid)
 		return types.Success
@@ -286,6 +293,8 @@ func (service *HTTPRestService) DeleteNetworkContainerInternal(
 	defer service.Unlock()
 	if service.state.ContainerStatus != nil {
 		delete(service.state.ContainerStatus, req.NetworkContainerid)
+		deleteNCVersions(req.NetworkContainerid)
+		service.publishNCEvent(cns.NetworkContainerDeleted, ncStatus)
 	}
 
 	if service.state.ContainerIDByOrchestratorContext != nil {
@@ -324,6 +333,15 @@ func (service *HTTPRestService) CreateOrUpdateNetworkContainerInternal(
 		return types.InvalidPrimaryIPConfig
 	}
 
+	// Validate the IPv6 PrimaryCA if the NC is dual-stack
+	isDualStack := req.IPv6Configuration.IPSubnet.IPAddress != ""
+	if isDualStack {
+		if err := validateIPSubnet(req.IPv6Configuration.IPSubnet); err != nil || !isIPv6Address(req.IPv6Configuration.IPSubnet.IPAddress) {
+			logger.Errorf("[Azure CNS] Error. IPv6 PrimaryCA is invalid, NC Req: %v", req)
+			return types.InvalidPrimaryIPConfig
+		}
+	}
+
 	// Validate SecondaryIPConfig
 	for _, secIpconfig := range req.SecondaryIPConfigs {
 		// Validate Ipconfig
@@ -331,6 +349,12 @@ func (service *HTTPRestService) CreateOrUpdateNetworkContainerInternal(
 			logger.Errorf("Failed to add IPConfig to state: %+v, empty IPSubnet.IPAddress", secIpconfig)
 			return types.InvalidSecondaryIPConfig
 		}
+
+		// IPv6 SecondaryIPConfigs need the IPv6 PrimaryCA of the NC
+		if isIPv6Address(secIpconfig.IPAddress) && !isDualStack {
+			logger.Errorf("Failed to add IPConfig to state: %+v, IPv6 address in NC without IPv6Configuration", secIpconfig)
+			return types.InvalidSecondaryIPConfig
+		}
 	}
 
 	// Validate if state exists already
@@ -341,6 +365,10 @@ func (service *HTTPRestService) CreateOrUpdateNetworkContainerInternal(
 			logger.Errorf("[Azure CNS] Error. PrimaryCA is not same, NCId %s, old CA %s, new CA %s", req.NetworkContainerid, existingReq.PrimaryInterfaceIdentifier, req.PrimaryInterfaceIdentifier)
 			return types.PrimaryCANotSame
 		}
+		if !reflect.DeepEqual(existingReq.IPv6Configuration, req.IPv6Configuration) {
+			logger.Errorf("[Azure CNS] Error. IPv6 PrimaryCA is not same, NCId %s, old CA %+v, new CA %+v", req.NetworkContainerid, existingReq.IPv6Configuration, req.IPv6Configuration)
+			return types.PrimaryCANotSame
+		}
 	}
 
 	// This will Create Or Update the NC state.
@@ -348,7 +376,7 @@ func (service *HTTPRestService) CreateOrUpdateNetworkContainerInternal(
 
 	// If the NC was created successfully, log NC snapshot.
 	if returnCode == 0 {
-		logNCSnapshot(req)
+		service.logNCSnapshot(req)
 	} else {
 		logger.Errorf(returnMessage)
 	}
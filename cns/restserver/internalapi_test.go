@@ -4,8 +4,12 @@
 package restserver
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strconv"
@@ -14,6 +18,7 @@ import (
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/nmagentclient"
 	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/google/uuid"
 )
@@ -176,6 +181,149 @@ func TestPendingIPsGotUpdatedWhenSyncHostNCVersion(t *testing.T) {
 	}
 }
 
+func TestSyncHostNCVersionRetriesNMAgentFailures(t *testing.T) {
+	req := createNCReqeustForSyncHostNCVersion(t)
+	nmagentServer.FailNext(nmagentclient.OpGetNcVersionListWithOutToken, http.StatusServiceUnavailable)
+
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+	containerStatus := svc.state.ContainerStatus[req.NetworkContainerid]
+	if containerStatus.HostVersion != "0" {
+		t.Errorf("Unexpected containerStatus.HostVersion %s, expeted host version should be 0 in string", containerStatus.HostVersion)
+	}
+}
+
+func TestSyncHostNCVersionWhenNMAgentFails(t *testing.T) {
+	req := createNCReqeustForSyncHostNCVersion(t)
+	nmagentServer.FailNext(nmagentclient.OpGetNcVersionListWithOutToken, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+	containerStatus := svc.state.ContainerStatus[req.NetworkContainerid]
+	if containerStatus.HostVersion != "-1" {
+		t.Errorf("Unexpected containerStatus.HostVersion %s, expeted host version should be -1 in string", containerStatus.HostVersion)
+	}
+	for _, podIPConfigState := range svc.PodIPConfigState {
+		if podIPConfigState.State != cns.PendingProgramming {
+			t.Errorf("Unexpected State %s, expeted State is %s, IP address is %s", podIPConfigState.State, cns.PendingProgramming, podIPConfigState.IPAddress)
+		}
+	}
+}
+
+func TestSyncHostNCVersionWhenNCIsNotProgrammed(t *testing.T) {
+	req := createNCReqeustForSyncHostNCVersion(t)
+	nmagentServer.RemoveNC(req.NetworkContainerid)
+
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+	containerStatus := svc.state.ContainerStatus[req.NetworkContainerid]
+	if containerStatus.HostVersion != "-1" {
+		t.Errorf("Unexpected containerStatus.HostVersion %s, expeted host version should be -1 in string", containerStatus.HostVersion)
+	}
+}
+
+func TestPendingProgrammingIPsWaitForNMAgentNCVersion(t *testing.T) {
+	req := createNCReqeustForSyncHostNCVersion(t)
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+
+	// DNC adds an IP in NC version 1, which NMAgent has yet to program.
+	pendingIPAddress := "10.0.0.17"
+	req.SecondaryIPConfigs[uuid.New().String()] = newSecondaryIPConfig(pendingIPAddress, 1)
+	req = createNCReqInternal(t, req.SecondaryIPConfigs, req.NetworkContainerid, "1")
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+
+	if pending := svc.GetPendingProgramIPConfigs(); len(pending) != 1 || pending[0].IPAddress != pendingIPAddress {
+		t.Fatalf("Expected only %s to be pending programming, got %+v", pendingIPAddress, pending)
+	}
+	validateNCStatus(t, req.NetworkContainerid, "1", "0", 1)
+
+	// the pending IP is not handed to pods, neither when desired nor when any IP is requested
+	if _, err := svc.AllocateDesiredIPConfigs(testPod1Info, pendingIPAddress); err == nil {
+		t.Fatalf("Expected the allocation of pending programming IP %s to fail", pendingIPAddress)
+	}
+	if _, err := svc.AllocateAnyAvailableIPConfigs(testPod1Info); err != nil {
+		t.Fatalf("Unexpected failure to allocate the available IP: %v", err)
+	}
+	if _, err := svc.AllocateAnyAvailableIPConfigs(testPod2Info); err == nil {
+		t.Fatalf("Expected the allocation to fail while the only free IP is pending programming")
+	}
+
+	nmagentServer.SetNCVersion(req.NetworkContainerid, "1")
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+
+	if pending := svc.GetPendingProgramIPConfigs(); len(pending) != 0 {
+		t.Fatalf("Expected no IPs pending programming once NMAgent programmed NC version 1, got %+v", pending)
+	}
+	validateNCStatus(t, req.NetworkContainerid, "1", "1", 0)
+	if _, err := svc.AllocateDesiredIPConfigs(testPod2Info, pendingIPAddress); err != nil {
+		t.Fatalf("Unexpected failure to allocate the programmed IP %s: %v", pendingIPAddress, err)
+	}
+}
+
+func TestAllocatePendingProgrammingIPsWhenConfigured(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+	svc.allocatePendingProgrammingIPs = true
+	defer func() { svc.allocatePendingProgrammingIPs = false }()
+
+	// NMAgent has yet to report a version of the NC, so its IP is pending programming.
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{uuid.New().String(): newSecondaryIPConfig("10.0.0.18", 0)}
+	req := createNCReqInternal(t, secondaryIPConfigs, "testPendingProgrammingNc", "0")
+	pending := svc.GetPendingProgramIPConfigs()
+	if len(pending) != 1 {
+		t.Fatalf("Expected 1 IP pending programming, got %+v", pending)
+	}
+	podIPInfos, err := svc.AllocateAnyAvailableIPConfigs(testPod1Info)
+	if err != nil {
+		t.Fatalf("Unexpected failure to allocate the pending programming IP: %v", err)
+	}
+	if podIPInfos[0].PodIPConfig.IPAddress != pending[0].IPAddress {
+		t.Fatalf("Expected the pending programming IP %s to be allocated, got %+v", pending[0].IPAddress, podIPInfos)
+	}
+	validateNCStatus(t, req.NetworkContainerid, "0", "-1", 0)
+}
+
+func TestGetNetworkContainerStatusOfUnknownNC(t *testing.T) {
+	restartService()
+	setEnv(t)
+
+	var resp cns.GetNetworkContainerStatusResponse
+	getNetworkContainerStatus(t, "unknown-nc", &resp)
+	if resp.Response.ReturnCode != types.UnknownContainerID {
+		t.Errorf("Unexpected ReturnCode %s, expected %s", resp.Response.ReturnCode, types.UnknownContainerID)
+	}
+}
+
+// validateNCStatus validates the versions of the NC, and its count of IPs pending programming, in the NC status
+// and in the version metrics.
+func validateNCStatus(t *testing.T, ncID, version, hostVersion string, pendingProgrammingIPCount int) {
+	var resp cns.GetNetworkContainerStatusResponse
+	getNetworkContainerStatus(t, ncID, &resp)
+	if resp.Response.ReturnCode != types.Success {
+		t.Fatalf("Failed to get status of NC %s: %+v", ncID, resp.Response)
+	}
+	if resp.Version != version || resp.AzureHostVersion != hostVersion || resp.PendingProgrammingIPCount != pendingProgrammingIPCount {
+		t.Errorf("Unexpected status of NC %s: %+v, expected version %s, host version %s and %d IPs pending programming",
+			ncID, resp, version, hostVersion, pendingProgrammingIPCount)
+	}
+
+	requested := metricValue(t, ipamNCRequestedVersion.WithLabelValues(ncID)).GetGauge().GetValue()
+	programmed := metricValue(t, ipamNCProgrammedVersion.WithLabelValues(ncID)).GetGauge().GetValue()
+	if strconv.Itoa(int(requested)) != version || strconv.Itoa(int(programmed)) != hostVersion {
+		t.Errorf("Unexpected version metrics of NC %s: requested %v, programmed %v", ncID, requested, programmed)
+	}
+}
+
+func getNetworkContainerStatus(t *testing.T, ncID string, resp *cns.GetNetworkContainerStatusResponse) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&cns.GetNetworkContainerStatusRequest{NetworkContainerid: ncID}); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	svc.getNetworkContainerStatus(w, httptest.NewRequest(http.MethodPost, cns.GetNetworkContainerStatus, &body))
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func createNCReqeustForSyncHostNCVersion(t *testing.T) cns.CreateNetworkContainerRequest {
 	restartService()
 	setEnv(t)
@@ -185,6 +333,8 @@ func createNCReqeustForSyncHostNCVersion(t *testing.T) cns.CreateNetworkContaine
 	ncVersion := 0
 	secondaryIPConfigs := make(map[string]cns.SecondaryIPConfig)
 	ncID := "testNc1"
+	// NMAgent has programmed the NC version.
+	nmagentServer.SetNCVersion(ncID, strconv.Itoa(ncVersion))
 
 	// Build secondaryIPConfig, it will have one item as {IPAddress:"10.0.0.16", NCVersion: 0}
 	ipAddress := "10.0.0.16"
@@ -4,9 +4,13 @@
 package restserver
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/filter"
@@ -16,42 +20,73 @@ import (
 
 // used to request an IPConfig from the CNS state
 func (service *HTTPRestService) requestIPConfigHandler(w http.ResponseWriter, r *http.Request) {
-	var (
-		err             error
-		ipconfigRequest cns.IPConfigRequest
-		podIpInfo       cns.PodIpInfo
-		returnCode      types.ResponseCode
-		returnMessage   string
-	)
+	var ipconfigRequest cns.IPConfigRequest
 
-	err = service.Listener.Decode(w, r, &ipconfigRequest)
+	err := service.Listener.Decode(w, r, &ipconfigRequest)
 	operationName := "requestIPConfigHandler"
 	logger.Request(service.Name+operationName, ipconfigRequest, err)
 	if err != nil {
 		return
 	}
 
+	reserveResp := service.RequestIPConfig(ipconfigRequest)
+
+	err = service.Listener.Encode(w, &reserveResp)
+	logger.ResponseEx(service.Name+operationName, ipconfigRequest, reserveResp, reserveResp.Response.ReturnCode, err)
+}
+
+// RequestIPConfig returns the IPConfig already allocated to the pod in the request,
+// or allocates a new one from the CNS state.
+func (service *HTTPRestService) RequestIPConfig(ipconfigRequest cns.IPConfigRequest) cns.IPConfigResponse {
+	var (
+		err           error
+		podIPInfos    []cns.PodIpInfo
+		returnCode    types.ResponseCode
+		returnMessage string
+	)
+
+	if !service.ipIntake.admit() {
+		return cns.IPConfigResponse{
+			Response: cns.Response{
+				ReturnCode: types.ShuttingDown,
+				Message:    fmt.Sprintf("AllocateIPConfig failed: %v, IP config request is %s", errShuttingDown, ipconfigRequest),
+			},
+		}
+	}
+	defer service.ipIntake.done()
+
 	// retrieve ipconfig from nc
 	_, returnCode, returnMessage = service.validateIPConfigRequest(ipconfigRequest)
 	if returnCode == types.Success {
-		if podIpInfo, err = requestIPConfigHelper(service, ipconfigRequest); err != nil {
+		if podIPInfos, err = requestIPConfigHelper(service, ipconfigRequest); err != nil {
 			returnCode = types.FailedToAllocateIPConfig
+			switch {
+			case errors.Is(err, errIPRequestQueueFull) || errors.Is(err, errIPRequestWaitTimeout):
+				returnCode = types.IPConfigRequestThrottled
+			case errors.Is(err, errNamespaceIPQuotaExceeded):
+				returnCode = types.NamespaceIPQuotaExceeded
+			case errors.Is(err, errShuttingDown):
+				returnCode = types.ShuttingDown
+			}
 			returnMessage = fmt.Sprintf("AllocateIPConfig failed: %v, IP config request is %s", err, ipconfigRequest)
 		}
 	}
 
-	resp := cns.Response{
-		ReturnCode: returnCode,
-		Message:    returnMessage,
+	resp := cns.IPConfigResponse{
+		Response: cns.Response{
+			ReturnCode: returnCode,
+			Message:    returnMessage,
+		},
 	}
-
-	reserveResp := &cns.IPConfigResponse{
-		Response: resp,
+	for i := range podIPInfos {
+		if isIPv6Address(podIPInfos[i].PodIPConfig.IPAddress) {
+			resp.PodIpInfoV6 = &podIPInfos[i]
+		} else {
+			resp.PodIpInfo = podIPInfos[i]
+		}
 	}
-	reserveResp.PodIpInfo = podIpInfo
 
-	err = service.Listener.Encode(w, &reserveResp)
-	logger.ResponseEx(service.Name+operationName, ipconfigRequest, reserveResp, resp.ReturnCode, err)
+	return resp
 }
 
 func (service *HTTPRestService) releaseIPConfigHandler(w http.ResponseWriter, r *http.Request) {
@@ -73,15 +108,30 @@ func (service *HTTPRestService) releaseIPConfigHandler(w http.ResponseWriter, r
 		return
 	}
 
+	resp = service.ReleaseIPConfig(req)
+}
+
+// ReleaseIPConfig marks the IPConfig allocated to the pod in the request as Available.
+func (service *HTTPRestService) ReleaseIPConfig(req cns.IPConfigRequest) cns.Response {
+	// IPs are still released while shutting down, since the pods on a draining node are deleted
+	service.ipIntake.track()
+	defer service.ipIntake.done()
+
+	resp := cns.Response{}
+
 	var podInfo cns.PodInfo
 	podInfo, resp.ReturnCode, resp.Message = service.validateIPConfigRequest(req)
+	if resp.ReturnCode != types.Success {
+		return resp
+	}
 
-	if err = service.releaseIPConfig(podInfo); err != nil {
+	if err := service.releaseIPConfig(podInfo); err != nil {
 		resp.ReturnCode = types.UnexpectedError
 		resp.Message = err.Error()
 		logger.Errorf("releaseIPConfigHandler releaseIPConfig failed because %v, release IP config info %s", resp.Message, req)
-		return
 	}
+
+	return resp
 }
 
 // MarkIPAsPendingRelease will set the IPs which are in PendingProgramming or Available to PendingRelease state
@@ -93,7 +143,7 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 
 	for uuid, existingIpConfig := range service.PodIPConfigState {
 		if existingIpConfig.State == cns.PendingProgramming {
-			updatedIpConfig, err := service.updateIPConfigState(uuid, cns.PendingRelease, existingIpConfig.PodInfo)
+			updatedIpConfig, err := service.updateIPConfigState(uuid, cns.PendingRelease, existingIpConfig.PodInfo, causeMarkIPAsPendingRelease)
 			if err != nil {
 				return nil, err
 			}
@@ -108,7 +158,7 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 	// if not all expected IPs are set to PendingRelease, then check the Available IPs
 	for uuid, existingIpConfig := range service.PodIPConfigState {
 		if existingIpConfig.State == cns.Available {
-			updatedIpConfig, err := service.updateIPConfigState(uuid, cns.PendingRelease, existingIpConfig.PodInfo)
+			updatedIpConfig, err := service.updateIPConfigState(uuid, cns.PendingRelease, existingIpConfig.PodInfo, causeMarkIPAsPendingRelease)
 			if err != nil {
 				return nil, err
 			}
@@ -125,12 +175,30 @@ func (service *HTTPRestService) MarkIPAsPendingRelease(totalIpsToRelease int) (m
 	return pendingReleasedIps, nil
 }
 
-func (service *HTTPRestService) updateIPConfigState(ipID string, updatedState cns.IPConfigState, podInfo cns.PodInfo) (cns.IPConfigurationStatus, error) {
+// updateIPConfigState sets the state and pod of the IPConfig and records the transition with its cause.
+// Caller will acquire/release the service lock.
+func (service *HTTPRestService) updateIPConfigState(ipID string, updatedState cns.IPConfigState, podInfo cns.PodInfo, cause string) (cns.IPConfigurationStatus, error) {
 	if ipConfig, found := service.PodIPConfigState[ipID]; found {
 		logger.Printf("[updateIPConfigState] Changing IpId [%s] state to [%s], podInfo [%+v]. Current config [%+v]", ipID, updatedState, podInfo, ipConfig)
+		previousState := ipConfig.State
+		previousPodInfo := ipConfig.PodInfo
+		if previousState != updatedState {
+			ipConfig.StateChangedAt = time.Now()
+		}
 		ipConfig.State = updatedState
 		ipConfig.PodInfo = podInfo
 		service.PodIPConfigState[ipID] = ipConfig
+		service.publishIPConfigEvent(cns.IPConfigModified, ipConfig, previousState)
+		if updatedState == cns.Available {
+			service.ipRequests.signalIPAvailable()
+		}
+
+		// attribute releases to the pod which held the IP
+		transitioned := ipConfig
+		if transitioned.PodInfo == nil {
+			transitioned.PodInfo = previousPodInfo
+		}
+		service.recordIPStateTransition(transitioned, previousState, updatedState, cause)
 		return ipConfig, nil
 	}
 
@@ -156,7 +224,7 @@ func (service *HTTPRestService) MarkIpsAsAvailableUntransacted(ncID string, newH
 				if ipConfigStatus, exist := service.PodIPConfigState[uuid]; !exist {
 					logger.Errorf("IP %s with uuid as %s exist in service state Secondary IP list but can't find in PodIPConfigState", ipConfigStatus.IPAddress, uuid)
 				} else if ipConfigStatus.State == cns.PendingProgramming && secondaryIPConfigs.NCVersion <= newHostNCVersion {
-					_, err := service.updateIPConfigState(uuid, cns.Available, nil)
+					_, err := service.updateIPConfigState(uuid, cns.Available, nil, causeNCVersionProgrammed)
 					if err != nil {
 						logger.Errorf("Error updating IPConfig [%+v] state to Available, err: %+v", ipConfigStatus, err)
 					}
@@ -236,9 +304,10 @@ func (service *HTTPRestService) GetHTTPStruct() HTTPRestServiceData {
 	defer service.RUnlock()
 
 	return HTTPRestServiceData{
-		PodIPIDByPodInterfaceKey: service.PodIPIDByPodInterfaceKey,
-		PodIPConfigState:         service.PodIPConfigState,
-		IPAMPoolMonitor:          service.IPAMPoolMonitor.GetStateSnapshot(),
+		PodIPIDByPodInterfaceKey:   service.PodIPIDByPodInterfaceKey,
+		PodIPv6IDByPodInterfaceKey: service.PodIPv6IDByPodInterfaceKey,
+		PodIPConfigState:           service.PodIPConfigState,
+		IPAMPoolMonitor:            service.IPAMPoolMonitor.GetStateSnapshot(),
 	}
 }
 
@@ -263,16 +332,97 @@ func (service *HTTPRestService) getIPAddressesHandler(w http.ResponseWriter, r *
 		logger.ResponseEx(service.Name, req, resp, resp.Response.ReturnCode, err)
 	}()
 
-	err = service.Listener.Decode(w, r, &req)
-	if err != nil {
+	if r.Method == http.MethodGet {
+		if req, err = getIPAddressesRequestFromQuery(r.URL.Query()); err != nil {
+			statusCode = types.InvalidParameter
+			returnMessage = err.Error()
+			return
+		}
+	} else if err = service.Listener.Decode(w, r, &req); err != nil {
 		returnMessage = err.Error()
 		logger.Errorf("getIPAddressesHandler decode failed because %v, GetIPAddressesRequest is %v",
 			returnMessage, req)
 		return
 	}
 
-	// Get all IPConfigs matching a state, and append to a slice of IPAddressState
-	resp.IPConfigurationStatus = filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.PredicatesForStates(req.IPConfigStateFilter...)...)
+	// Get all IPConfigs matching the filters of the request
+	resp.IPConfigurationStatus, err = service.GetIPConfigsMatchingRequest(req)
+	if err != nil {
+		statusCode = types.InvalidParameter
+		returnMessage = err.Error()
+	}
+}
+
+// Query parameters of the GetIPAddresses endpoint when it is queried with GET, matching the fields of
+// cns.GetIPAddressesRequest. The state parameter is repeated to match IPs in any of the states.
+const (
+	ipAddressesQueryState          = "state"
+	ipAddressesQueryNCID           = "ncID"
+	ipAddressesQueryPodName        = "podName"
+	ipAddressesQueryPodNamespace   = "podNamespace"
+	ipAddressesQueryCIDR           = "cidr"
+	ipAddressesQueryMinTimeInState = "minTimeInState"
+)
+
+// getIPAddressesRequestFromQuery returns the GetIPAddressesRequest of the query parameters.
+func getIPAddressesRequestFromQuery(query url.Values) (cns.GetIPAddressesRequest, error) {
+	req := cns.GetIPAddressesRequest{
+		NCID:         query.Get(ipAddressesQueryNCID),
+		PodName:      query.Get(ipAddressesQueryPodName),
+		PodNamespace: query.Get(ipAddressesQueryPodNamespace),
+		IPCIDR:       query.Get(ipAddressesQueryCIDR),
+	}
+	for _, state := range query[ipAddressesQueryState] {
+		req.IPConfigStateFilter = append(req.IPConfigStateFilter, cns.IPConfigState(state))
+	}
+	if minTimeInState := query.Get(ipAddressesQueryMinTimeInState); minTimeInState != "" {
+		d, err := time.ParseDuration(minTimeInState)
+		if err != nil {
+			return req, fmt.Errorf("invalid %s %s: %w", ipAddressesQueryMinTimeInState, minTimeInState, err)
+		}
+		req.MinTimeInState = d
+	}
+	return req, nil
+}
+
+// GetIPConfigsMatchingRequest returns a filtered list of IPs which are in any of the States of the request,
+// and match every other filter of the request which is set.
+func (service *HTTPRestService) GetIPConfigsMatchingRequest(req cns.GetIPAddressesRequest) ([]cns.IPConfigurationStatus, error) {
+	predicates := []filter.IPConfigStatePredicate{filter.Any(filter.PredicatesForStates(req.IPConfigStateFilter...)...)}
+	if req.NCID != "" {
+		predicates = append(predicates, filter.NCID(req.NCID))
+	}
+	if req.PodName != "" {
+		predicates = append(predicates, filter.PodName(req.PodName))
+	}
+	if req.PodNamespace != "" {
+		predicates = append(predicates, filter.PodNamespace(req.PodNamespace))
+	}
+	if req.IPCIDR != "" {
+		_, cidr, err := net.ParseCIDR(req.IPCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP CIDR %s: %w", req.IPCIDR, err)
+		}
+		predicates = append(predicates, filter.IPInCIDR(cidr))
+	}
+	if req.MinTimeInState < 0 {
+		return nil, fmt.Errorf("invalid min time in state %s", req.MinTimeInState) //nolint:goerr113
+	}
+	if req.MinTimeInState > 0 {
+		predicates = append(predicates, filter.StateChangedBefore(time.Now().Add(-req.MinTimeInState)))
+	}
+
+	service.RLock()
+	defer service.RUnlock()
+	return filter.MatchAllIPConfigStates(service.PodIPConfigState, predicates...), nil
+}
+
+// GetIPConfigsMatchingStates returns a filtered list of IPs which are in
+// any of the passed States.
+func (service *HTTPRestService) GetIPConfigsMatchingStates(states ...cns.IPConfigState) []cns.IPConfigurationStatus {
+	service.RLock()
+	defer service.RUnlock()
+	return filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.PredicatesForStates(states...)...)
 }
 
 // GetAllocatedIPConfigs returns a filtered list of IPs which are in
@@ -310,23 +460,23 @@ func (service *HTTPRestService) GetPendingReleaseIPConfigs() []cns.IPConfigurati
 // SetIPConfigAsAllocated takes a lock of the service, and sets the ipconfig in the CNS state as allocated.
 // Does not take a lock.
 func (service *HTTPRestService) setIPConfigAsAllocated(ipconfig cns.IPConfigurationStatus, podInfo cns.PodInfo) error {
-	ipconfig, err := service.updateIPConfigState(ipconfig.ID, cns.Allocated, podInfo)
+	ipconfig, err := service.updateIPConfigState(ipconfig.ID, cns.Allocated, podInfo, causeRequestIPConfig)
 	if err != nil {
 		return err
 	}
 
-	service.PodIPIDByPodInterfaceKey[podInfo.Key()] = ipconfig.ID
+	service.podIPIDByPodInterfaceKeyFor(ipconfig.IPAddress)[podInfo.Key()] = ipconfig.ID
 	return nil
 }
 
 // SetIPConfigAsAllocated and sets the ipconfig in the CNS state as allocated, does not take a lock
 func (service *HTTPRestService) setIPConfigAsAvailable(ipconfig cns.IPConfigurationStatus, podInfo cns.PodInfo) (cns.IPConfigurationStatus, error) {
-	ipconfig, err := service.updateIPConfigState(ipconfig.ID, cns.Available, nil)
+	ipconfig, err := service.updateIPConfigState(ipconfig.ID, cns.Available, nil, causeReleaseIPConfig)
 	if err != nil {
 		return cns.IPConfigurationStatus{}, err
 	}
 
-	delete(service.PodIPIDByPodInterfaceKey, podInfo.Key())
+	delete(service.podIPIDByPodInterfaceKeyFor(ipconfig.IPAddress), podInfo.Key())
 	logger.Printf("[setIPConfigAsAvailable] Deleted outdated pod info %s from PodIPIDByOrchestratorContext since IP %s with ID %s will be released and set as Available",
 		podInfo.Key(), ipconfig.IPAddress, ipconfig.ID)
 	return ipconfig, nil
@@ -339,25 +489,37 @@ func (service *HTTPRestService) releaseIPConfig(podInfo cns.PodInfo) error {
 	service.Lock()
 	defer service.Unlock()
 
-	ipID := service.PodIPIDByPodInterfaceKey[podInfo.Key()]
-	if ipID != "" {
-		if ipconfig, isExist := service.PodIPConfigState[ipID]; isExist {
-			logger.Printf("[releaseIPConfig] Releasing IP %+v for pod %+v", ipconfig.IPAddress, podInfo)
-			_, err := service.setIPConfigAsAvailable(ipconfig, podInfo)
-			if err != nil {
-				return fmt.Errorf("[releaseIPConfig] failed to mark IPConfig [%+v] as Available. err: %v", ipconfig, err)
-			}
-			logger.Printf("[releaseIPConfig] Released IP %+v for pod %+v", ipconfig.IPAddress, podInfo)
-		} else {
+	// the IPv4 and IPv6 addresses of a dual-stack pod are validated before either is released,
+	// so that both are released together or neither is.
+	var ipconfigs []cns.IPConfigurationStatus
+	for _, ipID := range []string{service.PodIPIDByPodInterfaceKey[podInfo.Key()], service.PodIPv6IDByPodInterfaceKey[podInfo.Key()]} {
+		if ipID == "" {
+			continue
+		}
+
+		ipconfig, isExist := service.PodIPConfigState[ipID]
+		if !isExist {
 			logger.Errorf("[releaseIPConfig] Failed to get release ipconfig %+v and pod info is %+v. Pod to IPID exists, but IPID to IPConfig doesn't exist, CNS State potentially corrupt",
 				ipconfig.IPAddress, podInfo)
 			return fmt.Errorf("[releaseIPConfig] releaseIPConfig failed. IPconfig %+v and pod info is %+v. Pod to IPID exists, but IPID to IPConfig doesn't exist, CNS State potentially corrupt",
 				ipconfig.IPAddress, podInfo)
 		}
-	} else {
+		ipconfigs = append(ipconfigs, ipconfig)
+	}
+
+	if len(ipconfigs) == 0 {
 		logger.Errorf("[releaseIPConfig] SetIPConfigAsAvailable ignoring request to release, no allocation found for pod [%+v]", podInfo)
 		return nil
 	}
+
+	for _, ipconfig := range ipconfigs {
+		logger.Printf("[releaseIPConfig] Releasing IP %+v for pod %+v", ipconfig.IPAddress, podInfo)
+		_, err := service.setIPConfigAsAvailable(ipconfig, podInfo)
+		if err != nil {
+			return fmt.Errorf("[releaseIPConfig] failed to mark IPConfig [%+v] as Available. err: %v", ipconfig, err)
+		}
+		logger.Printf("[releaseIPConfig] Released IP %+v for pod %+v", ipconfig.IPAddress, podInfo)
+	}
 	return nil
 }
 
@@ -373,8 +535,12 @@ func (service *HTTPRestService) MarkExistingIPsAsPending(pendingIPIDs []string)
 			}
 
 			logger.Printf("[MarkExistingIPsAsPending]: Marking IP [%+v] to PendingRelease", ipconfig)
+			previousState := ipconfig.State
 			ipconfig.State = cns.PendingRelease
+			ipconfig.StateChangedAt = time.Now()
 			service.PodIPConfigState[id] = ipconfig
+			service.publishIPConfigEvent(cns.IPConfigModified, ipconfig, previousState)
+			service.recordIPStateTransition(ipconfig, previousState, cns.PendingRelease, causeMarkExistingIPsAsPending)
 		} else {
 			logger.Errorf("Inconsistent state, ipconfig with ID [%v] marked as pending release, but does not exist in state", id)
 		}
@@ -382,111 +548,267 @@ func (service *HTTPRestService) MarkExistingIPsAsPending(pendingIPIDs []string)
 	return nil
 }
 
-func (service *HTTPRestService) GetExistingIPConfig(podInfo cns.PodInfo) (cns.PodIpInfo, bool, error) {
-	var (
-		podIpInfo cns.PodIpInfo
-		isExist   bool
-	)
-
+// GetExistingIPConfigs returns the IPConfigs already allocated to the pod, IPv4 first.
+func (service *HTTPRestService) GetExistingIPConfigs(podInfo cns.PodInfo) ([]cns.PodIpInfo, bool, error) {
 	service.RLock()
 	defer service.RUnlock()
 
-	ipID := service.PodIPIDByPodInterfaceKey[podInfo.Key()]
-	if ipID != "" {
-		if ipState, isExist := service.PodIPConfigState[ipID]; isExist {
-			err := service.populateIpConfigInfoUntransacted(ipState, &podIpInfo)
-			return podIpInfo, isExist, err
+	var ipConfigs []cns.IPConfigurationStatus
+	for _, ipID := range []string{service.PodIPIDByPodInterfaceKey[podInfo.Key()], service.PodIPv6IDByPodInterfaceKey[podInfo.Key()]} {
+		if ipID == "" {
+			continue
+		}
+
+		ipState, isExist := service.PodIPConfigState[ipID]
+		if !isExist {
+			logger.Errorf("Failed to get existing ipconfig. Pod to IPID exists, but IPID to IPConfig doesn't exist, CNS State potentially corrupt")
+			return nil, false, fmt.Errorf("Failed to get existing ipconfig. Pod to IPID exists, but IPID to IPConfig doesn't exist, CNS State potentially corrupt")
 		}
+		ipConfigs = append(ipConfigs, ipState)
+	}
 
-		logger.Errorf("Failed to get existing ipconfig. Pod to IPID exists, but IPID to IPConfig doesn't exist, CNS State potentially corrupt")
-		return podIpInfo, isExist, fmt.Errorf("Failed to get existing ipconfig. Pod to IPID exists, but IPID to IPConfig doesn't exist, CNS State potentially corrupt")
+	if len(ipConfigs) == 0 {
+		return nil, false, nil
 	}
 
-	return podIpInfo, isExist, nil
+	podIPInfos, err := service.populateIPConfigInfosUntransacted(ipConfigs)
+	return podIPInfos, true, err
+}
+
+// AllocateDesiredIPConfigs allocates the desired IP addresses to the pod. If the NC of the desired
+// IPs is dual-stack and no IP of one of its families is desired, any available IP of that family
+// is allocated too. Either every IP is allocated or none is.
+// PendingProgramming IPs are only allocated if CNS is configured to allocate them.
+func (service *HTTPRestService) AllocateDesiredIPConfigs(podInfo cns.PodInfo, desiredIPAddresses ...string) ([]cns.PodIpInfo, error) {
+	return service.allocateDesiredIPConfigs(podInfo, false, desiredIPAddresses...)
 }
 
-func (service *HTTPRestService) AllocateDesiredIPConfig(podInfo cns.PodInfo, desiredIpAddress string) (cns.PodIpInfo, error) {
-	var podIpInfo cns.PodIpInfo
+// allocateDesiredIPConfigs allocates the desired IPs to the pod. When reconciling the IPs pods are running
+// with, PendingProgramming IPs are allocated and the namespace IP quotas are not enforced.
+func (service *HTTPRestService) allocateDesiredIPConfigs(
+	podInfo cns.PodInfo, reconciling bool, desiredIPAddresses ...string) ([]cns.PodIpInfo, error) {
 	service.Lock()
 	defer service.Unlock()
 
-	found := false
-	for _, ipConfig := range service.PodIPConfigState {
-		if ipConfig.IPAddress == desiredIpAddress {
-			if ipConfig.State == cns.Allocated {
-				// This IP has already been allocated, if it is allocated to same pod, then return the same
-				// IPconfiguration
-				if ipConfig.PodInfo.Key() == podInfo.Key() {
-					logger.Printf("[AllocateDesiredIPConfig]: IP Config [%+v] is already allocated to this Pod [%+v]", ipConfig, podInfo)
-					found = true
-				} else {
-					return podIpInfo, fmt.Errorf("[AllocateDesiredIPConfig] Desired IP is already allocated %+v, requested for pod %+v", ipConfig, podInfo)
-				}
-			} else if ipConfig.State == cns.Available || ipConfig.State == cns.PendingProgramming {
-				// This race can happen during restart, where CNS state is lost and thus we have lost the NC programmed version
-				// As part of reconcile, we mark IPs as Allocated which are already allocated to PODs (listed from APIServer)
-				if err := service.setIPConfigAsAllocated(ipConfig, podInfo); err != nil {
-					return podIpInfo, err
-				}
-				found = true
-			} else {
-				return podIpInfo, fmt.Errorf("[AllocateDesiredIPConfig] Desired IP is not available %+v", ipConfig)
+	allowPendingProgramming := reconciling || service.allocatePendingProgrammingIPs
+
+	var (
+		ipConfigs  []cns.IPConfigurationStatus
+		toAllocate []cns.IPConfigurationStatus
+		families   = map[bool]bool{} // keyed by whether the family is IPv6
+	)
+	for _, desiredIPAddress := range desiredIPAddresses {
+		ipConfig, found := service.getIPConfigByAddressUntransacted(desiredIPAddress)
+		if !found {
+			return nil, fmt.Errorf("Requested IP not found in pool")
+		}
+
+		switch ipConfig.State {
+		case cns.Allocated:
+			// This IP has already been allocated, if it is allocated to same pod, then return the same
+			// IPconfiguration
+			if ipConfig.PodInfo.Key() != podInfo.Key() {
+				return nil, fmt.Errorf("[AllocateDesiredIPConfigs] Desired IP is already allocated %+v, requested for pod %+v", ipConfig, podInfo)
+			}
+			logger.Printf("[AllocateDesiredIPConfigs]: IP Config [%+v] is already allocated to this Pod [%+v]", ipConfig, podInfo)
+		case cns.Available:
+			toAllocate = append(toAllocate, ipConfig)
+		case cns.PendingProgramming:
+			// This race can happen during restart, where CNS state is lost and thus we have lost the NC programmed version
+			// As part of reconcile, we mark IPs as Allocated which are already allocated to PODs (listed from APIServer)
+			if !allowPendingProgramming {
+				//nolint:goerr113
+				return nil, fmt.Errorf("[AllocateDesiredIPConfigs] Desired IP is pending programming by NMAgent %+v", ipConfig)
+			}
+			toAllocate = append(toAllocate, ipConfig)
+		default:
+			return nil, fmt.Errorf("[AllocateDesiredIPConfigs] Desired IP is not available %+v", ipConfig)
+		}
+
+		ipConfigs = append(ipConfigs, ipConfig)
+		families[isIPv6Address(ipConfig.IPAddress)] = true
+	}
+
+	// a reconciled pod is running with only the IPs it was recorded with, so no IP of the other family is added.
+	if !reconciling && len(ipConfigs) > 0 && service.isDualStackNCUntransacted(ipConfigs[0].NCID) {
+		for _, ipv6 := range []bool{false, true} {
+			if families[ipv6] {
+				continue
 			}
 
-			if found {
-				err := service.populateIpConfigInfoUntransacted(ipConfig, &podIpInfo)
-				return podIpInfo, err
+			ipConfig, found := service.getAvailableIPConfigUntransacted(ipConfigs[0].NCID, ipv6)
+			if !found {
+				//nolint:goerr113
+				return nil, fmt.Errorf("[AllocateDesiredIPConfigs] no free %s IPs available in dual-stack NC %s", ipFamilyName(ipv6), ipConfigs[0].NCID)
 			}
+			ipConfigs = append(ipConfigs, ipConfig)
+			toAllocate = append(toAllocate, ipConfig)
+		}
+	}
+
+	if !reconciling {
+		if err := service.checkNamespaceIPQuotaUntransacted(podInfo, len(toAllocate)); err != nil {
+			return nil, err
 		}
 	}
-	return podIpInfo, fmt.Errorf("Requested IP not found in pool")
+
+	for _, ipConfig := range toAllocate {
+		if err := service.setIPConfigAsAllocated(ipConfig, podInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	return service.populateIPConfigInfosUntransacted(ipConfigs)
 }
 
-func (service *HTTPRestService) AllocateAnyAvailableIPConfig(podInfo cns.PodInfo) (cns.PodIpInfo, error) {
+// AllocateAnyAvailableIPConfigs allocates any available IPv4 address to the pod and, if its NC is
+// dual-stack, any available IPv6 address of the same NC. Either both are allocated or neither is.
+func (service *HTTPRestService) AllocateAnyAvailableIPConfigs(podInfo cns.PodInfo) ([]cns.PodIpInfo, error) {
 	service.Lock()
 	defer service.Unlock()
 
-	for _, ipState := range service.PodIPConfigState {
-		if ipState.State == cns.Available {
-			if err := service.setIPConfigAsAllocated(ipState, podInfo); err != nil {
-				return cns.PodIpInfo{}, err
-			}
+	ipConfigs, err := service.getAvailableIPConfigsUntransacted()
+	if err != nil {
+		return nil, err
+	}
 
-			podIPInfo := cns.PodIpInfo{}
-			if err := service.populateIpConfigInfoUntransacted(ipState, &podIPInfo); err != nil {
-				return cns.PodIpInfo{}, err
-			}
+	if err := service.checkNamespaceIPQuotaUntransacted(podInfo, len(ipConfigs)); err != nil {
+		return nil, err
+	}
 
-			return podIPInfo, nil
+	for _, ipConfig := range ipConfigs {
+		if err := service.setIPConfigAsAllocated(ipConfig, podInfo); err != nil {
+			return nil, err
 		}
 	}
-	//nolint:goerr113
-	return cns.PodIpInfo{}, fmt.Errorf("no more free IPs available, waiting on Azure CNS to allocated more")
+
+	return service.populateIPConfigInfosUntransacted(ipConfigs)
 }
 
-// If IPConfig is already allocated for pod, it returns that else it returns one of the available ipconfigs.
-func requestIPConfigHelper(service *HTTPRestService, req cns.IPConfigRequest) (cns.PodIpInfo, error) {
-	var (
-		podIpInfo cns.PodIpInfo
-		isExist   bool
-	)
+// getAvailableIPConfigsUntransacted returns an available IPv4 IPConfig and, if its NC is dual-stack, an available
+// IPv6 IPConfig of the same NC. The NCs are tried in turn, since a dual-stack NC may have free IPv4 addresses
+// but no free IPv6 ones while another NC has both.
+// Caller will acquire/release the service lock.
+func (service *HTTPRestService) getAvailableIPConfigsUntransacted() ([]cns.IPConfigurationStatus, error) {
+	err := errNoAvailableIPConfig
+	tried := make(map[string]bool)
+	for _, ipConfig := range service.PodIPConfigState {
+		if tried[ipConfig.NCID] {
+			continue
+		}
+		tried[ipConfig.NCID] = true
+
+		ipState, found := service.getAvailableIPConfigUntransacted(ipConfig.NCID, false)
+		if !found {
+			continue
+		}
+		if !service.isDualStackNCUntransacted(ipState.NCID) {
+			return []cns.IPConfigurationStatus{ipState}, nil
+		}
 
+		ipv6State, found := service.getAvailableIPConfigUntransacted(ipState.NCID, true)
+		if !found {
+			err = fmt.Errorf("no free IPv6 IPs in dual-stack NC %s: %w", ipState.NCID, errNoAvailableIPConfig)
+			continue
+		}
+		return []cns.IPConfigurationStatus{ipState, ipv6State}, nil
+	}
+	return nil, err
+}
+
+// getIPConfigByAddressUntransacted returns the IPConfig with the IP address.
+// Caller will acquire/release the service lock.
+func (service *HTTPRestService) getIPConfigByAddressUntransacted(ipAddress string) (cns.IPConfigurationStatus, bool) {
+	for _, ipConfig := range service.PodIPConfigState {
+		if ipConfig.IPAddress == ipAddress {
+			return ipConfig, true
+		}
+	}
+	return cns.IPConfigurationStatus{}, false
+}
+
+// getAvailableIPConfigUntransacted returns an Available IPConfig of the family in the NC, or in any NC if ncID is empty.
+// If CNS is configured to allocate PendingProgramming IPs, one is returned when no IP is Available.
+// Caller will acquire/release the service lock.
+func (service *HTTPRestService) getAvailableIPConfigUntransacted(ncID string, ipv6 bool) (cns.IPConfigurationStatus, bool) {
+	var pendingProgramming *cns.IPConfigurationStatus
+	for _, ipConfig := range service.PodIPConfigState {
+		if (ncID != "" && ipConfig.NCID != ncID) || isIPv6Address(ipConfig.IPAddress) != ipv6 {
+			continue
+		}
+		switch ipConfig.State {
+		case cns.Available:
+			return ipConfig, true
+		case cns.PendingProgramming:
+			if service.allocatePendingProgrammingIPs && pendingProgramming == nil {
+				ipConfig := ipConfig
+				pendingProgramming = &ipConfig
+			}
+		}
+	}
+	if pendingProgramming != nil {
+		return *pendingProgramming, true
+	}
+	return cns.IPConfigurationStatus{}, false
+}
+
+// isDualStackNCUntransacted returns whether the NC has an IPv6 configuration and so allocates an IP of each family to pods.
+// Caller will acquire/release the service lock.
+func (service *HTTPRestService) isDualStackNCUntransacted(ncID string) bool {
+	ncStatus, exists := service.state.ContainerStatus[ncID]
+	return exists && ncStatus.CreateNetworkContainerRequest.IPv6Configuration.IPSubnet.IPAddress != ""
+}
+
+// podIPIDByPodInterfaceKeyFor returns the map from pod to allocated IP ID of the family of the IP address.
+func (service *HTTPRestService) podIPIDByPodInterfaceKeyFor(ipAddress string) map[string]string {
+	if isIPv6Address(ipAddress) {
+		return service.PodIPv6IDByPodInterfaceKey
+	}
+	return service.PodIPIDByPodInterfaceKey
+}
+
+// If IPConfigs are already allocated for pod, it returns those else it allocates the desired or any available ipconfigs.
+// The IPv4 PodIpInfo is returned first, followed by the IPv6 PodIpInfo if the pod is dual-stack.
+func requestIPConfigHelper(service *HTTPRestService, req cns.IPConfigRequest) ([]cns.PodIpInfo, error) {
 	// check if ipconfig already allocated for this pod and return if exists or error
 	// if error, ipstate is nil, if exists, ipstate is not nil and error is nil
 	podInfo, err := cns.NewPodInfoFromIPConfigRequest(req)
 	if err != nil {
-		return podIpInfo, err
+		return nil, err
 	}
 
-	if podIpInfo, isExist, err = service.GetExistingIPConfig(podInfo); err != nil || isExist {
-		return podIpInfo, err
+	var desiredIPAddresses []string
+	if req.DesiredIPAddress != "" {
+		desiredIPAddresses = append(desiredIPAddresses, req.DesiredIPAddress)
 	}
 
-	// return desired IPConfig
-	if req.DesiredIPAddress != "" {
-		return service.AllocateDesiredIPConfig(podInfo, req.DesiredIPAddress)
+	return requestIPConfigsHelper(service, podInfo, desiredIPAddresses...)
+}
+
+// requestIPConfigsHelper returns the IPConfigs already allocated to the pod, or allocates the desired
+// IP addresses or any available ones if none are desired.
+func requestIPConfigsHelper(service *HTTPRestService, podInfo cns.PodInfo, desiredIPAddresses ...string) ([]cns.PodIpInfo, error) {
+	podIPInfos, isExist, err := service.GetExistingIPConfigs(podInfo)
+	if err != nil || isExist {
+		return podIPInfos, err
 	}
 
-	// return any free IPConfig
-	return service.AllocateAnyAvailableIPConfig(podInfo)
+	// return desired IPConfigs
+	if len(desiredIPAddresses) > 0 {
+		return service.AllocateDesiredIPConfigs(podInfo, desiredIPAddresses...)
+	}
+
+	// return any free IPConfigs, waiting in the IP request queue if there are none
+	return service.allocateAnyAvailableIPConfigsQueued(podInfo)
+}
+
+// reconcilePodIPConfigs returns the IPConfigs already allocated to the pod, or allocates the IP addresses the pod
+// holds. The pod is running with them, so they are allocated even if they are PendingProgramming or
+// exceed the IP quota of its namespace.
+func (service *HTTPRestService) reconcilePodIPConfigs(podInfo cns.PodInfo, ipAddresses ...string) ([]cns.PodIpInfo, error) {
+	podIPInfos, isExist, err := service.GetExistingIPConfigs(podInfo)
+	if err != nil || isExist {
+		return podIPInfos, err
+	}
+	return service.allocateDesiredIPConfigs(podInfo, true, ipAddresses...)
 }
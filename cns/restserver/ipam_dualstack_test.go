@@ -0,0 +1,189 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIPv6PrimaryIP    = "fd00::5"
+	testIPv6Gateway      = "fd00::1"
+	testIPv6PrefixLength = 64
+
+	testIPv6IP1   = "fd00::10"
+	testIPv6GUID1 = "4e5e4b3c-9c37-4c1b-8b6a-3a2f1a6e2a01"
+	testIPv6IP2   = "fd00::11"
+	testIPv6GUID2 = "4e5e4b3c-9c37-4c1b-8b6a-3a2f1a6e2a02"
+
+	testNCID2 = "e1ee8bd1-0a34-4a6e-8cb4-3a4f6f5a7c2d"
+)
+
+// newDualStackTestService returns a test service with a dual-stack NC which has the IPv4 secondary IPs
+// testIP1 and testIP2, and the passed IPv6 secondary IPs.
+func newDualStackTestService(t *testing.T, ipv6SecondaryIPs map[string]string) *HTTPRestService {
+	svc := getTestService()
+
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{
+		testPod1GUID: newSecondaryIPConfig(testIP1, -1),
+		testPod2GUID: newSecondaryIPConfig(testIP2, -1),
+	}
+	for id, ip := range ipv6SecondaryIPs {
+		secondaryIPConfigs[id] = newSecondaryIPConfig(ip, -1)
+	}
+
+	req := generateNetworkContainerRequest(secondaryIPConfigs, testNCID, "-1")
+	req.IPv6Configuration = cns.IPConfiguration{
+		IPSubnet: cns.IPSubnet{
+			IPAddress:    testIPv6PrimaryIP,
+			PrefixLength: testIPv6PrefixLength,
+		},
+		GatewayIPAddress: testIPv6Gateway,
+	}
+	require.Equal(t, types.Success, svc.CreateOrUpdateNetworkContainerInternal(req))
+
+	return svc
+}
+
+func newTestIPConfigRequest(t *testing.T, podInfo cns.PodInfo, desiredIPAddress string) cns.IPConfigRequest {
+	req := cns.IPConfigRequest{
+		PodInterfaceID:   podInfo.InterfaceID(),
+		InfraContainerID: podInfo.InfraContainerID(),
+		DesiredIPAddress: desiredIPAddress,
+	}
+	var err error
+	req.OrchestratorContext, err = podInfo.OrchestratorContext()
+	require.NoError(t, err)
+	return req
+}
+
+func TestRequestIPConfigDualStack(t *testing.T) {
+	svc := newDualStackTestService(t, map[string]string{testIPv6GUID1: testIPv6IP1})
+
+	resp := svc.RequestIPConfig(newTestIPConfigRequest(t, testPod1Info, testIP1))
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+
+	assert.Equal(t, testIP1, resp.PodIpInfo.PodIPConfig.IPAddress)
+	assert.Equal(t, gatewayIp, resp.PodIpInfo.NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+
+	require.NotNil(t, resp.PodIpInfoV6)
+	assert.Equal(t, testIPv6IP1, resp.PodIpInfoV6.PodIPConfig.IPAddress)
+	assert.EqualValues(t, testIPv6PrefixLength, resp.PodIpInfoV6.PodIPConfig.PrefixLength)
+	assert.Equal(t, testIPv6PrimaryIP, resp.PodIpInfoV6.NetworkContainerPrimaryIPConfig.IPSubnet.IPAddress)
+	assert.Equal(t, testIPv6Gateway, resp.PodIpInfoV6.NetworkContainerPrimaryIPConfig.GatewayIPAddress)
+
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testIPv6GUID1].State)
+	assert.Equal(t, testIPv6GUID1, svc.PodIPv6IDByPodInterfaceKey[testPod1Info.Key()])
+
+	// a repeated request returns the existing allocation of both families.
+	resp = svc.RequestIPConfig(newTestIPConfigRequest(t, testPod1Info, ""))
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, testIP1, resp.PodIpInfo.PodIPConfig.IPAddress)
+	require.NotNil(t, resp.PodIpInfoV6)
+	assert.Equal(t, testIPv6IP1, resp.PodIpInfoV6.PodIPConfig.IPAddress)
+}
+
+func TestAllocateDesiredIPConfigsAddsIPv6(t *testing.T) {
+	svc := newDualStackTestService(t, map[string]string{testIPv6GUID1: testIPv6IP1})
+
+	podIPInfos, err := svc.AllocateDesiredIPConfigs(testPod2Info, testIP2)
+	require.NoError(t, err)
+	require.Len(t, podIPInfos, 2)
+	assert.Equal(t, testIP2, podIPInfos[0].PodIPConfig.IPAddress)
+	assert.Equal(t, testIPv6IP1, podIPInfos[1].PodIPConfig.IPAddress)
+}
+
+func TestAllocateDualStackIsAtomic(t *testing.T) {
+	svc := newDualStackTestService(t, map[string]string{testIPv6GUID1: testIPv6IP1})
+
+	_, err := svc.AllocateDesiredIPConfigs(testPod1Info, testIP1)
+	require.NoError(t, err)
+
+	// the only IPv6 address is taken, so the IPv4 address must not be allocated either.
+	_, err = svc.AllocateAnyAvailableIPConfigs(testPod2Info)
+	require.Error(t, err)
+	assert.Equal(t, cns.Available, svc.PodIPConfigState[testPod2GUID].State)
+	assert.NotContains(t, svc.PodIPIDByPodInterfaceKey, testPod2Info.Key())
+
+	_, err = svc.AllocateDesiredIPConfigs(testPod2Info, testIP2)
+	require.Error(t, err)
+	assert.Equal(t, cns.Available, svc.PodIPConfigState[testPod2GUID].State)
+}
+
+func TestReleaseIPConfigDualStack(t *testing.T) {
+	svc := newDualStackTestService(t, map[string]string{testIPv6GUID1: testIPv6IP1, testIPv6GUID2: testIPv6IP2})
+
+	resp := svc.RequestIPConfig(newTestIPConfigRequest(t, testPod1Info, testIP1))
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	require.NotNil(t, resp.PodIpInfoV6)
+	ipv6ID := svc.PodIPv6IDByPodInterfaceKey[testPod1Info.Key()]
+
+	releaseResp := svc.ReleaseIPConfig(newTestIPConfigRequest(t, testPod1Info, ""))
+	require.Equal(t, types.Success, releaseResp.ReturnCode, releaseResp.Message)
+
+	assert.Equal(t, cns.Available, svc.PodIPConfigState[testPod1GUID].State)
+	assert.Equal(t, cns.Available, svc.PodIPConfigState[ipv6ID].State)
+	assert.Empty(t, svc.PodIPIDByPodInterfaceKey)
+	assert.Empty(t, svc.PodIPv6IDByPodInterfaceKey)
+
+	// releasing again is a no-op.
+	releaseResp = svc.ReleaseIPConfig(newTestIPConfigRequest(t, testPod1Info, ""))
+	assert.Equal(t, types.Success, releaseResp.ReturnCode)
+}
+
+func TestCreateNCWithIPv6SecondaryIPsRequiresIPv6Configuration(t *testing.T) {
+	svc := getTestService()
+
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{
+		testPod1GUID:  newSecondaryIPConfig(testIP1, -1),
+		testIPv6GUID1: newSecondaryIPConfig(testIPv6IP1, -1),
+	}
+	req := generateNetworkContainerRequest(secondaryIPConfigs, testNCID, "-1")
+	assert.Equal(t, types.InvalidSecondaryIPConfig, svc.CreateOrUpdateNetworkContainerInternal(req))
+
+	req.IPv6Configuration.IPSubnet = cns.IPSubnet{IPAddress: primaryIp, PrefixLength: subnetPrfixLength}
+	assert.Equal(t, types.InvalidPrimaryIPConfig, svc.CreateOrUpdateNetworkContainerInternal(req))
+}
+
+func TestAllocateAnyAvailableIPConfigsPairsFamiliesPerNC(t *testing.T) {
+	svc := newDualStackTestService(t, map[string]string{testIPv6GUID1: testIPv6IP1})
+
+	// the NC keeps a free IPv4 address but runs out of IPv6 addresses.
+	_, err := svc.AllocateDesiredIPConfigs(testPod1Info, testIP1)
+	require.NoError(t, err)
+
+	req := generateNetworkContainerRequest(map[string]cns.SecondaryIPConfig{
+		testPod3GUID:  newSecondaryIPConfig(testIP3, -1),
+		testIPv6GUID2: newSecondaryIPConfig(testIPv6IP2, -1),
+	}, testNCID2, "-1")
+	req.IPv6Configuration = cns.IPConfiguration{
+		IPSubnet:         cns.IPSubnet{IPAddress: testIPv6PrimaryIP, PrefixLength: testIPv6PrefixLength},
+		GatewayIPAddress: testIPv6Gateway,
+	}
+	require.Equal(t, types.Success, svc.CreateOrUpdateNetworkContainerInternal(req))
+
+	podIPInfos, err := svc.AllocateAnyAvailableIPConfigs(testPod2Info)
+	require.NoError(t, err)
+	require.Len(t, podIPInfos, 2)
+	assert.Equal(t, testIP3, podIPInfos[0].PodIPConfig.IPAddress)
+	assert.Equal(t, testIPv6IP2, podIPInfos[1].PodIPConfig.IPAddress)
+	assert.Equal(t, cns.Available, svc.PodIPConfigState[testPod2GUID].State)
+}
+
+func TestReconcilePodIPConfigsKeepsRecordedFamilies(t *testing.T) {
+	svc := newDualStackTestService(t, map[string]string{testIPv6GUID1: testIPv6IP1})
+
+	// a pod which was allocated only an IPv4 address before the NC became dual-stack isn't given an IPv6 address.
+	podIPInfos, err := svc.reconcilePodIPConfigs(testPod1Info, testIP1)
+	require.NoError(t, err)
+	require.Len(t, podIPInfos, 1)
+	assert.Equal(t, testIP1, podIPInfos[0].PodIPConfig.IPAddress)
+	assert.Equal(t, cns.Available, svc.PodIPConfigState[testIPv6GUID1].State)
+	assert.NotContains(t, svc.PodIPv6IDByPodInterfaceKey, testPod1Info.Key())
+}
@@ -4,13 +4,21 @@
 package restserver
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
 	"github.com/Azure/azure-container-networking/cns/common"
 	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/google/uuid"
 )
 
 var (
@@ -67,10 +75,11 @@ func requestIpAddressAndGetState(t *testing.T, req cns.IPConfigRequest) (cns.IPC
 		err       error
 	)
 
-	PodIpInfo, err = requestIPConfigHelper(svc, req)
+	podIPInfos, err := requestIPConfigHelper(svc, req)
 	if err != nil {
 		return ipState, err
 	}
+	PodIpInfo = podIPInfos[0]
 
 	if reflect.DeepEqual(PodIpInfo.NetworkContainerPrimaryIPConfig.IPSubnet.IPAddress, primaryIp) != true {
 		t.Fatalf("PrimarIP is not added as expected ipConfig %+v, expected primaryIP: %+v", PodIpInfo.NetworkContainerPrimaryIPConfig, primaryIp)
@@ -110,6 +119,9 @@ func requestIpAddressAndGetState(t *testing.T, req cns.IPConfigRequest) (cns.IPC
 	ipId := svc.PodIPIDByPodInterfaceKey[podInfo.Key()]
 	ipState = svc.PodIPConfigState[ipId]
 
+	// callers compare the rest of the state, not when it changed
+	ipState.StateChangedAt = time.Time{}
+
 	return ipState, err
 }
 
@@ -535,6 +547,7 @@ func validateIpState(t *testing.T, actualIps []cns.IPConfigurationStatus, expect
 	for _, actualIp := range actualIps {
 		var expectedIp cns.IPConfigurationStatus
 		var found bool
+		actualIp.StateChangedAt = time.Time{}
 		for _, expectedIp = range expectedList {
 			if reflect.DeepEqual(actualIp, expectedIp) == true {
 				found = true
@@ -720,3 +733,114 @@ func TestIPAMMarkExistingIPConfigAsPending(t *testing.T) {
 		t.Fatalf("Expected to see ID %v in pending release ipconfigs, actual %+v", testPod1GUID, allocatedIPConfigs)
 	}
 }
+
+// getIPAddresses gets the IPs matching the query from the GetIPAddresses endpoint with GET,
+// or POSTs req if the query is empty.
+func getIPAddresses(t *testing.T, query string, req *cns.GetIPAddressesRequest) cns.GetIPAddressStatusResponse {
+	var (
+		httpReq *http.Request
+		err     error
+	)
+	if req != nil {
+		var body bytes.Buffer
+		if err = json.NewEncoder(&body).Encode(req); err != nil {
+			t.Fatal(err)
+		}
+		httpReq, err = http.NewRequest(http.MethodPost, cns.GetIPAddresses, &body)
+	} else {
+		httpReq, err = http.NewRequest(http.MethodGet, cns.GetIPAddresses+"?"+query, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	svc.getIPAddressesHandler(w, httpReq)
+	var resp cns.GetIPAddressStatusResponse
+	if err = decodeResponse(w, &resp); err != nil {
+		t.Fatalf("GetIPAddresses failed: %v", err)
+	}
+	return resp
+}
+
+func TestGetIPAddressesFilters(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+
+	ncID := "testFilterNc"
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{}
+	for _, ip := range []string{"10.0.2.1", "10.0.2.2", "10.0.3.1"} {
+		secondaryIPConfigs[uuid.New().String()] = newSecondaryIPConfig(ip, 0)
+	}
+	createNCReqInternal(t, secondaryIPConfigs, ncID, "0")
+	nmagentServer.SetNCVersion(ncID, "0")
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+
+	podInfo := cns.NewPodInfo("filter-eth0", uuid.New().String(), "testfilterpod", "testfilternamespace")
+	podIPInfo, err := svc.AllocateDesiredIPConfigs(podInfo, "10.0.2.1")
+	if err != nil {
+		t.Fatalf("Expected to allocate the IP, got %v", err)
+	}
+	ipID := svc.PodIPIDByPodInterfaceKey[podInfo.Key()]
+	if svc.PodIPConfigState[ipID].StateChangedAt.IsZero() {
+		t.Fatalf("Expected the time the IP was allocated to be recorded, got %+v", svc.PodIPConfigState[ipID])
+	}
+	// the IP was allocated two hours ago
+	allocated := svc.PodIPConfigState[ipID]
+	allocated.StateChangedAt = time.Now().Add(-2 * time.Hour)
+	svc.PodIPConfigState[ipID] = allocated
+
+	tests := []struct {
+		name  string
+		query string
+		req   *cns.GetIPAddressesRequest
+		want  []string
+	}{
+		{
+			name:  "allocated to the namespace for over an hour",
+			query: "state=Allocated&podNamespace=testfilternamespace&minTimeInState=1h",
+			want:  []string{podIPInfo[0].PodIPConfig.IPAddress},
+		},
+		{
+			name:  "allocated for over three hours",
+			query: "state=Allocated&minTimeInState=3h",
+		},
+		{
+			name:  "available in the NC and CIDR",
+			query: "state=Available&state=Allocated&ncID=testFilterNc&cidr=10.0.3.0/24",
+			want:  []string{"10.0.3.1"},
+		},
+		{
+			name:  "no state",
+			query: "ncID=testFilterNc",
+		},
+		{
+			name: "posted request",
+			req:  &cns.GetIPAddressesRequest{IPConfigStateFilter: []cns.IPConfigState{cns.Allocated, cns.Available}, NCID: ncID, PodName: "testfilterpod"},
+			want: []string{"10.0.2.1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			resp := getIPAddresses(t, tt.query, tt.req)
+			if resp.Response.ReturnCode != types.Success {
+				t.Fatalf("Expected GetIPAddresses to succeed, got %+v", resp.Response)
+			}
+			var got []string
+			for _, ipConfig := range resp.IPConfigurationStatus {
+				got = append(got, ipConfig.IPAddress)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Fatalf("Expected IPs %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, query := range []string{"state=Allocated&cidr=10.0.2.0", "state=Allocated&minTimeInState=hour"} {
+		if resp := getIPAddresses(t, query, nil); resp.Response.ReturnCode != types.InvalidParameter {
+			t.Fatalf("Expected query %s to be rejected, got %+v", query, resp.Response)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+// ipConfigWatcherBufferSize is the number of events a watcher may fall behind
+// before it is dropped.
+const ipConfigWatcherBufferSize = 256
+
+// ipConfigWatchers fans out IPConfigEvents to every active watcher.
+// A watcher which does not drain its channel fast enough has its channel
+// closed so that it can re-list and watch again instead of silently missing events.
+type ipConfigWatchers struct {
+	sync.Mutex
+	next     int
+	watchers map[int]chan cns.IPConfigEvent
+}
+
+func (w *ipConfigWatchers) add() (int, <-chan cns.IPConfigEvent) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.watchers == nil {
+		w.watchers = make(map[int]chan cns.IPConfigEvent)
+	}
+
+	id := w.next
+	w.next++
+	ch := make(chan cns.IPConfigEvent, ipConfigWatcherBufferSize)
+	w.watchers[id] = ch
+	return id, ch
+}
+
+func (w *ipConfigWatchers) remove(id int) {
+	w.Lock()
+	defer w.Unlock()
+
+	if ch, ok := w.watchers[id]; ok {
+		close(ch)
+		delete(w.watchers, id)
+	}
+}
+
+func (w *ipConfigWatchers) publish(event cns.IPConfigEvent) {
+	w.Lock()
+	defer w.Unlock()
+
+	for id, ch := range w.watchers {
+		select {
+		case ch <- event:
+		default:
+			logger.Errorf("[ipConfigWatchers] Watcher %d fell behind, dropping it", id)
+			close(ch)
+			delete(w.watchers, id)
+		}
+	}
+}
+
+// WatchIPConfigs returns the current PodIPConfigState, a channel which receives
+// every subsequent change to it, and a func to stop watching. The snapshot and
+// subscription are taken atomically so no change is missed or repeated. The
+// channel is closed when the watch is stopped or when the watcher falls too far behind.
+func (service *HTTPRestService) WatchIPConfigs() ([]cns.IPConfigurationStatus, <-chan cns.IPConfigEvent, func()) {
+	service.RLock()
+	defer service.RUnlock()
+
+	current := make([]cns.IPConfigurationStatus, 0, len(service.PodIPConfigState))
+	for _, ipConfig := range service.PodIPConfigState {
+		current = append(current, ipConfig)
+	}
+
+	id, ch := service.ipConfigWatchers.add()
+	return current, ch, func() {
+		service.ipConfigWatchers.remove(id)
+	}
+}
+
+// publishIPConfigEvent notifies the watchers of a change to the PodIPConfigState.
+// Callers hold the service lock so events are published in the order they are applied.
+func (service *HTTPRestService) publishIPConfigEvent(eventType cns.IPConfigEventType, ipConfig cns.IPConfigurationStatus, previousState cns.IPConfigState) {
+	service.ipConfigWatchers.publish(cns.IPConfigEvent{
+		Type:                  eventType,
+		IPConfigurationStatus: ipConfig,
+		PreviousState:         previousState,
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/pkg/errors"
+)
+
+// causeReleaseLeakedIP is recorded in the IP state transition audit trail when a leaked IP is released.
+const causeReleaseLeakedIP = "ReleaseLeakedIP"
+
+// IPLeakDetectorConfig configures the periodic detection of leaked IPs.
+type IPLeakDetectorConfig struct {
+	// Interval between leak detection runs.
+	Interval time.Duration
+	// Release leaked IPs once they have been leaked for the GracePeriod.
+	Release bool
+	// GracePeriod an IP must be continuously leaked for before it is released.
+	GracePeriod time.Duration
+	// DryRun logs the leaked IPs which would be released instead of releasing them.
+	DryRun bool
+}
+
+// ipLeakTracker remembers when each leaked or partially held IP was first detected and the result of the last run.
+// The zero value is an empty tracker.
+type ipLeakTracker struct {
+	sync.Mutex
+	leakedSince        map[string]time.Time // IPConfig ID is key
+	partiallyHeldSince map[string]time.Time // IPConfig ID is key
+	leaked             []cns.LeakedIP
+}
+
+// DetectIPLeaks compares the Allocated IPs in the CNS state against the pods reported by each of
+// the providers and returns the IPs which are not held according to all of them. An IP is held
+// according to a provider if the provider reports the IP, or a pod with the same name and namespace
+// as the pod it is allocated to.
+// IPs held according to none of the providers are leaked, and are released once they have been leaked
+// for the grace period, unless the config is a dry run. IPs held according to some of the providers
+// only, such as a pod whose CNI endpoint is missing or a deleted pod whose CNI endpoint was left
+// behind, are reported as partially held but never released, as releasing the IP of a running pod
+// would give it to a second pod.
+//
+// A provider error aborts the run without changing the leak state, since an IP can't be known to be
+// leaked without the full picture of the pods on the node. Without providers no IP is leaked.
+func (service *HTTPRestService) DetectIPLeaks(config IPLeakDetectorConfig, providers ...cns.PodInfoByIPProvider) ([]cns.LeakedIP, error) {
+	podInfoByIPs := make([]map[string]cns.PodInfo, 0, len(providers))
+	for _, provider := range providers {
+		podInfoByIP, err := provider.PodInfoByIP()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the pods on the node")
+		}
+		podInfoByIPs = append(podInfoByIPs, podInfoByIP)
+	}
+
+	return service.detectIPLeaks(time.Now(), config, podInfoByIPs), nil
+}
+
+func (service *HTTPRestService) detectIPLeaks(now time.Time, config IPLeakDetectorConfig, podInfoByIPs []map[string]cns.PodInfo) []cns.LeakedIP {
+	heldByPods := make([]map[string]bool, len(podInfoByIPs))
+	for i, podInfoByIP := range podInfoByIPs {
+		heldByPods[i] = make(map[string]bool, len(podInfoByIP))
+		for _, podInfo := range podInfoByIP {
+			heldByPods[i][podInfo.Namespace()+"/"+podInfo.Name()] = true
+		}
+	}
+
+	// heldBy returns the number of providers the IP is held according to.
+	heldBy := func(ipconfig cns.IPConfigurationStatus) int {
+		held := 0
+		for i, podInfoByIP := range podInfoByIPs {
+			if _, ok := podInfoByIP[ipconfig.IPAddress]; ok {
+				held++
+			} else if ipconfig.PodInfo != nil && heldByPods[i][ipconfig.PodInfo.Namespace()+"/"+ipconfig.PodInfo.Name()] {
+				held++
+			}
+		}
+		return held
+	}
+
+	service.Lock()
+	defer service.Unlock()
+
+	service.ipLeaks.Lock()
+	defer service.ipLeaks.Unlock()
+
+	leakedSince := map[string]time.Time{}
+	partiallyHeldSince := map[string]time.Time{}
+	leaked := []cns.LeakedIP{}
+	released := 0
+	for id, ipconfig := range service.PodIPConfigState {
+		// without providers nothing is known about the pods on the node, so no IP is leaked.
+		if ipconfig.State != cns.Allocated || len(podInfoByIPs) == 0 {
+			continue
+		}
+
+		held := heldBy(ipconfig)
+		if held == len(podInfoByIPs) {
+			continue
+		}
+
+		if held > 0 {
+			since, ok := service.ipLeaks.partiallyHeldSince[id]
+			if !ok {
+				since = now
+				logger.Printf("[detectIPLeaks] IP %s allocated to pod %+v is held by its pod according to %d of %d sources only, not releasing it",
+					ipconfig.IPAddress, ipconfig.PodInfo, held, len(podInfoByIPs))
+			}
+			partiallyHeldSince[id] = since
+			leaked = append(leaked, cns.LeakedIP{IPConfigurationStatus: ipconfig, LeakedSince: since, PartiallyHeld: true})
+			continue
+		}
+
+		since, ok := service.ipLeaks.leakedSince[id]
+		if !ok {
+			since = now
+			logger.Printf("[detectIPLeaks] IP %s allocated to pod %+v is not held by its pod on the node", ipconfig.IPAddress, ipconfig.PodInfo)
+		}
+		leak := cns.LeakedIP{IPConfigurationStatus: ipconfig, LeakedSince: since}
+
+		if config.Release && now.Sub(since) >= config.GracePeriod {
+			if config.DryRun {
+				logger.Printf("[detectIPLeaks] Dry run, not releasing IP %s leaked since %v", ipconfig.IPAddress, since)
+			} else if err := service.releaseLeakedIP(ipconfig); err != nil {
+				logger.Errorf("[detectIPLeaks] Failed to release IP %s leaked since %v, err:%v", ipconfig.IPAddress, since, err)
+			} else {
+				logger.Printf("[detectIPLeaks] Released IP %s leaked since %v", ipconfig.IPAddress, since)
+				leak.Released = true
+				released++
+			}
+		}
+
+		if !leak.Released {
+			leakedSince[id] = since
+		}
+		leaked = append(leaked, leak)
+	}
+
+	sort.Slice(leaked, func(i, j int) bool {
+		return leaked[i].IPConfigurationStatus.ID < leaked[j].IPConfigurationStatus.ID
+	})
+
+	service.ipLeaks.leakedSince = leakedSince
+	service.ipLeaks.partiallyHeldSince = partiallyHeldSince
+	service.ipLeaks.leaked = leaked
+
+	ipamLeakedIPCount.Set(float64(len(leakedSince)))
+	ipamPartiallyHeldIPCount.Set(float64(len(partiallyHeldSince)))
+	ipamReleasedLeakedIPCount.Add(float64(released))
+
+	return leaked
+}
+
+// releaseLeakedIP marks a leaked IP as Available.
+// Note: this func is an untransacted API as the caller will take a Service lock
+func (service *HTTPRestService) releaseLeakedIP(ipconfig cns.IPConfigurationStatus) error {
+	if _, err := service.updateIPConfigState(ipconfig.ID, cns.Available, nil, causeReleaseLeakedIP); err != nil {
+		return err
+	}
+
+	podIPIDByPodInterfaceKey := service.podIPIDByPodInterfaceKeyFor(ipconfig.IPAddress)
+	if ipconfig.PodInfo != nil && podIPIDByPodInterfaceKey[ipconfig.PodInfo.Key()] == ipconfig.ID {
+		delete(podIPIDByPodInterfaceKey, ipconfig.PodInfo.Key())
+	}
+
+	return nil
+}
+
+// GetLeakedIPs returns the leaked IPs found by the most recent leak detection run.
+func (service *HTTPRestService) GetLeakedIPs() []cns.LeakedIP {
+	service.ipLeaks.Lock()
+	defer service.ipLeaks.Unlock()
+
+	leaked := make([]cns.LeakedIP, len(service.ipLeaks.leaked))
+	copy(leaked, service.ipLeaks.leaked)
+	return leaked
+}
+
+// DetectIPLeaksPeriodically runs DetectIPLeaks on every config interval until the ctx is cancelled.
+func (service *HTTPRestService) DetectIPLeaksPeriodically(ctx context.Context, config IPLeakDetectorConfig, providers ...cns.PodInfoByIPProvider) {
+	logger.Printf("[Azure CNS] Detecting leaked IPs every %v, release: %t, grace period: %v, dry run: %t",
+		config.Interval, config.Release, config.GracePeriod, config.DryRun)
+
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := service.DetectIPLeaks(config, providers...); err != nil {
+				logger.Errorf("[Azure CNS] Failed to detect leaked IPs, err:%v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Handles requests for the leaked IPs found by the most recent leak detection run.
+func (service *HTTPRestService) getLeakedIPsHandler(w http.ResponseWriter, r *http.Request) {
+	resp := cns.GetLeakedIPsResponse{
+		LeakedIPs: service.GetLeakedIPs(),
+	}
+
+	err := service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp.Response, resp.Response.ReturnCode, err)
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIPLeakTestService returns a test service with testIP1 allocated to testPod1 and testIP2 to testPod2.
+func newIPLeakTestService(t *testing.T) *HTTPRestService {
+	svc := getTestService()
+
+	state1, _ := NewPodStateWithOrchestratorContext(testIP1, testPod1GUID, testNCID, cns.Available, 24, 0, testPod1Info)
+	state2, _ := NewPodStateWithOrchestratorContext(testIP2, testPod2GUID, testNCID, cns.Available, 24, 0, testPod2Info)
+	ipconfigs := map[string]cns.IPConfigurationStatus{
+		state1.ID: state1,
+		state2.ID: state2,
+	}
+	require.NoError(t, UpdatePodIpConfigState(t, svc, ipconfigs))
+
+	for ip, podInfo := range map[string]cns.PodInfo{testIP1: testPod1Info, testIP2: testPod2Info} {
+		req := cns.IPConfigRequest{
+			PodInterfaceID:   podInfo.InterfaceID(),
+			InfraContainerID: podInfo.InfraContainerID(),
+			DesiredIPAddress: ip,
+		}
+		req.OrchestratorContext, _ = podInfo.OrchestratorContext()
+		_, err := requestIPConfigHelper(svc, req)
+		require.NoError(t, err)
+	}
+
+	return svc
+}
+
+func TestDetectIPLeaks(t *testing.T) {
+	svc := newIPLeakTestService(t)
+
+	// the pods hold their IPs according to both providers, by IP or by name if the IP isn't reported.
+	kube := map[string]cns.PodInfo{
+		testIP1:      cns.NewPodInfo("", "", testPod1Info.Name(), testPod1Info.Namespace()),
+		"10.0.0.100": cns.NewPodInfo("", "", testPod2Info.Name(), testPod2Info.Namespace()),
+	}
+	cni := map[string]cns.PodInfo{"10.0.0.101": testPod1Info, testIP2: testPod2Info}
+	leaked := svc.detectIPLeaks(time.Now(), IPLeakDetectorConfig{}, []map[string]cns.PodInfo{kube, cni})
+	assert.Empty(t, leaked)
+
+	// once testPod2 is gone from both providers its IP is leaked.
+	delete(kube, "10.0.0.100")
+	delete(cni, testIP2)
+	leaked = svc.detectIPLeaks(time.Now(), IPLeakDetectorConfig{}, []map[string]cns.PodInfo{kube, cni})
+	require.Len(t, leaked, 1)
+	assert.Equal(t, testIP2, leaked[0].IPConfigurationStatus.IPAddress)
+	assert.False(t, leaked[0].Released)
+	assert.False(t, leaked[0].PartiallyHeld)
+	assert.Equal(t, leaked, svc.GetLeakedIPs())
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testPod2GUID].State)
+
+	// and stops being leaked if it is held again.
+	kube[testIP2] = testPod2Info
+	cni[testIP2] = testPod2Info
+	leaked = svc.detectIPLeaks(time.Now(), IPLeakDetectorConfig{}, []map[string]cns.PodInfo{kube, cni})
+	assert.Empty(t, leaked)
+	assert.Empty(t, svc.GetLeakedIPs())
+}
+
+func TestDetectIPLeaksNeverReleasesPartiallyHeldIPs(t *testing.T) {
+	svc := newIPLeakTestService(t)
+
+	// testPod2 is running according to the apiserver, but its CNI endpoint is missing.
+	kube := map[string]cns.PodInfo{testIP1: testPod1Info, testIP2: testPod2Info}
+	cni := map[string]cns.PodInfo{testIP1: testPod1Info}
+	config := IPLeakDetectorConfig{Release: true}
+	start := time.Now()
+
+	leaked := svc.detectIPLeaks(start, config, []map[string]cns.PodInfo{kube, cni})
+	require.Len(t, leaked, 1)
+	assert.Equal(t, testIP2, leaked[0].IPConfigurationStatus.IPAddress)
+	assert.True(t, leaked[0].PartiallyHeld)
+	assert.False(t, leaked[0].Released)
+
+	leaked = svc.detectIPLeaks(start.Add(time.Hour), config, []map[string]cns.PodInfo{kube, cni})
+	require.Len(t, leaked, 1)
+	assert.True(t, leaked[0].PartiallyHeld)
+	assert.False(t, leaked[0].Released)
+	assert.Equal(t, start, leaked[0].LeakedSince)
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testPod2GUID].State)
+
+	// the grace period of the leak starts once the IP is held according to none of the providers.
+	delete(kube, testIP2)
+	config.GracePeriod = time.Minute
+	leaked = svc.detectIPLeaks(start.Add(2*time.Hour), config, []map[string]cns.PodInfo{kube, cni})
+	require.Len(t, leaked, 1)
+	assert.False(t, leaked[0].PartiallyHeld)
+	assert.False(t, leaked[0].Released)
+	assert.Equal(t, start.Add(2*time.Hour), leaked[0].LeakedSince)
+}
+
+func TestDetectIPLeaksWithoutProviders(t *testing.T) {
+	svc := newIPLeakTestService(t)
+
+	leaked, err := svc.DetectIPLeaks(IPLeakDetectorConfig{Release: true})
+	require.NoError(t, err)
+	assert.Empty(t, leaked)
+	assert.Empty(t, svc.GetLeakedIPs())
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testPod1GUID].State)
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testPod2GUID].State)
+}
+
+func TestDetectIPLeaksReleasesAfterGracePeriod(t *testing.T) {
+	svc := newIPLeakTestService(t)
+
+	kube := map[string]cns.PodInfo{testIP1: testPod1Info}
+	config := IPLeakDetectorConfig{Release: true, GracePeriod: time.Minute}
+	start := time.Now()
+
+	leaked := svc.detectIPLeaks(start, config, []map[string]cns.PodInfo{kube})
+	require.Len(t, leaked, 1)
+	assert.False(t, leaked[0].Released)
+
+	// the grace period is measured from when the leak was first detected.
+	leaked = svc.detectIPLeaks(start.Add(time.Minute), config, []map[string]cns.PodInfo{kube})
+	require.Len(t, leaked, 1)
+	assert.True(t, leaked[0].Released)
+	assert.Equal(t, start, leaked[0].LeakedSince)
+
+	assert.Equal(t, cns.Available, svc.PodIPConfigState[testPod2GUID].State)
+	assert.NotContains(t, svc.PodIPIDByPodInterfaceKey, testPod2Info.Key())
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testPod1GUID].State)
+
+	transitions := svc.GetIPStateTransitions(cns.GetIPStateTransitionsRequest{IPAddress: testIP2})
+	assert.Equal(t, causeReleaseLeakedIP, transitions[len(transitions)-1].Cause)
+
+	// a released IP is no longer leaked.
+	leaked = svc.detectIPLeaks(start.Add(2*time.Minute), config, []map[string]cns.PodInfo{kube})
+	assert.Empty(t, leaked)
+}
+
+func TestDetectIPLeaksDryRun(t *testing.T) {
+	svc := newIPLeakTestService(t)
+
+	kube := map[string]cns.PodInfo{testIP1: testPod1Info}
+	config := IPLeakDetectorConfig{Release: true, DryRun: true}
+
+	leaked := svc.detectIPLeaks(time.Now(), config, []map[string]cns.PodInfo{kube})
+	require.Len(t, leaked, 1)
+	assert.False(t, leaked[0].Released)
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testPod2GUID].State)
+}
+
+func TestDetectIPLeaksProviderError(t *testing.T) {
+	svc := newIPLeakTestService(t)
+
+	errProvider := errors.New("apiserver unavailable")
+	_, err := svc.DetectIPLeaks(IPLeakDetectorConfig{Release: true}, cns.PodInfoByIPProviderFunc(func() (map[string]cns.PodInfo, error) {
+		return nil, errProvider
+	}))
+	assert.ErrorIs(t, err, errProvider)
+	assert.Empty(t, svc.GetLeakedIPs())
+	assert.Equal(t, cns.Allocated, svc.PodIPConfigState[testPod2GUID].State)
+}
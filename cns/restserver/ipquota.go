@@ -0,0 +1,162 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+// errNamespaceIPQuotaExceeded is returned when allocating IPs to a pod would exceed the max of its namespace,
+// or would allocate IPs reserved for other namespaces.
+var errNamespaceIPQuotaExceeded = errors.New("namespace IP quota exceeded")
+
+// Reasons IP requests are rejected by the namespace IP quotas.
+const (
+	quotaRejectionMaxIPs   = "max_ips"
+	quotaRejectionReserved = "reserved_ips"
+)
+
+// namespaceIPQuotas enforces the IP quotas of pod namespaces. The IPs allocated to the pods of each
+// namespace are counted from the IP state transitions, so that the quotas are checked without
+// scanning the IPs of every pod.
+// The zero value has no quotas.
+type namespaceIPQuotas struct {
+	sync.Mutex
+	// namespace is key
+	quotas map[string]common.NamespaceIPQuota
+	// namespace is key, the count of IPs allocated to its pods
+	allocated map[string]int
+}
+
+// configure replaces the quotas. The allocated IPs are still counted, so the quotas can be
+// reconfigured while CNS is running.
+func (q *namespaceIPQuotas) configure(quotas []common.NamespaceIPQuota) {
+	q.Lock()
+	defer q.Unlock()
+
+	for namespace := range q.quotas {
+		ipamNamespaceMaxIPCount.DeleteLabelValues(namespace)
+		ipamNamespaceReservedIPCount.DeleteLabelValues(namespace)
+		ipamNamespaceAllocatedIPCount.DeleteLabelValues(namespace)
+	}
+
+	q.quotas = make(map[string]common.NamespaceIPQuota, len(quotas))
+	if q.allocated == nil {
+		q.allocated = map[string]int{}
+	}
+	for _, quota := range quotas {
+		q.quotas[quota.Namespace] = quota
+		ipamNamespaceMaxIPCount.WithLabelValues(quota.Namespace).Set(float64(quota.MaxIPs))
+		ipamNamespaceReservedIPCount.WithLabelValues(quota.Namespace).Set(float64(quota.ReservedIPs))
+		ipamNamespaceAllocatedIPCount.WithLabelValues(quota.Namespace).Set(float64(q.allocated[quota.Namespace]))
+		logger.Printf("[Azure CNS] Namespace %s is allocated at most %d IPs (0 is unlimited), and has %d IPs reserved",
+			quota.Namespace, quota.MaxIPs, quota.ReservedIPs)
+	}
+}
+
+// isEnabled returns whether any namespace has a quota.
+func (q *namespaceIPQuotas) isEnabled() bool {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.quotas) > 0
+}
+
+// observe counts the IPs allocated to and released by the pods of each namespace.
+func (q *namespaceIPQuotas) observe(transition cns.IPStateTransition) {
+	if transition.PodNamespace == "" || transition.From == transition.To {
+		return
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.allocated == nil {
+		q.allocated = map[string]int{}
+	}
+	namespace := transition.PodNamespace
+	switch {
+	case transition.To == cns.Allocated:
+		q.allocated[namespace]++
+	case transition.From == cns.Allocated:
+		q.allocated[namespace]--
+	default:
+		return
+	}
+
+	if q.allocated[namespace] <= 0 {
+		delete(q.allocated, namespace)
+	}
+	if _, ok := q.quotas[namespace]; ok {
+		ipamNamespaceAllocatedIPCount.WithLabelValues(namespace).Set(float64(q.allocated[namespace]))
+	}
+}
+
+// check returns an error wrapping errNamespaceIPQuotaExceeded if allocating count IPs to a pod of the
+// namespace would exceed the max of the namespace, or would leave fewer of the free IPs than are
+// reserved for other namespaces and not yet allocated to them.
+func (q *namespaceIPQuotas) check(namespace string, count, free int) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if quota, ok := q.quotas[namespace]; ok && quota.MaxIPs > 0 && q.allocated[namespace]+count > quota.MaxIPs {
+		ipamNamespaceQuotaRejectionCount.WithLabelValues(namespace, quotaRejectionMaxIPs).Inc()
+		return fmt.Errorf("%w: namespace %s has %d of its max %d IPs allocated",
+			errNamespaceIPQuotaExceeded, namespace, q.allocated[namespace], quota.MaxIPs)
+	}
+
+	if reserved := q.unallocatedReservedUntransacted(namespace); free-count < reserved {
+		ipamNamespaceQuotaRejectionCount.WithLabelValues(namespace, quotaRejectionReserved).Inc()
+		return fmt.Errorf("%w: %d of the %d free IPs are reserved for other namespaces",
+			errNamespaceIPQuotaExceeded, reserved, free)
+	}
+	return nil
+}
+
+// unallocatedReserved returns the count of IPs reserved for namespaces which are not allocated to their pods.
+func (q *namespaceIPQuotas) unallocatedReserved() int {
+	q.Lock()
+	defer q.Unlock()
+	return q.unallocatedReservedUntransacted("")
+}
+
+// unallocatedReservedUntransacted returns the count of IPs reserved for namespaces other than the excluded
+// one which are not allocated to their pods.
+// Caller holds the lock.
+func (q *namespaceIPQuotas) unallocatedReservedUntransacted(excluded string) int {
+	reserved := 0
+	for namespace, quota := range q.quotas {
+		if namespace != excluded && quota.ReservedIPs > q.allocated[namespace] {
+			reserved += quota.ReservedIPs - q.allocated[namespace]
+		}
+	}
+	return reserved
+}
+
+// checkNamespaceIPQuotaUntransacted returns an error wrapping errNamespaceIPQuotaExceeded if the quota of the
+// pod's namespace doesn't allow allocating count more IPs to the pod.
+// Caller will acquire/release the service lock.
+func (service *HTTPRestService) checkNamespaceIPQuotaUntransacted(podInfo cns.PodInfo, count int) error {
+	if count == 0 || !service.ipQuotas.isEnabled() {
+		return nil
+	}
+
+	free := 0
+	for _, ipConfig := range service.PodIPConfigState {
+		if ipConfig.State == cns.Available || (ipConfig.State == cns.PendingProgramming && service.allocatePendingProgrammingIPs) {
+			free++
+		}
+	}
+	return service.ipQuotas.check(podInfo.Namespace(), count, free)
+}
+
+// GetUnallocatedReservedIPCount returns the count of IPs reserved for pod namespaces which are not allocated to their pods.
+func (service *HTTPRestService) GetUnallocatedReservedIPCount() int {
+	return service.ipQuotas.unallocatedReserved()
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allocatedTransition(namespace string, from, to cns.IPConfigState) cns.IPStateTransition {
+	return cns.IPStateTransition{PodNamespace: namespace, From: from, To: to}
+}
+
+func TestNamespaceIPQuotasCheck(t *testing.T) {
+	var q namespaceIPQuotas
+	assert.False(t, q.isEnabled())
+	assert.NoError(t, q.check("any", 1, 1))
+
+	q.configure([]common.NamespaceIPQuota{
+		{Namespace: "limited", MaxIPs: 2},
+		{Namespace: "reserved", ReservedIPs: 2},
+	})
+	assert.True(t, q.isEnabled())
+	assert.Equal(t, 2, q.unallocatedReserved())
+
+	q.observe(allocatedTransition("limited", cns.Available, cns.Allocated))
+	q.observe(allocatedTransition("limited", cns.Available, cns.Allocated))
+	err := q.check("limited", 1, 10)
+	assert.True(t, errors.Is(err, errNamespaceIPQuotaExceeded), "got %v", err)
+
+	q.observe(allocatedTransition("limited", cns.Allocated, cns.PendingRelease))
+	assert.NoError(t, q.check("limited", 1, 10))
+
+	// the last 2 free IPs are reserved for the other namespace
+	err = q.check("other", 1, 2)
+	assert.True(t, errors.Is(err, errNamespaceIPQuotaExceeded), "got %v", err)
+	assert.NoError(t, q.check("reserved", 2, 2))
+
+	q.observe(allocatedTransition("reserved", cns.Available, cns.Allocated))
+	assert.Equal(t, 1, q.unallocatedReserved())
+	assert.NoError(t, q.check("other", 1, 2))
+
+	// reconfiguring keeps the allocated IPs
+	q.configure([]common.NamespaceIPQuota{{Namespace: "limited", MaxIPs: 1}})
+	err = q.check("limited", 1, 10)
+	assert.True(t, errors.Is(err, errNamespaceIPQuotaExceeded), "got %v", err)
+	assert.Equal(t, 0, q.unallocatedReserved())
+}
+
+func TestRequestIPConfigEnforcesNamespaceIPQuotas(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+	svc.ipQuotas.configure([]common.NamespaceIPQuota{
+		{Namespace: "testquotalimited", MaxIPs: 1},
+		{Namespace: "testquotareserved", ReservedIPs: 1},
+	})
+	defer svc.ipQuotas.configure(nil)
+
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{}
+	for _, ip := range []string{"10.0.0.40", "10.0.0.41", "10.0.0.42"} {
+		secondaryIPConfigs[uuid.New().String()] = newSecondaryIPConfig(ip, 0)
+	}
+	req := createNCReqInternal(t, secondaryIPConfigs, "testQuotaNc", "0")
+	nmagentServer.SetNCVersion(req.NetworkContainerid, "0")
+	svc.SyncHostNCVersion(context.Background(), cns.CRD, 500*time.Millisecond)
+	assert.Equal(t, 1, svc.GetUnallocatedReservedIPCount())
+
+	limited1 := cns.NewPodInfo("quota1-eth0", uuid.New().String(), "quota1", "testquotalimited")
+	resp := svc.RequestIPConfig(newTestIPConfigRequest(t, limited1, ""))
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+
+	limited2 := cns.NewPodInfo("quota2-eth0", uuid.New().String(), "quota2", "testquotalimited")
+	resp = svc.RequestIPConfig(newTestIPConfigRequest(t, limited2, ""))
+	assert.Equal(t, types.NamespaceIPQuotaExceeded, resp.Response.ReturnCode)
+
+	// one IP is left for an unlimited namespace, the last is reserved
+	other1 := cns.NewPodInfo("quota3-eth0", uuid.New().String(), "quota3", "testquotaother")
+	resp = svc.RequestIPConfig(newTestIPConfigRequest(t, other1, ""))
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+
+	other2 := cns.NewPodInfo("quota4-eth0", uuid.New().String(), "quota4", "testquotaother")
+	resp = svc.RequestIPConfig(newTestIPConfigRequest(t, other2, ""))
+	assert.Equal(t, types.NamespaceIPQuotaExceeded, resp.Response.ReturnCode)
+
+	reserved := cns.NewPodInfo("quota5-eth0", uuid.New().String(), "quota5", "testquotareserved")
+	resp = svc.RequestIPConfig(newTestIPConfigRequest(t, reserved, ""))
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, 0, svc.GetUnallocatedReservedIPCount())
+
+	// releasing the IP of the limited namespace frees its quota
+	releaseResp := svc.ReleaseIPConfig(newTestIPConfigRequest(t, limited1, ""))
+	require.Equal(t, types.Success, releaseResp.ReturnCode)
+	resp = svc.RequestIPConfig(newTestIPConfigRequest(t, limited2, ""))
+	assert.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+}
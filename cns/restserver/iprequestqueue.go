@@ -0,0 +1,175 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+var (
+	// errNoAvailableIPConfig is returned when the pool has no Available IP to allocate.
+	errNoAvailableIPConfig = errors.New("no more free IPs available, waiting on Azure CNS to allocate more")
+	// errIPRequestQueueFull is returned when a request can't be queued as the queue is at its max length.
+	errIPRequestQueueFull = errors.New("too many requests are waiting for an IP")
+	// errIPRequestWaitTimeout is returned when a queued request waited the max wait without getting an IP.
+	errIPRequestWaitTimeout = errors.New("timed out waiting for an IP")
+)
+
+// ipRequestQueue queues the requests for any Available IP while the pool has none, and serves them
+// in arrival order as IPs become Available. Only the request at the head of the queue retries the
+// allocation, so the queued requests don't contend on the service lock.
+// The zero value is a disabled queue.
+type ipRequestQueue struct {
+	sync.Mutex
+	config common.IPRequestQueueSettings
+	// turns of the queued requests in arrival order. The turn of the head of the queue is closed.
+	turns []chan struct{}
+	// ipAvailable wakes the head of the queue when an IP becomes Available.
+	ipAvailable chan struct{}
+}
+
+// configure sets the bounds of the queue, and must be called before the queue is used.
+func (q *ipRequestQueue) configure(config common.IPRequestQueueSettings) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.config = config
+	q.ipAvailable = make(chan struct{}, 1)
+	if config.MaxLength > 0 {
+		logger.Printf("[Azure CNS] Queueing up to %d IP requests for up to %v while no IPs are available", config.MaxLength, config.MaxWait)
+	}
+}
+
+// signalIPAvailable wakes the head of the queue to retry its allocation. It doesn't block, so it can
+// be called with the service lock held, and a signal sent while no request is waiting is kept for the
+// next head of the queue.
+func (q *ipRequestQueue) signalIPAvailable() {
+	select {
+	case q.ipAvailable <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue adds a turn to the back of the queue, or returns errIPRequestQueueFull if it is at its max length.
+func (q *ipRequestQueue) enqueue() (chan struct{}, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.turns) >= q.config.MaxLength {
+		return nil, errIPRequestQueueFull
+	}
+
+	turn := make(chan struct{})
+	if len(q.turns) == 0 {
+		close(turn)
+	}
+	q.turns = append(q.turns, turn)
+	ipamIPRequestQueueDepth.Set(float64(len(q.turns)))
+	return turn, nil
+}
+
+// dequeue removes the turn from the queue and, if it was the head of the queue, passes the turn to the next request.
+func (q *ipRequestQueue) dequeue(turn chan struct{}) {
+	q.Lock()
+	defer q.Unlock()
+
+	for i := range q.turns {
+		if q.turns[i] != turn {
+			continue
+		}
+
+		q.turns = append(q.turns[:i], q.turns[i+1:]...)
+		if i == 0 && len(q.turns) > 0 {
+			close(q.turns[0])
+		}
+		break
+	}
+	ipamIPRequestQueueDepth.Set(float64(len(q.turns)))
+}
+
+// isEmpty returns whether no requests are waiting.
+func (q *ipRequestQueue) isEmpty() bool {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.turns) == 0
+}
+
+// isEnabled returns whether requests are queued while no IPs are Available.
+func (q *ipRequestQueue) isEnabled() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.config.MaxLength > 0
+}
+
+// allocateAnyAvailableIPConfigsQueued allocates any Available IPs to the pod. If there are none, or
+// other requests are already waiting for IPs, the request waits for its turn in the IP request queue
+// and then for IPs to become Available, bounded by the max length and max wait of the queue.
+func (service *HTTPRestService) allocateAnyAvailableIPConfigsQueued(podInfo cns.PodInfo) ([]cns.PodIpInfo, error) {
+	q := &service.ipRequests
+	if !q.isEnabled() {
+		return service.AllocateAnyAvailableIPConfigs(podInfo)
+	}
+
+	// requests only skip the queue if no other request is waiting, so they are served in arrival order.
+	if q.isEmpty() {
+		podIPInfos, err := service.AllocateAnyAvailableIPConfigs(podInfo)
+		if !errors.Is(err, errNoAvailableIPConfig) {
+			return podIPInfos, err
+		}
+	}
+
+	turn, err := q.enqueue()
+	if err != nil {
+		logger.Errorf("[allocateAnyAvailableIPConfigsQueued] Rejecting IP request for pod %+v, err:%v", podInfo, err)
+		ipamThrottledIPRequestCount.WithLabelValues("queue_full").Inc()
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		q.dequeue(turn)
+		ipamIPRequestQueueWaitLatency.Observe(time.Since(start).Seconds())
+	}()
+
+	timeout := time.NewTimer(q.config.MaxWait)
+	defer timeout.Stop()
+
+	shuttingDown := service.ipIntake.closingChan()
+	select {
+	case <-turn:
+	case <-timeout.C:
+		ipamThrottledIPRequestCount.WithLabelValues("wait_timeout").Inc()
+		return nil, errIPRequestWaitTimeout
+	case <-shuttingDown:
+		return nil, errShuttingDown
+	}
+
+	for {
+		// a retried request of the pod may have been allocated IPs while this one was waiting.
+		podIPInfos, isExist, err := service.GetExistingIPConfigs(podInfo)
+		if err != nil || isExist {
+			return podIPInfos, err
+		}
+
+		podIPInfos, err = service.AllocateAnyAvailableIPConfigs(podInfo)
+		if !errors.Is(err, errNoAvailableIPConfig) {
+			return podIPInfos, err
+		}
+
+		select {
+		case <-q.ipAvailable:
+		case <-timeout.C:
+			ipamThrottledIPRequestCount.WithLabelValues("wait_timeout").Inc()
+			return nil, errIPRequestWaitTimeout
+		case <-shuttingDown:
+			return nil, errShuttingDown
+		}
+	}
+}
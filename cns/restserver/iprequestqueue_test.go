@@ -0,0 +1,110 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPod4Info = cns.NewPodInfo("718e04-eth1", testPod4GUID, "testpod4", "testpod4namespace")
+
+// newIPRequestQueueTestService returns a test service with an empty pool, testIP1 and testIP2 are allocated
+// to testPod1 and testPod2, and an IP request queue with the settings.
+func newIPRequestQueueTestService(t *testing.T, settings common.IPRequestQueueSettings) *HTTPRestService {
+	svc := newIPLeakTestService(t)
+	svc.ipRequests.configure(settings)
+	return svc
+}
+
+// requestIPConfigAsync requests an IP for the pod in the background.
+func requestIPConfigAsync(t *testing.T, svc *HTTPRestService, podInfo cns.PodInfo) <-chan cns.IPConfigResponse {
+	req := newTestIPConfigRequest(t, podInfo, "")
+	resp := make(chan cns.IPConfigResponse, 1)
+	go func() {
+		resp <- svc.RequestIPConfig(req)
+	}()
+	return resp
+}
+
+func requireQueueLength(t *testing.T, svc *HTTPRestService, length int) {
+	require.Eventually(t, func() bool {
+		svc.ipRequests.Lock()
+		defer svc.ipRequests.Unlock()
+		return len(svc.ipRequests.turns) == length
+	}, time.Second, time.Millisecond)
+}
+
+func TestIPRequestQueueDisabled(t *testing.T) {
+	svc := newIPRequestQueueTestService(t, common.IPRequestQueueSettings{})
+
+	resp := svc.RequestIPConfig(newTestIPConfigRequest(t, testPod3Info, ""))
+	assert.Equal(t, types.FailedToAllocateIPConfig, resp.Response.ReturnCode)
+}
+
+func TestIPRequestQueueWaitTimeout(t *testing.T) {
+	svc := newIPRequestQueueTestService(t, common.IPRequestQueueSettings{MaxLength: 1, MaxWait: 10 * time.Millisecond})
+
+	resp := svc.RequestIPConfig(newTestIPConfigRequest(t, testPod3Info, ""))
+	assert.Equal(t, types.IPConfigRequestThrottled, resp.Response.ReturnCode)
+	assert.Contains(t, resp.Response.Message, errIPRequestWaitTimeout.Error())
+	requireQueueLength(t, svc, 0)
+}
+
+func TestIPRequestQueueFull(t *testing.T) {
+	svc := newIPRequestQueueTestService(t, common.IPRequestQueueSettings{MaxLength: 1, MaxWait: time.Minute})
+
+	queued := requestIPConfigAsync(t, svc, testPod3Info)
+	requireQueueLength(t, svc, 1)
+
+	resp := svc.RequestIPConfig(newTestIPConfigRequest(t, testPod4Info, ""))
+	assert.Equal(t, types.IPConfigRequestThrottled, resp.Response.ReturnCode)
+	assert.Contains(t, resp.Response.Message, errIPRequestQueueFull.Error())
+
+	// the queued request still gets the next IP released.
+	releaseResp := svc.ReleaseIPConfig(newTestIPConfigRequest(t, testPod1Info, ""))
+	require.Equal(t, types.Success, releaseResp.ReturnCode)
+
+	resp = <-queued
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, testIP1, resp.PodIpInfo.PodIPConfig.IPAddress)
+	requireQueueLength(t, svc, 0)
+}
+
+func TestIPRequestQueueServesInArrivalOrder(t *testing.T) {
+	svc := newIPRequestQueueTestService(t, common.IPRequestQueueSettings{MaxLength: 10, MaxWait: time.Minute})
+
+	first := requestIPConfigAsync(t, svc, testPod3Info)
+	requireQueueLength(t, svc, 1)
+	second := requestIPConfigAsync(t, svc, testPod4Info)
+	requireQueueLength(t, svc, 2)
+
+	releaseResp := svc.ReleaseIPConfig(newTestIPConfigRequest(t, testPod1Info, ""))
+	require.Equal(t, types.Success, releaseResp.ReturnCode)
+
+	resp := <-first
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, testIP1, resp.PodIpInfo.PodIPConfig.IPAddress)
+	requireQueueLength(t, svc, 1)
+
+	select {
+	case resp = <-second:
+		t.Fatalf("Expected the second request to wait for an IP, got %+v", resp)
+	default:
+	}
+
+	releaseResp = svc.ReleaseIPConfig(newTestIPConfigRequest(t, testPod2Info, ""))
+	require.Equal(t, types.Success, releaseResp.ReturnCode)
+
+	resp = <-second
+	require.Equal(t, types.Success, resp.Response.ReturnCode, resp.Response.Message)
+	assert.Equal(t, testIP2, resp.PodIpInfo.PodIPConfig.IPAddress)
+	requireQueueLength(t, svc, 0)
+}
@@ -0,0 +1,340 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ipStateTransitionsStoreKey is the store key the IP state transition audit trail is persisted under.
+	ipStateTransitionsStoreKey = "IPStateTransitions"
+	// ipStateTransitionsFileSuffix replaces the extension of the CNS store file to name the file the audit trail
+	// is persisted to, so that the CNS store and its generations aren't rewritten with every transition.
+	ipStateTransitionsFileSuffix = "-ipstatetransitions.json"
+	// ipStateTransitionsCapacity is the number of IP state transitions and NC changes kept before the oldest are overwritten.
+	ipStateTransitionsCapacity = 4096
+	// ipStateTransitionsSaveInterval is how often new transitions are persisted.
+	ipStateTransitionsSaveInterval = 30 * time.Second
+	// ncSnapshotIPStateTransitions is the number of most recent transitions included in an NC snapshot.
+	ncSnapshotIPStateTransitions = 50
+)
+
+// Causes recorded in the IP state transition audit trail.
+const (
+	causeRequestIPConfig          = "RequestIPConfig"
+	causeReleaseIPConfig          = "ReleaseIPConfig"
+	causeMarkIPAsPendingRelease   = "MarkIPAsPendingRelease"
+	causeMarkExistingIPsAsPending = "MarkExistingIPsAsPending"
+	causeNCVersionProgrammed      = "NCVersionProgrammed"
+	causeNCSecondaryIPAdded       = "NCSecondaryIPAdded"
+	causeNCSecondaryIPRemoved     = "NCSecondaryIPRemoved"
+)
+
+// ipStateTransitionLog is a bounded ring buffer of the changes to the CNS state: the IP state transitions
+// which make up the audit trail, and the NC changes. The event streams are served from it, so every
+// change gets an event ID one greater than the previous one.
+// The zero value is an empty log holding up to ipStateTransitionsCapacity changes.
+type ipStateTransitionLog struct {
+	sync.Mutex
+	events  []cns.Event
+	lastID  uint64
+	updated chan struct{} // closed and replaced when a change is recorded, and closed for good when the log is
+	closed  bool
+	dirty   bool
+}
+
+func (l *ipStateTransitionLog) record(transition cns.IPStateTransition) {
+	l.append(cns.Event{
+		Type:              cns.IPStateChanged,
+		Timestamp:         transition.Timestamp,
+		IPStateTransition: &transition,
+	})
+}
+
+// append adds the event to the log with the next ID.
+func (l *ipStateTransitionLog) append(event cns.Event) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.lastID++
+	event.ID = l.lastID
+	if len(l.events) < ipStateTransitionsCapacity {
+		l.events = append(l.events, event)
+	} else {
+		l.events[(event.ID-1)%ipStateTransitionsCapacity] = event
+	}
+	if event.IPStateTransition != nil {
+		l.dirty = true
+	}
+
+	if !l.closed {
+		close(l.updatedUntransacted())
+		l.updated = nil
+	}
+}
+
+// updatedUntransacted returns the channel closed when the next change is recorded.
+// Caller holds the lock.
+func (l *ipStateTransitionLog) updatedUntransacted() chan struct{} {
+	if l.updated == nil {
+		l.updated = make(chan struct{})
+	}
+	return l.updated
+}
+
+// list returns the transitions matching the predicate, oldest first.
+func (l *ipStateTransitionLog) list(predicate func(cns.IPStateTransition) bool) []cns.IPStateTransition {
+	l.Lock()
+	defer l.Unlock()
+
+	matching := []cns.IPStateTransition{}
+	for _, event := range l.sinceUntransacted(l.lastID - uint64(len(l.events))) {
+		if event.IPStateTransition != nil && (predicate == nil || predicate(*event.IPStateTransition)) {
+			matching = append(matching, *event.IPStateTransition)
+		}
+	}
+	return matching
+}
+
+// since returns the events after the event with the ID, oldest first, and a channel closed when more are
+// recorded or the log is closed. It returns false if events after the ID are no longer buffered, or if
+// the ID is later than the last event, so it isn't known which events the caller missed.
+func (l *ipStateTransitionLog) since(id uint64) ([]cns.Event, bool, <-chan struct{}) {
+	l.Lock()
+	defer l.Unlock()
+
+	updated := l.updatedUntransacted()
+	oldestID := l.lastID - uint64(len(l.events)) + 1
+	if id > l.lastID || id+1 < oldestID {
+		return nil, false, updated
+	}
+	return l.sinceUntransacted(id), true, updated
+}
+
+// sinceUntransacted returns the buffered events after the event with the ID, oldest first.
+// Caller holds the lock.
+func (l *ipStateTransitionLog) sinceUntransacted(id uint64) []cns.Event {
+	events := make([]cns.Event, 0, l.lastID-id)
+	for next := id + 1; next <= l.lastID; next++ {
+		events = append(events, l.events[(next-1)%ipStateTransitionsCapacity])
+	}
+	return events
+}
+
+// last returns the ID of the last event, 0 if there is none.
+func (l *ipStateTransitionLog) last() uint64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.lastID
+}
+
+// close ends the streams waiting on the log.
+func (l *ipStateTransitionLog) close() {
+	l.Lock()
+	defer l.Unlock()
+
+	if !l.closed {
+		close(l.updatedUntransacted())
+		l.closed = true
+	}
+}
+
+// isClosed returns true once the log is closed.
+func (l *ipStateTransitionLog) isClosed() bool {
+	l.Lock()
+	defer l.Unlock()
+	return l.closed
+}
+
+// restore replaces the contents of the log with the persisted transitions, keeping the newest if
+// there are more than fit.
+func (l *ipStateTransitionLog) restore(transitions []cns.IPStateTransition) {
+	l.Lock()
+	defer l.Unlock()
+
+	if len(transitions) > ipStateTransitionsCapacity {
+		transitions = transitions[len(transitions)-ipStateTransitionsCapacity:]
+	}
+	l.events = make([]cns.Event, len(transitions))
+	for i := range transitions {
+		transition := transitions[i]
+		l.events[i] = cns.Event{
+			ID:                uint64(i + 1),
+			Type:              cns.IPStateChanged,
+			Timestamp:         transition.Timestamp,
+			IPStateTransition: &transition,
+		}
+	}
+	l.lastID = uint64(len(transitions))
+	l.dirty = false
+}
+
+// save writes the transitions in the log to the store if they have changed since they were last saved.
+// The log is not held while writing so that recording transitions is never blocked on the disk.
+func (l *ipStateTransitionLog) save(kvs store.KeyValueStore) error {
+	l.Lock()
+	if !l.dirty {
+		l.Unlock()
+		return nil
+	}
+	l.dirty = false
+	l.Unlock()
+
+	if err := kvs.Write(ipStateTransitionsStoreKey, l.list(nil)); err != nil {
+		l.Lock()
+		l.dirty = true
+		l.Unlock()
+		return err
+	}
+	return nil
+}
+
+// recordIPStateTransition adds a change of the State of ipConfig to the audit trail and the IP state metrics.
+func (service *HTTPRestService) recordIPStateTransition(ipConfig cns.IPConfigurationStatus, from, to cns.IPConfigState, cause string) {
+	transition := cns.IPStateTransition{
+		Timestamp:  time.Now(),
+		IPConfigID: ipConfig.ID,
+		IPAddress:  ipConfig.IPAddress,
+		NCID:       ipConfig.NCID,
+		From:       from,
+		To:         to,
+		Cause:      cause,
+	}
+	if ipConfig.PodInfo != nil {
+		transition.PodName = ipConfig.PodInfo.Name()
+		transition.PodNamespace = ipConfig.PodInfo.Namespace()
+		transition.PodInterfaceID = ipConfig.PodInfo.InterfaceID()
+		transition.InfraContainerID = ipConfig.PodInfo.InfraContainerID()
+	}
+	service.ipStateTransitions.record(transition)
+	service.ipStateMetrics.observe(transition)
+	service.ipQuotas.observe(transition)
+}
+
+// GetIPStateTransitions returns the recorded IP state transitions matching the request, oldest first.
+func (service *HTTPRestService) GetIPStateTransitions(req cns.GetIPStateTransitionsRequest) []cns.IPStateTransition {
+	return service.ipStateTransitions.list(func(transition cns.IPStateTransition) bool {
+		return (req.IPAddress == "" || req.IPAddress == transition.IPAddress) &&
+			(req.PodName == "" || req.PodName == transition.PodName) &&
+			(req.PodNamespace == "" || req.PodNamespace == transition.PodNamespace) &&
+			(req.NCID == "" || req.NCID == transition.NCID)
+	})
+}
+
+// newIPStateTransitionsStore returns the store the audit trail is persisted to, next to the CNS store.
+// No generations of it are kept, since the audit trail is only diagnostic.
+func newIPStateTransitionsStore(cnsStore store.KeyValueStore) (store.KeyValueStore, error) {
+	fileName := cnsStore.GetFileName()
+	if fileName == "" {
+		return nil, errors.New("CNS store has no file")
+	}
+	return store.NewJsonFileStore(strings.TrimSuffix(fileName, filepath.Ext(fileName))+ipStateTransitionsFileSuffix, store.WithGenerations(0)) //nolint:wrapcheck
+}
+
+// restoreIPStateTransitions restores the IP state transition audit trail from its store. The audit trail persisted
+// in the CNS store by previous versions is moved to its store.
+func (service *HTTPRestService) restoreIPStateTransitions() {
+	if service.store == nil {
+		return
+	}
+
+	transitionsStore, err := newIPStateTransitionsStore(service.store)
+	if err != nil {
+		logger.Errorf("[Azure CNS] Failed to create the IP state transitions store, err:%v", err)
+		return
+	}
+	service.transitionsStore = transitionsStore
+
+	var transitions []cns.IPStateTransition
+	err = transitionsStore.Read(ipStateTransitionsStoreKey, &transitions)
+	if err == store.ErrKeyNotFound {
+		err = service.moveLegacyIPStateTransitions(&transitions)
+	}
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Errorf("[Azure CNS] Failed to restore IP state transitions, err:%v", err)
+		}
+		return
+	}
+
+	service.ipStateTransitions.restore(transitions)
+	logger.Printf("[Azure CNS] Restored %d IP state transitions", len(transitions))
+}
+
+// moveLegacyIPStateTransitions reads the audit trail persisted in the CNS store by previous versions, writes it to
+// its own store and clears it from the CNS store.
+func (service *HTTPRestService) moveLegacyIPStateTransitions(transitions *[]cns.IPStateTransition) error {
+	if err := service.store.Read(ipStateTransitionsStoreKey, transitions); err != nil {
+		return err //nolint:wrapcheck // store.ErrKeyNotFound is checked by the caller
+	}
+	if err := service.transitionsStore.Write(ipStateTransitionsStoreKey, *transitions); err != nil {
+		return errors.Wrap(err, "failed to move the IP state transitions out of the CNS store")
+	}
+
+	if kvs, ok := service.store.(store.TransactionalKeyValueStore); ok {
+		err := kvs.Update(func(tx store.Transaction) error {
+			return tx.Delete(ipStateTransitionsStoreKey)
+		})
+		return errors.Wrap(err, "failed to clear the IP state transitions from the CNS store")
+	}
+	// the JSON file store can't delete a key, so it is left empty
+	return errors.Wrap(service.store.Write(ipStateTransitionsStoreKey, nil), "failed to clear the IP state transitions from the CNS store")
+}
+
+// saveIPStateTransitions persists the IP state transition audit trail if it has changed.
+func (service *HTTPRestService) saveIPStateTransitions() {
+	if service.transitionsStore == nil {
+		return
+	}
+
+	if err := service.ipStateTransitions.save(service.transitionsStore); err != nil {
+		logger.Errorf("[Azure CNS] Failed to save IP state transitions, err:%v", err)
+	}
+}
+
+// saveIPStateTransitionsPeriodically persists the IP state transition audit trail until the ctx is cancelled.
+func (service *HTTPRestService) saveIPStateTransitionsPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			service.saveIPStateTransitions()
+		}
+	}
+}
+
+func (service *HTTPRestService) getIPStateTransitionsHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		req  cns.GetIPStateTransitionsRequest
+		resp cns.GetIPStateTransitionsResponse
+	)
+
+	err := service.Listener.Decode(w, r, &req)
+	if err != nil {
+		resp.Response.ReturnCode = types.UnexpectedError
+		resp.Response.Message = err.Error()
+		logger.Errorf("getIPStateTransitionsHandler decode failed because %v, GetIPStateTransitionsRequest is %v",
+			err, req)
+	} else {
+		resp.IPStateTransitions = service.GetIPStateTransitions(req)
+	}
+
+	err = service.Listener.Encode(w, &resp)
+	logger.Response(service.Name, resp.Response, resp.Response.ReturnCode, err)
+}
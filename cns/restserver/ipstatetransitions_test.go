@@ -0,0 +1,127 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPStateTransitionLogWrapsOldestFirst(t *testing.T) {
+	var l ipStateTransitionLog
+	total := ipStateTransitionsCapacity + 10
+	for i := 0; i < total; i++ {
+		l.record(cns.IPStateTransition{IPConfigID: strconv.Itoa(i)})
+	}
+
+	transitions := l.list(nil)
+	require.Len(t, transitions, ipStateTransitionsCapacity)
+	assert.Equal(t, "10", transitions[0].IPConfigID)
+	assert.Equal(t, strconv.Itoa(total-1), transitions[len(transitions)-1].IPConfigID)
+}
+
+func TestIPStateTransitionLogSaveAndRestore(t *testing.T) {
+	kvs, err := store.NewJsonFileStore(filepath.Join(t.TempDir(), "azure-cns.json"))
+	require.NoError(t, err)
+
+	var l ipStateTransitionLog
+	for i := 0; i < ipStateTransitionsCapacity+1; i++ {
+		l.record(cns.IPStateTransition{IPConfigID: strconv.Itoa(i)})
+	}
+	require.NoError(t, l.save(kvs))
+	assert.False(t, l.dirty)
+
+	var persisted []cns.IPStateTransition
+	require.NoError(t, kvs.Read(ipStateTransitionsStoreKey, &persisted))
+
+	var restored ipStateTransitionLog
+	restored.restore(persisted)
+	assert.Equal(t, l.list(nil), restored.list(nil))
+
+	// recording after a restore appends after the newest restored transition.
+	restored.record(cns.IPStateTransition{IPConfigID: "new"})
+	transitions := restored.list(nil)
+	assert.Equal(t, "2", transitions[0].IPConfigID)
+	assert.Equal(t, "new", transitions[len(transitions)-1].IPConfigID)
+}
+
+func TestIPStateTransitionsRecordedForRequestAndRelease(t *testing.T) {
+	svc := getTestService()
+
+	state1, _ := NewPodStateWithOrchestratorContext(testIP1, testPod1GUID, testNCID, cns.Available, 24, 0, testPod1Info)
+	state2, _ := NewPodStateWithOrchestratorContext(testIP2, testPod2GUID, testNCID, cns.Available, 24, 0, testPod2Info)
+	ipconfigs := map[string]cns.IPConfigurationStatus{
+		state1.ID: state1,
+		state2.ID: state2,
+	}
+	require.NoError(t, UpdatePodIpConfigState(t, svc, ipconfigs))
+
+	req := cns.IPConfigRequest{
+		PodInterfaceID:   testPod1Info.InterfaceID(),
+		InfraContainerID: testPod1Info.InfraContainerID(),
+		DesiredIPAddress: testIP1,
+	}
+	req.OrchestratorContext, _ = testPod1Info.OrchestratorContext()
+	_, err := requestIPConfigHelper(svc, req)
+	require.NoError(t, err)
+	require.NoError(t, svc.releaseIPConfig(testPod1Info))
+
+	transitions := svc.GetIPStateTransitions(cns.GetIPStateTransitionsRequest{IPAddress: testIP1})
+	require.Len(t, transitions, 3)
+	assert.Equal(t, cns.IPConfigState(""), transitions[0].From)
+	assert.Equal(t, cns.Available, transitions[0].To)
+	assert.Equal(t, causeNCSecondaryIPAdded, transitions[0].Cause)
+	assert.Equal(t, cns.Available, transitions[1].From)
+	assert.Equal(t, cns.Allocated, transitions[1].To)
+	assert.Equal(t, causeRequestIPConfig, transitions[1].Cause)
+	assert.Equal(t, cns.Allocated, transitions[2].From)
+	assert.Equal(t, cns.Available, transitions[2].To)
+	assert.Equal(t, causeReleaseIPConfig, transitions[2].Cause)
+
+	// the release is attributed to the pod which held the IP.
+	assert.Equal(t, testPod1Info.Name(), transitions[2].PodName)
+	assert.Equal(t, testPod1Info.Namespace(), transitions[2].PodNamespace)
+
+	byPod := svc.GetIPStateTransitions(cns.GetIPStateTransitionsRequest{PodName: testPod1Info.Name(), PodNamespace: testPod1Info.Namespace()})
+	assert.Equal(t, transitions[1:], byPod)
+
+	byNC := svc.GetIPStateTransitions(cns.GetIPStateTransitionsRequest{NCID: testNCID})
+	assert.Len(t, byNC, 4)
+}
+
+func TestIPStateTransitionsAreMovedOutOfTheCNSStore(t *testing.T) {
+	dir := t.TempDir()
+	kvs, err := store.NewJsonFileStore(filepath.Join(dir, "azure-cns.json"))
+	require.NoError(t, err)
+	legacy := []cns.IPStateTransition{{IPConfigID: "legacy", To: cns.Available}}
+	require.NoError(t, kvs.Write(ipStateTransitionsStoreKey, legacy))
+
+	s := &HTTPRestService{store: kvs}
+	s.restoreIPStateTransitions()
+	assert.Equal(t, legacy, s.ipStateTransitions.list(nil))
+
+	var cleared []cns.IPStateTransition
+	require.NoError(t, kvs.Read(ipStateTransitionsStoreKey, &cleared))
+	assert.Empty(t, cleared)
+
+	// new transitions are saved to their own file, which is restored from on the next start.
+	s.ipStateTransitions.record(cns.IPStateTransition{IPConfigID: "new"})
+	s.saveIPStateTransitions()
+	require.NoError(t, kvs.Read(ipStateTransitionsStoreKey, &cleared))
+	assert.Empty(t, cleared)
+
+	restarted := &HTTPRestService{store: kvs}
+	restarted.restoreIPStateTransitions()
+	assert.Equal(t, filepath.Join(dir, "azure-cns"+ipStateTransitionsFileSuffix), restarted.transitionsStore.GetFileName())
+	transitions := restarted.ipStateTransitions.list(nil)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, "legacy", transitions[0].IPConfigID)
+	assert.Equal(t, "new", transitions[1].IPConfigID)
+}
@@ -1,9 +1,16 @@
 package restserver
 
 import (
+	"bytes"
+	"encoding/json"
+	"mime"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -15,25 +22,312 @@ var httpRequestLatency = prometheus.NewHistogramVec(
 		//nolint:gomnd
 		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1 ms to ~16 seconds
 	},
-	// TODO(rbtr):
-	// there's no easy way to extract the HTTP response code from the response due to the
-	// way the restserver is designed currently - but we should fix that and include "code" as
-	// a label and value.
-	[]string{"url", "verb"},
+	[]string{"url", "verb", "code"},
+)
+
+var httpRequestCount = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Request count by endpoint, verb, response code, and CNS return code.",
+	},
+	[]string{"url", "verb", "code", "return_code"},
+)
+
+var ipamLeakedIPCount = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "ipam_leaked_ips",
+		Help: "Allocated IP count not held by any pod on the node.",
+	},
+)
+
+var ipamPartiallyHeldIPCount = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "ipam_partially_held_ips",
+		Help: "Allocated IP count held by a pod according to some sources of the pods on the node only.",
+	},
+)
+
+var ipamReleasedLeakedIPCount = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "ipam_released_leaked_ips_total",
+		Help: "Leaked IP count released by the leak detector.",
+	},
+)
+
+var ipamIPRequestQueueDepth = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "ipam_ip_request_queue_depth",
+		Help: "IP request count waiting in the queue for an Available IP.",
+	},
+)
+
+var ipamIPRequestQueueWaitLatency = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Name: "ipam_ip_request_queue_wait_seconds",
+		Help: "Time in seconds IP requests waited in the queue for an Available IP.",
+		//nolint:gomnd
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15), // 1 ms to ~16 seconds
+	},
+)
+
+var ipamThrottledIPRequestCount = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "ipam_throttled_ip_requests_total",
+		Help: "IP request count rejected by the IP request queue, by reason.",
+	},
+	[]string{"reason"},
+)
+
+var ipamNCIPCount = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "ipam_nc_ips",
+		Help: "IP count by NC and IPConfigState.",
+	},
+	[]string{"nc_id", "state"},
+)
+
+var ipamIPTimeInState = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "ipam_ip_time_in_state_seconds",
+		Help: "Time in seconds IPs spent in an IPConfigState before leaving it, by state.",
+		//nolint:gomnd
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 18), // 100 ms to ~3.6 hours
+	},
+	[]string{"state"},
+)
+
+var ipamNCRequestedVersion = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "ipam_nc_requested_version",
+		Help: "NC version requested by DNC, by NC.",
+	},
+	[]string{"nc_id"},
+)
+
+var ipamNCProgrammedVersion = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "ipam_nc_programmed_version",
+		Help: "NC version NMAgent reported as programmed, by NC. -1 until NMAgent reports a version.",
+	},
+	[]string{"nc_id"},
+)
+
+var ipamNamespaceAllocatedIPCount = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "ipam_namespace_allocated_ips",
+		Help: "IP count allocated to the pods of each namespace with an IP quota.",
+	},
+	[]string{"namespace"},
+)
+
+var ipamNamespaceMaxIPCount = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "ipam_namespace_max_ips",
+		Help: "Max IP count allocated to the pods of each namespace with an IP quota, 0 if unlimited.",
+	},
+	[]string{"namespace"},
+)
+
+var ipamNamespaceReservedIPCount = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "ipam_namespace_reserved_ips",
+		Help: "IP count reserved for the pods of each namespace with an IP quota.",
+	},
+	[]string{"namespace"},
+)
+
+var ipamNamespaceQuotaRejectionCount = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "ipam_namespace_quota_rejections_total",
+		Help: "IP request count rejected by the namespace IP quotas, by namespace and reason.",
+	},
+	[]string{"namespace", "reason"},
 )
 
 func init() {
 	metrics.Registry.MustRegister(
 		httpRequestLatency,
+		httpRequestCount,
+		ipamLeakedIPCount,
+		ipamPartiallyHeldIPCount,
+		ipamReleasedLeakedIPCount,
+		ipamIPRequestQueueDepth,
+		ipamIPRequestQueueWaitLatency,
+		ipamThrottledIPRequestCount,
+		ipamNCIPCount,
+		ipamIPTimeInState,
+		ipamNCRequestedVersion,
+		ipamNCProgrammedVersion,
+		ipamNamespaceAllocatedIPCount,
+		ipamNamespaceMaxIPCount,
+		ipamNamespaceReservedIPCount,
+		ipamNamespaceQuotaRejectionCount,
 	)
 }
 
-func newHandlerFuncWithHistogram(handler http.HandlerFunc, histogram *prometheus.HistogramVec) http.HandlerFunc {
+// ipConfigStates are the IPConfigStates reported for each NC.
+var ipConfigStates = []cns.IPConfigState{cns.Available, cns.Allocated, cns.PendingRelease, cns.PendingProgramming}
+
+const (
+	// maxRecordedResponseSize is the size of the response body kept to read the CNS return code from.
+	maxRecordedResponseSize = 1 << 20
+	// contentTypeJSON is the media type of the response bodies the CNS return code is read from.
+	contentTypeJSON = "application/json"
+)
+
+// responseRecorder records the status code and the start of the body written to the ResponseWriter.
+// Only JSON bodies are recorded, so that streamed events and debug bundles aren't copied.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	// isJSON is whether the body is JSON, known from the Content-Type once the body is first written.
+	isJSON *bool
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	if r.isJSON == nil {
+		mediaType, _, err := mime.ParseMediaType(r.Header().Get("Content-Type"))
+		isJSON := err == nil && mediaType == contentTypeJSON
+		r.isJSON = &isJSON
+	}
+	if remaining := maxRecordedResponseSize - r.body.Len(); *r.isJSON && remaining > 0 {
+		if len(b) > remaining {
+			r.body.Write(b[:remaining])
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered response to the client, so that streamed responses aren't held back by the recorder.
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// returnCode returns the CNS return code of the response, which is either at the top level of the
+// body or in its Response, or "" if the body is not a CNS response.
+func (r *responseRecorder) returnCode() string {
+	if r.body.Len() == 0 {
+		return ""
+	}
+
+	var resp struct {
+		ReturnCode *types.ResponseCode
+		Response   *cns.Response
+	}
+	if err := json.Unmarshal(r.body.Bytes(), &resp); err != nil {
+		return ""
+	}
+	switch {
+	case resp.Response != nil:
+		return resp.Response.ReturnCode.String()
+	case resp.ReturnCode != nil:
+		return resp.ReturnCode.String()
+	}
+	return ""
+}
+
+// newHandlerFuncWithMetrics records the latency, response code and CNS return code of requests to the route.
+func newHandlerFuncWithMetrics(route string, handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w}
 		defer func() {
-			histogram.WithLabelValues(req.URL.RequestURI(), req.Method).Observe(time.Since(start).Seconds())
+			if recorder.statusCode == 0 {
+				recorder.statusCode = http.StatusOK
+			}
+			code := strconv.Itoa(recorder.statusCode)
+			httpRequestLatency.WithLabelValues(route, req.Method, code).Observe(time.Since(start).Seconds())
+			httpRequestCount.WithLabelValues(route, req.Method, code, recorder.returnCode()).Inc()
 		}()
-		handler(w, req)
+		handler(recorder, req)
+	}
+}
+
+// ipStateMetrics tracks the IP count of each NC in each IPConfigState, and when each IP entered its
+// state, from the IP state transitions.
+type ipStateMetrics struct {
+	sync.Mutex
+	// NC ID is key, the IP count in each state
+	ncIPCounts map[string]map[cns.IPConfigState]int
+	// IPConfig ID is key, when the IP entered its state
+	enteredState map[string]time.Time
+}
+
+// observe updates the metrics with the IP state transition. A transition from "" adds an IP and a
+// transition to "" removes it.
+func (m *ipStateMetrics) observe(transition cns.IPStateTransition) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.ncIPCounts == nil {
+		m.ncIPCounts = map[string]map[cns.IPConfigState]int{}
+		m.enteredState = map[string]time.Time{}
+	}
+
+	counts, ok := m.ncIPCounts[transition.NCID]
+	if !ok {
+		counts = map[cns.IPConfigState]int{}
+		m.ncIPCounts[transition.NCID] = counts
+	}
+
+	if transition.From != "" {
+		counts[transition.From]--
+		if entered, ok := m.enteredState[transition.IPConfigID]; ok {
+			ipamIPTimeInState.WithLabelValues(string(transition.From)).Observe(transition.Timestamp.Sub(entered).Seconds())
+		}
 	}
+	if transition.To != "" {
+		counts[transition.To]++
+		m.enteredState[transition.IPConfigID] = transition.Timestamp
+	} else {
+		delete(m.enteredState, transition.IPConfigID)
+	}
+
+	total := 0
+	for _, state := range ipConfigStates {
+		total += counts[state]
+	}
+	if total == 0 {
+		// the NC has no IPs left, stop reporting it
+		delete(m.ncIPCounts, transition.NCID)
+		for _, state := range ipConfigStates {
+			ipamNCIPCount.DeleteLabelValues(transition.NCID, string(state))
+		}
+		return
+	}
+	for _, state := range ipConfigStates {
+		ipamNCIPCount.WithLabelValues(transition.NCID, string(state)).Set(float64(counts[state]))
+	}
+}
+
+// observeNCVersions reports the NC version requested by DNC and the one NMAgent reported as programmed.
+// Versions which are not numbers are not reported.
+func observeNCVersions(ncID, requestedVersion, programmedVersion string) {
+	if v, err := strconv.Atoi(requestedVersion); err == nil {
+		ipamNCRequestedVersion.WithLabelValues(ncID).Set(float64(v))
+	}
+	if v, err := strconv.Atoi(programmedVersion); err == nil {
+		ipamNCProgrammedVersion.WithLabelValues(ncID).Set(float64(v))
+	}
+}
+
+// deleteNCVersions stops reporting the versions of the deleted NC.
+func deleteNCVersions(ncID string) {
+	ipamNCRequestedVersion.DeleteLabelValues(ncID)
+	ipamNCProgrammedVersion.DeleteLabelValues(ncID)
 }
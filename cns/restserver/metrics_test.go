@@ -0,0 +1,114 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func metricValue(t *testing.T, metric prometheus.Metric) *dto.Metric {
+	var m dto.Metric
+	require.NoError(t, metric.Write(&m))
+	return &m
+}
+
+func TestHandlerMetricsByRoute(t *testing.T) {
+	handler := newHandlerFuncWithMetrics("/test/route", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"ReturnCode":39,"Message":"denied"}`))
+	})
+	count := httpRequestCount.WithLabelValues("/test/route", http.MethodPost, "403", types.UnauthorizedPeer.String())
+	before := metricValue(t, count).GetCounter().GetValue()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/test/route?query=1", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, before+1, metricValue(t, count).GetCounter().GetValue())
+	latency := httpRequestLatency.WithLabelValues("/test/route", http.MethodPost, "403").(prometheus.Metric)
+	assert.NotZero(t, metricValue(t, latency).GetHistogram().GetSampleCount())
+}
+
+func newJSONResponseRecorder() *responseRecorder {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func TestHandlerMetricsReadNestedReturnCode(t *testing.T) {
+	recorder := newJSONResponseRecorder()
+	_, _ = recorder.Write([]byte(`{"IPConfiguration":{},"Response":{"ReturnCode":0,"Message":""}}`))
+	assert.Equal(t, http.StatusOK, recorder.statusCode)
+	assert.Equal(t, types.Success.String(), recorder.returnCode())
+
+	recorder = newJSONResponseRecorder()
+	_, _ = recorder.Write([]byte("not json"))
+	assert.Equal(t, "", recorder.returnCode())
+}
+
+func TestHandlerMetricsOnlyRecordJSONBodies(t *testing.T) {
+	for _, contentType := range []string{"", "text/event-stream", "application/gzip"} {
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Type", contentType)
+		recorder := &responseRecorder{ResponseWriter: w}
+		_, _ = recorder.Write([]byte(`{"ReturnCode":0}`))
+		assert.Zero(t, recorder.body.Len(), contentType)
+		assert.Equal(t, "", recorder.returnCode(), contentType)
+		assert.Equal(t, `{"ReturnCode":0}`, w.Body.String(), "the body is still written")
+	}
+}
+
+func TestRegisteredHandlersReportMetrics(t *testing.T) {
+	count := httpRequestCount.WithLabelValues(cns.V2Prefix+cns.NumberOfCPUCoresPath, http.MethodGet, "200", types.Success.String())
+	before := metricValue(t, count).GetCounter().GetValue()
+
+	req, err := http.NewRequest(http.MethodGet, cns.V2Prefix+cns.NumberOfCPUCoresPath, nil)
+	require.NoError(t, err)
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, before+1, metricValue(t, count).GetCounter().GetValue())
+}
+
+func TestIPStateMetrics(t *testing.T) {
+	var m ipStateMetrics
+	start := time.Now()
+	ncID := "metrics-nc"
+	stateCount := func(state cns.IPConfigState) float64 {
+		return metricValue(t, ipamNCIPCount.WithLabelValues(ncID, string(state))).GetGauge().GetValue()
+	}
+	timeInAllocated := func() uint64 {
+		return metricValue(t, ipamIPTimeInState.WithLabelValues(string(cns.Allocated)).(prometheus.Metric)).GetHistogram().GetSampleCount()
+	}
+
+	m.observe(cns.IPStateTransition{Timestamp: start, IPConfigID: "ip1", NCID: ncID, To: cns.Available})
+	m.observe(cns.IPStateTransition{Timestamp: start, IPConfigID: "ip2", NCID: ncID, To: cns.PendingProgramming})
+	m.observe(cns.IPStateTransition{Timestamp: start, IPConfigID: "ip1", NCID: ncID, From: cns.Available, To: cns.Allocated})
+	assert.Equal(t, float64(0), stateCount(cns.Available))
+	assert.Equal(t, float64(1), stateCount(cns.Allocated))
+	assert.Equal(t, float64(1), stateCount(cns.PendingProgramming))
+	assert.Equal(t, float64(0), stateCount(cns.PendingRelease))
+
+	observed := timeInAllocated()
+	m.observe(cns.IPStateTransition{Timestamp: start.Add(time.Minute), IPConfigID: "ip1", NCID: ncID, From: cns.Allocated, To: cns.Available})
+	assert.Equal(t, observed+1, timeInAllocated())
+	assert.Equal(t, float64(1), stateCount(cns.Available))
+
+	// removing the last IPs of the NC stops reporting it
+	m.observe(cns.IPStateTransition{Timestamp: start, IPConfigID: "ip1", NCID: ncID, From: cns.Available})
+	m.observe(cns.IPStateTransition{Timestamp: start, IPConfigID: "ip2", NCID: ncID, From: cns.PendingProgramming})
+	assert.Empty(t, m.ncIPCounts)
+	assert.Empty(t, m.enteredState)
+	assert.False(t, ipamNCIPCount.DeleteLabelValues(ncID, string(cns.Available)))
+}
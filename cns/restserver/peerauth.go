@@ -0,0 +1,94 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+)
+
+var (
+	errNoPeerCredentials = errors.New("the credentials of the peer on the unix socket could not be read")
+	errUnixSocketOnly    = errors.New("mutating APIs are only served on the unix socket")
+)
+
+// newHandlerFuncWithPeerAuthorization wraps a mutating handler so that it is only served to the
+// requests allowed by authorizeRequest.
+func (service *HTTPRestService) newHandlerFuncWithPeerAuthorization(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := service.authorizeRequest(r.Context()); err != nil {
+			creds, _ := acn.PeerCredentialsFromContext(r.Context())
+			logger.Errorf("[Azure CNS] Rejecting %s from pid %d uid %d: %v", r.URL.Path, creds.PID, creds.UID, err)
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusForbidden)
+			_ = service.Listener.Encode(w, &cns.Response{
+				ReturnCode: types.UnauthorizedPeer,
+				Message:    err.Error(),
+			})
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// authorizeRequest returns an error if a mutating request must not be served. The requests received on
+// a unix socket are only served to the peers allowed by the PeerAuthorizationSettings, and are rejected
+// if the credentials of the peer could not be read. The requests received on other listeners carry no
+// peer credentials, and are rejected if the mutating APIs are only served on the unix socket.
+func (service *HTTPRestService) authorizeRequest(ctx context.Context) error {
+	peer := acn.ConnPeerFromContext(ctx)
+	if !peer.UnixSocket {
+		if service.peerAuthorization.UnixSocketOnly {
+			return errUnixSocketOnly
+		}
+		return nil
+	}
+
+	if peer.Credentials == nil {
+		return errNoPeerCredentials
+	}
+
+	return service.authorizePeer(*peer.Credentials)
+}
+
+// authorizePeer returns an error if the peer is not allowed by the PeerAuthorizationSettings.
+func (service *HTTPRestService) authorizePeer(creds acn.PeerCredentials) error {
+	settings := service.peerAuthorization
+	if len(settings.AllowedUIDs) == 0 && len(settings.AllowedBinaries) == 0 {
+		return nil
+	}
+
+	for _, uid := range settings.AllowedUIDs {
+		if creds.UID == uid {
+			return nil
+		}
+	}
+
+	if len(settings.AllowedBinaries) == 0 {
+		//nolint:goerr113
+		return fmt.Errorf("uid %d is not allowed", creds.UID)
+	}
+
+	binary, err := acn.PeerBinary(creds)
+	if err != nil {
+		return err
+	}
+
+	for _, allowed := range settings.AllowedBinaries {
+		if binary == allowed {
+			return nil
+		}
+	}
+
+	//nolint:goerr113
+	return fmt.Errorf("uid %d running %s is not allowed", creds.UID, binary)
+}
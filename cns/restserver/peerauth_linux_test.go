@@ -0,0 +1,220 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/rpc"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestAuthorizePeer(t *testing.T) {
+	self, err := os.Executable()
+	require.NoError(t, err)
+	creds := acn.PeerCredentials{PID: int32(os.Getpid()), UID: uint32(os.Getuid())}
+
+	tests := []struct {
+		name     string
+		settings common.PeerAuthorizationSettings
+		allowed  bool
+	}{
+		{
+			name:    "no restrictions",
+			allowed: true,
+		},
+		{
+			name:     "allowed uid",
+			settings: common.PeerAuthorizationSettings{AllowedUIDs: []uint32{creds.UID}},
+			allowed:  true,
+		},
+		{
+			name:     "other uid",
+			settings: common.PeerAuthorizationSettings{AllowedUIDs: []uint32{creds.UID + 1}},
+			allowed:  false,
+		},
+		{
+			name: "other uid, allowed binary",
+			settings: common.PeerAuthorizationSettings{
+				AllowedUIDs:     []uint32{creds.UID + 1},
+				AllowedBinaries: []string{self},
+			},
+			allowed: true,
+		},
+		{
+			name:     "other binary",
+			settings: common.PeerAuthorizationSettings{AllowedBinaries: []string{"/opt/cni/bin/azure-vnet"}},
+			allowed:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := &HTTPRestService{peerAuthorization: tt.settings}
+			err := service.authorizePeer(creds)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPeerAuthorizationOnUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "cns.sock")
+	listener, err := acn.NewListener(&url.URL{Scheme: "unix", Path: socket})
+	require.NoError(t, err)
+
+	service := getTestService()
+	service.Listener = listener
+	service.peerAuthorization = common.PeerAuthorizationSettings{AllowedUIDs: []uint32{uint32(os.Getuid()) + 1}}
+
+	listener.AddHandler("/mutate", service.newHandlerFuncWithPeerAuthorization(func(w http.ResponseWriter, r *http.Request) {}))
+	require.NoError(t, listener.Start(make(chan error, 1)))
+	defer listener.Stop()
+
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	resp, err := client.Post("http://localhost/mutate", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	service.peerAuthorization.AllowedUIDs = append(service.peerAuthorization.AllowedUIDs, uint32(os.Getuid()))
+	resp, err = client.Post("http://localhost/mutate", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMutatingRoutesRequirePeerAuthorization(t *testing.T) {
+	// the requests are served by the service started by TestMain, svc may have been replaced by another test.
+	restService := service.(*HTTPRestService)
+	restService.peerAuthorization = common.PeerAuthorizationSettings{UnixSocketOnly: true}
+	defer func() { restService.peerAuthorization = common.PeerAuthorizationSettings{} }()
+
+	for _, path := range []string{
+		cns.SetEnvironmentPath,
+		cns.CreateNetworkPath,
+		cns.DeleteNetworkPath,
+		cns.CreateHnsNetworkPath,
+		cns.DeleteHnsNetworkPath,
+		cns.CreateOrUpdateNetworkContainer,
+	} {
+		for _, route := range []string{path, cns.V2Prefix + path} {
+			req := httptest.NewRequest(http.MethodPost, route, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusForbidden, w.Code, "route %s", route)
+		}
+	}
+}
+
+func TestAuthorizeRequest(t *testing.T) {
+	creds := acn.PeerCredentials{PID: int32(os.Getpid()), UID: uint32(os.Getuid())}
+
+	tests := []struct {
+		name     string
+		settings common.PeerAuthorizationSettings
+		peer     *acn.ConnPeer
+		allowed  bool
+	}{
+		{
+			name:    "tcp",
+			allowed: true,
+		},
+		{
+			name:     "tcp, unix socket only",
+			settings: common.PeerAuthorizationSettings{UnixSocketOnly: true},
+			allowed:  false,
+		},
+		{
+			name:     "unix socket, unix socket only",
+			settings: common.PeerAuthorizationSettings{UnixSocketOnly: true},
+			peer:     &acn.ConnPeer{UnixSocket: true, Credentials: &creds},
+			allowed:  true,
+		},
+		{
+			name:    "unix socket without credentials",
+			peer:    &acn.ConnPeer{UnixSocket: true},
+			allowed: false,
+		},
+		{
+			name:     "unix socket, other uid",
+			settings: common.PeerAuthorizationSettings{AllowedUIDs: []uint32{creds.UID + 1}},
+			peer:     &acn.ConnPeer{UnixSocket: true, Credentials: &creds},
+			allowed:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			service := &HTTPRestService{peerAuthorization: tt.settings}
+			ctx := context.Background()
+			if tt.peer != nil {
+				ctx = acn.ContextWithConnPeer(ctx, *tt.peer)
+			}
+			err := service.authorizeRequest(ctx)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGRPCPeerAuthorizationOnUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "cns-grpc.sock")
+	service := getTestService()
+	service.peerAuthorization = common.PeerAuthorizationSettings{AllowedUIDs: []uint32{uint32(os.Getuid()) + 1}}
+	defer func() { service.peerAuthorization = common.PeerAuthorizationSettings{} }()
+
+	config := common.ServiceConfig{
+		ErrChan:      make(chan error, 1),
+		GRPCSettings: common.GRPCSettings{Enable: true, Socket: socket},
+	}
+	require.NoError(t, service.startGRPCServer(&config))
+	defer service.grpcServer.Stop()
+
+	client, err := rpc.NewClient(context.Background(), "unix://"+socket, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer client.Close()
+
+	orchestratorContext, _ := testPod1Info.OrchestratorContext()
+	req := &cns.IPConfigRequest{
+		PodInterfaceID:      testPod1Info.InterfaceID(),
+		InfraContainerID:    testPod1Info.InfraContainerID(),
+		OrchestratorContext: orchestratorContext,
+	}
+	err = client.ReleaseIPAddress(context.Background(), req)
+	var rpcErr *rpc.Error
+	require.True(t, errors.As(err, &rpcErr), "expected rpc.Error, got %v", err)
+	assert.Equal(t, types.UnauthorizedPeer, rpcErr.Code)
+
+	service.peerAuthorization.AllowedUIDs = append(service.peerAuthorization.AllowedUIDs, uint32(os.Getuid()))
+	assert.NoError(t, client.ReleaseIPAddress(context.Background(), req))
+}
@@ -4,11 +4,18 @@
 package restserver
 
 import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/Azure/azure-container-networking/cns"
+	v1 "github.com/Azure/azure-container-networking/cns/api/v1"
 	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/dockerclient"
 	"github.com/Azure/azure-container-networking/cns/imdsclient"
 	"github.com/Azure/azure-container-networking/cns/ipamclient"
@@ -16,6 +23,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/networkcontainers"
 	"github.com/Azure/azure-container-networking/cns/nmagentclient"
 	"github.com/Azure/azure-container-networking/cns/routes"
+	"github.com/Azure/azure-container-networking/cns/rpc"
 	"github.com/Azure/azure-container-networking/cns/types"
 	acn "github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/store"
@@ -37,17 +45,42 @@ var (
 // HTTPRestService represents http listener for CNS - Container Networking Service.
 type HTTPRestService struct {
 	*cns.Service
-	dockerClient             *dockerclient.DockerClient
-	imdsClient               imdsclient.ImdsClientInterface
-	ipamClient               *ipamclient.IpamClient
-	nmagentClient            nmagentclient.NMAgentClientInterface
-	networkContainer         *networkcontainers.NetworkContainers
-	PodIPIDByPodInterfaceKey map[string]string                    // PodInterfaceId is key and value is Pod IP (SecondaryIP) uuid.
-	PodIPConfigState         map[string]cns.IPConfigurationStatus // Secondary IP ID(uuid) is key
-	IPAMPoolMonitor          cns.IPAMPoolMonitor
-	routingTable             *routes.RoutingTable
-	store                    store.KeyValueStore
-	state                    *httpRestServiceState
+	containerRuntime           containerruntime.Client
+	imdsClient                 imdsclient.ImdsClientInterface
+	ipamClient                 *ipamclient.IpamClient
+	nmagentClient              nmagentclient.NMAgentClientInterface
+	networkContainer           *networkcontainers.NetworkContainers
+	PodIPIDByPodInterfaceKey   map[string]string                    // PodInterfaceId is key and value is Pod IPv4 (SecondaryIP) uuid.
+	PodIPv6IDByPodInterfaceKey map[string]string                    // PodInterfaceId is key and value is Pod IPv6 (SecondaryIP) uuid.
+	PodIPConfigState           map[string]cns.IPConfigurationStatus // Secondary IP ID(uuid) is key
+	IPAMPoolMonitor            cns.IPAMPoolMonitor
+	routingTable               *routes.RoutingTable
+	store                      store.KeyValueStore
+	containerStatusStore       *store.KeyedMap
+	state                      *httpRestServiceState
+	ipConfigWatchers           ipConfigWatchers
+	ipStateTransitions         ipStateTransitionLog
+	transitionsStore           store.KeyValueStore
+	ipStateMetrics             ipStateMetrics
+	stateRestored              bool
+	healthChecksLock           sync.Mutex
+	readinessChecks            []healthCheck
+	serviceLockProbe           serviceLockProbe
+	stopSavingTransitions      context.CancelFunc
+	ipLeaks                    ipLeakTracker
+	ipRequests                 ipRequestQueue
+	ipQuotas                   namespaceIPQuotas
+	ipIntake                   ipRequestIntake
+	grpcServer                 *rpc.Server
+	peerAuthorization          common.PeerAuthorizationSettings
+	// allocate IPs to pods before NMAgent reports their NC version as programmed
+	allocatePendingProgrammingIPs bool
+	// CNS config with its secrets redacted, included in debug bundles
+	redactedCNSConfig interface{}
+	// status of the CNS config file and its reloads, if CNS reloads it
+	configStatus cns.ConfigStatusProvider
+	// whether the node is being deleted, in which case its unallocated IPs are released on shutdown
+	nodeDeletionCheck func(context.Context) (bool, error)
 	sync.RWMutex
 	dncPartitionKey string
 }
@@ -59,9 +92,10 @@ type GetHTTPServiceDataResponse struct {
 
 // HTTPRestServiceData represents in-memory CNS data in the debug API paths.
 type HTTPRestServiceData struct {
-	PodIPIDByPodInterfaceKey map[string]string                    // PodInterfaceId is key and value is Pod IP uuid.
-	PodIPConfigState         map[string]cns.IPConfigurationStatus // secondaryipid(uuid) is key
-	IPAMPoolMonitor          cns.IpamPoolMonitorStateSnapshot
+	PodIPIDByPodInterfaceKey   map[string]string                    // PodInterfaceId is key and value is Pod IPv4 uuid.
+	PodIPv6IDByPodInterfaceKey map[string]string                    // PodInterfaceId is key and value is Pod IPv6 uuid.
+	PodIPConfigState           map[string]cns.IPConfigurationStatus // secondaryipid(uuid) is key
+	IPAMPoolMonitor            cns.IpamPoolMonitorStateSnapshot
 }
 
 type Response struct {
@@ -80,6 +114,7 @@ type containerstatus struct {
 
 // httpRestServiceState contains the state we would like to persist.
 type httpRestServiceState struct {
+	SchemaVersion                    int
 	Location                         string
 	NetworkType                      string
 	OrchestratorType                 string
@@ -123,23 +158,32 @@ func NewHTTPRestService(config *common.ServiceConfig, imdsClientInterface imdscl
 	serviceState.joinedNetworks = make(map[string]struct{})
 
 	podIPIDByPodInterfaceKey := make(map[string]string)
+	podIPv6IDByPodInterfaceKey := make(map[string]string)
 	podIPConfigState := make(map[string]cns.IPConfigurationStatus)
 
 	return &HTTPRestService{
-		Service:                  service,
-		store:                    service.Service.Store,
-		dockerClient:             dc,
-		imdsClient:               imdsClient,
-		ipamClient:               ic,
-		nmagentClient:            nmagentClient,
-		networkContainer:         nc,
-		PodIPIDByPodInterfaceKey: podIPIDByPodInterfaceKey,
-		PodIPConfigState:         podIPConfigState,
-		routingTable:             routingTable,
-		state:                    serviceState,
+		Service:                    service,
+		store:                      service.Service.Store,
+		containerStatusStore:       store.NewKeyedMap(containerStatusStoreKey),
+		containerRuntime:           dc,
+		imdsClient:                 imdsClient,
+		ipamClient:                 ic,
+		nmagentClient:              nmagentClient,
+		networkContainer:           nc,
+		PodIPIDByPodInterfaceKey:   podIPIDByPodInterfaceKey,
+		PodIPv6IDByPodInterfaceKey: podIPv6IDByPodInterfaceKey,
+		PodIPConfigState:           podIPConfigState,
+		routingTable:               routingTable,
+		state:                      serviceState,
 	}, nil
 }
 
+// SetContainerRuntimeClient replaces the Docker client the service creates networks with and resolves
+// the sandboxes of containers with. It must be called before the service is started.
+func (service *HTTPRestService) SetContainerRuntimeClient(client containerruntime.Client) {
+	service.containerRuntime = client
+}
+
 // Init starts the CNS listener.
 func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 	err := service.Initialize(config)
@@ -148,67 +192,98 @@ func (service *HTTPRestService) Init(config *common.ServiceConfig) error {
 		return err
 	}
 
-	service.restoreState()
+	if err = service.restoreState(); err != nil {
+		return err
+	}
+	service.restoreIPStateTransitions()
 	err = service.restoreNetworkState()
 	if err != nil {
 		logger.Errorf("[Azure CNS]  Failed to restore network state, err:%v.", err)
 		return err
 	}
+	service.Lock()
+	service.stateRestored = true
+	service.Unlock()
+
+	service.peerAuthorization = config.PeerAuthorization
+	service.ipRequests.configure(config.IPRequestQueue)
+	service.ipQuotas.configure(config.NamespaceIPQuotas)
+	service.allocatePendingProgrammingIPs = config.AllocatePendingProgrammingIPs
+	service.redactedCNSConfig = config.RedactedCNSConfig
 
 	// Add handlers.
 	listener := service.Listener
+	// mutating network, IPAM and NC handlers are only served to the requests allowed by authorizeRequest
+	authorized := service.newHandlerFuncWithPeerAuthorization
+	// every handler reports its latency and response codes by route
+	addHandler := func(route string, handler http.HandlerFunc) {
+		listener.AddHandler(route, newHandlerFuncWithMetrics(route, handler))
+	}
 	// default handlers
-	listener.AddHandler(cns.SetEnvironmentPath, service.setEnvironment)
-	listener.AddHandler(cns.CreateNetworkPath, service.createNetwork)
-	listener.AddHandler(cns.DeleteNetworkPath, service.deleteNetwork)
-	listener.AddHandler(cns.ReserveIPAddressPath, service.reserveIPAddress)
-	listener.AddHandler(cns.ReleaseIPAddressPath, service.releaseIPAddress)
-	listener.AddHandler(cns.GetHostLocalIPPath, service.getHostLocalIP)
-	listener.AddHandler(cns.GetIPAddressUtilizationPath, service.getIPAddressUtilization)
-	listener.AddHandler(cns.GetUnhealthyIPAddressesPath, service.getUnhealthyIPAddresses)
-	listener.AddHandler(cns.CreateOrUpdateNetworkContainer, service.createOrUpdateNetworkContainer)
-	listener.AddHandler(cns.DeleteNetworkContainer, service.deleteNetworkContainer)
-	listener.AddHandler(cns.GetInterfaceForContainer, service.getInterfaceForContainer)
-	listener.AddHandler(cns.SetOrchestratorType, service.setOrchestratorType)
-	listener.AddHandler(cns.GetNetworkContainerByOrchestratorContext, service.getNetworkContainerByOrchestratorContext)
-	listener.AddHandler(cns.AttachContainerToNetwork, service.attachNetworkContainerToNetwork)
-	listener.AddHandler(cns.DetachContainerFromNetwork, service.detachNetworkContainerFromNetwork)
-	listener.AddHandler(cns.CreateHnsNetworkPath, service.createHnsNetwork)
-	listener.AddHandler(cns.DeleteHnsNetworkPath, service.deleteHnsNetwork)
-	listener.AddHandler(cns.NumberOfCPUCoresPath, service.getNumberOfCPUCores)
-	listener.AddHandler(cns.CreateHostNCApipaEndpointPath, service.createHostNCApipaEndpoint)
-	listener.AddHandler(cns.DeleteHostNCApipaEndpointPath, service.deleteHostNCApipaEndpoint)
-	listener.AddHandler(cns.PublishNetworkContainer, service.publishNetworkContainer)
-	listener.AddHandler(cns.UnpublishNetworkContainer, service.unpublishNetworkContainer)
-	listener.AddHandler(cns.RequestIPConfig, newHandlerFuncWithHistogram(service.requestIPConfigHandler, httpRequestLatency))
-	listener.AddHandler(cns.ReleaseIPConfig, newHandlerFuncWithHistogram(service.releaseIPConfigHandler, httpRequestLatency))
-	listener.AddHandler(cns.NmAgentSupportedApisPath, service.nmAgentSupportedApisHandler)
-	listener.AddHandler(cns.GetIPAddresses, service.getIPAddressesHandler)
-	listener.AddHandler(cns.GetPodIPOrchestratorContext, service.getPodIPIDByOrchestratorContexthandler)
-	listener.AddHandler(cns.GetHTTPRestData, service.GetHTTPRestDataHandler)
+	addHandler(cns.SetEnvironmentPath, authorized(service.setEnvironment))
+	addHandler(cns.CreateNetworkPath, authorized(service.createNetwork))
+	addHandler(cns.DeleteNetworkPath, authorized(service.deleteNetwork))
+	addHandler(cns.ReserveIPAddressPath, authorized(service.reserveIPAddress))
+	addHandler(cns.ReleaseIPAddressPath, authorized(service.releaseIPAddress))
+	addHandler(cns.GetHostLocalIPPath, service.getHostLocalIP)
+	addHandler(cns.GetIPAddressUtilizationPath, service.getIPAddressUtilization)
+	addHandler(cns.GetUnhealthyIPAddressesPath, service.getUnhealthyIPAddresses)
+	addHandler(cns.CreateOrUpdateNetworkContainer, authorized(service.createOrUpdateNetworkContainer))
+	addHandler(cns.DeleteNetworkContainer, authorized(service.deleteNetworkContainer))
+	addHandler(cns.GetInterfaceForContainer, service.getInterfaceForContainer)
+	addHandler(cns.SetOrchestratorType, authorized(service.setOrchestratorType))
+	addHandler(cns.GetNetworkContainerByOrchestratorContext, service.getNetworkContainerByOrchestratorContext)
+	addHandler(cns.GetNetworkContainerStatus, service.getNetworkContainerStatus)
+	addHandler(cns.AttachContainerToNetwork, authorized(service.attachNetworkContainerToNetwork))
+	addHandler(cns.DetachContainerFromNetwork, authorized(service.detachNetworkContainerFromNetwork))
+	addHandler(cns.CreateHnsNetworkPath, authorized(service.createHnsNetwork))
+	addHandler(cns.DeleteHnsNetworkPath, authorized(service.deleteHnsNetwork))
+	addHandler(cns.NumberOfCPUCoresPath, service.getNumberOfCPUCores)
+	addHandler(cns.CreateHostNCApipaEndpointPath, authorized(service.createHostNCApipaEndpoint))
+	addHandler(cns.DeleteHostNCApipaEndpointPath, authorized(service.deleteHostNCApipaEndpoint))
+	addHandler(cns.PublishNetworkContainer, authorized(service.publishNetworkContainer))
+	addHandler(cns.UnpublishNetworkContainer, authorized(service.unpublishNetworkContainer))
+	addHandler(cns.RequestIPConfig, authorized(service.requestIPConfigHandler))
+	addHandler(cns.ReleaseIPConfig, authorized(service.releaseIPConfigHandler))
+	addHandler(cns.NmAgentSupportedApisPath, service.nmAgentSupportedApisHandler)
+	addHandler(cns.GetIPAddresses, service.getIPAddressesHandler)
+	addHandler(cns.GetPodIPOrchestratorContext, service.getPodIPIDByOrchestratorContexthandler)
+	addHandler(cns.GetHTTPRestData, service.GetHTTPRestDataHandler)
+	addHandler(cns.GetIPStateTransitions, service.getIPStateTransitionsHandler)
+	addHandler(cns.GetLeakedIPs, service.getLeakedIPsHandler)
+	addHandler(cns.GetDebugBundle, authorized(service.debugBundleHandler))
+	addHandler(cns.GetConfigStatus, service.getConfigStatusHandler)
+	addHandler(cns.HealthzPath, service.healthzHandler)
+	addHandler(cns.ReadyzPath, service.readyzHandler)
+	addHandler(cns.EventsPath, service.eventsHandler)
+	addHandler(cns.GetHealthReportPath, service.getHealthReport)
 
 	// handlers for v0.2
-	listener.AddHandler(cns.V2Prefix+cns.SetEnvironmentPath, service.setEnvironment)
-	listener.AddHandler(cns.V2Prefix+cns.CreateNetworkPath, service.createNetwork)
-	listener.AddHandler(cns.V2Prefix+cns.DeleteNetworkPath, service.deleteNetwork)
-	listener.AddHandler(cns.V2Prefix+cns.ReserveIPAddressPath, service.reserveIPAddress)
-	listener.AddHandler(cns.V2Prefix+cns.ReleaseIPAddressPath, service.releaseIPAddress)
-	listener.AddHandler(cns.V2Prefix+cns.GetHostLocalIPPath, service.getHostLocalIP)
-	listener.AddHandler(cns.V2Prefix+cns.GetIPAddressUtilizationPath, service.getIPAddressUtilization)
-	listener.AddHandler(cns.V2Prefix+cns.GetUnhealthyIPAddressesPath, service.getUnhealthyIPAddresses)
-	listener.AddHandler(cns.V2Prefix+cns.CreateOrUpdateNetworkContainer, service.createOrUpdateNetworkContainer)
-	listener.AddHandler(cns.V2Prefix+cns.DeleteNetworkContainer, service.deleteNetworkContainer)
-	listener.AddHandler(cns.V2Prefix+cns.GetInterfaceForContainer, service.getInterfaceForContainer)
-	listener.AddHandler(cns.V2Prefix+cns.SetOrchestratorType, service.setOrchestratorType)
-	listener.AddHandler(cns.V2Prefix+cns.GetNetworkContainerByOrchestratorContext, service.getNetworkContainerByOrchestratorContext)
-	listener.AddHandler(cns.V2Prefix+cns.AttachContainerToNetwork, service.attachNetworkContainerToNetwork)
-	listener.AddHandler(cns.V2Prefix+cns.DetachContainerFromNetwork, service.detachNetworkContainerFromNetwork)
-	listener.AddHandler(cns.V2Prefix+cns.CreateHnsNetworkPath, service.createHnsNetwork)
-	listener.AddHandler(cns.V2Prefix+cns.DeleteHnsNetworkPath, service.deleteHnsNetwork)
-	listener.AddHandler(cns.V2Prefix+cns.NumberOfCPUCoresPath, service.getNumberOfCPUCores)
-	listener.AddHandler(cns.V2Prefix+cns.CreateHostNCApipaEndpointPath, service.createHostNCApipaEndpoint)
-	listener.AddHandler(cns.V2Prefix+cns.DeleteHostNCApipaEndpointPath, service.deleteHostNCApipaEndpoint)
-	listener.AddHandler(cns.V2Prefix+cns.NmAgentSupportedApisPath, service.nmAgentSupportedApisHandler)
+	addHandler(cns.V2Prefix+cns.SetEnvironmentPath, authorized(service.setEnvironment))
+	addHandler(cns.V2Prefix+cns.CreateNetworkPath, authorized(service.createNetwork))
+	addHandler(cns.V2Prefix+cns.DeleteNetworkPath, authorized(service.deleteNetwork))
+	addHandler(cns.V2Prefix+cns.ReserveIPAddressPath, authorized(service.reserveIPAddress))
+	addHandler(cns.V2Prefix+cns.ReleaseIPAddressPath, authorized(service.releaseIPAddress))
+	addHandler(cns.V2Prefix+cns.GetHostLocalIPPath, service.getHostLocalIP)
+	addHandler(cns.V2Prefix+cns.GetIPAddressUtilizationPath, service.getIPAddressUtilization)
+	addHandler(cns.V2Prefix+cns.GetUnhealthyIPAddressesPath, service.getUnhealthyIPAddresses)
+	addHandler(cns.V2Prefix+cns.CreateOrUpdateNetworkContainer, authorized(service.createOrUpdateNetworkContainer))
+	addHandler(cns.V2Prefix+cns.DeleteNetworkContainer, authorized(service.deleteNetworkContainer))
+	addHandler(cns.V2Prefix+cns.GetInterfaceForContainer, service.getInterfaceForContainer)
+	addHandler(cns.V2Prefix+cns.SetOrchestratorType, authorized(service.setOrchestratorType))
+	addHandler(cns.V2Prefix+cns.GetNetworkContainerByOrchestratorContext, service.getNetworkContainerByOrchestratorContext)
+	addHandler(cns.V2Prefix+cns.GetNetworkContainerStatus, service.getNetworkContainerStatus)
+	addHandler(cns.V2Prefix+cns.AttachContainerToNetwork, authorized(service.attachNetworkContainerToNetwork))
+	addHandler(cns.V2Prefix+cns.DetachContainerFromNetwork, authorized(service.detachNetworkContainerFromNetwork))
+	addHandler(cns.V2Prefix+cns.CreateHnsNetworkPath, authorized(service.createHnsNetwork))
+	addHandler(cns.V2Prefix+cns.DeleteHnsNetworkPath, authorized(service.deleteHnsNetwork))
+	addHandler(cns.V2Prefix+cns.NumberOfCPUCoresPath, service.getNumberOfCPUCores)
+	addHandler(cns.V2Prefix+cns.CreateHostNCApipaEndpointPath, authorized(service.createHostNCApipaEndpoint))
+	addHandler(cns.V2Prefix+cns.DeleteHostNCApipaEndpointPath, authorized(service.deleteHostNCApipaEndpoint))
+	addHandler(cns.V2Prefix+cns.NmAgentSupportedApisPath, service.nmAgentSupportedApisHandler)
+
+	// resource-oriented v1 API, routed by path and method
+	listener.AddHandler(v1.Prefix+"/", service.newV1Router().ServeHTTP)
 
 	// Initialize HTTP client to be reused in CNS
 	connectionTimeout, _ := service.GetOption(acn.OptHttpConnectionTimeout).(int)
@@ -230,11 +305,58 @@ func (service *HTTPRestService) Start(config *common.ServiceConfig) error {
 		return err
 	}
 
+	if config.GRPCSettings.Enable {
+		if err := service.startGRPCServer(config); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.stopSavingTransitions = cancel
+	go service.saveIPStateTransitionsPeriodically(ctx, ipStateTransitionsSaveInterval)
+
+	return nil
+}
+
+// startGRPCServer serves the CNS IPAM gRPC API alongside the HTTP listener, on the unix socket if one is
+// set. Its mutating methods are authorized like the mutating HTTP APIs.
+func (service *HTTPRestService) startGRPCServer(config *common.ServiceConfig) error {
+	network, address := "tcp", net.JoinHostPort(config.GRPCSettings.IPAddress, strconv.Itoa(int(config.GRPCSettings.Port)))
+	if config.GRPCSettings.Socket != "" {
+		network, address = "unix", config.GRPCSettings.Socket
+		// Remove a socket left behind by a previous instance which did not exit cleanly.
+		if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+			logger.Errorf("[Azure CNS] Failed to remove stale gRPC socket %s, err:%v.", address, err)
+			return err
+		}
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		logger.Errorf("[Azure CNS] Failed to listen for gRPC on %s, err:%v.", address, err)
+		return err
+	}
+
+	service.grpcServer = rpc.NewServer(service, rpc.WithPeerAuthorization(service.authorizeRequest)...)
+	go func() {
+		if err := service.grpcServer.Serve(l); err != nil {
+			config.ErrChan <- err
+		}
+	}()
+
+	logger.Printf("[Azure CNS] Listening for gRPC on %s.", address)
 	return nil
 }
 
 // Stop stops the CNS.
 func (service *HTTPRestService) Stop() {
+	if service.grpcServer != nil {
+		service.grpcServer.Stop()
+	}
+	if service.stopSavingTransitions != nil {
+		service.stopSavingTransitions()
+	}
+	service.saveIPStateTransitions()
+	service.ipStateTransitions.close()
 	service.Uninitialize()
 	logger.Printf("[Azure CNS]  Service stopped.")
 }
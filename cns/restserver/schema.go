@@ -0,0 +1,24 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"encoding/json"
+
+	"github.com/Azure/azure-container-networking/store"
+)
+
+// cnsStateSchema is the ordered list of migrations of the persisted httpRestServiceState.
+// Add a migration with the next version whenever a change to the persisted fields of the
+// state or its network containers can't be read correctly by JSON leniency alone.
+var cnsStateSchema = store.Schema{
+	Name: "CNS",
+	Migrations: []store.Migration{
+		{
+			Version:     1,
+			Description: "add schema version",
+			Migrate:     func(map[string]json.RawMessage) error { return nil },
+		},
+	},
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreStateMigratesUnversionedState(t *testing.T) {
+	svc := getTestService()
+	fileName := filepath.Join(t.TempDir(), "azure-cns.json")
+	kvs, err := store.NewJsonFileStore(fileName)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Write(storeKey, map[string]interface{}{"NodeID": "node", "Initialized": true}))
+	svc.store = kvs
+
+	require.NoError(t, svc.restoreState())
+	assert.Equal(t, cnsStateSchema.Version(), svc.state.SchemaVersion)
+	assert.Equal(t, "node", svc.state.NodeID)
+	assert.True(t, svc.state.Initialized)
+}
+
+func TestRestoreStateRefusesNewerState(t *testing.T) {
+	svc := getTestService()
+	fileName := filepath.Join(t.TempDir(), "azure-cns.json")
+	kvs, err := store.NewJsonFileStore(fileName)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Write(storeKey, map[string]interface{}{store.SchemaVersionField: cnsStateSchema.Version() + 1, "NodeID": "node"}))
+	svc.store = kvs
+
+	err = svc.restoreState()
+	assert.True(t, errors.Is(err, store.ErrNewerStateSchemaVersion))
+	assert.FileExists(t, fileName, "newer state must not be removed")
+	assert.Empty(t, svc.state.NodeID)
+}
+
+func TestSaveStateKeepsNCsUnderTheirOwnKeys(t *testing.T) {
+	svc := getTestService()
+	kvs, err := store.NewBoltStore(filepath.Join(t.TempDir(), "azure-cns.db"))
+	require.NoError(t, err)
+	svc.store = kvs
+
+	svc.state.NodeID = "node"
+	svc.state.ContainerStatus = map[string]containerstatus{
+		"nc1": {ID: "nc1", HostVersion: "1"},
+		"nc2": {ID: "nc2", HostVersion: "2"},
+	}
+	require.NoError(t, svc.saveState())
+
+	var ncStatus containerstatus
+	require.NoError(t, kvs.Read(containerStatusStoreKey+"/nc1", &ncStatus))
+	assert.Equal(t, "1", ncStatus.HostVersion)
+	var state httpRestServiceState
+	require.NoError(t, kvs.Read(storeKey, &state))
+	assert.Empty(t, state.ContainerStatus)
+
+	delete(svc.state.ContainerStatus, "nc1")
+	require.NoError(t, svc.saveState())
+	assert.Equal(t, store.ErrKeyNotFound, kvs.Read(containerStatusStoreKey+"/nc1", &ncStatus))
+
+	restored := getTestService()
+	restored.store = kvs
+	require.NoError(t, restored.restoreState())
+	assert.Equal(t, "node", restored.state.NodeID)
+	require.Len(t, restored.state.ContainerStatus, 1)
+	assert.Equal(t, "2", restored.state.ContainerStatus["nc2"].HostVersion)
+}
+
+func TestRestoreStateKeepsNCsWhenTheStateKeyIsCorrupt(t *testing.T) {
+	svc := getTestService()
+	fileName := filepath.Join(t.TempDir(), "azure-cns.db")
+	kvs, err := store.NewBoltStore(fileName)
+	require.NoError(t, err)
+	svc.store = kvs
+
+	svc.state.NodeID = "node"
+	svc.state.ContainerStatus = map[string]containerstatus{"nc1": {ID: "nc1", HostVersion: "1"}}
+	require.NoError(t, svc.saveState())
+	require.NoError(t, kvs.Write(storeKey, "corrupt"))
+
+	restored := getTestService()
+	restored.store = kvs
+	require.NoError(t, restored.restoreState())
+	assert.FileExists(t, fileName)
+	assert.Empty(t, restored.state.NodeID)
+	require.Len(t, restored.state.ContainerStatus, 1)
+	assert.Equal(t, "1", restored.state.ContainerStatus["nc1"].HostVersion)
+
+	var state httpRestServiceState
+	assert.Equal(t, store.ErrKeyNotFound, kvs.Read(storeKey, &state))
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+)
+
+// errShuttingDown is returned for the IP requests CNS doesn't serve since it is shutting down.
+var errShuttingDown = errors.New("CNS is shutting down")
+
+// ipRequestIntake tracks the IP requests in flight, so that a shutdown can stop taking new ones and
+// wait for the others to finish. The zero value takes requests.
+type ipRequestIntake struct {
+	sync.Mutex
+	closed   bool
+	inFlight int
+	// closing is closed when the intake is closed, to wake the requests waiting for IPs
+	closing chan struct{}
+	// drained is closed when the intake is closed and no requests are in flight
+	drained chan struct{}
+}
+
+// admit starts a request for new IPs, or returns false if the intake is closed.
+func (i *ipRequestIntake) admit() bool {
+	i.Lock()
+	defer i.Unlock()
+	if i.closed {
+		return false
+	}
+	i.inFlight++
+	return true
+}
+
+// track starts a request which is served even while the intake is closed, such as an IP release.
+func (i *ipRequestIntake) track() {
+	i.Lock()
+	defer i.Unlock()
+	i.inFlight++
+}
+
+// done finishes a request started by admit or track.
+func (i *ipRequestIntake) done() {
+	i.Lock()
+	defer i.Unlock()
+	i.inFlight--
+	if i.closed && i.inFlight == 0 {
+		i.closeDrainedUnlocked()
+	}
+}
+
+// closingChan returns a channel which is closed when the intake is closed.
+func (i *ipRequestIntake) closingChan() <-chan struct{} {
+	i.Lock()
+	defer i.Unlock()
+	i.initUnlocked()
+	return i.closing
+}
+
+// close stops admitting requests, and returns a channel which is closed once no requests are in flight.
+func (i *ipRequestIntake) close() <-chan struct{} {
+	i.Lock()
+	defer i.Unlock()
+	i.initUnlocked()
+	if !i.closed {
+		i.closed = true
+		close(i.closing)
+		if i.inFlight == 0 {
+			i.closeDrainedUnlocked()
+		}
+	}
+	return i.drained
+}
+
+func (i *ipRequestIntake) initUnlocked() {
+	if i.closing == nil {
+		i.closing = make(chan struct{})
+		i.drained = make(chan struct{})
+	}
+}
+
+func (i *ipRequestIntake) closeDrainedUnlocked() {
+	select {
+	case <-i.drained:
+	default:
+		close(i.drained)
+	}
+}
+
+func (i *ipRequestIntake) inFlightCount() int {
+	i.Lock()
+	defer i.Unlock()
+	return i.inFlight
+}
+
+// SetNodeDeletionCheck sets the check of whether the node is being deleted, in which case a shutdown
+// releases the IPs which aren't allocated to pods. It must be called before the service is started.
+func (service *HTTPRestService) SetNodeDeletionCheck(check func(context.Context) (bool, error)) {
+	service.nodeDeletionCheck = check
+}
+
+// Shutdown stops CNS gracefully before the ctx is done. It stops allocating IPs, waits for the IP
+// requests in flight to finish and, if the node is being deleted, releases the IPs which aren't
+// allocated to pods back to DNC. Then it flushes the state and stops the service.
+func (service *HTTPRestService) Shutdown(ctx context.Context) {
+	service.drainForShutdown(ctx)
+	service.Stop()
+}
+
+// drainForShutdown does the steps of a graceful shutdown up to stopping the service.
+func (service *HTTPRestService) drainForShutdown(ctx context.Context) {
+	logger.Printf("[Azure CNS] Shutting down, no longer allocating IPs")
+	select {
+	case <-service.ipIntake.close():
+		logger.Printf("[Azure CNS] IP requests in flight finished")
+	case <-ctx.Done():
+		logger.Errorf("[Azure CNS] Stopping with %d IP requests in flight: %v", service.ipIntake.inFlightCount(), ctx.Err())
+	}
+
+	if service.nodeDeletionCheck != nil && service.IPAMPoolMonitor != nil {
+		if deleting, err := service.nodeDeletionCheck(ctx); err != nil {
+			logger.Errorf("[Azure CNS] Failed to check whether the node is being deleted, keeping its IPs: %v", err)
+		} else if deleting {
+			logger.Printf("[Azure CNS] Node is being deleted, releasing its unallocated IPs")
+			if err := service.IPAMPoolMonitor.ReleaseUnallocatedIPs(ctx); err != nil {
+				logger.Errorf("[Azure CNS] Failed to release the unallocated IPs of the node: %v", err)
+			}
+		}
+	}
+
+	service.Lock()
+	if err := service.saveState(); err != nil {
+		logger.Errorf("[Azure CNS] Failed to flush state on shutdown: %v", err)
+	}
+	service.Unlock()
+}
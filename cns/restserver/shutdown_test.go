@@ -0,0 +1,108 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newShutdownTestService creates a service which isn't shared with the other tests, since a shutdown
+// stops it from allocating IPs.
+func newShutdownTestService(t *testing.T) (*HTTPRestService, *fakes.IPAMPoolMonitorFake) {
+	var config common.ServiceConfig
+	httpsvc, err := NewHTTPRestService(&config, fakes.NewFakeImdsClient(), fakes.NewFakeNMAgentClient())
+	require.NoError(t, err)
+	service := httpsvc.(*HTTPRestService)
+	poolMonitor := &fakes.IPAMPoolMonitorFake{}
+	service.IPAMPoolMonitor = poolMonitor
+	service.state.OrchestratorType = cns.KubernetesCRD
+	return service, poolMonitor
+}
+
+func TestIPRequestIntake(t *testing.T) {
+	var intake ipRequestIntake
+	require.True(t, intake.admit())
+	intake.track()
+
+	drained := intake.close()
+	assert.False(t, intake.admit())
+	select {
+	case <-intake.closingChan():
+	default:
+		t.Fatal("the closing channel wasn't closed")
+	}
+
+	intake.done()
+	select {
+	case <-drained:
+		t.Fatal("drained with a request in flight")
+	default:
+	}
+
+	// releases are still served while draining
+	intake.track()
+	intake.done()
+	intake.done()
+	select {
+	case <-drained:
+	default:
+		t.Fatal("not drained without requests in flight")
+	}
+	assert.Equal(t, drained, intake.close())
+}
+
+func TestShutdownStopsIPRequests(t *testing.T) {
+	service, poolMonitor := newShutdownTestService(t)
+	service.drainForShutdown(context.Background())
+	assert.False(t, poolMonitor.ReleasedUnallocatedIPs)
+
+	podInfo := cns.NewPodInfo("shutdown-eth0", uuid.New().String(), "shutdown", "default")
+	resp := service.RequestIPConfig(newTestIPConfigRequest(t, podInfo, ""))
+	assert.Equal(t, types.ShuttingDown, resp.Response.ReturnCode)
+
+	// the pods of a draining node are still deleted
+	releaseResp := service.ReleaseIPConfig(newTestIPConfigRequest(t, podInfo, ""))
+	assert.NotEqual(t, types.ShuttingDown, releaseResp.ReturnCode)
+}
+
+func TestShutdownWakesQueuedIPRequests(t *testing.T) {
+	service, _ := newShutdownTestService(t)
+	service.ipRequests.configure(common.IPRequestQueueSettings{MaxLength: 1, MaxWait: time.Hour})
+
+	podInfo := cns.NewPodInfo("shutdownqueued-eth0", uuid.New().String(), "shutdownqueued", "default")
+	responses := make(chan cns.IPConfigResponse)
+	go func() {
+		responses <- service.RequestIPConfig(newTestIPConfigRequest(t, podInfo, ""))
+	}()
+	require.Eventually(t, func() bool { return !service.ipRequests.isEmpty() }, 5*time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	service.drainForShutdown(ctx)
+	require.NoError(t, ctx.Err(), "the queued request wasn't finished")
+	assert.Equal(t, types.ShuttingDown, (<-responses).Response.ReturnCode)
+}
+
+func TestShutdownReleasesUnallocatedIPsOfDeletedNode(t *testing.T) {
+	service, poolMonitor := newShutdownTestService(t)
+	service.SetNodeDeletionCheck(func(context.Context) (bool, error) { return false, errors.New("apiserver unreachable") })
+	service.drainForShutdown(context.Background())
+	assert.False(t, poolMonitor.ReleasedUnallocatedIPs)
+
+	service, poolMonitor = newShutdownTestService(t)
+	service.SetNodeDeletionCheck(func(context.Context) (bool, error) { return true, nil })
+	service.drainForShutdown(context.Background())
+	assert.True(t, poolMonitor.ReleasedUnallocatedIPs)
+}
@@ -6,14 +6,17 @@ package restserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"net"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/Azure/azure-container-networking/aitelemetry"
 	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
 	"github.com/Azure/azure-container-networking/cns/dockerclient"
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/cns/networkcontainers"
@@ -61,7 +64,13 @@ func (service *HTTPRestService) saveState() error {
 
 	// Update time stamp.
 	service.state.TimeStamp = time.Now()
-	err := service.store.Write(storeKey, &service.state)
+	service.state.SchemaVersion = cnsStateSchema.Version()
+	var err error
+	if kvs, ok := service.store.(store.TransactionalKeyValueStore); ok {
+		err = service.saveContainerStatus(kvs)
+	} else {
+		err = service.store.Write(storeKey, &service.state)
+	}
 	if err == nil {
 		logger.Printf("[Azure CNS]  State saved successfully.\n")
 	} else {
@@ -71,31 +80,92 @@ func (service *HTTPRestService) saveState() error {
 	return err
 }
 
-// restoreState restores CNS state from persistent store.
-func (service *HTTPRestService) restoreState() {
+// saveContainerStatus writes the state without its NC statuses under the CNS store key and only the
+// NC statuses which changed under keys of their own, in a single transaction.
+func (service *HTTPRestService) saveContainerStatus(kvs store.TransactionalKeyValueStore) error {
+	state := *service.state
+	state.ContainerStatus = make(map[string]containerstatus)
+	ncs := make(map[string]interface{}, len(service.state.ContainerStatus))
+	for ncID, ncStatus := range service.state.ContainerStatus {
+		ncs[ncID] = ncStatus
+	}
+
+	return service.containerStatusStore.Update(kvs, ncs, func(tx store.Transaction) error {
+		return tx.Write(storeKey, &state)
+	})
+}
+
+// restoreContainerStatus adds the NC statuses kept under keys of their own by transactional stores to the state.
+// If the state already holds NC statuses, it was migrated from a JSON file store since and they are kept instead.
+func (service *HTTPRestService) restoreContainerStatus() error {
+	if _, ok := service.store.(store.TransactionalKeyValueStore); !ok {
+		return nil
+	}
+
+	migrated := len(service.state.ContainerStatus) > 0
+	if service.state.ContainerStatus == nil {
+		service.state.ContainerStatus = make(map[string]containerstatus)
+	}
+	return service.containerStatusStore.Read(service.store, func(ncID string, raw json.RawMessage) error {
+		if migrated {
+			return nil
+		}
+		var ncStatus containerstatus
+		if err := json.Unmarshal(raw, &ncStatus); err != nil {
+			return fmt.Errorf("failed to decode the status of NC %s: %w", ncID, err)
+		}
+		service.state.ContainerStatus[ncID] = ncStatus
+		return nil
+	})
+}
+
+// restoreState restores CNS state from persistent store, migrating it to the current schema.
+// State written by a newer CNS is refused rather than discarded, so that a rollback can't lose it.
+func (service *HTTPRestService) restoreState() error {
 	logger.Printf("[Azure CNS] restoreState")
 
 	// Skip if a store is not provided.
 	if service.store == nil {
 		logger.Printf("[Azure CNS]  store not initialized.")
-		return
+		return nil
 	}
 
 	// Read any persisted state.
-	err := service.store.Read(storeKey, &service.state)
+	_, err := cnsStateSchema.Read(service.store, storeKey, &service.state)
 	if err != nil {
+		kvs, transactional := service.store.(store.TransactionalKeyValueStore)
 		if err == store.ErrKeyNotFound {
-			// Nothing to restore.
+			// Nothing to restore, but for the NC statuses kept under keys of their own.
 			logger.Printf("[Azure CNS]  No state to restore.\n")
+		} else if errors.Is(err, store.ErrNewerStateSchemaVersion) || err == store.ErrUnsupportedStoreSchemaVersion {
+			logger.Errorf("[Azure CNS]  Refusing to restore state, err:%v", err)
+			return err
+		} else if transactional {
+			// Removing the store would also remove the NC statuses kept under keys of their own, so only the state key is.
+			logger.Errorf("[Azure CNS]  Failed to restore state, err:%v. Removing the state key", err)
+			*service.state = httpRestServiceState{}
+			if err = kvs.Update(func(tx store.Transaction) error { return tx.Delete(storeKey) }); err != nil {
+				logger.Errorf("[Azure CNS]  Failed to remove the state key, err:%v", err)
+				return err
+			}
 		} else {
 			logger.Errorf("[Azure CNS]  Failed to restore state, err:%v. Removing azure-cns.json", err)
 			service.store.Remove()
+			return nil
 		}
 
-		return
+		if !transactional {
+			return nil
+		}
+	}
+
+	if err = service.restoreContainerStatus(); err != nil {
+		logger.Errorf("[Azure CNS]  Failed to restore NC statuses, err:%v", err)
+		return err
 	}
 
 	logger.Printf("[Azure CNS]  Restored state, %+v\n", service.state)
+	return nil
 }
 
 func (service *HTTPRestService) saveNetworkContainerGoalState(
@@ -105,6 +175,15 @@ func (service *HTTPRestService) saveNetworkContainerGoalState(
 	service.Lock()
 	defer service.Unlock()
 
+	_, returnCode, returnMessage := service.saveNetworkContainerGoalStateUntransacted(req)
+	return returnCode, returnMessage
+}
+
+// saveNetworkContainerGoalStateUntransacted saves the goal state of the NC and returns whether the NC is new.
+// Note: this func is an untransacted API as the caller will take a Service lock
+func (service *HTTPRestService) saveNetworkContainerGoalStateUntransacted(
+	req cns.CreateNetworkContainerRequest,
+) (bool, types.ResponseCode, string) {
 	var (
 		hostVersion                string
 		existingSecondaryIPConfigs map[string]cns.SecondaryIPConfig // uuid is key
@@ -139,6 +218,7 @@ func (service *HTTPRestService) saveNetworkContainerGoalState(
 			HostVersion:                   hostVersion,
 			VfpUpdateComplete:             vfpUpdateComplete,
 		}
+	observeNCVersions(req.NetworkContainerid, req.Version, hostVersion)
 
 	switch req.NetworkContainerType {
 	case cns.AzureContainerInstance:
@@ -169,7 +249,7 @@ func (service *HTTPRestService) saveNetworkContainerGoalState(
 			podInfo, err := cns.UnmarshalPodInfo(req.OrchestratorContext)
 			if err != nil {
 				errBuf := fmt.Sprintf("Unmarshalling %s failed with error %v", req.NetworkContainerType, err)
-				return types.UnexpectedError, errBuf
+				return false, types.UnexpectedError, errBuf
 			}
 
 			logger.Printf("Pod info %v", podInfo)
@@ -184,22 +264,27 @@ func (service *HTTPRestService) saveNetworkContainerGoalState(
 			// Validate and Update the SecondaryIpConfig state
 			returnCode, returnMesage := service.updateIPConfigsStateUntransacted(req, existingSecondaryIPConfigs, hostVersion)
 			if returnCode != 0 {
-				return returnCode, returnMesage
+				return false, returnCode, returnMesage
 			}
 		default:
 			errMsg := fmt.Sprintf("Unsupported orchestrator type: %s", service.state.OrchestratorType)
 			logger.Errorf(errMsg)
-			return types.UnsupportedOrchestratorType, errMsg
+			return false, types.UnsupportedOrchestratorType, errMsg
 		}
 
 	default:
 		errMsg := fmt.Sprintf("Unsupported network container type %s", req.NetworkContainerType)
 		logger.Errorf(errMsg)
-		return types.UnsupportedNetworkContainerType, errMsg
+		return false, types.UnsupportedNetworkContainerType, errMsg
 	}
 
 	service.saveState()
-	return 0, ""
+	if ok {
+		service.publishNCEvent(cns.NetworkContainerUpdated, service.state.ContainerStatus[req.NetworkContainerid])
+	} else {
+		service.publishNCEvent(cns.NetworkContainerCreated, service.state.ContainerStatus[req.NetworkContainerid])
+	}
+	return !ok, 0, ""
 }
 
 // This func will compute the deltaIpConfigState which needs to be updated (Added or Deleted) from the inmemory map
@@ -286,15 +371,21 @@ func (service *HTTPRestService) addIPConfigStateUntransacted(ncID string, hostVe
 		}
 		// add the new State
 		ipconfigStatus := cns.IPConfigurationStatus{
-			NCID:      ncID,
-			ID:        ipID,
-			IPAddress: ipconfig.IPAddress,
-			State:     newIPCNSStatus,
-			PodInfo:   nil,
+			NCID:           ncID,
+			ID:             ipID,
+			IPAddress:      ipconfig.IPAddress,
+			State:          newIPCNSStatus,
+			PodInfo:        nil,
+			StateChangedAt: time.Now(),
 		}
 		logger.Printf("[Azure-Cns] Add IP %s as %s", ipconfig.IPAddress, newIPCNSStatus)
 
 		service.PodIPConfigState[ipID] = ipconfigStatus
+		service.publishIPConfigEvent(cns.IPConfigAdded, ipconfigStatus, "")
+		if newIPCNSStatus == cns.Available {
+			service.ipRequests.signalIPAvailable()
+		}
+		service.recordIPStateTransition(ipconfigStatus, "", newIPCNSStatus, causeNCSecondaryIPAdded)
 
 		// Todo Update batch API and maintain the count
 	}
@@ -330,15 +421,20 @@ func (service *HTTPRestService) removeToBeDeletedIPStateUntransacted(
 	}
 
 	// Delete this ip from PODIpConfigState Map
+	ipConfigStatus, exists := service.PodIPConfigState[ipID]
 	logger.Printf("[Azure-Cns] Delete the PodIpConfigState, IpId: %s, IPConfigStatus: %v",
 		ipID,
-		service.PodIPConfigState[ipID])
+		ipConfigStatus)
 	delete(service.PodIPConfigState, ipID)
+	if exists {
+		service.publishIPConfigEvent(cns.IPConfigDeleted, ipConfigStatus, ipConfigStatus.State)
+		service.recordIPStateTransition(ipConfigStatus, ipConfigStatus.State, "", causeNCSecondaryIPRemoved)
+	}
 	return 0, ""
 }
 
 func (service *HTTPRestService) getNetworkContainerResponse(
-	req cns.GetNetworkContainerRequest,
+	ctx context.Context, req cns.GetNetworkContainerRequest,
 ) cns.GetNetworkContainerResponse {
 	var (
 		containerID                 string
@@ -374,7 +470,7 @@ func (service *HTTPRestService) getNetworkContainerResponse(
 		if exists {
 			// If the goal state is available with CNS, check if the NC is pending VFP programming
 			waitingForUpdate, getNetworkContainerResponse.Response.ReturnCode, getNetworkContainerResponse.Response.Message =
-				service.isNCWaitingForUpdate(service.state.ContainerStatus[containerID].CreateNetworkContainerRequest.Version, containerID)
+				service.isNCWaitingForUpdate(ctx, service.state.ContainerStatus[containerID].CreateNetworkContainerRequest.Version, containerID)
 			// If the return code is not success, return the error to the caller
 			if getNetworkContainerResponse.Response.ReturnCode == types.NetworkContainerVfpProgramPending {
 				logger.Errorf("[Azure-CNS] isNCWaitingForUpdate failed for NC: %s with error: %s",
@@ -405,7 +501,7 @@ func (service *HTTPRestService) getNetworkContainerResponse(
 			)
 
 			service.Unlock()
-			getNetworkContainerResponse.Response.ReturnCode, getNetworkContainerResponse.Response.Message = service.SyncNodeStatus(dncEP, infraVnet, nodeID, req.OrchestratorContext)
+			getNetworkContainerResponse.Response.ReturnCode, getNetworkContainerResponse.Response.Message = service.SyncNodeStatus(ctx, dncEP, infraVnet, nodeID, req.OrchestratorContext)
 			service.Lock()
 			if getNetworkContainerResponse.Response.ReturnCode == types.NotFound {
 				return getNetworkContainerResponse
@@ -493,7 +589,7 @@ func (service *HTTPRestService) restoreNetworkState() error {
 	return nil
 }
 
-func (service *HTTPRestService) attachOrDetachHelper(req cns.ConfigureContainerNetworkingRequest, operation, method string) cns.Response {
+func (service *HTTPRestService) attachOrDetachHelper(ctx context.Context, req cns.ConfigureContainerNetworkingRequest, operation, method string) cns.Response {
 	if method != "POST" {
 		return cns.Response{
 			ReturnCode: types.InvalidParameter,
@@ -517,7 +613,7 @@ func (service *HTTPRestService) attachOrDetachHelper(req cns.ConfigureContainerN
 	if service.ChannelMode == cns.Managed && operation == attach {
 		if ok {
 			if !existing.VfpUpdateComplete {
-				_, returnCode, message := service.isNCWaitingForUpdate(existing.CreateNetworkContainerRequest.Version, req.NetworkContainerid)
+				_, returnCode, message := service.isNCWaitingForUpdate(ctx, existing.CreateNetworkContainerRequest.Version, req.NetworkContainerid)
 				if returnCode == types.NetworkContainerVfpProgramPending {
 					return cns.Response{
 						ReturnCode: returnCode,
@@ -532,7 +628,7 @@ func (service *HTTPRestService) attachOrDetachHelper(req cns.ConfigureContainerN
 				nodeID    = service.GetOption(acn.OptNodeID).(string)
 			)
 
-			returnCode, msg := service.SyncNodeStatus(dncEP, infraVnet, nodeID, json.RawMessage{})
+			returnCode, msg := service.SyncNodeStatus(ctx, dncEP, infraVnet, nodeID, json.RawMessage{})
 			if returnCode != types.Success {
 				return cns.Response{
 					ReturnCode: returnCode,
@@ -557,19 +653,27 @@ func (service *HTTPRestService) attachOrDetachHelper(req cns.ConfigureContainerN
 		if err != nil {
 			returnCode = types.UnexpectedError
 			returnMessage = fmt.Sprintf("Unmarshalling orchestrator context failed with error %+v", err)
-		} else {
-			nc := service.networkContainer
-			netPluginConfig := service.getNetPluginDetails()
-			switch operation {
-			case attach:
-				err = nc.Attach(podInfo, req.Containerid, netPluginConfig)
-			case detach:
-				err = nc.Detach(podInfo, req.Containerid, netPluginConfig)
-			}
-			if err != nil {
-				returnCode = types.UnexpectedError
-				returnMessage = fmt.Sprintf("[Azure CNS] Error. "+operation+"ContainerToNetwork failed %+v", err.Error())
-			}
+			break
+		}
+
+		sandbox, err := service.sandbox(ctx, req.Containerid, operation)
+		if err != nil {
+			returnCode = types.UnexpectedError
+			returnMessage = fmt.Sprintf("[Azure CNS] Error. Failed to find the sandbox of container %s: %v", req.Containerid, err)
+			break
+		}
+
+		nc := service.networkContainer
+		netPluginConfig := service.getNetPluginDetails()
+		switch operation {
+		case attach:
+			err = nc.Attach(podInfo, sandbox, netPluginConfig)
+		case detach:
+			err = nc.Detach(podInfo, sandbox, netPluginConfig)
+		}
+		if err != nil {
+			returnCode = types.UnexpectedError
+			returnMessage = fmt.Sprintf("[Azure CNS] Error. "+operation+"ContainerToNetwork failed %+v", err.Error())
 		}
 
 	default:
@@ -583,6 +687,18 @@ func (service *HTTPRestService) attachOrDetachHelper(req cns.ConfigureContainerN
 	}
 }
 
+// sandbox returns the sandbox of the container the network container is attached to or detached from.
+// A container which is gone is detached from the network container with no network namespace,
+// so that the CNI plugin still deletes its endpoint.
+func (service *HTTPRestService) sandbox(ctx context.Context, containerID, operation string) (containerruntime.Sandbox, error) {
+	sandbox, err := service.containerRuntime.Sandbox(ctx, containerID)
+	if operation == detach && errors.Is(err, containerruntime.ErrSandboxNotFound) {
+		logger.Printf("[Azure CNS] Detaching container %s which is gone: %v", containerID, err)
+		return containerruntime.Sandbox{ID: containerID, NetNS: containerruntime.NoNetNS}, nil
+	}
+	return sandbox, err
+}
+
 func (service *HTTPRestService) getNetPluginDetails() *networkcontainers.NetPluginConfiguration {
 	pluginBinPath, _ := service.GetOption(acn.OptNetPluginPath).(string)
 	configPath, _ := service.GetOption(acn.OptNetPluginConfigFile).(string)
@@ -618,14 +734,16 @@ func (service *HTTPRestService) setNetworkStateJoined(networkID string) {
 
 // Join Network by calling nmagent
 func (service *HTTPRestService) joinNetwork(
+	ctx context.Context,
 	networkID string,
-	joinNetworkURL string) (*http.Response, error, error) {
+	joinNetworkURL string) (*nmagentclient.Response, error, error) {
 	var err error
-	joinResponse, joinErr := nmagentclient.JoinNetwork(
+	joinResponse, joinErr := service.nmagentClient.JoinNetwork(
+		ctx,
 		networkID,
 		joinNetworkURL)
 
-	if joinErr == nil && joinResponse.StatusCode == http.StatusOK {
+	if joinErr == nil {
 		// Network joined successfully
 		service.setNetworkStateJoined(networkID)
 		logger.Printf("[Azure-CNS] setNetworkStateJoined for network: %s", networkID)
@@ -636,7 +754,7 @@ func (service *HTTPRestService) joinNetwork(
 	return joinResponse, joinErr, err
 }
 
-func logNCSnapshot(createNetworkContainerRequest cns.CreateNetworkContainerRequest) {
+func (service *HTTPRestService) logNCSnapshot(createNetworkContainerRequest cns.CreateNetworkContainerRequest) {
 	aiEvent := aitelemetry.Event{
 		EventName:  logger.CnsNCSnapshotEventStr,
 		Properties: make(map[string]string),
@@ -653,6 +771,12 @@ func logNCSnapshot(createNetworkContainerRequest cns.CreateNetworkContainerReque
 	aiEvent.Properties[logger.NetworkContainerTypeStr] = createNetworkContainerRequest.NetworkContainerType
 	aiEvent.Properties[logger.OrchestratorContextStr] = fmt.Sprintf("%s", createNetworkContainerRequest.OrchestratorContext)
 
+	transitions := service.GetIPStateTransitions(cns.GetIPStateTransitionsRequest{NCID: createNetworkContainerRequest.NetworkContainerid})
+	if len(transitions) > ncSnapshotIPStateTransitions {
+		transitions = transitions[len(transitions)-ncSnapshotIPStateTransitions:]
+	}
+	aiEvent.Properties[logger.IPStateTransitionsStr] = fmt.Sprintf("%+v", transitions)
+
 	// TODO - Add for SecondaryIPs (Task: https://msazure.visualstudio.com/One/_workitems/edit/7711831)
 
 	logger.LogEvent(aiEvent)
@@ -661,27 +785,17 @@ func logNCSnapshot(createNetworkContainerRequest cns.CreateNetworkContainerReque
 // Sends network container snapshots to App Insights telemetry.
 func (service *HTTPRestService) logNCSnapshots() {
 	for _, ncStatus := range service.state.ContainerStatus {
-		logNCSnapshot(ncStatus.CreateNetworkContainerRequest)
+		service.logNCSnapshot(ncStatus.CreateNetworkContainerRequest)
 	}
 
 	logger.Printf("[Azure CNS] Logging periodic NC snapshots. NC Count %d", len(service.state.ContainerStatus))
 }
 
 // Sets up periodic timer for sending network container snapshots
-func (service *HTTPRestService) SendNCSnapShotPeriodically(ctx context.Context, ncSnapshotIntervalInMinutes int) {
+func (service *HTTPRestService) SendNCSnapShotPeriodically(ctx context.Context, ncSnapshotInterval *common.Interval) {
 	// Emit snapshot on startup and then emit it periodically.
 	service.logNCSnapshots()
-
-	ticker := time.NewTicker(time.Minute * time.Duration(ncSnapshotIntervalInMinutes))
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			service.logNCSnapshots()
-		}
-	}
+	ncSnapshotInterval.Tick(ctx, service.logNCSnapshots)
 }
 
 func (service *HTTPRestService) validateIPConfigRequest(
@@ -717,6 +831,9 @@ func (service *HTTPRestService) populateIpConfigInfoUntransacted(ipConfigStatus
 	}
 
 	primaryIpConfiguration = ncStatus.CreateNetworkContainerRequest.IPConfiguration
+	if isIPv6Address(ipConfigStatus.IPAddress) {
+		primaryIpConfiguration = ncStatus.CreateNetworkContainerRequest.IPv6Configuration
+	}
 
 	podIpInfo.PodIPConfig = cns.IPSubnet{
 		IPAddress:    ipConfigStatus.IPAddress,
@@ -737,12 +854,41 @@ func (service *HTTPRestService) populateIpConfigInfoUntransacted(ipConfigStatus
 	return nil
 }
 
+// populateIPConfigInfosUntransacted returns the PodIpInfos of the IPConfigs, IPv4 first.
+func (service *HTTPRestService) populateIPConfigInfosUntransacted(ipConfigStatuses []cns.IPConfigurationStatus) ([]cns.PodIpInfo, error) {
+	sort.SliceStable(ipConfigStatuses, func(i, j int) bool {
+		return !isIPv6Address(ipConfigStatuses[i].IPAddress) && isIPv6Address(ipConfigStatuses[j].IPAddress)
+	})
+
+	podIPInfos := make([]cns.PodIpInfo, len(ipConfigStatuses))
+	for i := range ipConfigStatuses {
+		if err := service.populateIpConfigInfoUntransacted(ipConfigStatuses[i], &podIPInfos[i]); err != nil {
+			return nil, err
+		}
+	}
+	return podIPInfos, nil
+}
+
+// isIPv6Address returns whether the IP address is an IPv6 address.
+func isIPv6Address(ipAddress string) bool {
+	ip := net.ParseIP(ipAddress)
+	return ip != nil && ip.To4() == nil
+}
+
+// ipFamilyName returns the name of the IP family for logs and errors.
+func ipFamilyName(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
 // isNCWaitingForUpdate :- Determine whether NC version on NMA matches programmed version
 // Return error and waitingForUpdate as true only CNS gets response from NMAgent indicating
 // the VFP programming is pending
 // This returns success / waitingForUpdate as false in all other cases.
 func (service *HTTPRestService) isNCWaitingForUpdate(
-	ncVersion, ncid string,
+	ctx context.Context, ncVersion, ncid string,
 ) (waitingForUpdate bool, returnCode types.ResponseCode, message string) {
 	waitingForUpdate = true
 	ncStatus, ok := service.state.ContainerStatus[ncid]
@@ -764,28 +910,19 @@ func (service *HTTPRestService) isNCWaitingForUpdate(
 		return
 	}
 
-	resp, err := nmagentclient.GetNetworkContainerVersion(ncid, getNCVersionURL.(string))
+	ctx, cancel := context.WithTimeout(ctx, ncVersionCheckTimeout)
+	defer cancel()
+	versionResponse, err := service.nmagentClient.GetNetworkContainerVersion(ctx, ncid, getNCVersionURL.(string))
 	if err != nil {
 		logger.Printf("[Azure CNS] Failed to get NC version status from NMAgent with error: %+v. "+
 			"Skipping GetNCVersionStatus check from NMAgent", err)
 		returnCode = types.NetworkContainerVfpProgramCheckSkipped
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		logger.Printf("[Azure CNS] Failed to get NC version status from NMAgent with http status %d. "+
-			"Skipping GetNCVersionStatus check from NMAgent", resp.StatusCode)
-		returnCode = types.NetworkContainerVfpProgramCheckSkipped
-		return
-	}
 
-	var versionResponse nmagentclient.NMANetworkContainerResponse
-	rBytes, _ := ioutil.ReadAll(resp.Body)
-	json.Unmarshal(rBytes, &versionResponse)
 	if versionResponse.ResponseCode != "200" {
 		returnCode = types.NetworkContainerVfpProgramPending
-		message = fmt.Sprintf("Failed to get NC version status from NMAgent. NC: %s, Response %s", ncid, rBytes)
+		message = fmt.Sprintf("Failed to get NC version status from NMAgent. NC: %s, Response %+v", ncid, *versionResponse)
 		return
 	}
 
@@ -0,0 +1,339 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/cns"
+	v1 "github.com/Azure/azure-container-networking/cns/api/v1"
+	"github.com/Azure/azure-container-networking/cns/filter"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	gorillamux "github.com/gorilla/mux"
+)
+
+// v1IPConfigStates are the IP config states listed by the v1 API.
+var v1IPConfigStates = []cns.IPConfigState{cns.Allocated, cns.Available, cns.PendingProgramming, cns.PendingRelease}
+
+// v1HandlerFunc handles a request to an operation of the v1 API, and returns the status and body of the response.
+type v1HandlerFunc func(r *http.Request, params map[string]string) (int, interface{})
+
+// v1Handlers are the handlers of the v1 API operations by operation ID.
+func (service *HTTPRestService) v1Handlers() map[string]v1HandlerFunc {
+	return map[string]v1HandlerFunc{
+		"getOpenAPIDocument":     service.getOpenAPIDocumentV1,
+		"listNetworkContainers":  service.listNetworkContainersV1,
+		"getNetworkContainer":    service.getNetworkContainerV1,
+		"putNetworkContainer":    service.putNetworkContainerV1,
+		"deleteNetworkContainer": service.deleteNetworkContainerV1,
+		"listIPAddresses":        service.listIPAddressesV1,
+		"listPodIPAddresses":     service.listPodIPAddressesV1,
+		"requestPodIPAddresses":  service.requestPodIPAddressesV1,
+		"releasePodIPAddresses":  service.releasePodIPAddressesV1,
+	}
+}
+
+// newV1Router returns the router of the v1 API. The requests to each operation are validated against the
+// OpenAPI document before they are handled, mutating operations are only served to authorized peers, and
+// every operation reports its latency and response codes by path.
+func (service *HTTPRestService) newV1Router() *gorillamux.Router {
+	router := gorillamux.NewRouter()
+	handlers := service.v1Handlers()
+	for i := range v1.Operations {
+		op := &v1.Operations[i]
+		handler, ok := handlers[op.ID]
+		if !ok {
+			logger.Errorf("[Azure CNS] No handler for v1 operation %s", op.ID)
+			continue
+		}
+
+		handlerFunc := service.newV1HandlerFunc(op, handler)
+		if op.Mutating {
+			handlerFunc = service.newHandlerFuncWithPeerAuthorization(handlerFunc)
+		}
+		router.HandleFunc(op.Path, newHandlerFuncWithMetrics(op.Path, handlerFunc)).Methods(op.Method)
+	}
+
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeV1Response(w, http.StatusNotFound, &v1.Error{ReturnCode: types.NotFound, Message: "no v1 API resource at " + r.URL.Path})
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeV1Response(w, http.StatusMethodNotAllowed, &v1.Error{ReturnCode: types.UnsupportedVerb, Message: r.Method + " is not supported by " + r.URL.Path})
+	})
+	return router
+}
+
+// newV1HandlerFunc validates the requests to the operation, and writes the responses of the handler.
+func (service *HTTPRestService) newV1HandlerFunc(op *v1.Operation, handler v1HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := gorillamux.Vars(r)
+		if err := v1.ValidateRequest(op, r, params); err != nil {
+			logger.Errorf("[Azure CNS] Invalid %s request %s %s: %v", op.ID, r.Method, r.URL, err)
+			writeV1Response(w, http.StatusBadRequest, &v1.Error{ReturnCode: types.InvalidRequest, Message: err.Error()})
+			return
+		}
+
+		status, body := handler(r, params)
+		writeV1Response(w, status, body)
+		logger.Printf("[Azure CNS] %s %s %s responded %d", op.ID, r.Method, r.URL, status)
+	}
+}
+
+func writeV1Response(w http.ResponseWriter, status int, body interface{}) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Errorf("[Azure CNS] Failed to encode v1 response: %v", err)
+	}
+}
+
+// v1Error returns the status and body of the response to a request which failed with the code.
+func v1Error(code types.ResponseCode, message string) (int, interface{}) {
+	return v1StatusOf(code), &v1.Error{ReturnCode: code, Message: message}
+}
+
+// v1StatusOf returns the HTTP status of the responses to requests which failed with the code.
+func v1StatusOf(code types.ResponseCode) int {
+	switch code {
+	case types.Success:
+		return http.StatusOK
+	case types.InvalidParameter, types.InvalidRequest, types.NetworkContainerNotSpecified, types.MalformedSubnet,
+		types.InvalidPrimaryIPConfig, types.InvalidSecondaryIPConfig, types.UnsupportedNetworkContainerType,
+		types.EmptyOrchestratorContext, types.UnsupportedOrchestratorContext, types.UnsupportedNCVersion:
+		return http.StatusBadRequest
+	case types.UnauthorizedPeer, types.NamespaceIPQuotaExceeded:
+		return http.StatusForbidden
+	case types.UnknownContainerID, types.NotFound:
+		return http.StatusNotFound
+	case types.UnsupportedVerb:
+		return http.StatusMethodNotAllowed
+	case types.UnsupportedOrchestratorType, types.PrimaryCANotSame, types.InconsistentIPConfigState:
+		return http.StatusConflict
+	case types.IPConfigRequestThrottled:
+		return http.StatusTooManyRequests
+	case types.FailedToAllocateIPConfig, types.NetworkContainerVfpProgramPending, types.ShuttingDown:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (service *HTTPRestService) getOpenAPIDocumentV1(*http.Request, map[string]string) (int, interface{}) {
+	return http.StatusOK, v1.OpenAPI()
+}
+
+func (service *HTTPRestService) listNetworkContainersV1(*http.Request, map[string]string) (int, interface{}) {
+	service.RLock()
+	defer service.RUnlock()
+
+	list := v1.NetworkContainerList{Items: []v1.NetworkContainer{}}
+	for _, ncStatus := range service.state.ContainerStatus {
+		list.Items = append(list.Items, service.networkContainerV1Untransacted(ncStatus))
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].ID < list.Items[j].ID })
+	return http.StatusOK, list
+}
+
+func (service *HTTPRestService) getNetworkContainerV1(_ *http.Request, params map[string]string) (int, interface{}) {
+	service.RLock()
+	defer service.RUnlock()
+
+	ncStatus, ok := service.state.ContainerStatus[params["id"]]
+	if !ok {
+		return v1Error(types.UnknownContainerID, "network container "+params["id"]+" not found")
+	}
+	return http.StatusOK, service.networkContainerV1Untransacted(ncStatus)
+}
+
+func (service *HTTPRestService) putNetworkContainerV1(r *http.Request, params map[string]string) (int, interface{}) {
+	var req cns.CreateNetworkContainerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return v1Error(types.InvalidRequest, "failed to decode request: "+err.Error())
+	}
+	if req.NetworkContainerid == "" {
+		req.NetworkContainerid = params["id"]
+	}
+	if req.NetworkContainerid != params["id"] {
+		return v1Error(types.InvalidParameter, "NetworkContainerid "+req.NetworkContainerid+" is not the id in the path")
+	}
+
+	if code, message := service.createOrUpdateNetworkContainerOnHost(req); code != types.Success {
+		return v1Error(code, message)
+	}
+
+	// the NC is saved and read back under the same lock, so that the response is the NC this request saved.
+	service.Lock()
+	created, code, message := service.saveNetworkContainerGoalStateUntransacted(req)
+	if code != types.Success {
+		service.Unlock()
+		return v1Error(code, message)
+	}
+	nc := service.networkContainerV1Untransacted(service.state.ContainerStatus[req.NetworkContainerid])
+	service.Unlock()
+	service.logNCSnapshot(req)
+
+	if created {
+		return http.StatusCreated, nc
+	}
+	return http.StatusOK, nc
+}
+
+func (service *HTTPRestService) deleteNetworkContainerV1(_ *http.Request, params map[string]string) (int, interface{}) {
+	existed, code, message := service.deleteNetworkContainerByID(params["id"])
+	switch {
+	case !existed:
+		return v1Error(types.UnknownContainerID, "network container "+params["id"]+" not found")
+	case code != types.Success:
+		return v1Error(code, message)
+	}
+	return http.StatusNoContent, nil
+}
+
+func (service *HTTPRestService) listIPAddressesV1(r *http.Request, _ map[string]string) (int, interface{}) {
+	query := r.URL.Query()
+	limit := v1.DefaultIPsLimit
+	if l := query.Get("limit"); l != "" {
+		limit, _ = strconv.Atoi(l) // validated against the OpenAPI document
+	}
+	var after string
+	if token := query.Get("continue"); token != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return v1Error(types.InvalidParameter, "invalid continue token "+token)
+		}
+		after = string(decoded)
+	}
+
+	var states []cns.IPConfigState
+	for _, state := range query["state"] {
+		states = append(states, cns.IPConfigState(state))
+	}
+	if len(states) == 0 {
+		states = v1IPConfigStates
+	}
+
+	service.RLock()
+	ipConfigs := filter.MatchAnyIPConfigState(service.PodIPConfigState, filter.PredicatesForStates(states...)...)
+	service.RUnlock()
+	sort.Slice(ipConfigs, func(i, j int) bool { return ipConfigs[i].ID < ipConfigs[j].ID })
+
+	list := v1.IPAddressList{Items: []v1.IPAddress{}}
+	start := sort.Search(len(ipConfigs), func(i int) bool { return ipConfigs[i].ID > after })
+	for _, ipConfig := range ipConfigs[start:] {
+		if len(list.Items) == limit {
+			list.Continue = base64.RawURLEncoding.EncodeToString([]byte(list.Items[limit-1].ID))
+			break
+		}
+		list.Items = append(list.Items, ipAddressV1(ipConfig))
+	}
+	return http.StatusOK, list
+}
+
+func (service *HTTPRestService) listPodIPAddressesV1(_ *http.Request, params map[string]string) (int, interface{}) {
+	list := v1.IPAddressList{Items: []v1.IPAddress{}}
+
+	service.RLock()
+	for _, ipConfig := range service.PodIPConfigState {
+		if ipConfig.PodInfo != nil && ipConfig.PodInfo.Name() == params["name"] && ipConfig.PodInfo.Namespace() == params["namespace"] {
+			list.Items = append(list.Items, ipAddressV1(ipConfig))
+		}
+	}
+	service.RUnlock()
+
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].ID < list.Items[j].ID })
+	return http.StatusOK, list
+}
+
+func (service *HTTPRestService) requestPodIPAddressesV1(r *http.Request, params map[string]string) (int, interface{}) {
+	var req v1.PodIPsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return v1Error(types.InvalidRequest, "failed to decode request: "+err.Error())
+	}
+	ipconfigRequest, err := podIPConfigRequestV1(params, req.InterfaceID, req.InfraContainerID)
+	if err != nil {
+		return v1Error(types.UnexpectedError, err.Error())
+	}
+	ipconfigRequest.DesiredIPAddress = req.DesiredIPAddress
+
+	resp := service.RequestIPConfig(ipconfigRequest)
+	if resp.Response.ReturnCode != types.Success {
+		return v1Error(resp.Response.ReturnCode, resp.Response.Message)
+	}
+	podIPs := v1.PodIPs{IPs: []cns.PodIpInfo{resp.PodIpInfo}}
+	if resp.PodIpInfoV6 != nil {
+		podIPs.IPs = append(podIPs.IPs, *resp.PodIpInfoV6)
+	}
+	return http.StatusOK, podIPs
+}
+
+func (service *HTTPRestService) releasePodIPAddressesV1(r *http.Request, params map[string]string) (int, interface{}) {
+	query := r.URL.Query()
+	ipconfigRequest, err := podIPConfigRequestV1(params, query.Get("interfaceID"), query.Get("infraContainerID"))
+	if err != nil {
+		return v1Error(types.UnexpectedError, err.Error())
+	}
+
+	if resp := service.ReleaseIPConfig(ipconfigRequest); resp.ReturnCode != types.Success {
+		return v1Error(resp.ReturnCode, resp.Message)
+	}
+	return http.StatusNoContent, nil
+}
+
+// podIPConfigRequestV1 returns the IP config request for the network interface of the pod in the path.
+func podIPConfigRequestV1(params map[string]string, interfaceID, infraContainerID string) (cns.IPConfigRequest, error) {
+	orchestratorContext, err := json.Marshal(cns.KubernetesPodInfo{PodName: params["name"], PodNamespace: params["namespace"]})
+	return cns.IPConfigRequest{
+		PodInterfaceID:      interfaceID,
+		InfraContainerID:    infraContainerID,
+		OrchestratorContext: orchestratorContext,
+	}, err //nolint:wrapcheck // marshalling the strings can't fail
+}
+
+// networkContainerV1Untransacted returns the v1 resource of the NC.
+// Caller will acquire/release the service lock.
+func (service *HTTPRestService) networkContainerV1Untransacted(ncStatus containerstatus) v1.NetworkContainer {
+	nc := v1.NetworkContainer{
+		ID:                ncStatus.ID,
+		Type:              ncStatus.CreateNetworkContainerRequest.NetworkContainerType,
+		Version:           ncStatus.CreateNetworkContainerRequest.Version,
+		ProgrammedVersion: ncStatus.HostVersion,
+		IPCounts:          map[cns.IPConfigState]int{},
+	}
+	for _, state := range v1IPConfigStates {
+		nc.IPCounts[state] = 0
+	}
+	for _, ipConfig := range service.PodIPConfigState {
+		if ipConfig.NCID == ncStatus.ID {
+			nc.IPCounts[ipConfig.State]++
+		}
+	}
+	return nc
+}
+
+func ipAddressV1(ipConfig cns.IPConfigurationStatus) v1.IPAddress {
+	ip := v1.IPAddress{
+		ID:        ipConfig.ID,
+		NCID:      ipConfig.NCID,
+		IPAddress: ipConfig.IPAddress,
+		State:     ipConfig.State,
+	}
+	if ipConfig.PodInfo != nil {
+		ip.Pod = &v1.Pod{
+			Name:             ipConfig.PodInfo.Name(),
+			Namespace:        ipConfig.PodInfo.Namespace(),
+			InterfaceID:      ipConfig.PodInfo.InterfaceID(),
+			InfraContainerID: ipConfig.PodInfo.InfraContainerID(),
+		}
+	}
+	return ip
+}
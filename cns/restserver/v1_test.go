@@ -0,0 +1,210 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns"
+	v1 "github.com/Azure/azure-container-networking/cns/api/v1"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveV1 serves the request through the v1 router, and decodes the body of the response into out if it is set.
+func serveV1(t *testing.T, method, target string, body, out interface{}) int {
+	var reqBody bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&reqBody).Encode(body))
+	}
+	w := httptest.NewRecorder()
+	svc.newV1Router().ServeHTTP(w, httptest.NewRequest(method, target, &reqBody))
+	if out != nil {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(out), "response body: %s", w.Body.String())
+	}
+	return w.Code
+}
+
+func TestV1HandlersServeEveryOperation(t *testing.T) {
+	handlers := svc.v1Handlers()
+	assert.Len(t, handlers, len(v1.Operations))
+	for _, op := range v1.Operations {
+		assert.Contains(t, handlers, op.ID)
+	}
+}
+
+func TestV1GetOpenAPIDocument(t *testing.T) {
+	var doc map[string]interface{}
+	require.Equal(t, http.StatusOK, serveV1(t, http.MethodGet, v1.OpenAPIPath, nil, &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Contains(t, doc["paths"], v1.PodIPsPath)
+}
+
+func TestV1NetworkContainerLifecycle(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+
+	ncID := "testV1Nc"
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{uuid.New().String(): newSecondaryIPConfig("10.0.0.20", 0)}
+	req := generateNetworkContainerRequest(secondaryIPConfigs, "", "0")
+
+	var nc v1.NetworkContainer
+	require.Equal(t, http.StatusCreated, serveV1(t, http.MethodPut, "/v1/ncs/"+ncID, req, &nc))
+	assert.Equal(t, ncID, nc.ID)
+	assert.Equal(t, "0", nc.Version)
+	assert.Equal(t, 1, nc.IPCounts[cns.Available]+nc.IPCounts[cns.PendingProgramming])
+
+	req.NetworkContainerid = ncID
+	require.Equal(t, http.StatusOK, serveV1(t, http.MethodPut, "/v1/ncs/"+ncID, req, &nc))
+
+	var list v1.NetworkContainerList
+	require.Equal(t, http.StatusOK, serveV1(t, http.MethodGet, v1.NCsPath, nil, &list))
+	assert.Contains(t, list.Items, nc)
+
+	require.Equal(t, http.StatusNoContent, serveV1(t, http.MethodDelete, "/v1/ncs/"+ncID, nil, nil))
+
+	var errResp v1.Error
+	require.Equal(t, http.StatusNotFound, serveV1(t, http.MethodGet, "/v1/ncs/"+ncID, nil, &errResp))
+	assert.Equal(t, types.UnknownContainerID, errResp.ReturnCode)
+	assert.Equal(t, http.StatusNotFound, serveV1(t, http.MethodDelete, "/v1/ncs/"+ncID, nil, nil))
+}
+
+func TestV1ConcurrentPutsCreateTheNetworkContainerOnce(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+
+	ncID := "testV1ConcurrentNc"
+	req := generateNetworkContainerRequest(map[string]cns.SecondaryIPConfig{uuid.New().String(): newSecondaryIPConfig("10.0.0.21", 0)}, ncID, "0")
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	const puts = 8
+	codes := make(chan int, puts)
+	var wg sync.WaitGroup
+	for i := 0; i < puts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			svc.newV1Router().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/ncs/"+ncID, bytes.NewReader(body)))
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		if code == http.StatusCreated {
+			created++
+		} else {
+			assert.Equal(t, http.StatusOK, code)
+		}
+	}
+	assert.Equal(t, 1, created)
+	assert.Equal(t, http.StatusNoContent, serveV1(t, http.MethodDelete, "/v1/ncs/"+ncID, nil, nil))
+}
+
+func TestV1PutNetworkContainerWithMismatchedID(t *testing.T) {
+	req := generateNetworkContainerRequest(map[string]cns.SecondaryIPConfig{}, "otherNc", "0")
+	var errResp v1.Error
+	require.Equal(t, http.StatusBadRequest, serveV1(t, http.MethodPut, "/v1/ncs/testV1Nc", req, &errResp))
+	assert.Equal(t, types.InvalidParameter, errResp.ReturnCode)
+}
+
+func TestV1ListIPAddressesPaginates(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{}
+	for _, ip := range []string{"10.0.0.21", "10.0.0.22", "10.0.0.23"} {
+		secondaryIPConfigs[uuid.New().String()] = newSecondaryIPConfig(ip, 0)
+	}
+	createNCReqInternal(t, secondaryIPConfigs, "testV1PaginatedNc", "0")
+
+	var ids []string
+	target := v1.IPsPath + "?limit=2"
+	for pages := 0; target != ""; pages++ {
+		require.Less(t, pages, 2)
+		var list v1.IPAddressList
+		require.Equal(t, http.StatusOK, serveV1(t, http.MethodGet, target, nil, &list))
+		for _, ip := range list.Items {
+			ids = append(ids, ip.ID)
+		}
+		target = ""
+		if list.Continue != "" {
+			target = v1.IPsPath + "?limit=2&continue=" + list.Continue
+		}
+	}
+	assert.Len(t, ids, len(svc.PodIPConfigState))
+	assert.IsIncreasing(t, ids)
+
+	var errResp v1.Error
+	assert.Equal(t, http.StatusBadRequest, serveV1(t, http.MethodGet, v1.IPsPath+"?continue=%25", nil, &errResp))
+}
+
+func TestV1ValidatesRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   interface{}
+		status int
+	}{
+		{name: "limit over the maximum", method: http.MethodGet, target: v1.IPsPath + "?limit=1001", status: http.StatusBadRequest},
+		{name: "unknown state", method: http.MethodGet, target: v1.IPsPath + "?state=Leaked", status: http.StatusBadRequest},
+		{name: "unknown query parameter", method: http.MethodGet, target: v1.IPsPath + "?page=2", status: http.StatusBadRequest},
+		{name: "missing interface ID", method: http.MethodPost, target: "/v1/pods/ns/pod/ips", body: v1.PodIPsRequest{}, status: http.StatusBadRequest},
+		{name: "invalid desired IP", method: http.MethodPost, target: "/v1/pods/ns/pod/ips", body: v1.PodIPsRequest{InterfaceID: "eth0", DesiredIPAddress: "10.0.0"}, status: http.StatusBadRequest},
+		{name: "missing release interface ID", method: http.MethodDelete, target: "/v1/pods/ns/pod/ips", status: http.StatusBadRequest},
+		{name: "unknown path", method: http.MethodGet, target: "/v1/nodes", status: http.StatusNotFound},
+		{name: "unsupported method", method: http.MethodPatch, target: v1.NCsPath, status: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var errResp v1.Error
+			require.Equal(t, tt.status, serveV1(t, tt.method, tt.target, tt.body, &errResp))
+			assert.NotEqual(t, types.Success, errResp.ReturnCode)
+		})
+	}
+}
+
+func TestV1PodIPAddresses(t *testing.T) {
+	restartService()
+	setEnv(t)
+	setOrchestratorTypeInternal(cns.KubernetesCRD)
+
+	secondaryIPConfigs := map[string]cns.SecondaryIPConfig{uuid.New().String(): newSecondaryIPConfig("10.0.0.24", 0)}
+	createNCReqInternal(t, secondaryIPConfigs, "testV1PodNc", "0")
+	nmagentServer.SetNCVersion("testV1PodNc", "0")
+	svc.allocatePendingProgrammingIPs = true
+	defer func() { svc.allocatePendingProgrammingIPs = false }()
+
+	podIPsPath := "/v1/pods/testv1namespace/testv1pod/ips"
+	var podIPs v1.PodIPs
+	require.Equal(t, http.StatusOK, serveV1(t, http.MethodPost, podIPsPath, v1.PodIPsRequest{InterfaceID: "testv1-eth0", InfraContainerID: "testv1"}, &podIPs))
+	require.Len(t, podIPs.IPs, 1)
+	assert.Equal(t, "10.0.0.24", podIPs.IPs[0].PodIPConfig.IPAddress)
+
+	var list v1.IPAddressList
+	require.Equal(t, http.StatusOK, serveV1(t, http.MethodGet, podIPsPath, nil, &list))
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, cns.Allocated, list.Items[0].State)
+	assert.Equal(t, &v1.Pod{Name: "testv1pod", Namespace: "testv1namespace", InterfaceID: "testv1-eth0", InfraContainerID: "testv1"}, list.Items[0].Pod)
+
+	require.Equal(t, http.StatusNoContent, serveV1(t, http.MethodDelete, podIPsPath+"?interfaceID=testv1-eth0&infraContainerID=testv1", nil, nil))
+	require.Equal(t, http.StatusOK, serveV1(t, http.MethodGet, podIPsPath, nil, &list))
+	assert.Empty(t, list.Items)
+}
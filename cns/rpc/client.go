@@ -0,0 +1,143 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	pb "github.com/Azure/azure-container-networking/proto/cnsipam/v1"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Error is returned by the Client when CNS fails a request.
+type Error struct {
+	Code    types.ResponseCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("[rpc] Code: %d (%s), Error: %s", e.Code, e.Code, e.Message)
+}
+
+// Client calls the CNS IPAM gRPC APIs.
+type Client struct {
+	conn *grpc.ClientConn
+	c    pb.CNSIPAMClient
+}
+
+// NewClient dials CNS at the target and returns a Client for it.
+func NewClient(ctx context.Context, target string, opts ...grpc.DialOption) (*Client, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithInsecure()}
+	}
+	conn, err := grpc.DialContext(ctx, target, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial CNS at %s", target)
+	}
+	return &Client{
+		conn: conn,
+		c:    pb.NewCNSIPAMClient(conn),
+	}, nil
+}
+
+// Close closes the connection to CNS.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// callError converts a failed gRPC call to an *Error when CNS returned a
+// ResponseCode in the trailer, otherwise returns the gRPC error.
+func callError(err error, trailer metadata.MD) error {
+	if err == nil {
+		return nil
+	}
+	if v := trailer.Get(ReturnCodeKey); len(v) > 0 {
+		if code, convErr := strconv.Atoi(v[0]); convErr == nil {
+			return &Error{Code: types.ResponseCode(code), Message: status.Convert(err).Message()}
+		}
+	}
+	return err
+}
+
+// RequestIPAddress requests an IPConfig for the pod from CNS.
+func (c *Client) RequestIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) (*cns.IPConfigResponse, error) {
+	req, err := ipConfigRequestToProto(ipconfig)
+	if err != nil {
+		return nil, err
+	}
+	var trailer metadata.MD
+	resp, err := c.c.RequestIPConfig(ctx, req, grpc.Trailer(&trailer))
+	if err != nil {
+		return nil, callError(err, trailer)
+	}
+	out := &cns.IPConfigResponse{
+		PodIpInfo: podIPInfoFromProto(resp.GetPodIpInfo()),
+		Response:  cns.Response{ReturnCode: types.Success},
+	}
+	if resp.GetPodIpInfoV6() != nil {
+		podIPInfoV6 := podIPInfoFromProto(resp.GetPodIpInfoV6())
+		out.PodIpInfoV6 = &podIPInfoV6
+	}
+	return out, nil
+}
+
+// ReleaseIPAddress releases the IPConfig allocated to the pod.
+func (c *Client) ReleaseIPAddress(ctx context.Context, ipconfig *cns.IPConfigRequest) error {
+	req, err := ipConfigRequestToProto(ipconfig)
+	if err != nil {
+		return err
+	}
+	var trailer metadata.MD
+	_, err = c.c.ReleaseIPConfig(ctx, req, grpc.Trailer(&trailer))
+	return callError(err, trailer)
+}
+
+// GetIPAddressesMatchingStates returns the IPConfigs in any of the passed states.
+func (c *Client) GetIPAddressesMatchingStates(ctx context.Context, stateFilter ...cns.IPConfigState) ([]cns.IPConfigurationStatus, error) {
+	resp, err := c.c.GetIPAddresses(ctx, &pb.GetIPAddressesRequest{StateFilter: statesToProtoSlice(stateFilter)})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]cns.IPConfigurationStatus, 0, len(resp.GetIpConfigurations()))
+	for _, s := range resp.GetIpConfigurations() {
+		out = append(out, ipConfigurationStatusFromProto(s))
+	}
+	return out, nil
+}
+
+// WatchIPConfigs streams changes to IPConfigs entering or leaving any of the
+// passed states, starting with an Added event for each matching IP. The
+// returned channel is closed when the context is cancelled or the stream
+// ends; the error channel then receives the reason, if any.
+func (c *Client) WatchIPConfigs(ctx context.Context, stateFilter ...cns.IPConfigState) (<-chan cns.IPConfigEvent, <-chan error, error) {
+	stream, err := c.c.WatchIPConfigs(ctx, &pb.WatchIPConfigsRequest{StateFilter: statesToProtoSlice(stateFilter)})
+	if err != nil {
+		return nil, nil, err
+	}
+	events := make(chan cns.IPConfigEvent)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(events)
+		defer close(errs)
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() == nil {
+					errs <- err
+				}
+				return
+			}
+			select {
+			case events <- ipConfigEventFromProto(event):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, errs, nil
+}
@@ -0,0 +1,224 @@
+package rpc
+
+import (
+	"encoding/json"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/types"
+	pb "github.com/Azure/azure-container-networking/proto/cnsipam/v1"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+var statesToProto = map[cns.IPConfigState]pb.IPConfigState{
+	cns.Available:          pb.IPConfigState_IP_CONFIG_STATE_AVAILABLE,
+	cns.Allocated:          pb.IPConfigState_IP_CONFIG_STATE_ALLOCATED,
+	cns.PendingRelease:     pb.IPConfigState_IP_CONFIG_STATE_PENDING_RELEASE,
+	cns.PendingProgramming: pb.IPConfigState_IP_CONFIG_STATE_PENDING_PROGRAMMING,
+}
+
+var statesFromProto = map[pb.IPConfigState]cns.IPConfigState{
+	pb.IPConfigState_IP_CONFIG_STATE_AVAILABLE:           cns.Available,
+	pb.IPConfigState_IP_CONFIG_STATE_ALLOCATED:           cns.Allocated,
+	pb.IPConfigState_IP_CONFIG_STATE_PENDING_RELEASE:     cns.PendingRelease,
+	pb.IPConfigState_IP_CONFIG_STATE_PENDING_PROGRAMMING: cns.PendingProgramming,
+}
+
+var eventTypesToProto = map[cns.IPConfigEventType]pb.EventType{
+	cns.IPConfigAdded:    pb.EventType_EVENT_TYPE_ADDED,
+	cns.IPConfigModified: pb.EventType_EVENT_TYPE_MODIFIED,
+	cns.IPConfigDeleted:  pb.EventType_EVENT_TYPE_DELETED,
+}
+
+var eventTypesFromProto = map[pb.EventType]cns.IPConfigEventType{
+	pb.EventType_EVENT_TYPE_ADDED:    cns.IPConfigAdded,
+	pb.EventType_EVENT_TYPE_MODIFIED: cns.IPConfigModified,
+	pb.EventType_EVENT_TYPE_DELETED:  cns.IPConfigDeleted,
+}
+
+// codeForResponseCode maps the CNS ResponseCode to the closest gRPC status code.
+func codeForResponseCode(code types.ResponseCode) codes.Code {
+	switch code {
+	case types.Success:
+		return codes.OK
+	case types.InvalidParameter, types.InvalidRequest, types.EmptyOrchestratorContext, types.UnsupportedOrchestratorContext:
+		return codes.InvalidArgument
+	case types.UnsupportedOrchestratorType:
+		return codes.FailedPrecondition
+	case types.UnauthorizedPeer:
+		return codes.PermissionDenied
+	case types.FailedToAllocateIPConfig:
+		return codes.ResourceExhausted
+	case types.IPConfigRequestThrottled, types.ShuttingDown:
+		return codes.Unavailable
+	case types.NotFound, types.UnknownContainerID:
+		return codes.NotFound
+	case types.UnexpectedError:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
+
+func statesToProtoSlice(states []cns.IPConfigState) []pb.IPConfigState {
+	out := make([]pb.IPConfigState, 0, len(states))
+	for _, s := range states {
+		out = append(out, statesToProto[s])
+	}
+	return out
+}
+
+// statesFromProtoSlice converts a state filter, which must not contain IP_CONFIG_STATE_UNSPECIFIED or unknown states
+// as dropping them would widen the filter.
+func statesFromProtoSlice(states []pb.IPConfigState) ([]cns.IPConfigState, error) {
+	out := make([]cns.IPConfigState, 0, len(states))
+	for _, s := range states {
+		state, ok := statesFromProto[s]
+		if !ok {
+			return nil, errors.Errorf("invalid state %v in the state filter", s)
+		}
+		out = append(out, state)
+	}
+	return out, nil
+}
+
+func podInfoToProto(podInfo cns.PodInfo) *pb.PodInfo {
+	if podInfo == nil {
+		return nil
+	}
+	return &pb.PodInfo{
+		Name:             podInfo.Name(),
+		Namespace:        podInfo.Namespace(),
+		InfraContainerId: podInfo.InfraContainerID(),
+		InterfaceId:      podInfo.InterfaceID(),
+	}
+}
+
+func podInfoFromProto(podInfo *pb.PodInfo) cns.PodInfo {
+	if podInfo == nil {
+		return nil
+	}
+	return cns.NewPodInfo(podInfo.GetInfraContainerId(), podInfo.GetInterfaceId(), podInfo.GetName(), podInfo.GetNamespace())
+}
+
+func ipConfigRequestToProto(req *cns.IPConfigRequest) (*pb.IPConfigRequest, error) {
+	var podInfo cns.KubernetesPodInfo
+	if len(req.OrchestratorContext) != 0 {
+		if err := json.Unmarshal(req.OrchestratorContext, &podInfo); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal orchestrator context")
+		}
+	}
+	return &pb.IPConfigRequest{
+		DesiredIpAddress: req.DesiredIPAddress,
+		PodInterfaceId:   req.PodInterfaceID,
+		InfraContainerId: req.InfraContainerID,
+		PodInfo: &pb.PodInfo{
+			Name:             podInfo.PodName,
+			Namespace:        podInfo.PodNamespace,
+			InfraContainerId: req.InfraContainerID,
+			InterfaceId:      req.PodInterfaceID,
+		},
+	}, nil
+}
+
+func ipConfigRequestFromProto(req *pb.IPConfigRequest) (cns.IPConfigRequest, error) {
+	out := cns.IPConfigRequest{
+		DesiredIPAddress: req.GetDesiredIpAddress(),
+		PodInterfaceID:   req.GetPodInterfaceId(),
+		InfraContainerID: req.GetInfraContainerId(),
+	}
+	if req.GetPodInfo() == nil {
+		return out, nil
+	}
+	orchestratorContext, err := json.Marshal(cns.KubernetesPodInfo{
+		PodName:      req.GetPodInfo().GetName(),
+		PodNamespace: req.GetPodInfo().GetNamespace(),
+	})
+	if err != nil {
+		return out, errors.Wrap(err, "failed to marshal orchestrator context")
+	}
+	out.OrchestratorContext = orchestratorContext
+	return out, nil
+}
+
+func ipSubnetToProto(s cns.IPSubnet) *pb.IPSubnet {
+	return &pb.IPSubnet{
+		IpAddress:    s.IPAddress,
+		PrefixLength: uint32(s.PrefixLength),
+	}
+}
+
+func ipSubnetFromProto(s *pb.IPSubnet) cns.IPSubnet {
+	return cns.IPSubnet{
+		IPAddress:    s.GetIpAddress(),
+		PrefixLength: uint8(s.GetPrefixLength()),
+	}
+}
+
+func podIPInfoToProto(info cns.PodIpInfo) *pb.PodIPInfo {
+	return &pb.PodIPInfo{
+		PodIpConfig: ipSubnetToProto(info.PodIPConfig),
+		NetworkContainerPrimaryIpConfig: &pb.IPConfiguration{
+			IpSubnet:         ipSubnetToProto(info.NetworkContainerPrimaryIPConfig.IPSubnet),
+			DnsServers:       info.NetworkContainerPrimaryIPConfig.DNSServers,
+			GatewayIpAddress: info.NetworkContainerPrimaryIPConfig.GatewayIPAddress,
+		},
+		HostPrimaryIpInfo: &pb.HostIPInfo{
+			Gateway:   info.HostPrimaryIPInfo.Gateway,
+			PrimaryIp: info.HostPrimaryIPInfo.PrimaryIP,
+			Subnet:    info.HostPrimaryIPInfo.Subnet,
+		},
+	}
+}
+
+func podIPInfoFromProto(info *pb.PodIPInfo) cns.PodIpInfo {
+	return cns.PodIpInfo{
+		PodIPConfig: ipSubnetFromProto(info.GetPodIpConfig()),
+		NetworkContainerPrimaryIPConfig: cns.IPConfiguration{
+			IPSubnet:         ipSubnetFromProto(info.GetNetworkContainerPrimaryIpConfig().GetIpSubnet()),
+			DNSServers:       info.GetNetworkContainerPrimaryIpConfig().GetDnsServers(),
+			GatewayIPAddress: info.GetNetworkContainerPrimaryIpConfig().GetGatewayIpAddress(),
+		},
+		HostPrimaryIPInfo: cns.HostIPInfo{
+			Gateway:   info.GetHostPrimaryIpInfo().GetGateway(),
+			PrimaryIP: info.GetHostPrimaryIpInfo().GetPrimaryIp(),
+			Subnet:    info.GetHostPrimaryIpInfo().GetSubnet(),
+		},
+	}
+}
+
+func ipConfigurationStatusToProto(s cns.IPConfigurationStatus) *pb.IPConfigurationStatus {
+	return &pb.IPConfigurationStatus{
+		NcId:      s.NCID,
+		Id:        s.ID,
+		IpAddress: s.IPAddress,
+		State:     statesToProto[s.State],
+		PodInfo:   podInfoToProto(s.PodInfo),
+	}
+}
+
+func ipConfigurationStatusFromProto(s *pb.IPConfigurationStatus) cns.IPConfigurationStatus {
+	return cns.IPConfigurationStatus{
+		NCID:      s.GetNcId(),
+		ID:        s.GetId(),
+		IPAddress: s.GetIpAddress(),
+		State:     statesFromProto[s.GetState()],
+		PodInfo:   podInfoFromProto(s.GetPodInfo()),
+	}
+}
+
+func ipConfigEventToProto(e cns.IPConfigEvent) *pb.IPConfigEvent {
+	return &pb.IPConfigEvent{
+		Type:            eventTypesToProto[e.Type],
+		IpConfiguration: ipConfigurationStatusToProto(e.IPConfigurationStatus),
+		PreviousState:   statesToProto[e.PreviousState],
+	}
+}
+
+func ipConfigEventFromProto(e *pb.IPConfigEvent) cns.IPConfigEvent {
+	return cns.IPConfigEvent{
+		Type:                  eventTypesFromProto[e.GetType()],
+		IPConfigurationStatus: ipConfigurationStatusFromProto(e.GetIpConfiguration()),
+		PreviousState:         statesFromProto[e.GetPreviousState()],
+	}
+}
@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	"net"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// mutatingMethods are the methods only served to the peers allowed by the authorization.
+var mutatingMethods = map[string]bool{
+	"/azure.cnsipam.v1.CNSIPAM/RequestIPConfig": true,
+	"/azure.cnsipam.v1.CNSIPAM/ReleaseIPConfig": true,
+}
+
+// connPeerAuthInfo carries the ConnPeer of a connection to its calls.
+type connPeerAuthInfo struct {
+	credentials.CommonAuthInfo
+	peer acn.ConnPeer
+}
+
+func (connPeerAuthInfo) AuthType() string {
+	return "conn-peer"
+}
+
+// connPeerCredentials are TransportCredentials without transport security which read the ConnPeer of
+// the connections, like the ConnContext of the unix socket HTTP listeners.
+type connPeerCredentials struct{}
+
+func (connPeerCredentials) ClientHandshake(_ context.Context, _ string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, connPeerAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}, nil
+}
+
+func (connPeerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, connPeerAuthInfo{
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity},
+		peer:           acn.NewConnPeer(conn),
+	}, nil
+}
+
+func (connPeerCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "insecure"}
+}
+
+func (c connPeerCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (connPeerCredentials) OverrideServerName(string) error {
+	return nil
+}
+
+// WithPeerAuthorization returns the ServerOptions which only serve the mutating methods when authorize
+// returns nil for their ctx. The ctx carries the acn.ConnPeer of the connection the call was received on.
+func WithPeerAuthorization(authorize func(context.Context) error) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.Creds(connPeerCredentials{}),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if !mutatingMethods[info.FullMethod] {
+				return handler(ctx, req)
+			}
+			if p, ok := peer.FromContext(ctx); ok {
+				if authInfo, ok := p.AuthInfo.(connPeerAuthInfo); ok {
+					ctx = acn.ContextWithConnPeer(ctx, authInfo.peer)
+				}
+			}
+			if err := authorize(ctx); err != nil {
+				logger.Errorf("[rpc] Rejecting %s: %v", info.FullMethod, err)
+				return nil, responseError(ctx, cns.Response{ReturnCode: types.UnauthorizedPeer, Message: err.Error()})
+			}
+			return handler(ctx, req)
+		}),
+	}
+}
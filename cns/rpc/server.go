@@ -0,0 +1,172 @@
+// Package rpc serves the CNS IPAM APIs over gRPC, using the contract in
+// proto/cnsipam, and provides a client for them.
+package rpc
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/types"
+	pb "github.com/Azure/azure-container-networking/proto/cnsipam/v1"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ReturnCodeKey is the trailer metadata key carrying the CNS types.ResponseCode
+// of a failed call, so clients can recover the exact CNS error.
+const ReturnCodeKey = "cns-return-code"
+
+// IPAMService is the CNS IPAM state the gRPC server is backed by.
+type IPAMService interface {
+	RequestIPConfig(cns.IPConfigRequest) cns.IPConfigResponse
+	ReleaseIPConfig(cns.IPConfigRequest) cns.Response
+	GetIPConfigsMatchingStates(...cns.IPConfigState) []cns.IPConfigurationStatus
+	WatchIPConfigs() ([]cns.IPConfigurationStatus, <-chan cns.IPConfigEvent, func())
+}
+
+// Server is a gRPC server for the CNS IPAM APIs.
+type Server struct {
+	svc  IPAMService
+	grpc *grpc.Server
+}
+
+var _ pb.CNSIPAMServer = (*ipamServer)(nil)
+
+// ipamServer implements the generated CNSIPAMServer on an IPAMService.
+type ipamServer struct {
+	svc IPAMService
+}
+
+// NewServer creates a new gRPC Server for the passed IPAMService.
+func NewServer(svc IPAMService, opts ...grpc.ServerOption) *Server {
+	s := &Server{
+		svc:  svc,
+		grpc: grpc.NewServer(opts...),
+	}
+	pb.RegisterCNSIPAMServer(s.grpc, &ipamServer{svc: svc})
+	return s
+}
+
+// Serve accepts connections on the listener until Stop is called.
+func (s *Server) Serve(l net.Listener) error {
+	logger.Printf("[rpc] Serving CNS IPAM gRPC on %s", l.Addr())
+	return s.grpc.Serve(l)
+}
+
+// Stop closes the listener and all open connections, including watch streams.
+func (s *Server) Stop() {
+	s.grpc.Stop()
+}
+
+func responseError(ctx context.Context, resp cns.Response) error {
+	if resp.ReturnCode == types.Success {
+		return nil
+	}
+	if err := grpc.SetTrailer(ctx, metadata.Pairs(ReturnCodeKey, strconv.Itoa(int(resp.ReturnCode)))); err != nil {
+		logger.Errorf("[rpc] Failed to set return code trailer: %v", err)
+	}
+	return status.Error(codeForResponseCode(resp.ReturnCode), resp.Message)
+}
+
+func (s *ipamServer) RequestIPConfig(ctx context.Context, in *pb.IPConfigRequest) (*pb.IPConfigResponse, error) {
+	req, err := ipConfigRequestFromProto(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := s.svc.RequestIPConfig(req)
+	logger.ResponseEx("[rpc] RequestIPConfig", req, resp, resp.Response.ReturnCode, nil)
+	if err := responseError(ctx, resp.Response); err != nil {
+		return nil, err
+	}
+	out := &pb.IPConfigResponse{PodIpInfo: podIPInfoToProto(resp.PodIpInfo)}
+	if resp.PodIpInfoV6 != nil {
+		out.PodIpInfoV6 = podIPInfoToProto(*resp.PodIpInfoV6)
+	}
+	return out, nil
+}
+
+func (s *ipamServer) ReleaseIPConfig(ctx context.Context, in *pb.IPConfigRequest) (*pb.ReleaseIPConfigResponse, error) {
+	req, err := ipConfigRequestFromProto(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := s.svc.ReleaseIPConfig(req)
+	logger.ResponseEx("[rpc] ReleaseIPConfig", req, resp, resp.ReturnCode, nil)
+	if err := responseError(ctx, resp); err != nil {
+		return nil, err
+	}
+	return &pb.ReleaseIPConfigResponse{}, nil
+}
+
+func (s *ipamServer) GetIPAddresses(_ context.Context, in *pb.GetIPAddressesRequest) (*pb.GetIPAddressesResponse, error) {
+	states, err := statesFromProtoSlice(in.GetStateFilter())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ipConfigs := s.svc.GetIPConfigsMatchingStates(states...)
+	resp := &pb.GetIPAddressesResponse{
+		IpConfigurations: make([]*pb.IPConfigurationStatus, 0, len(ipConfigs)),
+	}
+	for i := range ipConfigs {
+		resp.IpConfigurations = append(resp.IpConfigurations, ipConfigurationStatusToProto(ipConfigs[i]))
+	}
+	return resp, nil
+}
+
+// WatchIPConfigs first sends an Added event for every IP currently matching the
+// filter and then streams each subsequent change.
+func (s *ipamServer) WatchIPConfigs(in *pb.WatchIPConfigsRequest, stream pb.CNSIPAM_WatchIPConfigsServer) error {
+	states, err := statesFromProtoSlice(in.GetStateFilter())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	current, events, stop := s.svc.WatchIPConfigs()
+	defer stop()
+
+	for i := range current {
+		event := cns.IPConfigEvent{Type: cns.IPConfigAdded, IPConfigurationStatus: current[i]}
+		if !eventMatchesStates(event, states) {
+			continue
+		}
+		if err := stream.Send(ipConfigEventToProto(event)); err != nil {
+			return errors.Wrap(err, "failed to send initial state")
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.Aborted, "watch fell behind the CNS state, watch again")
+			}
+			if !eventMatchesStates(event, states) {
+				continue
+			}
+			if err := stream.Send(ipConfigEventToProto(event)); err != nil {
+				return errors.Wrap(err, "failed to send event")
+			}
+		}
+	}
+}
+
+// eventMatchesStates is true if the IP in the event entered or left any of the
+// states, or if no states are passed.
+func eventMatchesStates(event cns.IPConfigEvent, states []cns.IPConfigState) bool {
+	if len(states) == 0 {
+		return true
+	}
+	for _, state := range states {
+		if event.IPConfigurationStatus.State == state || event.PreviousState == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// cns-sandbox runs CNS against a fake DNC, NMAgent and IMDS, and drives it with a scenario file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/sandbox"
+	"github.com/Azure/azure-container-networking/log"
+)
+
+const name = "cns-sandbox"
+
+func main() {
+	scenarioPath := flag.String("scenario", "", "Path of the scenario JSON file. Without one the sandbox only serves CNS with the initial IPs.")
+	listenURL := flag.String("listen", "tcp://localhost:10090", "URL CNS serves its API on.")
+	serve := flag.Bool("serve", false, "Keep serving the CNS API after the scenario steps until interrupted.")
+	debug := flag.Bool("debug", false, "Log at debug level.")
+	flag.Parse()
+
+	logLevel := log.LevelInfo
+	if *debug {
+		logLevel = log.LevelDebug
+	}
+	logger.InitLogger(name, logLevel, log.TargetStderr, "")
+
+	if err := run(*scenarioPath, *listenURL, *serve || *scenarioPath == ""); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func run(scenarioPath, listenURL string, serve bool) error {
+	scenario := &sandbox.Scenario{}
+	if scenarioPath != "" {
+		var err error
+		if scenario, err = sandbox.LoadScenario(scenarioPath); err != nil {
+			return err
+		}
+	} else {
+		sandbox.SetScenarioDefaults(scenario)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	s, err := sandbox.New(scenario, listenURL)
+	if err != nil {
+		return err
+	}
+	defer s.Stop()
+
+	if err = s.Start(ctx); err != nil {
+		return err
+	}
+	logger.Printf("[sandbox] CNS is serving on %s", listenURL)
+
+	if err = s.Run(ctx); err != nil {
+		return err
+	}
+	logger.Printf("[sandbox] Scenario passed")
+
+	if serve {
+		<-ctx.Done()
+	}
+	return nil
+}
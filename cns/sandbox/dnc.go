@@ -0,0 +1,247 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package sandbox
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/singletenantcontroller"
+	"github.com/Azure/azure-container-networking/cns/singletenantcontroller/kubecontroller"
+	"github.com/Azure/azure-container-networking/cns/types"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var _ singletenantcontroller.RequestController = (*dnc)(nil)
+
+// ErrNoFreeSubnetIPs is returned when the fake DNC has assigned every IP in the subnet.
+var ErrNoFreeSubnetIPs = errors.New("no free IPs left in the subnet")
+
+// dnc is a fake DNC behind a fake NodeNetworkConfig request controller. After the DNC latency it
+// assigns IPs from the subnet to the NC in the NodeNetworkConfig status to match the spec requested
+// by the IPAM pool monitor, and hands the status to CNS as the CRD reconciler does.
+type dnc struct {
+	sync.Mutex
+	ctx         context.Context
+	service     *restserver.HTTPRestService
+	poolMonitor cns.IPAMPoolMonitor
+	nmAgent     *nmAgent
+	latency     time.Duration
+	subnet      *net.IPNet
+	nnc         v1alpha.NodeNetworkConfig
+	started     bool
+}
+
+func newDNC(service *restserver.HTTPRestService, nma *nmAgent, scenario *Scenario) (*dnc, error) {
+	_, subnet, err := net.ParseCIDR(scenario.SubnetAddressSpace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid subnet address space %s", scenario.SubnetAddressSpace)
+	}
+
+	return &dnc{
+		ctx:     context.Background(),
+		service: service,
+		nmAgent: nma,
+		latency: time.Duration(scenario.DNCLatencyInMs) * time.Millisecond,
+		subnet:  subnet,
+		nnc: v1alpha.NodeNetworkConfig{
+			Spec: v1alpha.NodeNetworkConfigSpec{
+				RequestedIPCount: scenario.InitialIPCount,
+			},
+			Status: v1alpha.NodeNetworkConfigStatus{
+				Scaler: scenario.Scaler,
+				NetworkContainers: []v1alpha.NetworkContainer{
+					{
+						ID:                 scenario.NCID,
+						PrimaryIP:          scenario.PrimaryIP,
+						SubnetName:         "sandbox",
+						SubnetAddressSpace: scenario.SubnetAddressSpace,
+						DefaultGateway:     scenario.DefaultGateway,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// Init assigns the initially requested IPs and hands them to CNS.
+func (d *dnc) Init(ctx context.Context) error {
+	d.Lock()
+	d.ctx = ctx
+	d.Unlock()
+
+	return d.reconcile()
+}
+
+// Start marks the request controller as started, updates are reconciled as they are requested.
+func (d *dnc) Start(context.Context) error {
+	d.Lock()
+	defer d.Unlock()
+
+	d.started = true
+	return nil
+}
+
+func (d *dnc) IsStarted() bool {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.started
+}
+
+// UpdateCRDSpec saves the spec and reconciles the status after the DNC latency.
+func (d *dnc) UpdateCRDSpec(_ context.Context, spec v1alpha.NodeNetworkConfigSpec) error {
+	d.Lock()
+	d.nnc.Spec = spec
+	ctx := d.ctx
+	d.Unlock()
+
+	go func() {
+		select {
+		case <-time.After(d.latency):
+		case <-ctx.Done():
+			return
+		}
+
+		if err := d.reconcile(); err != nil {
+			logger.Errorf("[sandbox-dnc] Failed to reconcile spec %+v, err:%v", spec, err)
+		}
+	}()
+	return nil
+}
+
+// reconcile releases the IPs not in use and assigns IPs until the requested IP count is met.
+func (d *dnc) reconcile() error {
+	return d.updateAssignments(func(nc *v1alpha.NetworkContainer, spec v1alpha.NodeNetworkConfigSpec) error {
+		notInUse := make(map[string]bool, len(spec.IPsNotInUse))
+		for _, name := range spec.IPsNotInUse {
+			notInUse[name] = true
+		}
+
+		assignments := []v1alpha.IPAssignment{}
+		for _, assignment := range nc.IPAssignments {
+			if !notInUse[assignment.Name] {
+				assignments = append(assignments, assignment)
+			}
+		}
+		nc.IPAssignments = assignments
+
+		return d.assignIPsUntransacted(nc, int(spec.RequestedIPCount)-len(nc.IPAssignments))
+	})
+}
+
+// addIPs assigns more IPs to the NC regardless of the requested IP count.
+func (d *dnc) addIPs(count int) error {
+	return d.updateAssignments(func(nc *v1alpha.NetworkContainer, _ v1alpha.NodeNetworkConfigSpec) error {
+		return d.assignIPsUntransacted(nc, count)
+	})
+}
+
+// removeIPs removes the most recently assigned IPs from the NC regardless of whether they are in use.
+func (d *dnc) removeIPs(count int) error {
+	return d.updateAssignments(func(nc *v1alpha.NetworkContainer, _ v1alpha.NodeNetworkConfigSpec) error {
+		if count > len(nc.IPAssignments) {
+			count = len(nc.IPAssignments)
+		}
+		nc.IPAssignments = nc.IPAssignments[:len(nc.IPAssignments)-count]
+		return nil
+	})
+}
+
+// updateAssignments updates the IPs assigned to the NC, bumps the NC version and hands the status to
+// CNS and the pool monitor. The update is reverted if CNS rejects it.
+func (d *dnc) updateAssignments(update func(*v1alpha.NetworkContainer, v1alpha.NodeNetworkConfigSpec) error) error {
+	d.Lock()
+	nc := &d.nnc.Status.NetworkContainers[0]
+	previous := *nc
+	previous.IPAssignments = append([]v1alpha.IPAssignment{}, nc.IPAssignments...)
+
+	if err := update(nc, d.nnc.Spec); err != nil {
+		*nc = previous
+		d.Unlock()
+		return err
+	}
+	nc.Version++
+
+	ncRequest, err := kubecontroller.CRDStatusToNCRequest(d.nnc.Status)
+	if err != nil {
+		*nc = previous
+		d.Unlock()
+		return errors.Wrap(err, "failed to translate the NodeNetworkConfig status")
+	}
+
+	if returnCode := d.service.CreateOrUpdateNetworkContainerInternal(ncRequest); returnCode != types.Success {
+		*nc = previous
+		d.Unlock()
+		return errors.Errorf("CNS rejected NC %s version %d with %d IPs: %s", nc.ID, nc.Version, len(ncRequest.SecondaryIPConfigs), returnCode)
+	}
+
+	d.nmAgent.publish(nc.ID, int(nc.Version))
+	logger.Printf("[sandbox-dnc] Published NC %s version %d with %d IPs", nc.ID, nc.Version, len(nc.IPAssignments))
+
+	scaler, spec := d.nnc.Status.Scaler, d.nnc.Spec
+	d.Unlock()
+
+	// the pool monitor calls UpdateCRDSpec with its own lock held, so it is updated without holding the DNC lock.
+	d.poolMonitor.Update(scaler, spec)
+	return nil
+}
+
+// assignIPsUntransacted assigns count free IPs of the subnet to the NC.
+// Caller will acquire/release the DNC lock.
+func (d *dnc) assignIPsUntransacted(nc *v1alpha.NetworkContainer, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	used := map[string]bool{nc.PrimaryIP: true, nc.DefaultGateway: true}
+	for _, assignment := range nc.IPAssignments {
+		used[assignment.IP] = true
+	}
+
+	ip := make(net.IP, len(d.subnet.IP))
+	copy(ip, d.subnet.IP)
+	for count > 0 {
+		incrementIP(ip)
+		if !d.subnet.Contains(ip) {
+			return ErrNoFreeSubnetIPs
+		}
+
+		if used[ip.String()] || isBroadcast(ip, d.subnet) {
+			continue
+		}
+
+		nc.IPAssignments = append(nc.IPAssignments, v1alpha.IPAssignment{
+			Name: uuid.New().String(),
+			IP:   ip.String(),
+		})
+		count--
+	}
+	return nil
+}
+
+func incrementIP(ip net.IP) {
+	for j := len(ip) - 1; j >= 0; j-- {
+		ip[j]++
+		if ip[j] > 0 {
+			break
+		}
+	}
+}
+
+func isBroadcast(ip net.IP, subnet *net.IPNet) bool {
+	for i := range ip {
+		if ip[i]|subnet.Mask[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
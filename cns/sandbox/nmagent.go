@@ -0,0 +1,70 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package sandbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/nmagentclient"
+)
+
+var _ nmagentclient.NMAgentClientInterface = (*nmAgent)(nil)
+
+// ncProgramming is an NC version published by the DNC and when the NMAgent finishes programming it.
+type ncProgramming struct {
+	version    int
+	programmed time.Time
+}
+
+// nmAgent is a fake NMAgent which programs each NC version published by the fake DNC after the programming latency.
+// The other NMAgent APIs always succeed.
+type nmAgent struct {
+	fakes.NMAgentClientTest
+	sync.Mutex
+	latency time.Duration
+	// NC ID is key
+	programmedVersions map[string]int
+	pending            map[string][]ncProgramming
+}
+
+func newNMAgent(latency time.Duration) *nmAgent {
+	return &nmAgent{
+		latency:            latency,
+		programmedVersions: map[string]int{},
+		pending:            map[string][]ncProgramming{},
+	}
+}
+
+// publish starts programming the NC version.
+func (nma *nmAgent) publish(ncID string, version int) {
+	nma.Lock()
+	defer nma.Unlock()
+
+	nma.pending[ncID] = append(nma.pending[ncID], ncProgramming{version: version, programmed: time.Now().Add(nma.latency)})
+}
+
+// GetNcVersionListWithOutToken returns the latest programmed version of each NC.
+func (nma *nmAgent) GetNcVersionListWithOutToken(_ context.Context, ncNeedUpdateList []string) (map[string]int, error) {
+	nma.Lock()
+	defer nma.Unlock()
+
+	now := time.Now()
+	ncVersionList := make(map[string]int, len(ncNeedUpdateList))
+	for _, ncID := range ncNeedUpdateList {
+		pending := nma.pending[ncID]
+		for len(pending) > 0 && !now.Before(pending[0].programmed) {
+			nma.programmedVersions[ncID] = pending[0].version
+			pending = pending[1:]
+		}
+		nma.pending[ncID] = pending
+
+		if version, ok := nma.programmedVersions[ncID]; ok {
+			ncVersionList[ncID] = version
+		}
+	}
+	return ncVersionList, nil
+}
@@ -0,0 +1,366 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+// Package sandbox runs the real CNS HTTPRestService and IPAM pool monitor against in-process fakes of
+// the DNC and NodeNetworkConfig request controller, NMAgent and IMDS, and drives them with a scenario
+// of pods and IPs being added and removed, so that CNS behaviour can be reproduced without an Azure VM.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/fakes"
+	"github.com/Azure/azure-container-networking/cns/ipampoolmonitor"
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	"github.com/Azure/azure-container-networking/cns/types"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/store"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// name of the CNS state file in the sandbox state directory
+	stateFileName = "azure-cns.json"
+	// namespace of the pods created for a Count of AddPods
+	podNamespace = "sandbox"
+	// interval between the retries of a pod's IP request, and between checks of an expectation
+	pollInterval = 100 * time.Millisecond
+	// timeout of a sync of the NC versions programmed by the NMAgent
+	syncHostNCTimeout = 500 * time.Millisecond
+)
+
+// ErrExpectationNotMet is returned when the IP counts in CNS don't match an expectation of the scenario.
+var ErrExpectationNotMet = errors.New("expectation not met")
+
+// Sandbox is a CNS running against in-process fakes.
+type Sandbox struct {
+	Service     *restserver.HTTPRestService
+	poolMonitor *ipampoolmonitor.CNSIPAMPoolMonitor
+	dnc         *dnc
+	scenario    *Scenario
+	config      common.ServiceConfig
+	stateDir    string
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+
+	podsLock sync.Mutex
+	pods     []cns.PodInfo // running pods in the order they got their IPs
+	podCount int
+}
+
+// New creates a sandbox for the scenario whose CNS serves its API on the URL, or doesn't serve it if the URL is empty.
+func New(scenario *Scenario, url string) (*Sandbox, error) {
+	stateDir, err := ioutil.TempDir("", "cns-sandbox")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the state directory")
+	}
+
+	s := &Sandbox{
+		scenario: scenario,
+		stateDir: stateDir,
+	}
+	s.config.Name = "cns-sandbox"
+	if s.config.Store, err = store.NewJsonFileStore(filepath.Join(stateDir, stateFileName)); err != nil {
+		os.RemoveAll(stateDir)
+		return nil, errors.Wrap(err, "failed to create the state store")
+	}
+
+	nma := newNMAgent(time.Duration(scenario.ProgrammingLatencyInMs) * time.Millisecond)
+	httpService, err := restserver.NewHTTPRestService(&s.config, fakes.NewFakeImdsClient(), nma)
+	if err != nil {
+		os.RemoveAll(stateDir)
+		return nil, errors.Wrap(err, "failed to create CNS")
+	}
+	s.Service = httpService.(*restserver.HTTPRestService)
+
+	if url == "" {
+		// the listener doesn't listen on the null address
+		url = "tcp://null"
+	}
+	s.Service.SetOption(acn.OptCnsURL, url)
+
+	if s.dnc, err = newDNC(s.Service, nma, scenario); err != nil {
+		os.RemoveAll(stateDir)
+		return nil, err
+	}
+	s.poolMonitor = ipampoolmonitor.NewCNSIPAMPoolMonitor(s.Service, s.dnc)
+	s.Service.IPAMPoolMonitor = s.poolMonitor
+	s.dnc.poolMonitor = s.poolMonitor
+
+	return s, nil
+}
+
+// Start initializes CNS with the initially requested IPs and starts the pool monitor, the NC version sync and the API.
+func (s *Sandbox) Start(ctx context.Context) error {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	if err := s.Service.Init(&s.config); err != nil {
+		return errors.Wrap(err, "failed to initialize CNS")
+	}
+	s.Service.SetNodeOrchestrator(&cns.SetOrchestratorTypeRequest{OrchestratorType: cns.KubernetesCRD})
+
+	if err := s.dnc.Init(ctx); err != nil {
+		return errors.Wrap(err, "failed to initialize the NodeNetworkConfig")
+	}
+	if err := s.dnc.Start(ctx); err != nil {
+		return errors.Wrap(err, "failed to start the request controller")
+	}
+
+	if err := s.Service.Start(&s.config); err != nil {
+		return errors.Wrap(err, "failed to start CNS")
+	}
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		if err := s.poolMonitor.Start(ctx, s.scenario.PoolMonitorRefreshInMs); err != nil {
+			logger.Printf("[sandbox] Exiting IPAM pool monitor: %v", err)
+		}
+	}()
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(time.Duration(s.scenario.SyncHostNCVersionIntervalInMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Service.SyncHostNCVersion(ctx, cns.CRD, syncHostNCTimeout/time.Millisecond)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops CNS and the background loops, and removes the CNS state.
+func (s *Sandbox) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.Service.Stop()
+	os.RemoveAll(s.stateDir)
+}
+
+// Run runs the steps of the scenario in order, and stops at the first which fails.
+func (s *Sandbox) Run(ctx context.Context) error {
+	for i, step := range s.scenario.Steps {
+		logger.Printf("[sandbox] Running step %d: %s", i, step.Op)
+		if err := s.RunStep(ctx, step); err != nil {
+			return errors.Wrapf(err, "step %d: %s failed", i, step.Op)
+		}
+	}
+	return nil
+}
+
+// RunStep runs a single step.
+func (s *Sandbox) RunStep(ctx context.Context, step Step) error {
+	duration := time.Duration(step.DurationInMs) * time.Millisecond
+
+	switch step.Op {
+	case AddPods:
+		return s.addPods(ctx, step.Pods, step.Count, duration)
+	case RemovePods:
+		return s.removePods(step.Pods, step.Count)
+	case AddIPs:
+		return s.dnc.addIPs(step.Count)
+	case RemoveIPs:
+		return s.dnc.removeIPs(step.Count)
+	case Sleep:
+		select {
+		case <-time.After(duration):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case Expect:
+		return s.expect(ctx, step.Expectation, duration)
+	default:
+		return errors.Errorf("unknown op %q", step.Op)
+	}
+}
+
+// addPods concurrently requests an IP for each pod, retrying for up to the timeout.
+func (s *Sandbox) addPods(ctx context.Context, names []string, count int, timeout time.Duration) error {
+	s.podsLock.Lock()
+	for i := 0; i < count; i++ {
+		s.podCount++
+		names = append(names, fmt.Sprintf("%s/pod-%d", podNamespace, s.podCount))
+	}
+	s.podsLock.Unlock()
+
+	var (
+		wg     sync.WaitGroup
+		failed = make(chan error, len(names))
+	)
+	for _, name := range names {
+		podInfo, err := newPodInfo(name)
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.requestIPConfig(ctx, podInfo, timeout); err != nil {
+				failed <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(failed)
+
+	var errs []string
+	for err := range failed {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("%d of %d pods didn't get an IP: %s", len(errs), len(names), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Sandbox) requestIPConfig(ctx context.Context, podInfo cns.PodInfo, timeout time.Duration) error {
+	orchestratorContext, err := podInfo.OrchestratorContext()
+	if err != nil {
+		return err
+	}
+	req := cns.IPConfigRequest{
+		OrchestratorContext: orchestratorContext,
+		PodInterfaceID:      podInfo.InterfaceID(),
+		InfraContainerID:    podInfo.InfraContainerID(),
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		resp := s.Service.RequestIPConfig(req)
+		if resp.Response.ReturnCode == types.Success {
+			logger.Printf("[sandbox] Pod %s/%s got IP %s", podInfo.Namespace(), podInfo.Name(), resp.PodIpInfo.PodIPConfig.IPAddress)
+			s.podsLock.Lock()
+			s.pods = append(s.pods, podInfo)
+			s.podsLock.Unlock()
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return errors.Errorf("pod %s/%s: %s: %s", podInfo.Namespace(), podInfo.Name(), resp.Response.ReturnCode, resp.Response.Message)
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// removePods releases the IPs of the named pods, or of the count oldest pods.
+func (s *Sandbox) removePods(names []string, count int) error {
+	s.podsLock.Lock()
+	defer s.podsLock.Unlock()
+
+	remove := map[string]bool{}
+	for _, name := range names {
+		podInfo, err := newPodInfo(name)
+		if err != nil {
+			return err
+		}
+		remove[podInfo.Key()] = true
+	}
+	for i := 0; i < count && i < len(s.pods); i++ {
+		remove[s.pods[i].Key()] = true
+	}
+
+	running := []cns.PodInfo{}
+	for _, podInfo := range s.pods {
+		if !remove[podInfo.Key()] {
+			running = append(running, podInfo)
+			continue
+		}
+
+		orchestratorContext, err := podInfo.OrchestratorContext()
+		if err != nil {
+			return err
+		}
+		resp := s.Service.ReleaseIPConfig(cns.IPConfigRequest{
+			OrchestratorContext: orchestratorContext,
+			PodInterfaceID:      podInfo.InterfaceID(),
+			InfraContainerID:    podInfo.InfraContainerID(),
+		})
+		if resp.ReturnCode != types.Success {
+			s.pods = append(running, s.pods[len(running):]...)
+			return errors.Errorf("failed to release the IP of pod %s/%s: %s: %s", podInfo.Namespace(), podInfo.Name(), resp.ReturnCode, resp.Message)
+		}
+		logger.Printf("[sandbox] Released the IP of pod %s/%s", podInfo.Namespace(), podInfo.Name())
+	}
+	s.pods = running
+	return nil
+}
+
+// expect waits up to the timeout for the IP counts in CNS to match the expectation.
+func (s *Sandbox) expect(ctx context.Context, expectation Expectation, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		mismatches := s.checkExpectation(expectation)
+		if len(mismatches) == 0 {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return errors.Wrap(ErrExpectationNotMet, strings.Join(mismatches, ", "))
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Sandbox) checkExpectation(expectation Expectation) []string {
+	var mismatches []string
+	check := func(name string, expected *int, actual int) {
+		if expected != nil && *expected != actual {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %d not %d", name, actual, *expected))
+		}
+	}
+
+	check("Allocated", expectation.Allocated, len(s.Service.GetAllocatedIPConfigs()))
+	check("Available", expectation.Available, len(s.Service.GetAvailableIPConfigs()))
+	check("PendingRelease", expectation.PendingRelease, len(s.Service.GetPendingReleaseIPConfigs()))
+	check("PendingProgramming", expectation.PendingProgramming, len(s.Service.GetPendingProgramIPConfigs()))
+
+	if expectation.RequestedIPCount != nil {
+		requested := s.poolMonitor.GetStateSnapshot().CachedNNC.Spec.RequestedIPCount
+		if requested != *expectation.RequestedIPCount {
+			mismatches = append(mismatches, fmt.Sprintf("RequestedIPCount is %d not %d", requested, *expectation.RequestedIPCount))
+		}
+	}
+	return mismatches
+}
+
+// newPodInfo returns the PodInfo of the namespace/name pod.
+func newPodInfo(name string) (cns.PodInfo, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.Errorf("invalid pod %q, expected namespace/name", name)
+	}
+
+	// the pod's container and interface IDs are derived from its name, so they are the same on release.
+	containerID := uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String()
+	return cns.NewPodInfo(containerID, containerID[:8]+"-eth0", parts[1], parts[0]), nil
+}
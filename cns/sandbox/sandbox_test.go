@@ -0,0 +1,131 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package sandbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/logger"
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	logger.InitLogger("testlogs", 0, 0, "./")
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+// newTestScenario returns a scenario with fast fakes and a batch size of 10.
+func newTestScenario(steps ...Step) *Scenario {
+	scenario := &Scenario{
+		Scaler: v1alpha.Scaler{
+			BatchSize:               10,
+			RequestThresholdPercent: 50,
+			ReleaseThresholdPercent: 150,
+			MaxIPCount:              30,
+		},
+		DNCLatencyInMs:                10,
+		ProgrammingLatencyInMs:        10,
+		PoolMonitorRefreshInMs:        10,
+		SyncHostNCVersionIntervalInMs: 10,
+		Steps:                         steps,
+	}
+	SetScenarioDefaults(scenario)
+	return scenario
+}
+
+func runTestScenario(t *testing.T, scenario *Scenario) error {
+	require.NoError(t, scenario.Validate())
+
+	s, err := New(scenario, "")
+	require.NoError(t, err)
+	defer s.Stop()
+
+	require.NoError(t, s.Start(context.Background()))
+	return s.Run(context.Background())
+}
+
+func TestSandboxScalesPoolUpAndDown(t *testing.T) {
+	scenario := newTestScenario(
+		Step{Op: Expect, DurationInMs: 5000, Expectation: Expectation{Available: intPtr(10), RequestedIPCount: int64Ptr(10)}},
+		Step{Op: AddPods, Count: 8, DurationInMs: 5000},
+		Step{Op: Expect, DurationInMs: 5000, Expectation: Expectation{Allocated: intPtr(8), Available: intPtr(12), RequestedIPCount: int64Ptr(20)}},
+		Step{Op: AddPods, Pods: []string{"default/web"}, DurationInMs: 5000},
+		Step{Op: RemovePods, Count: 8},
+		Step{Op: Expect, DurationInMs: 5000, Expectation: Expectation{Allocated: intPtr(1), Available: intPtr(9), PendingRelease: intPtr(0), RequestedIPCount: int64Ptr(10)}},
+		Step{Op: RemovePods, Pods: []string{"default/web"}},
+		Step{Op: Expect, DurationInMs: 5000, Expectation: Expectation{Allocated: intPtr(0), Available: intPtr(10)}},
+	)
+
+	require.NoError(t, runTestScenario(t, scenario))
+}
+
+func TestSandboxAddIPs(t *testing.T) {
+	scenario := newTestScenario(
+		Step{Op: AddIPs, Count: 5},
+		Step{Op: Expect, DurationInMs: 5000, Expectation: Expectation{Available: intPtr(15), PendingProgramming: intPtr(0)}},
+		Step{Op: RemoveIPs, Count: 5},
+		Step{Op: Expect, DurationInMs: 5000, Expectation: Expectation{Available: intPtr(10)}},
+	)
+
+	require.NoError(t, runTestScenario(t, scenario))
+}
+
+func TestSandboxExpectationNotMet(t *testing.T) {
+	scenario := newTestScenario(
+		Step{Op: Expect, DurationInMs: 100, Expectation: Expectation{Allocated: intPtr(1)}},
+	)
+
+	err := runTestScenario(t, scenario)
+	assert.True(t, errors.Is(err, ErrExpectationNotMet), "unexpected error %v", err)
+}
+
+func TestSandboxAddPodsFailsWhenPoolIsExhausted(t *testing.T) {
+	// the pool can't grow past MaxIPCount, so the 31st pod doesn't get an IP.
+	scenario := newTestScenario(
+		Step{Op: AddPods, Count: 31, DurationInMs: 1000},
+	)
+
+	err := runTestScenario(t, scenario)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 31 pods didn't get an IP")
+}
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario("scenarios/scale-up-down.json")
+	require.NoError(t, err)
+	assert.NotEmpty(t, scenario.NCID)
+	assert.Equal(t, "10.240.0.0/16", scenario.SubnetAddressSpace)
+	assert.Equal(t, int64(10), scenario.InitialIPCount)
+	assert.Len(t, scenario.Steps, 5)
+}
+
+func TestScenarioValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		step Step
+	}{
+		{name: "unknown op", step: Step{Op: "Restart"}},
+		{name: "pods without count or names", step: Step{Op: AddPods}},
+		{name: "pods with count and names", step: Step{Op: RemovePods, Count: 1, Pods: []string{"default/web"}}},
+		{name: "IPs without count", step: Step{Op: AddIPs}},
+		{name: "negative duration", step: Step{Op: Sleep, DurationInMs: -1}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, newTestScenario(tt.step).Validate())
+		})
+	}
+}
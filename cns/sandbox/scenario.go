@@ -0,0 +1,165 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package sandbox
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Scenario step ops.
+const (
+	// AddPods concurrently requests an IP for each of the Pods, or for Count new pods, as a burst of
+	// CNI ADDs would. A pod retries for up to DurationInMs to get an IP.
+	AddPods = "AddPods"
+	// RemovePods releases the IPs of the Pods, or of the Count oldest pods.
+	RemovePods = "RemovePods"
+	// AddIPs has the DNC assign Count more IPs to the NC, regardless of the requested IP count.
+	AddIPs = "AddIPs"
+	// RemoveIPs has the DNC remove the Count most recently assigned IPs from the NC, regardless of whether they are in use.
+	RemoveIPs = "RemoveIPs"
+	// Sleep waits for DurationInMs.
+	Sleep = "Sleep"
+	// Expect checks the IP counts in CNS match the Expectation, waiting up to DurationInMs for them to.
+	Expect = "Expect"
+)
+
+// Scenario describes the node the sandbox simulates and the steps to run against it.
+type Scenario struct {
+	// NC the IPs are assigned to, and its subnet, primary IP and gateway.
+	NCID               string
+	SubnetAddressSpace string
+	PrimaryIP          string
+	DefaultGateway     string
+	// Scaler of the NodeNetworkConfig.
+	Scaler v1alpha.Scaler
+	// RequestedIPCount of the NodeNetworkConfig when the sandbox starts.
+	InitialIPCount int64
+	// How long the fake DNC takes to update the NodeNetworkConfig status after a spec update.
+	DNCLatencyInMs int
+	// How long the fake NMAgent takes to program a new NC version.
+	ProgrammingLatencyInMs int
+	// Interval between IPAM pool monitor reconciles.
+	PoolMonitorRefreshInMs int
+	// Interval between syncs of the NC versions programmed by the NMAgent.
+	SyncHostNCVersionIntervalInMs int
+	Steps                         []Step
+}
+
+// Step of a scenario.
+type Step struct {
+	Op    string
+	Count int
+	// Pods as namespace/name.
+	Pods         []string
+	DurationInMs int
+	Expectation  Expectation
+}
+
+// Expectation of the IP counts in CNS, nil counts are not checked.
+type Expectation struct {
+	Allocated          *int
+	Available          *int
+	PendingRelease     *int
+	PendingProgramming *int
+	RequestedIPCount   *int64
+}
+
+// LoadScenario reads the scenario from the JSON file and sets its defaults.
+func LoadScenario(path string) (*Scenario, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read scenario")
+	}
+
+	var scenario Scenario
+	if err = json.Unmarshal(content, &scenario); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse scenario %s", path)
+	}
+
+	SetScenarioDefaults(&scenario)
+	if err = scenario.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid scenario %s", path)
+	}
+	return &scenario, nil
+}
+
+// SetScenarioDefaults sets the default values of the scenario fields which are not specified.
+func SetScenarioDefaults(scenario *Scenario) {
+	if scenario.NCID == "" {
+		scenario.NCID = uuid.New().String()
+	}
+
+	if scenario.SubnetAddressSpace == "" {
+		scenario.SubnetAddressSpace = "10.240.0.0/16"
+		if scenario.PrimaryIP == "" {
+			scenario.PrimaryIP = "10.240.0.4"
+		}
+		if scenario.DefaultGateway == "" {
+			scenario.DefaultGateway = "10.240.0.1"
+		}
+	}
+
+	if scenario.Scaler.BatchSize == 0 {
+		scenario.Scaler.BatchSize = 10
+	}
+
+	if scenario.Scaler.RequestThresholdPercent == 0 {
+		scenario.Scaler.RequestThresholdPercent = 50
+	}
+
+	if scenario.Scaler.ReleaseThresholdPercent == 0 {
+		scenario.Scaler.ReleaseThresholdPercent = 150
+	}
+
+	if scenario.Scaler.MaxIPCount == 0 {
+		scenario.Scaler.MaxIPCount = 250
+	}
+
+	if scenario.InitialIPCount == 0 {
+		scenario.InitialIPCount = scenario.Scaler.BatchSize
+	}
+
+	if scenario.PoolMonitorRefreshInMs == 0 {
+		scenario.PoolMonitorRefreshInMs = 1000
+	}
+
+	if scenario.SyncHostNCVersionIntervalInMs == 0 {
+		scenario.SyncHostNCVersionIntervalInMs = 1000
+	}
+}
+
+// Validate returns an error if a step of the scenario is invalid.
+func (scenario *Scenario) Validate() error {
+	if scenario.PrimaryIP == "" || scenario.DefaultGateway == "" {
+		return errors.New("PrimaryIP and DefaultGateway must be set with the SubnetAddressSpace")
+	}
+
+	for i, step := range scenario.Steps {
+		switch step.Op {
+		case AddPods, RemovePods:
+			if step.Count < 0 || (step.Count == 0) == (len(step.Pods) == 0) {
+				return errors.Errorf("step %d: %s needs either a Count or Pods", i, step.Op)
+			}
+			if step.DurationInMs < 0 {
+				return errors.Errorf("step %d: %s has a negative DurationInMs", i, step.Op)
+			}
+		case AddIPs, RemoveIPs:
+			if step.Count <= 0 {
+				return errors.Errorf("step %d: %s needs a Count", i, step.Op)
+			}
+		case Sleep, Expect:
+			if step.DurationInMs < 0 {
+				return errors.Errorf("step %d: %s has a negative DurationInMs", i, step.Op)
+			}
+		default:
+			return errors.Errorf("step %d: unknown op %q", i, step.Op)
+		}
+	}
+	return nil
+}
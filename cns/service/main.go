@@ -14,6 +14,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -26,6 +27,8 @@ import (
 	"github.com/Azure/azure-container-networking/cns/cnsclient"
 	"github.com/Azure/azure-container-networking/cns/common"
 	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/containerruntime"
+	"github.com/Azure/azure-container-networking/cns/criclient"
 	"github.com/Azure/azure-container-networking/cns/hnsclient"
 	"github.com/Azure/azure-container-networking/cns/imdsclient"
 	"github.com/Azure/azure-container-networking/cns/ipampoolmonitor"
@@ -54,6 +57,9 @@ const (
 
 	// 720 * acn.FiveSeconds sec sleeps = 1Hr
 	maxRetryNodeRegister = 720
+
+	// How often the config file is checked for changes to reload.
+	configWatchInterval = 30 * time.Second
 )
 
 var (
@@ -276,7 +282,7 @@ func printVersion() {
 }
 
 // RegisterNode - Tries to register node with DNC when CNS is started in managed DNC mode
-func registerNode(httpc *http.Client, httpRestService cns.HTTPService, dncEP, infraVnet, nodeID string) error {
+func registerNode(httpc *http.Client, nmaclient nmagentclient.NMAgentClientInterface, httpRestService cns.HTTPService, dncEP, infraVnet, nodeID string) error {
 	logger.Printf("[Azure CNS] Registering node %s with Infrastructure Network: %s PrivateEndpoint: %s", nodeID, infraVnet, dncEP)
 
 	var (
@@ -286,7 +292,7 @@ func registerNode(httpc *http.Client, httpRestService cns.HTTPService, dncEP, in
 	)
 
 	nodeRegisterRequest.NumCPU = numCPU
-	supportedApis, retErr := nmagentclient.GetNmAgentSupportedApis(httpc, "")
+	supportedApis, retErr := nmaclient.GetNmAgentSupportedApis(rootCtx, "")
 
 	if retErr != nil {
 		logger.Errorf("[Azure CNS] Failed to retrieve SupportedApis from NMagent of node %s with Infrastructure Network: %s PrivateEndpoint: %s",
@@ -423,6 +429,10 @@ func main() {
 
 	configuration.SetCNSConfigDefaults(&cnsconfig)
 	logger.Printf("[Azure CNS] Read config :%+v", cnsconfig)
+	// CNS starts with the defaults of the invalid fields, the invalid values of a reloaded config are rejected instead.
+	for _, err := range cnsconfig.ResetInvalidFields() {
+		logger.Errorf("[Azure CNS] Invalid cns config, using the default instead: %v", err)
+	}
 
 	if cnsconfig.WireserverIP != "" {
 		nmagentclient.WireserverIP = cnsconfig.WireserverIP
@@ -457,6 +467,14 @@ func main() {
 		logger.InitAI(aiConfig, ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
 	}
 
+	// The live settings of the config are applied again whenever the config file is changed, and the
+	// other changes are reported as needing a restart.
+	live := newLiveSettings(cnsconfig, logLevel)
+	var configWatcher *configuration.Watcher
+	if configPath, err := configuration.ConfigPath(); err == nil {
+		configWatcher = configuration.NewWatcher(configPath, cnsconfig, live.apply)
+	}
+
 	// Log platform information.
 	logger.Printf("Running on %v", platform.GetOSInfo())
 
@@ -467,10 +485,10 @@ func main() {
 	}
 
 	// Create the key value store.
-	storeFileName := storeFileLocation + name + ".json"
-	config.Store, err = store.NewJsonFileStore(storeFileName)
+	storeFileName := storeFileLocation + name
+	config.Store, err = store.NewKeyValueStore(cnsconfig.StoreType, storeFileName, store.WithGenerations(cnsconfig.StoreGenerations))
 	if err != nil {
-		logger.Errorf("Failed to create store file: %s, due to error %v\n", storeFileName, err)
+		logger.Errorf("Failed to create %s store file: %s, due to error %v\n", cnsconfig.StoreType, storeFileName, err)
 		return
 	}
 
@@ -486,6 +504,24 @@ func main() {
 		return
 	}
 
+	// Attach network containers through the CRI runtime on the nodes without Docker.
+	if cnsconfig.ContainerRuntimeSettings.Type == containerruntime.CRI {
+		endpoint := cnsconfig.ContainerRuntimeSettings.Endpoint
+		if endpoint == "" {
+			endpoint = criclient.DefaultEndpoint
+		}
+		criClient, err := criclient.NewClient(context.Background(), endpoint)
+		if err != nil {
+			logger.Errorf("Failed to create CRI client for %s, err:%v.\n", endpoint, err)
+			return
+		}
+		defer criClient.Close()
+		if httpRestServiceImpl, ok := httpRestService.(*restserver.HTTPRestService); ok {
+			httpRestServiceImpl.SetContainerRuntimeClient(criClient)
+		}
+		logger.Printf("[Azure CNS] Using the CRI runtime at %s", endpoint)
+	}
+
 	// Set CNS options.
 	httpRestService.SetOption(acn.OptCnsURL, cnsURL)
 	httpRestService.SetOption(acn.OptNetPluginPath, cniPath)
@@ -514,11 +550,46 @@ func main() {
 			}
 		}
 
+		config.GRPCSettings = common.GRPCSettings{
+			Enable:    cnsconfig.GRPCSettings.Enable,
+			IPAddress: cnsconfig.GRPCSettings.IPAddress,
+			Port:      cnsconfig.GRPCSettings.Port,
+			Socket:    cnsconfig.GRPCSettings.Socket,
+		}
+
+		config.PeerAuthorization = common.PeerAuthorizationSettings{
+			AllowedUIDs:     cnsconfig.PeerAuthorizationSettings.AllowedUIDs,
+			AllowedBinaries: cnsconfig.PeerAuthorizationSettings.AllowedBinaries,
+			UnixSocketOnly:  cnsconfig.PeerAuthorizationSettings.UnixSocketOnly,
+		}
+
+		if cnsconfig.IPRequestQueueSettings.Enable {
+			config.IPRequestQueue = common.IPRequestQueueSettings{
+				MaxLength: cnsconfig.IPRequestQueueSettings.MaxLength,
+				MaxWait:   time.Duration(cnsconfig.IPRequestQueueSettings.MaxWaitInSecs) * time.Second,
+			}
+		}
+
+		for _, quota := range cnsconfig.IPQuotaSettings.Namespaces {
+			config.NamespaceIPQuotas = append(config.NamespaceIPQuotas, common.NamespaceIPQuota{
+				Namespace:   quota.Namespace,
+				MaxIPs:      quota.MaxIPs,
+				ReservedIPs: quota.ReservedIPs,
+			})
+		}
+
+		config.AllocatePendingProgrammingIPs = cnsconfig.NCProgrammingSettings.AllocatePendingProgrammingIPs
+		config.RedactedCNSConfig = cnsconfig.Redacted()
+
 		err = httpRestService.Init(&config)
 		if err != nil {
 			logger.Errorf("Failed to init HTTPService, err:%v.\n", err)
 			return
 		}
+
+		if httpRestServiceImpl, ok := httpRestService.(*restserver.HTTPRestService); ok && configWatcher != nil {
+			httpRestServiceImpl.SetConfigStatusProvider(configWatcher)
+		}
 	}
 
 	// Initialze state in if CNS is running in CRD mode
@@ -556,7 +627,7 @@ func main() {
 		}
 		logger.Printf("Set GlobalPodInfoScheme %v", cns.GlobalPodInfoScheme)
 
-		err = InitializeCRDState(rootCtx, httpRestService, cnsconfig)
+		err = InitializeCRDState(rootCtx, httpRestService, cnsconfig, live)
 		if err != nil {
 			logger.Errorf("Failed to start CRD Controller, err:%v.\n", err)
 			return
@@ -566,7 +637,7 @@ func main() {
 	// Initialize multi-tenant controller if the CNS is running in MultiTenantCRD mode.
 	// It must be started before we start HTTPRestService.
 	if config.ChannelMode == cns.MultiTenantCRD {
-		err = InitializeMultiTenantController(rootCtx, httpRestService, cnsconfig)
+		err = InitializeMultiTenantController(rootCtx, httpRestService, cnsconfig, live)
 		if err != nil {
 			logger.Errorf("Failed to start multiTenantController, err:%v.\n", err)
 			return
@@ -582,9 +653,13 @@ func main() {
 		}
 	}
 
+	if configWatcher != nil {
+		go configWatcher.WatchPeriodically(rootCtx, configWatchInterval)
+	}
+
 	if !disableTelemetry {
 		go logger.SendHeartBeat(rootCtx, cnsconfig.TelemetrySettings.HeartBeatIntervalInMins)
-		go httpRestService.SendNCSnapShotPeriodically(rootCtx, cnsconfig.TelemetrySettings.SnapshotIntervalInMins)
+		go httpRestService.SendNCSnapShotPeriodically(rootCtx, live.ncSnapshotInterval)
 	}
 
 	// If CNS is running on managed DNC mode
@@ -601,7 +676,7 @@ func main() {
 		httpRestService.SetOption(acn.OptInfrastructureNetworkID, infravnet)
 		httpRestService.SetOption(acn.OptNodeID, nodeID)
 
-		registerErr := registerNode(acn.GetHttpClient(), httpRestService, privateEndpoint, infravnet, nodeID)
+		registerErr := registerNode(acn.GetHttpClient(), nmaclient, httpRestService, privateEndpoint, infravnet, nodeID)
 		if registerErr != nil {
 			logger.Errorf("[Azure CNS] Resgistering Node failed with error: %v PrivateEndpoint: %s InfrastructureNetworkID: %s NodeID: %s",
 				registerErr,
@@ -615,7 +690,7 @@ func main() {
 			tickerChannel := time.Tick(time.Duration(cnsconfig.ManagedSettings.NodeSyncIntervalInSeconds) * time.Second)
 			for {
 				<-tickerChannel
-				httpRestService.SyncNodeStatus(ep, vnet, node, json.RawMessage{})
+				httpRestService.SyncNodeStatus(rootCtx, ep, vnet, node, json.RawMessage{})
 			}
 		}(privateEndpoint, infravnet, nodeID)
 	}
@@ -645,10 +720,11 @@ func main() {
 		}
 
 		// Create the key value store.
-		pluginStoreFile := storeFileLocation + pluginName + ".json"
-		pluginConfig.Store, err = store.NewJsonFileStore(pluginStoreFile)
+		pluginStorePath := storeFileLocation + pluginName
+		pluginStoreType := store.DetectType(pluginStorePath)
+		pluginConfig.Store, err = store.NewKeyValueStore(pluginStoreType, pluginStorePath)
 		if err != nil {
-			logger.Errorf("Failed to create plugin store file %s, due to error : %v\n", pluginStoreFile, err)
+			logger.Errorf("Failed to create plugin store file %s, due to error : %v\n", pluginStorePath+pluginStoreType.FileExtension(), err)
 			return
 		}
 
@@ -683,7 +759,11 @@ func main() {
 
 	logger.Printf("stop cns service")
 	// Cleanup.
-	if httpRestService != nil {
+	if httpRestServiceImpl, ok := httpRestService.(*restserver.HTTPRestService); ok {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cnsconfig.ShutdownSettings.TimeoutInSecs)*time.Second)
+		httpRestServiceImpl.Shutdown(shutdownCtx)
+		cancel()
+	} else if httpRestService != nil {
 		httpRestService.Stop()
 	}
 
@@ -703,7 +783,7 @@ func main() {
 	logger.Close()
 }
 
-func InitializeMultiTenantController(ctx context.Context, httpRestService cns.HTTPService, cnsconfig configuration.CNSConfig) error {
+func InitializeMultiTenantController(ctx context.Context, httpRestService cns.HTTPService, cnsconfig configuration.CNSConfig, live *liveSettings) error {
 	var multiTenantController multitenantcontroller.RequestController
 	kubeConfig, err := ctrl.GetConfig()
 	if err != nil {
@@ -731,6 +811,13 @@ func InitializeMultiTenantController(ctx context.Context, httpRestService cns.HT
 		return err
 	}
 
+	httpRestServiceImpl.AddReadinessCheck("multitenant-controller", func() error {
+		if !multiTenantController.IsStarted() {
+			return fmt.Errorf("multiTenantController not started")
+		}
+		return nil
+	})
+
 	// Wait for multiTenantController to start.
 	go func() {
 		for {
@@ -757,24 +844,16 @@ func InitializeMultiTenantController(ctx context.Context, httpRestService cns.HT
 
 	// TODO: do we need this to be running?
 	logger.Printf("Starting SyncHostNCVersion")
-	go func() {
-		// Periodically poll vfp programmed NC version from NMAgent
-		tickerChannel := time.Tick(cnsconfig.SyncHostNCVersionIntervalMs * time.Millisecond)
-		for {
-			select {
-			case <-tickerChannel:
-				httpRestServiceImpl.SyncHostNCVersion(ctx, cnsconfig.ChannelMode, cnsconfig.SyncHostNCTimeoutMs)
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
+	// Periodically poll vfp programmed NC version from NMAgent
+	go live.syncHostNCVersionInterval.Tick(ctx, func() {
+		httpRestServiceImpl.SyncHostNCVersion(ctx, cnsconfig.ChannelMode, live.getSyncHostNCTimeout())
+	})
 
 	return nil
 }
 
 // initializeCRD state
-func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cnsconfig configuration.CNSConfig) error {
+func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cnsconfig configuration.CNSConfig, live *liveSettings) error {
 	var requestController singletenantcontroller.RequestController
 
 	logger.Printf("[Azure CNS] Starting request controller")
@@ -800,7 +879,7 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 	httpRestServiceImplementation.SetNodeOrchestrator(&orchestrator)
 
 	// Get crd implementation of request controller
-	requestController, err = kubecontroller.New(
+	crdRequestController, err := kubecontroller.New(
 		kubecontroller.Config{
 			InitializeFromCNI:  cnsconfig.InitializeFromCNI,
 			KubeConfig:         kubeConfig,
@@ -811,9 +890,17 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		logger.Errorf("[Azure CNS] Failed to make crd request controller :%v", err)
 		return err
 	}
+	requestController = crdRequestController
+	httpRestServiceImplementation.AddReadinessCheck("request-controller", func() error {
+		if !requestController.IsStarted() {
+			return fmt.Errorf("request controller not started")
+		}
+		return nil
+	})
 
 	// initialize the ipam pool monitor
 	httpRestServiceImplementation.IPAMPoolMonitor = ipampoolmonitor.NewCNSIPAMPoolMonitor(httpRestServiceImplementation, requestController)
+	httpRestServiceImplementation.SetNodeDeletionCheck(crdRequestController.IsNodeBeingDeleted)
 
 	err = requestController.Init(ctx)
 	if err != nil {
@@ -864,19 +951,67 @@ func InitializeCRDState(ctx context.Context, httpRestService cns.HTTPService, cn
 		}
 	}()
 
-	logger.Printf("Starting SyncHostNCVersion")
-	go func() {
-		// Periodically poll vfp programmed NC version from NMAgent
-		tickerChannel := time.Tick(cnsconfig.SyncHostNCVersionIntervalMs * time.Millisecond)
-		for {
-			select {
-			case <-tickerChannel:
-				httpRestServiceImplementation.SyncHostNCVersion(ctx, cnsconfig.ChannelMode, cnsconfig.SyncHostNCTimeoutMs)
-			case <-ctx.Done():
-				return
-			}
+	if cnsconfig.IPLeakDetectionSettings.Enable {
+		// Allocated IPs are leaked once they are held neither by a pod known to the apiserver
+		// nor by a CNI endpoint, if the CNI is new enough to dump its state.
+		podInfoByIPProviders := []cns.PodInfoByIPProvider{crdRequestController.PodInfoByIPProvider(ctx)}
+		if isGoodVer, err := cni.IsDumpStateVer(); err != nil {
+			logger.Errorf("error checking CNI ver: %v", err)
+		} else if isGoodVer {
+			podInfoByIPProviders = append(podInfoByIPProviders, cnireconciler.NewCNIPodInfoRefresher())
 		}
-	}()
+
+		logger.Printf("Starting IP leak detection")
+		go httpRestServiceImplementation.DetectIPLeaksPeriodically(ctx, restserver.IPLeakDetectorConfig{
+			Interval:    time.Duration(cnsconfig.IPLeakDetectionSettings.IntervalInSecs) * time.Second,
+			Release:     cnsconfig.IPLeakDetectionSettings.Release,
+			GracePeriod: time.Duration(cnsconfig.IPLeakDetectionSettings.GracePeriodInSecs) * time.Second,
+			DryRun:      cnsconfig.IPLeakDetectionSettings.DryRun,
+		}, podInfoByIPProviders...)
+	}
+
+	logger.Printf("Starting SyncHostNCVersion")
+	// Periodically poll vfp programmed NC version from NMAgent
+	go live.syncHostNCVersionInterval.Tick(ctx, func() {
+		httpRestServiceImplementation.SyncHostNCVersion(ctx, cnsconfig.ChannelMode, live.getSyncHostNCTimeout())
+	})
 
 	return nil
 }
+
+// liveSettings are the settings of the CNS config which are applied again when the config file is reloaded.
+type liveSettings struct {
+	cmdlineLogLevel           int
+	syncHostNCVersionInterval *common.Interval
+	ncSnapshotInterval        *common.Interval
+	// syncHostNCTimeout is a time.Duration, accessed atomically
+	syncHostNCTimeout int64
+}
+
+func newLiveSettings(cnsconfig configuration.CNSConfig, cmdlineLogLevel int) *liveSettings {
+	live := &liveSettings{
+		cmdlineLogLevel:           cmdlineLogLevel,
+		syncHostNCVersionInterval: common.NewInterval(cnsconfig.SyncHostNCVersionIntervalMs * time.Millisecond),
+		ncSnapshotInterval:        common.NewInterval(time.Duration(cnsconfig.TelemetrySettings.SnapshotIntervalInMins) * time.Minute),
+	}
+	live.apply(cnsconfig)
+	return live
+}
+
+// apply applies the live settings of a valid config.
+func (live *liveSettings) apply(cnsconfig configuration.CNSConfig) {
+	live.syncHostNCVersionInterval.Set(cnsconfig.SyncHostNCVersionIntervalMs * time.Millisecond)
+	atomic.StoreInt64(&live.syncHostNCTimeout, int64(cnsconfig.SyncHostNCTimeoutMs*time.Millisecond))
+	live.ncSnapshotInterval.Set(time.Duration(cnsconfig.TelemetrySettings.SnapshotIntervalInMins) * time.Minute)
+
+	ts := cnsconfig.TelemetrySettings
+	logger.SetTelemetryToggles(ts.DisableTrace, ts.DisableMetric, ts.DisableEvent)
+
+	if logLevel, err := cnsconfig.GetLogLevel(live.cmdlineLogLevel); err == nil {
+		logger.SetLogLevel(logLevel)
+	}
+}
+
+func (live *liveSettings) getSyncHostNCTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&live.syncHostNCTimeout))
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -30,6 +31,8 @@ const (
 	k8sNamespace   = "kube-system"
 	crdTypeName    = "nodenetworkconfigs"
 	allNamespaces  = ""
+	// toBeDeletedTaint is added by the cluster autoscaler to the nodes it drains to delete them
+	toBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
 )
 
 // Config has crdRequestController options
@@ -277,20 +280,60 @@ func (rc *requestController) initCNS(ctx context.Context) error {
 	return errors.Wrap(rc.CNSClient.ReconcileNCState(&ncRequest, podInfoByIP, nnc.Status.Scaler, nnc.Spec), "err in CNS reconciliation")
 }
 
-// kubePodsToPodInfoByIP maps kubernetes pods to cns.PodInfos by IP
+// kubePodsToPodInfoByIP maps kubernetes pods to cns.PodInfos by IP, including
+// both the IPv4 and IPv6 address of dual-stack pods.
 func (rc *requestController) kubePodsToPodInfoByIP(pods []corev1.Pod) (map[string]cns.PodInfo, error) {
 	podInfoByIP := map[string]cns.PodInfo{}
 	for _, pod := range pods {
 		if !pod.Spec.HostNetwork {
-			if _, ok := podInfoByIP[pod.Status.PodIP]; ok {
-				return nil, errors.Wrap(cns.ErrDuplicateIP, pod.Status.PodIP)
+			podInfo := cns.NewPodInfo("", "", pod.Name, pod.Namespace)
+			for _, podIP := range kubePodIPs(pod) {
+				if _, ok := podInfoByIP[podIP]; ok {
+					return nil, errors.Wrap(cns.ErrDuplicateIP, podIP)
+				}
+				podInfoByIP[podIP] = podInfo
 			}
-			podInfoByIP[pod.Status.PodIP] = cns.NewPodInfo("", "", pod.Name, pod.Namespace)
 		}
 	}
 	return podInfoByIP, nil
 }
 
+// kubePodIPs returns the IPs of the pod. PodIPs is only set by dual-stack aware
+// apiservers, and its first entry always matches PodIP.
+func kubePodIPs(pod corev1.Pod) []string {
+	if len(pod.Status.PodIPs) == 0 {
+		return []string{pod.Status.PodIP}
+	}
+
+	podIPs := make([]string, len(pod.Status.PodIPs))
+	for i := range pod.Status.PodIPs {
+		podIPs[i] = pod.Status.PodIPs[i].IP
+	}
+	return podIPs
+}
+
+// PodInfoByIPProvider returns a cns.PodInfoByIPProvider which lists the pods on the node using the
+// direct API client on every call. Pods which have not been assigned an IP yet are left out, and so are
+// pods which have terminated, since their sandbox and IP have been or are being torn down.
+func (rc *requestController) PodInfoByIPProvider(ctx context.Context) cns.PodInfoByIPProvider {
+	return cns.PodInfoByIPProviderFunc(func() (map[string]cns.PodInfo, error) {
+		pods, err := rc.getAllPods(ctx, rc.nodeName)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list pods on node")
+		}
+
+		assigned := make([]corev1.Pod, 0, len(pods.Items))
+		for i := range pods.Items {
+			status := pods.Items[i].Status
+			if status.PodIP == "" || status.Phase == corev1.PodSucceeded || status.Phase == corev1.PodFailed {
+				continue
+			}
+			assigned = append(assigned, pods.Items[i])
+		}
+		return rc.kubePodsToPodInfoByIP(assigned)
+	})
+}
+
 // UpdateCRDSpec updates the CRD spec
 func (rc *requestController) UpdateCRDSpec(ctx context.Context, nnc v1alpha.NodeNetworkConfigSpec) error {
 	nodeNetworkConfig, err := rc.getNodeNetConfig(ctx, rc.nodeName, k8sNamespace)
@@ -318,6 +361,28 @@ func (rc *requestController) UpdateCRDSpec(ctx context.Context, nnc v1alpha.Node
 	return nil
 }
 
+// IsNodeBeingDeleted returns whether the node running this program is deleted, is being deleted, or is
+// being drained by the cluster autoscaler to delete it.
+func (rc *requestController) IsNodeBeingDeleted(ctx context.Context) (bool, error) {
+	node, err := rc.directAPIClient.GetNode(ctx, rc.nodeName)
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get node")
+	}
+
+	if node.DeletionTimestamp != nil {
+		return true, nil
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == toBeDeletedTaint {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // getNodeNetConfig gets the nodeNetworkConfig CRD given the name and namespace of the CRD object
 func (rc *requestController) getNodeNetConfig(ctx context.Context, name, namespace string) (*v1alpha.NodeNetworkConfig, error) {
 	nodeNetworkConfig := &v1alpha.NodeNetworkConfig{}
@@ -13,6 +13,7 @@ import (
 	"github.com/Azure/azure-container-networking/cns/logger"
 	"github.com/Azure/azure-container-networking/crd/nodenetworkconfig/api/v1alpha"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,6 +38,7 @@ const (
 type MockAPI struct {
 	nodeNetConfigs map[MockKey]*v1alpha.NodeNetworkConfig
 	pods           map[MockKey]*corev1.Pod
+	nodes          map[string]*corev1.Node
 }
 
 // MockKey is the key to the mockAPI, namespace+"/"+name like in API server
@@ -174,6 +176,14 @@ func (mc *MockDirectAPIClient) ListPods(ctx context.Context, namespace, node str
 	return &pods, nil
 }
 
+func (mc *MockDirectAPIClient) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	node, ok := mc.mockAPI.nodes[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(corev1.Resource("nodes"), name)
+	}
+	return node, nil
+}
+
 func TestNewCrdRequestController(t *testing.T) {
 	// Test making request controller without logger initialized, should fail
 	_, err := New(Config{})
@@ -577,6 +587,69 @@ func TestGetPodsNonExistingNodeDirectClient(t *testing.T) {
 	}
 }
 
+// test that the pod info provider skips host network pods and pods without an IP
+func TestPodInfoByIPProvider(t *testing.T) {
+	mockPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      existingPodName,
+			Namespace: existingNamespace,
+		},
+		Status: corev1.PodStatus{
+			PodIP: allocatedPodIP,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: existingNNCName,
+		},
+	}
+	mockPodHostNetwork := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hostNetworkPodName,
+			Namespace: existingNamespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName:    existingNNCName,
+			HostNetwork: true,
+		},
+	}
+	mockPodPending1 := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod_pending_1",
+			Namespace: existingNamespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: existingNNCName,
+		},
+	}
+	mockPodPending2 := mockPodPending1.DeepCopy()
+	mockPodPending2.Name = "pod_pending_2"
+	mockAPI := &MockAPI{
+		pods: map[MockKey]*corev1.Pod{
+			{Namespace: existingNamespace, Name: existingPodName}:    mockPod,
+			{Namespace: existingNamespace, Name: hostNetworkPodName}: mockPodHostNetwork,
+			{Namespace: existingNamespace, Name: "pod_pending_1"}:    mockPodPending1,
+			{Namespace: existingNamespace, Name: "pod_pending_2"}:    mockPodPending2,
+		},
+	}
+	rc := &requestController{
+		directAPIClient: &MockDirectAPIClient{
+			mockAPI: mockAPI,
+		},
+		nodeName: existingNNCName,
+	}
+
+	podInfoByIP, err := rc.PodInfoByIPProvider(context.Background()).PodInfoByIP()
+	if err != nil {
+		t.Fatalf("Expected no error getting pod info by IP, got %v", err)
+	}
+
+	expected := map[string]cns.PodInfo{
+		allocatedPodIP: cns.NewPodInfo("", "", existingPodName, existingNamespace),
+	}
+	if !reflect.DeepEqual(podInfoByIP, expected) {
+		t.Fatalf("Expected pod info by IP %+v, got %+v", expected, podInfoByIP)
+	}
+}
+
 // test that cns init gets called
 func TestInitRequestController(t *testing.T) {
 	nodeNetConfigFill := &v1alpha.NodeNetworkConfig{
@@ -682,3 +755,62 @@ func TestInitRequestController(t *testing.T) {
 		t.Fatalf("Expected secondary ip config to be in ncrequest")
 	}
 }
+
+func TestIsNodeBeingDeleted(t *testing.T) {
+	now := metav1.Now()
+	mockAPI := &MockAPI{
+		nodes: map[string]*corev1.Node{
+			"running":  {ObjectMeta: metav1.ObjectMeta{Name: "running"}},
+			"deleting": {ObjectMeta: metav1.ObjectMeta{Name: "deleting", DeletionTimestamp: &now}},
+			"scaledown": {
+				ObjectMeta: metav1.ObjectMeta{Name: "scaledown"},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: toBeDeletedTaint, Effect: corev1.TaintEffectNoSchedule}}},
+			},
+		},
+	}
+
+	for node, expected := range map[string]bool{"running": false, "deleting": true, "scaledown": true, "deleted": true} {
+		rc := &requestController{
+			directAPIClient: &MockDirectAPIClient{mockAPI: mockAPI},
+			nodeName:        node,
+		}
+		deleting, err := rc.IsNodeBeingDeleted(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error checking whether node %s is being deleted, got %v", node, err)
+		}
+		if deleting != expected {
+			t.Fatalf("Expected node %s being deleted to be %t, got %t", node, expected, deleting)
+		}
+	}
+}
+
+func TestPodInfoByIPProviderSkipsTerminatedPods(t *testing.T) {
+	newPod := func(name, ip string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: existingNamespace},
+			Status:     corev1.PodStatus{PodIP: ip, Phase: phase},
+			Spec:       corev1.PodSpec{NodeName: existingNNCName},
+		}
+	}
+	mockAPI := &MockAPI{
+		pods: map[MockKey]*corev1.Pod{
+			{Namespace: existingNamespace, Name: "running"}:   newPod("running", "10.0.0.2", corev1.PodRunning),
+			{Namespace: existingNamespace, Name: "succeeded"}: newPod("succeeded", "10.0.0.3", corev1.PodSucceeded),
+			{Namespace: existingNamespace, Name: "failed"}:    newPod("failed", "10.0.0.4", corev1.PodFailed),
+			{Namespace: existingNamespace, Name: "pending"}:   newPod("pending", "", corev1.PodPending),
+		},
+	}
+	rc := &requestController{
+		directAPIClient: &MockDirectAPIClient{mockAPI: mockAPI},
+		nodeName:        existingNNCName,
+	}
+
+	podInfoByIP, err := rc.PodInfoByIPProvider(context.Background()).PodInfoByIP()
+	if err != nil {
+		t.Fatalf("Expected no error when getting the pods by IP, got %v", err)
+	}
+
+	if len(podInfoByIP) != 1 || podInfoByIP["10.0.0.2"] == nil || podInfoByIP["10.0.0.2"].Name() != "running" {
+		t.Fatalf("Expected only the running pod by IP, got %+v", podInfoByIP)
+	}
+}
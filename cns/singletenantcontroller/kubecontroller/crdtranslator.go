@@ -53,6 +53,24 @@ func CRDStatusToNCRequest(crdStatus v1alpha.NodeNetworkConfigStatus) (cns.Create
 		ipSubnet.PrefixLength = uint8(size)
 		ncRequest.IPConfiguration.IPSubnet = ipSubnet
 		ncRequest.IPConfiguration.GatewayIPAddress = nc.DefaultGateway
+
+		// dual-stack NCs also have an IPv6 primary IP and subnet
+		if nc.PrimaryIPv6 != "" {
+			if ip = net.ParseIP(nc.PrimaryIPv6); ip == nil || ip.To4() != nil {
+				return ncRequest, fmt.Errorf("Invalid PrimaryIPv6 %s:", nc.PrimaryIPv6)
+			}
+
+			if _, ipNet, err = net.ParseCIDR(nc.SubnetAddressSpaceV6); err != nil {
+				return ncRequest, fmt.Errorf("Invalid SubnetAddressSpaceV6 %s:, err:%s", nc.SubnetAddressSpaceV6, err)
+			}
+
+			size, _ = ipNet.Mask.Size()
+			ncRequest.IPv6Configuration.IPSubnet = cns.IPSubnet{
+				IPAddress:    ip.String(),
+				PrefixLength: uint8(size),
+			}
+			ncRequest.IPv6Configuration.GatewayIPAddress = nc.DefaultGatewayV6
+		}
 		var ncVersion int
 		if ncVersion, err = strconv.Atoi(ncRequest.Version); err != nil {
 			return ncRequest, fmt.Errorf("Invalid ncRequest.Version is %s in CRD, err:%s", ncRequest.Version, err)
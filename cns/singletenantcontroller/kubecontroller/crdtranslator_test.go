@@ -8,16 +8,21 @@ import (
 )
 
 const (
-	ncID               = "160005ba-cd02-11ea-87d0-0242ac130003"
-	primaryIp          = "10.0.0.1"
-	ipInCIDR           = "10.0.0.1/32"
-	ipMalformed        = "10.0.0.0.0"
-	defaultGateway     = "10.0.0.2"
-	subnetName         = "subnet1"
-	subnetAddressSpace = "10.0.0.0/24"
-	subnetPrefixLen    = 24
-	testSecIp1         = "10.0.0.2"
-	version            = 1
+	ncID                 = "160005ba-cd02-11ea-87d0-0242ac130003"
+	primaryIp            = "10.0.0.1"
+	ipInCIDR             = "10.0.0.1/32"
+	ipMalformed          = "10.0.0.0.0"
+	defaultGateway       = "10.0.0.2"
+	subnetName           = "subnet1"
+	subnetAddressSpace   = "10.0.0.0/24"
+	subnetPrefixLen      = 24
+	testSecIp1           = "10.0.0.2"
+	primaryIPv6          = "fd00::1"
+	defaultGatewayV6     = "fd00::2"
+	subnetAddressSpaceV6 = "fd00::/64"
+	subnetPrefixLenV6    = 64
+	testSecIPv6          = "fd00::3"
+	version              = 1
 )
 
 func TestStatusToNCRequestMalformedPrimaryIP(t *testing.T) {
@@ -240,3 +245,58 @@ func TestStatusToNCRequestSuccess(t *testing.T) {
 		t.Fatalf("Expected %d as the secondary IP config NC version but got %v", version, secondaryIP.NCVersion)
 	}
 }
+
+func TestStatusToNCRequestDualStack(t *testing.T) {
+	status := v1alpha.NodeNetworkConfigStatus{
+		NetworkContainers: []v1alpha.NetworkContainer{
+			{
+				PrimaryIP:   primaryIp,
+				PrimaryIPv6: primaryIPv6,
+				ID:          ncID,
+				IPAssignments: []v1alpha.IPAssignment{
+					{
+						Name: allocatedUUID,
+						IP:   testSecIp1,
+					},
+					{
+						Name: allocatedUUID + "-v6",
+						IP:   testSecIPv6,
+					},
+				},
+				SubnetName:           subnetName,
+				DefaultGateway:       defaultGateway,
+				DefaultGatewayV6:     defaultGatewayV6,
+				SubnetAddressSpace:   subnetAddressSpace,
+				SubnetAddressSpaceV6: subnetAddressSpaceV6,
+				Version:              version,
+			},
+		},
+	}
+
+	ncRequest, err := CRDStatusToNCRequest(status)
+	if err != nil {
+		t.Fatalf("Expected translation of dual-stack CRD status to succeed, got error :%v", err)
+	}
+
+	if ncRequest.IPv6Configuration.IPSubnet.IPAddress != primaryIPv6 {
+		t.Fatalf("Expected ncRequest's ipv6 configuration to have the ip %v but got %v", primaryIPv6, ncRequest.IPv6Configuration.IPSubnet.IPAddress)
+	}
+
+	if ncRequest.IPv6Configuration.IPSubnet.PrefixLength != uint8(subnetPrefixLenV6) {
+		t.Fatalf("Expected ncRequest's ipv6 configuration prefix length to be %v but got %v", subnetPrefixLenV6, ncRequest.IPv6Configuration.IPSubnet.PrefixLength)
+	}
+
+	if ncRequest.IPv6Configuration.GatewayIPAddress != defaultGatewayV6 {
+		t.Fatalf("Expected ncRequest's ipv6 configuration gateway to be %s but got %s", defaultGatewayV6, ncRequest.IPv6Configuration.GatewayIPAddress)
+	}
+
+	if secondaryIP := ncRequest.SecondaryIPConfigs[allocatedUUID+"-v6"]; secondaryIP.IPAddress != testSecIPv6 {
+		t.Fatalf("Expected %v as the secondary IPv6 config but got %v", testSecIPv6, secondaryIP.IPAddress)
+	}
+
+	// an IPv4 primary IPv6 is rejected
+	status.NetworkContainers[0].PrimaryIPv6 = primaryIp
+	if _, err = CRDStatusToNCRequest(status); err == nil {
+		t.Fatalf("Expected translation of CRD status with an IPv4 PrimaryIPv6 to fail")
+	}
+}
@@ -35,6 +35,12 @@ func (apiClient *APIDirectClient) ListPods(ctx context.Context, namespace, node
 	return pods, nil
 }
 
+// GetNode gets the node with the given name
+func (apiClient *APIDirectClient) GetNode(ctx context.Context, name string) (*corev1.Node, error) {
+	//nolint:wrapcheck
+	return apiClient.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+}
+
 // NewAPIDirectClient creates a new APIDirectClient
 func NewAPIDirectClient(kubeconfig *rest.Config) (*APIDirectClient, error) {
 	var (
@@ -22,4 +22,5 @@ type DirectCRDClient interface {
 // DirectAPIClient is an interface to talk directly with API Server without cache
 type DirectAPIClient interface {
 	ListPods(ctx context.Context, namespace, node string) (*corev1.PodList, error)
+	GetNode(ctx context.Context, name string) (*corev1.Node, error)
 }
@@ -38,6 +38,10 @@ const (
 	NetworkContainerVfpProgramCheckSkipped ResponseCode = 36
 	NmAgentSupportedApisError              ResponseCode = 37
 	UnsupportedNCVersion                   ResponseCode = 38
+	UnauthorizedPeer                       ResponseCode = 39
+	IPConfigRequestThrottled               ResponseCode = 40
+	NamespaceIPQuotaExceeded               ResponseCode = 41
+	ShuttingDown                           ResponseCode = 42
 	UnexpectedError                        ResponseCode = 99
 )
 
@@ -58,6 +62,8 @@ func (c ResponseCode) String() string {
 		return "InconsistentIPConfigState"
 	case InvalidParameter:
 		return "InvalidParameter"
+	case IPConfigRequestThrottled:
+		return "IPConfigRequestThrottled"
 	case InvalidPrimaryIPConfig:
 		return "InvalidPrimaryIPConfig"
 	case InvalidRequest:
@@ -66,6 +72,8 @@ func (c ResponseCode) String() string {
 		return "InvalidSecondaryIPConfig"
 	case MalformedSubnet:
 		return "MalformedSubnet"
+	case NamespaceIPQuotaExceeded:
+		return "NamespaceIPQuotaExceeded"
 	case NetworkContainerNotSpecified:
 		return "NetworkContainerNotSpecified"
 	case NetworkContainerPublishFailed:
@@ -88,8 +96,12 @@ func (c ResponseCode) String() string {
 		return "PrimaryCANotSame"
 	case ReservationNotFound:
 		return "ReservationNotFound"
+	case ShuttingDown:
+		return "ShuttingDown"
 	case Success:
 		return "Success"
+	case UnauthorizedPeer:
+		return "UnauthorizedPeer"
 	case UnexpectedError:
 		return "UnexpectedError"
 	case UnknownContainerID:
@@ -118,3 +130,8 @@ func (c ResponseCode) String() string {
 		return "UnknownError"
 	}
 }
+
+// Error makes a ResponseCode usable as the target of errors.Is, for errors which wrap the code CNS responded with.
+func (c ResponseCode) Error() string {
+	return c.String()
+}
@@ -11,11 +11,19 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 
 	"github.com/Azure/azure-container-networking/log"
 	localtls "github.com/Azure/azure-container-networking/server/tls"
 )
 
+const (
+	// socketDirMode is the mode of the directory created for a unix socket.
+	socketDirMode = 0o750
+	// socketFileMode is the mode of a unix socket, which only its owner and group may connect to.
+	socketFileMode = 0o660
+)
+
 // Listener represents an HTTP listener.
 type Listener struct {
 	URL            *url.URL
@@ -110,23 +118,65 @@ func (listener *Listener) Start(errChan chan<- error) error {
 		return nil
 	}
 
+	server := http.Server{
+		Handler: listener.mux,
+	}
+
+	if listener.protocol == "unix" {
+		if err = prepareUnixSocket(listener.localAddress); err != nil {
+			log.Printf("[Listener] Failed to prepare socket %s: %+v", listener.localAddress, err)
+			return err
+		}
+
+		// Make the credentials of the calling process available to the handlers.
+		server.ConnContext = withPeerCredentials
+	}
+
 	listener.l, err = net.Listen(listener.protocol, listener.localAddress)
 	if err != nil {
 		log.Printf("[Listener] Failed to listen: %+v", err)
 		return err
 	}
 
+	if listener.protocol == "unix" {
+		if err = os.Chmod(listener.localAddress, socketFileMode); err != nil {
+			log.Printf("[Listener] Failed to set the mode of socket %s: %+v", listener.localAddress, err)
+			listener.l.Close()
+			return err
+		}
+	}
+
 	log.Printf("[Listener] Started listening on %s.", listener.localAddress)
 
 	// Launch goroutine for servicing requests.
 	go func() {
-		errChan <- http.Serve(listener.l, listener.mux)
+		errChan <- server.Serve(listener.l)
 	}()
 
 	listener.active = true
 	return nil
 }
 
+// prepareUnixSocket creates the directory of the socket, and removes a socket left behind at the path by a
+// previous instance which did not exit cleanly. Any other file at the path is left alone and fails the listener.
+func prepareUnixSocket(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), socketDirMode); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path) //nolint:goerr113
+	}
+	return os.Remove(path)
+}
+
 // Stop stops listening for requests.
 func (listener *Listener) Stop() {
 	// Ignore if not active.
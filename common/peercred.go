@@ -0,0 +1,74 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package common
+
+import (
+	"context"
+	"net"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// PeerCredentials identifies the process on the other end of a unix socket connection.
+type PeerCredentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// ConnPeer describes the other end of the connection a request was received on.
+type ConnPeer struct {
+	// UnixSocket is whether the connection is on a unix socket.
+	UnixSocket bool
+	// Credentials of the peer process, nil if the connection is not on a unix socket or they could
+	// not be read.
+	Credentials *PeerCredentials
+}
+
+type connPeerKey struct{}
+
+// NewConnPeer returns the ConnPeer of the connection, reading the peer credentials of unix sockets.
+func NewConnPeer(c net.Conn) ConnPeer {
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ConnPeer{}
+	}
+
+	creds, err := getPeerCredentials(uc)
+	if err != nil {
+		log.Printf("[Listener] Failed to get peer credentials: %v", err)
+		return ConnPeer{UnixSocket: true}
+	}
+
+	return ConnPeer{UnixSocket: true, Credentials: &creds}
+}
+
+// ContextWithConnPeer returns a copy of the ctx carrying the ConnPeer.
+func ContextWithConnPeer(ctx context.Context, p ConnPeer) context.Context {
+	return context.WithValue(ctx, connPeerKey{}, p)
+}
+
+// ConnPeerFromContext returns the ConnPeer of the connection a request was received on. It is
+// the zero ConnPeer if the request was not received on a unix socket.
+func ConnPeerFromContext(ctx context.Context) ConnPeer {
+	p, _ := ctx.Value(connPeerKey{}).(ConnPeer)
+	return p
+}
+
+// PeerCredentialsFromContext returns the PeerCredentials of the connection a
+// request was received on. It returns false if the request was not received
+// on a unix socket or the credentials could not be read.
+func PeerCredentialsFromContext(ctx context.Context) (PeerCredentials, bool) {
+	p := ConnPeerFromContext(ctx)
+	if p.Credentials == nil {
+		return PeerCredentials{}, false
+	}
+	return *p.Credentials, true
+}
+
+// withPeerCredentials is used as the http.Server ConnContext for unix socket
+// listeners, and attaches the ConnPeer of the connection to the context.
+func withPeerCredentials(ctx context.Context, c net.Conn) context.Context {
+	return ContextWithConnPeer(ctx, NewConnPeer(c))
+}
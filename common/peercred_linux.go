@@ -0,0 +1,50 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package common
+
+import (
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// getPeerCredentials reads SO_PEERCRED from the unix socket.
+func getPeerCredentials(conn *net.UnixConn) (PeerCredentials, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return PeerCredentials{}, errors.Wrap(err, "failed to get raw conn")
+	}
+
+	var (
+		ucred   *unix.Ucred
+		sockErr error
+	)
+	err = raw.Control(func(fd uintptr) {
+		ucred, sockErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return PeerCredentials{}, errors.Wrap(err, "failed to control raw conn")
+	}
+	if sockErr != nil {
+		return PeerCredentials{}, errors.Wrap(sockErr, "failed to get SO_PEERCRED")
+	}
+
+	return PeerCredentials{
+		PID: ucred.Pid,
+		UID: ucred.Uid,
+		GID: ucred.Gid,
+	}, nil
+}
+
+// PeerBinary returns the path of the executable the peer process is running.
+func PeerBinary(creds PeerCredentials) (string, error) {
+	path, err := os.Readlink("/proc/" + strconv.Itoa(int(creds.PID)) + "/exe")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read executable of pid %d", creds.PID)
+	}
+	return path, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package common
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixListenerPeerCredentials(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "test.sock")
+	// a stale socket left behind by a previous instance must not block the listener.
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	listener, err := NewListener(&url.URL{Scheme: "unix", Path: socket})
+	require.NoError(t, err)
+
+	var (
+		creds PeerCredentials
+		found bool
+	)
+	listener.AddHandler("/", func(w http.ResponseWriter, r *http.Request) {
+		creds, found = PeerCredentialsFromContext(r.Context())
+	})
+	require.NoError(t, listener.Start(make(chan error, 1)))
+	defer listener.Stop()
+
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	resp, err := client.Get("http://localhost/")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.True(t, found)
+	assert.Equal(t, uint32(os.Getuid()), creds.UID)
+	assert.Equal(t, int32(os.Getpid()), creds.PID)
+
+	binary, err := PeerBinary(creds)
+	require.NoError(t, err)
+	self, err := os.Executable()
+	require.NoError(t, err)
+	assert.Equal(t, self, binary)
+}
+
+func TestUnixListenerSocketPath(t *testing.T) {
+	// the directory of the socket is created by the listener.
+	socket := filepath.Join(t.TempDir(), "run", "test.sock")
+	listener, err := NewListener(&url.URL{Scheme: "unix", Path: socket})
+	require.NoError(t, err)
+	require.NoError(t, listener.Start(make(chan error, 1)))
+	listener.Stop()
+
+	info, err := os.Stat(filepath.Dir(socket))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketDirMode), info.Mode().Perm())
+
+	// a file which isn't a socket is never removed.
+	file := filepath.Join(t.TempDir(), "test.sock")
+	require.NoError(t, os.WriteFile(file, []byte("state"), 0o600))
+	listener, err = NewListener(&url.URL{Scheme: "unix", Path: file})
+	require.NoError(t, err)
+	assert.Error(t, listener.Start(make(chan error, 1)))
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "state", string(content))
+}
+
+func TestUnixListenerSocketMode(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := NewListener(&url.URL{Scheme: "unix", Path: socket})
+	require.NoError(t, err)
+	require.NoError(t, listener.Start(make(chan error, 1)))
+	defer listener.Stop()
+
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketFileMode), info.Mode().Perm())
+}
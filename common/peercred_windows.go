@@ -0,0 +1,22 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package common
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+var errPeerCredentialsUnsupported = errors.New("peer credentials are not supported on windows")
+
+// getPeerCredentials is not supported on windows, where AF_UNIX has no SO_PEERCRED.
+func getPeerCredentials(*net.UnixConn) (PeerCredentials, error) {
+	return PeerCredentials{}, errPeerCredentialsUnsupported
+}
+
+// PeerBinary is not supported on windows.
+func PeerBinary(PeerCredentials) (string, error) {
+	return "", errPeerCredentialsUnsupported
+}
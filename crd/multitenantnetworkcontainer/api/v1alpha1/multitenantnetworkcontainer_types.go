@@ -7,6 +7,16 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 // Important: Run "make" to regenerate code after modifying this file
 
+// Types of the MultiTenantNetworkContainer status conditions.
+const (
+	// ConditionProgrammed is true when CNS has persisted the network container.
+	ConditionProgrammed = "Programmed"
+	// ConditionReady is true when the network container is programmed for the latest spec and can be used by its pod.
+	ConditionReady = "Ready"
+	// ConditionFailed is true when the last reconcile of the network container failed, its reason says why.
+	ConditionFailed = "Failed"
+)
+
 // MultiTenantInfo holds the encap type and id for the NC
 type MultiTenantInfo struct {
 	// EncapType is type of encapsulation
@@ -45,6 +55,12 @@ type MultiTenantNetworkContainerStatus struct {
 	PrimaryInterfaceIdentifier string `json:"primaryInterfaceIdentifier,omitempty"`
 	// MultiTenantInfo holds the encap type and id
 	MultiTenantInfo MultiTenantInfo `json:"multiTenantInfo,omitempty"`
+	// ObservedGeneration is the generation of the spec last reconciled by CNS
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the Programmed, Ready and Failed conditions of the network container
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
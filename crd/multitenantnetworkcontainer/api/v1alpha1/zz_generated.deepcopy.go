@@ -5,6 +5,7 @@
 package v1alpha1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -29,7 +30,7 @@ func (in *MultiTenantNetworkContainer) DeepCopyInto(out *MultiTenantNetworkConta
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiTenantNetworkContainer.
@@ -101,6 +102,13 @@ func (in *MultiTenantNetworkContainerSpec) DeepCopy() *MultiTenantNetworkContain
 func (in *MultiTenantNetworkContainerStatus) DeepCopyInto(out *MultiTenantNetworkContainerStatus) {
 	*out = *in
 	out.MultiTenantInfo = in.MultiTenantInfo
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiTenantNetworkContainerStatus.
@@ -69,14 +69,18 @@ const (
 )
 
 // NetworkContainer defines the structure of a Network Container as found in NetworkConfigStatus
+// The IPv6 fields are only set for dual-stack NCs, which then also have IPv6 IPAssignments.
 type NetworkContainer struct {
-	ID                 string         `json:"id,omitempty"`
-	PrimaryIP          string         `json:"primaryIP,omitempty"`
-	SubnetName         string         `json:"subnetName,omitempty"`
-	IPAssignments      []IPAssignment `json:"ipAssignments,omitempty"`
-	DefaultGateway     string         `json:"defaultGateway,omitempty"`
-	SubnetAddressSpace string         `json:"subnetAddressSpace,omitempty"`
-	Version            int64          `json:"version,omitempty"`
+	ID                   string         `json:"id,omitempty"`
+	PrimaryIP            string         `json:"primaryIP,omitempty"`
+	PrimaryIPv6          string         `json:"primaryIPv6,omitempty"`
+	SubnetName           string         `json:"subnetName,omitempty"`
+	IPAssignments        []IPAssignment `json:"ipAssignments,omitempty"`
+	DefaultGateway       string         `json:"defaultGateway,omitempty"`
+	DefaultGatewayV6     string         `json:"defaultGatewayV6,omitempty"`
+	SubnetAddressSpace   string         `json:"subnetAddressSpace,omitempty"`
+	SubnetAddressSpaceV6 string         `json:"subnetAddressSpaceV6,omitempty"`
+	Version              int64          `json:"version,omitempty"`
 }
 
 // IPAssignment groups an IP address and Name. Name is a UUID set by the the IP address assigner.
@@ -112,7 +112,13 @@ func (logger *Logger) GetLogDirectory() string {
 	return LogPath
 }
 
-// GetLogFileName returns the full log file name.
+// GetLogFileName returns the full name of the active log file. Rotated log files are named
+// after it, suffixed with their number.
+func (logger *Logger) GetLogFileName() string {
+	return logger.getLogFileName()
+}
+
+// getLogFileName returns the full log file name.
 func (logger *Logger) getLogFileName() string {
 	var logFileName string
 
@@ -4,7 +4,11 @@
 package network
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,11 +30,19 @@ const (
 	genericData = "com.docker.network.generic"
 )
 
+// Key of the endpoints in transactional stores, which keep every endpoint under a key of its own.
+const endpointsStoreKey = storeKey + "/Endpoints"
+
 var (
 	Ipv4DefaultRouteDstPrefix = net.IPNet{
 		IP:   net.IPv4zero,
 		Mask: net.IPv4Mask(0, 0, 0, 0),
 	}
+
+	Ipv6DefaultRouteDstPrefix = net.IPNet{
+		IP:   net.IPv6zero,
+		Mask: net.CIDRMask(0, 128),
+	}
 )
 
 type NetworkClient interface {
@@ -55,9 +67,11 @@ type EndpointClient interface {
 // NetworkManager manages the set of container networking resources.
 type networkManager struct {
 	Version            string
+	SchemaVersion      int
 	TimeStamp          time.Time
 	ExternalInterfaces map[string]*externalInterface
 	store              store.KeyValueStore
+	endpoints          *store.KeyedMap
 	sync.Mutex
 }
 
@@ -88,6 +102,7 @@ type NetworkManager interface {
 func NewNetworkManager() (NetworkManager, error) {
 	nm := &networkManager{
 		ExternalInterfaces: make(map[string]*externalInterface),
+		endpoints:          store.NewKeyedMap(endpointsStoreKey),
 	}
 
 	return nm, nil
@@ -119,8 +134,8 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 	// After a reboot, all address resources are implicitly released.
 	// Ignore the persisted state if it is older than the last reboot time.
 
-	// Read any persisted state.
-	err := nm.store.Read(storeKey, nm)
+	// Read any persisted state, migrating it to the current schema.
+	_, err := networkStateSchema.Read(nm.store, storeKey, nm)
 	if err != nil {
 		if err == store.ErrKeyNotFound {
 			log.Printf("[net] network store key not found")
@@ -135,6 +150,11 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 		}
 	}
 
+	if err := nm.restoreEndpoints(); err != nil {
+		log.Printf("[net] Failed to restore endpoints, err:%v\n", err)
+		return err
+	}
+
 	if isRehydrationRequired {
 		modTime, err := nm.store.GetModificationTime()
 		if err == nil {
@@ -143,7 +163,7 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 			if err == nil && rebootTime.After(modTime) {
 				log.Printf("[net] Detected Reboot")
 				rebooted = true
-				if clearNwConfig, err := platform.ClearNetworkConfiguration(); clearNwConfig {
+				if clearNwConfig, err := platform.ClearNetworkConfiguration(nm.removeStore); clearNwConfig {
 					if err != nil {
 						log.Printf("[net] Failed to clear network configuration, err:%v\n", err)
 						return err
@@ -208,6 +228,17 @@ func (nm *networkManager) restore(isRehydrationRequired bool) error {
 	return nil
 }
 
+// removeStore removes the file backing the store, whichever type it is.
+func (nm *networkManager) removeStore() error {
+	fileName := nm.store.GetFileName()
+	log.Printf("[net] Deleting the store %s", fileName)
+	nm.store.Remove()
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete the store %s: %v", fileName, err)
+	}
+	return nil
+}
+
 // Save writes network manager state to persistent store.
 func (nm *networkManager) save() error {
 	// Skip if a store is not provided.
@@ -217,8 +248,14 @@ func (nm *networkManager) save() error {
 
 	// Update time stamp.
 	nm.TimeStamp = time.Now()
+	nm.SchemaVersion = networkStateSchema.Version()
 
-	err := nm.store.Write(storeKey, nm)
+	var err error
+	if kvs, ok := nm.store.(store.TransactionalKeyValueStore); ok {
+		err = nm.saveEndpoints(kvs)
+	} else {
+		err = nm.store.Write(storeKey, nm)
+	}
 	if err == nil {
 		log.Printf("[net] Save succeeded.\n")
 	} else {
@@ -227,6 +264,92 @@ func (nm *networkManager) save() error {
 	return err
 }
 
+// endpointStoreID returns the ID of the endpoint in the endpoints of the store.
+func endpointStoreID(extIfName, networkID, endpointID string) string {
+	return extIfName + "/" + networkID + "/" + endpointID
+}
+
+// endpointsMap returns the KeyedMap the endpoints are kept in by transactional stores.
+func (nm *networkManager) endpointsMap() *store.KeyedMap {
+	if nm.endpoints == nil {
+		nm.endpoints = store.NewKeyedMap(endpointsStoreKey)
+	}
+	return nm.endpoints
+}
+
+// saveEndpoints writes the state without its endpoints under the network store key and
+// only the endpoints which changed under keys of their own, in a single transaction.
+func (nm *networkManager) saveEndpoints(kvs store.TransactionalKeyValueStore) error {
+	state := &networkManager{
+		Version:            nm.Version,
+		SchemaVersion:      nm.SchemaVersion,
+		TimeStamp:          nm.TimeStamp,
+		ExternalInterfaces: make(map[string]*externalInterface, len(nm.ExternalInterfaces)),
+	}
+	endpoints := make(map[string]interface{})
+	for extIfName, extIf := range nm.ExternalInterfaces {
+		extIfCopy := *extIf
+		extIfCopy.Networks = make(map[string]*network, len(extIf.Networks))
+		for networkID, nw := range extIf.Networks {
+			nwCopy := *nw
+			nwCopy.Endpoints = make(map[string]*endpoint)
+			extIfCopy.Networks[networkID] = &nwCopy
+			for endpointID, ep := range nw.Endpoints {
+				endpoints[endpointStoreID(extIfName, networkID, endpointID)] = ep
+			}
+		}
+		state.ExternalInterfaces[extIfName] = &extIfCopy
+	}
+
+	return nm.endpointsMap().Update(kvs, endpoints, func(tx store.Transaction) error {
+		return tx.Write(storeKey, state)
+	})
+}
+
+// restoreEndpoints adds the endpoints kept under keys of their own by transactional stores to their networks.
+// If the networks already hold endpoints, the state was migrated from a JSON file store since and they are kept instead.
+func (nm *networkManager) restoreEndpoints() error {
+	if _, ok := nm.store.(store.TransactionalKeyValueStore); !ok {
+		return nil
+	}
+
+	migrated := false
+	for _, extIf := range nm.ExternalInterfaces {
+		for _, nw := range extIf.Networks {
+			migrated = migrated || len(nw.Endpoints) > 0
+		}
+	}
+
+	return nm.endpointsMap().Read(nm.store, func(id string, raw json.RawMessage) error {
+		if migrated {
+			return nil
+		}
+		parts := strings.SplitN(id, "/", 3)
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid endpoint ID %s in store", id)
+		}
+		extIf, ok := nm.ExternalInterfaces[parts[0]]
+		if !ok {
+			log.Printf("[net] Ignoring endpoint %s of unknown external interface", id)
+			return nil
+		}
+		nw, ok := extIf.Networks[parts[1]]
+		if !ok {
+			log.Printf("[net] Ignoring endpoint %s of unknown network", id)
+			return nil
+		}
+		if nw.Endpoints == nil {
+			nw.Endpoints = make(map[string]*endpoint)
+		}
+		var ep endpoint
+		if err := json.Unmarshal(raw, &ep); err != nil {
+			return fmt.Errorf("failed to decode endpoint %s: %v", id, err)
+		}
+		nw.Endpoints[parts[2]] = &ep
+		return nil
+	})
+}
+
 //
 // NetworkManager API
 //
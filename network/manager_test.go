@@ -2,6 +2,9 @@ package network
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -80,6 +83,27 @@ var (
 				})
 			})
 
+			Context("When state has a newer schema version", func() {
+				It("Should refuse to restore it", func() {
+					dir, err := ioutil.TempDir("", "network")
+					Expect(err).NotTo(HaveOccurred())
+					defer os.RemoveAll(dir)
+
+					kvs, err := store.NewJsonFileStore(filepath.Join(dir, "azure-vnet.json"))
+					Expect(err).NotTo(HaveOccurred())
+					err = kvs.Write(storeKey, map[string]interface{}{
+						store.SchemaVersionField: networkStateSchema.Version() + 1,
+						"Version":                "v2",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					nm := &networkManager{store: kvs}
+					err = nm.restore(false)
+					Expect(errors.Is(err, store.ErrNewerStateSchemaVersion)).To(BeTrue())
+					Expect(nm.Version).To(BeEmpty())
+				})
+			})
+
 			Context("When GetModificationTime error and not rebooted", func() {
 				It("Should populate pointers", func() {
 					extIfName := "eth0"
@@ -123,6 +147,53 @@ var (
 					Expect(nm.TimeStamp).NotTo(Equal(time.Time{}))
 				})
 			})
+			Context("When store is transactional", func() {
+				It("Should keep every endpoint under a key of its own", func() {
+					dir, err := ioutil.TempDir("", "network")
+					Expect(err).NotTo(HaveOccurred())
+					defer os.RemoveAll(dir)
+
+					kvs, err := store.NewBoltStore(filepath.Join(dir, "azure-vnet.db"))
+					Expect(err).NotTo(HaveOccurred())
+					defer kvs.Remove()
+
+					nm := &networkManager{
+						store: kvs,
+						ExternalInterfaces: map[string]*externalInterface{
+							"eth0": {
+								Name: "eth0",
+								Networks: map[string]*network{
+									"azure": {
+										Id: "azure",
+										Endpoints: map[string]*endpoint{
+											"ep1": {Id: "ep1", IfName: "eth0"},
+										},
+									},
+								},
+							},
+						},
+					}
+					Expect(nm.save()).To(Succeed())
+
+					var ep endpoint
+					Expect(kvs.Read(endpointsStoreKey+"/eth0/azure/ep1", &ep)).To(Succeed())
+					Expect(ep.Id).To(Equal("ep1"))
+					var state networkManager
+					Expect(kvs.Read(storeKey, &state)).To(Succeed())
+					Expect(state.ExternalInterfaces["eth0"].Networks["azure"].Endpoints).To(BeEmpty())
+
+					delete(nm.ExternalInterfaces["eth0"].Networks["azure"].Endpoints, "ep1")
+					nm.ExternalInterfaces["eth0"].Networks["azure"].Endpoints["ep2"] = &endpoint{Id: "ep2"}
+					Expect(nm.save()).To(Succeed())
+					Expect(kvs.Read(endpointsStoreKey+"/eth0/azure/ep1", &ep)).To(Equal(store.ErrKeyNotFound))
+
+					restored := &networkManager{store: kvs}
+					Expect(restored.restore(false)).To(Succeed())
+					endpoints := restored.ExternalInterfaces["eth0"].Networks["azure"].Endpoints
+					Expect(endpoints).To(HaveLen(1))
+					Expect(endpoints["ep2"].Id).To(Equal("ep2"))
+				})
+			})
 		})
 
 		Describe("Test GetNumberOfEndpoints", func() {
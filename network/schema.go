@@ -0,0 +1,24 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package network
+
+import (
+	"encoding/json"
+
+	"github.com/Azure/azure-container-networking/store"
+)
+
+// networkStateSchema is the ordered list of migrations of the persisted network manager state.
+// Add a migration with the next version whenever a change to the persisted fields of the network
+// manager, its external interfaces, networks or endpoints can't be read correctly by JSON leniency alone.
+var networkStateSchema = store.Schema{
+	Name: "network",
+	Migrations: []store.Migration{
+		{
+			Version:     1,
+			Description: "add schema version",
+			Migrate:     func(map[string]json.RawMessage) error { return nil },
+		},
+	},
+}
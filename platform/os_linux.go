@@ -100,9 +100,9 @@ func SetOutboundSNAT(subnet string) error {
 	return nil
 }
 
-// ClearNetworkConfiguration clears the azure-vnet.json contents.
+// ClearNetworkConfiguration clears the network configuration persisted by CNI with clearStore.
 // This will be called only when reboot is detected - This is windows specific
-func ClearNetworkConfiguration() (bool, error) {
+func ClearNetworkConfiguration(clearStore func() error) (bool, error) {
 	return false, nil
 }
 
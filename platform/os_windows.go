@@ -108,15 +108,12 @@ func SetOutboundSNAT(subnet string) error {
 	return nil
 }
 
-// ClearNetworkConfiguration clears the azure-vnet.json contents.
+// ClearNetworkConfiguration clears the network configuration persisted by CNI with clearStore,
+// which removes its state file, azure-vnet.json or azure-vnet.db.
 // This will be called only when reboot is detected - This is windows specific
-func ClearNetworkConfiguration() (bool, error) {
-	jsonStore := CNIRuntimePath + "azure-vnet.json"
-	log.Printf("Deleting the json store %s", jsonStore)
-	cmd := exec.Command("cmd", "/c", "del", jsonStore)
-
-	if err := cmd.Run(); err != nil {
-		log.Printf("Error deleting the json store %s", jsonStore)
+func ClearNetworkConfiguration(clearStore func() error) (bool, error) {
+	if err := clearStore(); err != nil {
+		log.Printf("Error clearing the network configuration: %v", err)
 		return true, err
 	}
 
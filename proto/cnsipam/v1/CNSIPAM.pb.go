@@ -0,0 +1,1534 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.14.0
+// source: CNSIPAM.proto
+
+package cnsipam
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type IPConfigState int32
+
+const (
+	IPConfigState_IP_CONFIG_STATE_UNSPECIFIED         IPConfigState = 0
+	IPConfigState_IP_CONFIG_STATE_AVAILABLE           IPConfigState = 1
+	IPConfigState_IP_CONFIG_STATE_ALLOCATED           IPConfigState = 2
+	IPConfigState_IP_CONFIG_STATE_PENDING_RELEASE     IPConfigState = 3
+	IPConfigState_IP_CONFIG_STATE_PENDING_PROGRAMMING IPConfigState = 4
+)
+
+// Enum value maps for IPConfigState.
+var (
+	IPConfigState_name = map[int32]string{
+		0: "IP_CONFIG_STATE_UNSPECIFIED",
+		1: "IP_CONFIG_STATE_AVAILABLE",
+		2: "IP_CONFIG_STATE_ALLOCATED",
+		3: "IP_CONFIG_STATE_PENDING_RELEASE",
+		4: "IP_CONFIG_STATE_PENDING_PROGRAMMING",
+	}
+	IPConfigState_value = map[string]int32{
+		"IP_CONFIG_STATE_UNSPECIFIED":         0,
+		"IP_CONFIG_STATE_AVAILABLE":           1,
+		"IP_CONFIG_STATE_ALLOCATED":           2,
+		"IP_CONFIG_STATE_PENDING_RELEASE":     3,
+		"IP_CONFIG_STATE_PENDING_PROGRAMMING": 4,
+	}
+)
+
+func (x IPConfigState) Enum() *IPConfigState {
+	p := new(IPConfigState)
+	*p = x
+	return p
+}
+
+func (x IPConfigState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IPConfigState) Descriptor() protoreflect.EnumDescriptor {
+	return file_CNSIPAM_proto_enumTypes[0].Descriptor()
+}
+
+func (IPConfigState) Type() protoreflect.EnumType {
+	return &file_CNSIPAM_proto_enumTypes[0]
+}
+
+func (x IPConfigState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IPConfigState.Descriptor instead.
+func (IPConfigState) EnumDescriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{0}
+}
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED EventType = 0
+	EventType_EVENT_TYPE_ADDED       EventType = 1
+	EventType_EVENT_TYPE_MODIFIED    EventType = 2
+	EventType_EVENT_TYPE_DELETED     EventType = 3
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_ADDED",
+		2: "EVENT_TYPE_MODIFIED",
+		3: "EVENT_TYPE_DELETED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED": 0,
+		"EVENT_TYPE_ADDED":       1,
+		"EVENT_TYPE_MODIFIED":    2,
+		"EVENT_TYPE_DELETED":     3,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_CNSIPAM_proto_enumTypes[1].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_CNSIPAM_proto_enumTypes[1]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{1}
+}
+
+type PodInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name             string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace        string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	InfraContainerId string `protobuf:"bytes,3,opt,name=infra_container_id,json=infraContainerId,proto3" json:"infra_container_id,omitempty"`
+	InterfaceId      string `protobuf:"bytes,4,opt,name=interface_id,json=interfaceId,proto3" json:"interface_id,omitempty"`
+}
+
+func (x *PodInfo) Reset() {
+	*x = PodInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PodInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PodInfo) ProtoMessage() {}
+
+func (x *PodInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PodInfo.ProtoReflect.Descriptor instead.
+func (*PodInfo) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PodInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PodInfo) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *PodInfo) GetInfraContainerId() string {
+	if x != nil {
+		return x.InfraContainerId
+	}
+	return ""
+}
+
+func (x *PodInfo) GetInterfaceId() string {
+	if x != nil {
+		return x.InterfaceId
+	}
+	return ""
+}
+
+type IPConfigRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DesiredIpAddress string   `protobuf:"bytes,1,opt,name=desired_ip_address,json=desiredIpAddress,proto3" json:"desired_ip_address,omitempty"`
+	PodInterfaceId   string   `protobuf:"bytes,2,opt,name=pod_interface_id,json=podInterfaceId,proto3" json:"pod_interface_id,omitempty"`
+	InfraContainerId string   `protobuf:"bytes,3,opt,name=infra_container_id,json=infraContainerId,proto3" json:"infra_container_id,omitempty"`
+	PodInfo          *PodInfo `protobuf:"bytes,4,opt,name=pod_info,json=podInfo,proto3" json:"pod_info,omitempty"`
+}
+
+func (x *IPConfigRequest) Reset() {
+	*x = IPConfigRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfigRequest) ProtoMessage() {}
+
+func (x *IPConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfigRequest.ProtoReflect.Descriptor instead.
+func (*IPConfigRequest) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *IPConfigRequest) GetDesiredIpAddress() string {
+	if x != nil {
+		return x.DesiredIpAddress
+	}
+	return ""
+}
+
+func (x *IPConfigRequest) GetPodInterfaceId() string {
+	if x != nil {
+		return x.PodInterfaceId
+	}
+	return ""
+}
+
+func (x *IPConfigRequest) GetInfraContainerId() string {
+	if x != nil {
+		return x.InfraContainerId
+	}
+	return ""
+}
+
+func (x *IPConfigRequest) GetPodInfo() *PodInfo {
+	if x != nil {
+		return x.PodInfo
+	}
+	return nil
+}
+
+type IPSubnet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IpAddress    string `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	PrefixLength uint32 `protobuf:"varint,2,opt,name=prefix_length,json=prefixLength,proto3" json:"prefix_length,omitempty"`
+}
+
+func (x *IPSubnet) Reset() {
+	*x = IPSubnet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPSubnet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPSubnet) ProtoMessage() {}
+
+func (x *IPSubnet) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPSubnet.ProtoReflect.Descriptor instead.
+func (*IPSubnet) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *IPSubnet) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *IPSubnet) GetPrefixLength() uint32 {
+	if x != nil {
+		return x.PrefixLength
+	}
+	return 0
+}
+
+type IPConfiguration struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IpSubnet         *IPSubnet `protobuf:"bytes,1,opt,name=ip_subnet,json=ipSubnet,proto3" json:"ip_subnet,omitempty"`
+	DnsServers       []string  `protobuf:"bytes,2,rep,name=dns_servers,json=dnsServers,proto3" json:"dns_servers,omitempty"`
+	GatewayIpAddress string    `protobuf:"bytes,3,opt,name=gateway_ip_address,json=gatewayIpAddress,proto3" json:"gateway_ip_address,omitempty"`
+}
+
+func (x *IPConfiguration) Reset() {
+	*x = IPConfiguration{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfiguration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfiguration) ProtoMessage() {}
+
+func (x *IPConfiguration) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfiguration.ProtoReflect.Descriptor instead.
+func (*IPConfiguration) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IPConfiguration) GetIpSubnet() *IPSubnet {
+	if x != nil {
+		return x.IpSubnet
+	}
+	return nil
+}
+
+func (x *IPConfiguration) GetDnsServers() []string {
+	if x != nil {
+		return x.DnsServers
+	}
+	return nil
+}
+
+func (x *IPConfiguration) GetGatewayIpAddress() string {
+	if x != nil {
+		return x.GatewayIpAddress
+	}
+	return ""
+}
+
+type HostIPInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Gateway   string `protobuf:"bytes,1,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	PrimaryIp string `protobuf:"bytes,2,opt,name=primary_ip,json=primaryIp,proto3" json:"primary_ip,omitempty"`
+	Subnet    string `protobuf:"bytes,3,opt,name=subnet,proto3" json:"subnet,omitempty"`
+}
+
+func (x *HostIPInfo) Reset() {
+	*x = HostIPInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HostIPInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostIPInfo) ProtoMessage() {}
+
+func (x *HostIPInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostIPInfo.ProtoReflect.Descriptor instead.
+func (*HostIPInfo) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HostIPInfo) GetGateway() string {
+	if x != nil {
+		return x.Gateway
+	}
+	return ""
+}
+
+func (x *HostIPInfo) GetPrimaryIp() string {
+	if x != nil {
+		return x.PrimaryIp
+	}
+	return ""
+}
+
+func (x *HostIPInfo) GetSubnet() string {
+	if x != nil {
+		return x.Subnet
+	}
+	return ""
+}
+
+type PodIPInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PodIpConfig                     *IPSubnet        `protobuf:"bytes,1,opt,name=pod_ip_config,json=podIpConfig,proto3" json:"pod_ip_config,omitempty"`
+	NetworkContainerPrimaryIpConfig *IPConfiguration `protobuf:"bytes,2,opt,name=network_container_primary_ip_config,json=networkContainerPrimaryIpConfig,proto3" json:"network_container_primary_ip_config,omitempty"`
+	HostPrimaryIpInfo               *HostIPInfo      `protobuf:"bytes,3,opt,name=host_primary_ip_info,json=hostPrimaryIpInfo,proto3" json:"host_primary_ip_info,omitempty"`
+}
+
+func (x *PodIPInfo) Reset() {
+	*x = PodIPInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PodIPInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PodIPInfo) ProtoMessage() {}
+
+func (x *PodIPInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PodIPInfo.ProtoReflect.Descriptor instead.
+func (*PodIPInfo) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PodIPInfo) GetPodIpConfig() *IPSubnet {
+	if x != nil {
+		return x.PodIpConfig
+	}
+	return nil
+}
+
+func (x *PodIPInfo) GetNetworkContainerPrimaryIpConfig() *IPConfiguration {
+	if x != nil {
+		return x.NetworkContainerPrimaryIpConfig
+	}
+	return nil
+}
+
+func (x *PodIPInfo) GetHostPrimaryIpInfo() *HostIPInfo {
+	if x != nil {
+		return x.HostPrimaryIpInfo
+	}
+	return nil
+}
+
+type IPConfigResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PodIpInfo   *PodIPInfo `protobuf:"bytes,1,opt,name=pod_ip_info,json=podIpInfo,proto3" json:"pod_ip_info,omitempty"`
+	PodIpInfoV6 *PodIPInfo `protobuf:"bytes,2,opt,name=pod_ip_info_v6,json=podIpInfoV6,proto3" json:"pod_ip_info_v6,omitempty"`
+}
+
+func (x *IPConfigResponse) Reset() {
+	*x = IPConfigResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfigResponse) ProtoMessage() {}
+
+func (x *IPConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfigResponse.ProtoReflect.Descriptor instead.
+func (*IPConfigResponse) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *IPConfigResponse) GetPodIpInfo() *PodIPInfo {
+	if x != nil {
+		return x.PodIpInfo
+	}
+	return nil
+}
+
+func (x *IPConfigResponse) GetPodIpInfoV6() *PodIPInfo {
+	if x != nil {
+		return x.PodIpInfoV6
+	}
+	return nil
+}
+
+type ReleaseIPConfigResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ReleaseIPConfigResponse) Reset() {
+	*x = ReleaseIPConfigResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReleaseIPConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseIPConfigResponse) ProtoMessage() {}
+
+func (x *ReleaseIPConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseIPConfigResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseIPConfigResponse) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{7}
+}
+
+type IPConfigurationStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NcId      string        `protobuf:"bytes,1,opt,name=nc_id,json=ncId,proto3" json:"nc_id,omitempty"`
+	Id        string        `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	IpAddress string        `protobuf:"bytes,3,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	State     IPConfigState `protobuf:"varint,4,opt,name=state,proto3,enum=azure.cnsipam.v1.IPConfigState" json:"state,omitempty"`
+	PodInfo   *PodInfo      `protobuf:"bytes,5,opt,name=pod_info,json=podInfo,proto3" json:"pod_info,omitempty"`
+}
+
+func (x *IPConfigurationStatus) Reset() {
+	*x = IPConfigurationStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfigurationStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfigurationStatus) ProtoMessage() {}
+
+func (x *IPConfigurationStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfigurationStatus.ProtoReflect.Descriptor instead.
+func (*IPConfigurationStatus) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *IPConfigurationStatus) GetNcId() string {
+	if x != nil {
+		return x.NcId
+	}
+	return ""
+}
+
+func (x *IPConfigurationStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *IPConfigurationStatus) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *IPConfigurationStatus) GetState() IPConfigState {
+	if x != nil {
+		return x.State
+	}
+	return IPConfigState_IP_CONFIG_STATE_UNSPECIFIED
+}
+
+func (x *IPConfigurationStatus) GetPodInfo() *PodInfo {
+	if x != nil {
+		return x.PodInfo
+	}
+	return nil
+}
+
+type GetIPAddressesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// state_filter selects the IPs in any of these states.
+	// IP_CONFIG_STATE_UNSPECIFIED is rejected.
+	StateFilter []IPConfigState `protobuf:"varint,1,rep,packed,name=state_filter,json=stateFilter,proto3,enum=azure.cnsipam.v1.IPConfigState" json:"state_filter,omitempty"`
+}
+
+func (x *GetIPAddressesRequest) Reset() {
+	*x = GetIPAddressesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetIPAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIPAddressesRequest) ProtoMessage() {}
+
+func (x *GetIPAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIPAddressesRequest.ProtoReflect.Descriptor instead.
+func (*GetIPAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetIPAddressesRequest) GetStateFilter() []IPConfigState {
+	if x != nil {
+		return x.StateFilter
+	}
+	return nil
+}
+
+type GetIPAddressesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IpConfigurations []*IPConfigurationStatus `protobuf:"bytes,1,rep,name=ip_configurations,json=ipConfigurations,proto3" json:"ip_configurations,omitempty"`
+}
+
+func (x *GetIPAddressesResponse) Reset() {
+	*x = GetIPAddressesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetIPAddressesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIPAddressesResponse) ProtoMessage() {}
+
+func (x *GetIPAddressesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIPAddressesResponse.ProtoReflect.Descriptor instead.
+func (*GetIPAddressesResponse) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetIPAddressesResponse) GetIpConfigurations() []*IPConfigurationStatus {
+	if x != nil {
+		return x.IpConfigurations
+	}
+	return nil
+}
+
+type WatchIPConfigsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// state_filter limits the stream to IPs entering or leaving any of
+	// these states. An empty filter streams every change,
+	// IP_CONFIG_STATE_UNSPECIFIED is rejected.
+	StateFilter []IPConfigState `protobuf:"varint,1,rep,packed,name=state_filter,json=stateFilter,proto3,enum=azure.cnsipam.v1.IPConfigState" json:"state_filter,omitempty"`
+}
+
+func (x *WatchIPConfigsRequest) Reset() {
+	*x = WatchIPConfigsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchIPConfigsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchIPConfigsRequest) ProtoMessage() {}
+
+func (x *WatchIPConfigsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchIPConfigsRequest.ProtoReflect.Descriptor instead.
+func (*WatchIPConfigsRequest) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchIPConfigsRequest) GetStateFilter() []IPConfigState {
+	if x != nil {
+		return x.StateFilter
+	}
+	return nil
+}
+
+type IPConfigEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type            EventType              `protobuf:"varint,1,opt,name=type,proto3,enum=azure.cnsipam.v1.EventType" json:"type,omitempty"`
+	IpConfiguration *IPConfigurationStatus `protobuf:"bytes,2,opt,name=ip_configuration,json=ipConfiguration,proto3" json:"ip_configuration,omitempty"`
+	// previous_state is the state the IP was in before a Modified event.
+	PreviousState IPConfigState `protobuf:"varint,3,opt,name=previous_state,json=previousState,proto3,enum=azure.cnsipam.v1.IPConfigState" json:"previous_state,omitempty"`
+}
+
+func (x *IPConfigEvent) Reset() {
+	*x = IPConfigEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_CNSIPAM_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *IPConfigEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPConfigEvent) ProtoMessage() {}
+
+func (x *IPConfigEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_CNSIPAM_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPConfigEvent.ProtoReflect.Descriptor instead.
+func (*IPConfigEvent) Descriptor() ([]byte, []int) {
+	return file_CNSIPAM_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *IPConfigEvent) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *IPConfigEvent) GetIpConfiguration() *IPConfigurationStatus {
+	if x != nil {
+		return x.IpConfiguration
+	}
+	return nil
+}
+
+func (x *IPConfigEvent) GetPreviousState() IPConfigState {
+	if x != nil {
+		return x.PreviousState
+	}
+	return IPConfigState_IP_CONFIG_STATE_UNSPECIFIED
+}
+
+var File_CNSIPAM_proto protoreflect.FileDescriptor
+
+var file_CNSIPAM_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x43, 0x4e, 0x53, 0x49, 0x50, 0x41, 0x4d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x10, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76,
+	0x31, 0x22, 0x8c, 0x01, 0x0a, 0x07, 0x50, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12,
+	0x2c, 0x0a, 0x12, 0x69, 0x6e, 0x66, 0x72, 0x61, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e,
+	0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x69, 0x6e, 0x66,
+	0x72, 0x61, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x21, 0x0a,
+	0x0c, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x64,
+	0x22, 0xcd, 0x01, 0x0a, 0x0f, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x2c, 0x0a, 0x12, 0x64, 0x65, 0x73, 0x69, 0x72, 0x65, 0x64, 0x5f,
+	0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x10, 0x64, 0x65, 0x73, 0x69, 0x72, 0x65, 0x64, 0x49, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x12, 0x28, 0x0a, 0x10, 0x70, 0x6f, 0x64, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66,
+	0x61, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x70, 0x6f,
+	0x64, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x49, 0x64, 0x12, 0x2c, 0x0a, 0x12,
+	0x69, 0x6e, 0x66, 0x72, 0x61, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f,
+	0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x69, 0x6e, 0x66, 0x72, 0x61, 0x43,
+	0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x34, 0x0a, 0x08, 0x70, 0x6f,
+	0x64, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x61,
+	0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e,
+	0x50, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x70, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f,
+	0x22, 0x4e, 0x0a, 0x08, 0x49, 0x50, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x12, 0x1d, 0x0a, 0x0a,
+	0x69, 0x70, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x69, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x70,
+	0x72, 0x65, 0x66, 0x69, 0x78, 0x5f, 0x6c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x0c, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68,
+	0x22, 0x99, 0x01, 0x0a, 0x0f, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x37, 0x0a, 0x09, 0x69, 0x70, 0x5f, 0x73, 0x75, 0x62, 0x6e, 0x65,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e,
+	0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x53, 0x75, 0x62,
+	0x6e, 0x65, 0x74, 0x52, 0x08, 0x69, 0x70, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x12, 0x1f, 0x0a,
+	0x0b, 0x64, 0x6e, 0x73, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x0a, 0x64, 0x6e, 0x73, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x73, 0x12, 0x2c,
+	0x0a, 0x12, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x5f, 0x69, 0x70, 0x5f, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x67, 0x61, 0x74, 0x65,
+	0x77, 0x61, 0x79, 0x49, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x5d, 0x0a, 0x0a,
+	0x48, 0x6f, 0x73, 0x74, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x67, 0x61,
+	0x74, 0x65, 0x77, 0x61, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67, 0x61, 0x74,
+	0x65, 0x77, 0x61, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f,
+	0x69, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72,
+	0x79, 0x49, 0x70, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x22, 0x8b, 0x02, 0x0a, 0x09,
+	0x50, 0x6f, 0x64, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x3e, 0x0a, 0x0d, 0x70, 0x6f, 0x64,
+	0x5f, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d,
+	0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x53, 0x75, 0x62, 0x6e, 0x65, 0x74, 0x52, 0x0b, 0x70, 0x6f,
+	0x64, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x6f, 0x0a, 0x23, 0x6e, 0x65, 0x74,
+	0x77, 0x6f, 0x72, 0x6b, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x5f, 0x70,
+	0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63,
+	0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x1f, 0x6e, 0x65, 0x74, 0x77, 0x6f,
+	0x72, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x50, 0x72, 0x69, 0x6d, 0x61,
+	0x72, 0x79, 0x49, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x4d, 0x0a, 0x14, 0x68, 0x6f,
+	0x73, 0x74, 0x5f, 0x70, 0x72, 0x69, 0x6d, 0x61, 0x72, 0x79, 0x5f, 0x69, 0x70, 0x5f, 0x69, 0x6e,
+	0x66, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65,
+	0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x6f, 0x73, 0x74,
+	0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x11, 0x68, 0x6f, 0x73, 0x74, 0x50, 0x72, 0x69, 0x6d,
+	0x61, 0x72, 0x79, 0x49, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x91, 0x01, 0x0a, 0x10, 0x49, 0x50,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b,
+	0x0a, 0x0b, 0x70, 0x6f, 0x64, 0x5f, 0x69, 0x70, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69,
+	0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x09, 0x70, 0x6f, 0x64, 0x49, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x40, 0x0a, 0x0e, 0x70,
+	0x6f, 0x64, 0x5f, 0x69, 0x70, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x5f, 0x76, 0x36, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69,
+	0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x64, 0x49, 0x50, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x0b, 0x70, 0x6f, 0x64, 0x49, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x56, 0x36, 0x22, 0x19, 0x0a,
+	0x17, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0xc8, 0x01, 0x0a, 0x15, 0x49, 0x50, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x13, 0x0a, 0x05, 0x6e, 0x63, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x63, 0x49, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x70, 0x5f, 0x61, 0x64,
+	0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x69, 0x70, 0x41,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x35, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e,
+	0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x34, 0x0a,
+	0x08, 0x70, 0x6f, 0x64, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x19, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e,
+	0x76, 0x31, 0x2e, 0x50, 0x6f, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x70, 0x6f, 0x64, 0x49,
+	0x6e, 0x66, 0x6f, 0x22, 0x5b, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x49, 0x50, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x42, 0x0a, 0x0c,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70,
+	0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x52, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x22, 0x6e, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x49, 0x50, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x54, 0x0a, 0x11, 0x69, 0x70,
+	0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e,
+	0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x10,
+	0x69, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x22, 0x5b, 0x0a, 0x15, 0x57, 0x61, 0x74, 0x63, 0x68, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x42, 0x0a, 0x0c, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0e, 0x32,
+	0x1f, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e,
+	0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x52, 0x0b, 0x73, 0x74, 0x61, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x22, 0xdc, 0x01,
+	0x0a, 0x0d, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12,
+	0x2f, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e,
+	0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31,
+	0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65,
+	0x12, 0x52, 0x0a, 0x10, 0x69, 0x70, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x61, 0x7a, 0x75,
+	0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x52, 0x0f, 0x69, 0x70, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x46, 0x0a, 0x0e, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73,
+	0x5f, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1f, 0x2e, 0x61,
+	0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e,
+	0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x0d, 0x70,
+	0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x53, 0x74, 0x61, 0x74, 0x65, 0x2a, 0xbc, 0x01, 0x0a,
+	0x0d, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1f,
+	0x0a, 0x1b, 0x49, 0x50, 0x5f, 0x43, 0x4f, 0x4e, 0x46, 0x49, 0x47, 0x5f, 0x53, 0x54, 0x41, 0x54,
+	0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12,
+	0x1d, 0x0a, 0x19, 0x49, 0x50, 0x5f, 0x43, 0x4f, 0x4e, 0x46, 0x49, 0x47, 0x5f, 0x53, 0x54, 0x41,
+	0x54, 0x45, 0x5f, 0x41, 0x56, 0x41, 0x49, 0x4c, 0x41, 0x42, 0x4c, 0x45, 0x10, 0x01, 0x12, 0x1d,
+	0x0a, 0x19, 0x49, 0x50, 0x5f, 0x43, 0x4f, 0x4e, 0x46, 0x49, 0x47, 0x5f, 0x53, 0x54, 0x41, 0x54,
+	0x45, 0x5f, 0x41, 0x4c, 0x4c, 0x4f, 0x43, 0x41, 0x54, 0x45, 0x44, 0x10, 0x02, 0x12, 0x23, 0x0a,
+	0x1f, 0x49, 0x50, 0x5f, 0x43, 0x4f, 0x4e, 0x46, 0x49, 0x47, 0x5f, 0x53, 0x54, 0x41, 0x54, 0x45,
+	0x5f, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x5f, 0x52, 0x45, 0x4c, 0x45, 0x41, 0x53, 0x45,
+	0x10, 0x03, 0x12, 0x27, 0x0a, 0x23, 0x49, 0x50, 0x5f, 0x43, 0x4f, 0x4e, 0x46, 0x49, 0x47, 0x5f,
+	0x53, 0x54, 0x41, 0x54, 0x45, 0x5f, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x5f, 0x50, 0x52,
+	0x4f, 0x47, 0x52, 0x41, 0x4d, 0x4d, 0x49, 0x4e, 0x47, 0x10, 0x04, 0x2a, 0x6e, 0x0a, 0x09, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x16, 0x45, 0x56, 0x45, 0x4e,
+	0x54, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49,
+	0x45, 0x44, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f, 0x54, 0x59,
+	0x50, 0x45, 0x5f, 0x41, 0x44, 0x44, 0x45, 0x44, 0x10, 0x01, 0x12, 0x17, 0x0a, 0x13, 0x45, 0x56,
+	0x45, 0x4e, 0x54, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4d, 0x4f, 0x44, 0x49, 0x46, 0x49, 0x45,
+	0x44, 0x10, 0x02, 0x12, 0x16, 0x0a, 0x12, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f, 0x54, 0x59, 0x50,
+	0x45, 0x5f, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x44, 0x10, 0x03, 0x32, 0x87, 0x03, 0x0a, 0x07,
+	0x43, 0x4e, 0x53, 0x49, 0x50, 0x41, 0x4d, 0x12, 0x58, 0x0a, 0x0f, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x21, 0x2e, 0x61, 0x7a, 0x75,
+	0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e,
+	0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31,
+	0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x5f, 0x0a, 0x0f, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x50, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x12, 0x21, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73,
+	0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e,
+	0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61,
+	0x73, 0x65, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x63, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x49, 0x50, 0x41, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x65, 0x73, 0x12, 0x27, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73,
+	0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x49, 0x50, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x28, 0x2e,
+	0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31,
+	0x2e, 0x47, 0x65, 0x74, 0x49, 0x50, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5c, 0x0a, 0x0e, 0x57, 0x61, 0x74, 0x63, 0x68,
+	0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x12, 0x27, 0x2e, 0x61, 0x7a, 0x75, 0x72,
+	0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2e, 0x63, 0x6e, 0x73, 0x69, 0x70,
+	0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x50, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x46, 0x5a, 0x44, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x7a, 0x75, 0x72, 0x65, 0x2f, 0x61, 0x7a, 0x75, 0x72, 0x65, 0x2d,
+	0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x2d, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x69, 0x6e, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x63, 0x6e, 0x73, 0x69, 0x70,
+	0x61, 0x6d, 0x2f, 0x76, 0x31, 0x3b, 0x63, 0x6e, 0x73, 0x69, 0x70, 0x61, 0x6d, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_CNSIPAM_proto_rawDescOnce sync.Once
+	file_CNSIPAM_proto_rawDescData = file_CNSIPAM_proto_rawDesc
+)
+
+func file_CNSIPAM_proto_rawDescGZIP() []byte {
+	file_CNSIPAM_proto_rawDescOnce.Do(func() {
+		file_CNSIPAM_proto_rawDescData = protoimpl.X.CompressGZIP(file_CNSIPAM_proto_rawDescData)
+	})
+	return file_CNSIPAM_proto_rawDescData
+}
+
+var file_CNSIPAM_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_CNSIPAM_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_CNSIPAM_proto_goTypes = []interface{}{
+	(IPConfigState)(0),              // 0: azure.cnsipam.v1.IPConfigState
+	(EventType)(0),                  // 1: azure.cnsipam.v1.EventType
+	(*PodInfo)(nil),                 // 2: azure.cnsipam.v1.PodInfo
+	(*IPConfigRequest)(nil),         // 3: azure.cnsipam.v1.IPConfigRequest
+	(*IPSubnet)(nil),                // 4: azure.cnsipam.v1.IPSubnet
+	(*IPConfiguration)(nil),         // 5: azure.cnsipam.v1.IPConfiguration
+	(*HostIPInfo)(nil),              // 6: azure.cnsipam.v1.HostIPInfo
+	(*PodIPInfo)(nil),               // 7: azure.cnsipam.v1.PodIPInfo
+	(*IPConfigResponse)(nil),        // 8: azure.cnsipam.v1.IPConfigResponse
+	(*ReleaseIPConfigResponse)(nil), // 9: azure.cnsipam.v1.ReleaseIPConfigResponse
+	(*IPConfigurationStatus)(nil),   // 10: azure.cnsipam.v1.IPConfigurationStatus
+	(*GetIPAddressesRequest)(nil),   // 11: azure.cnsipam.v1.GetIPAddressesRequest
+	(*GetIPAddressesResponse)(nil),  // 12: azure.cnsipam.v1.GetIPAddressesResponse
+	(*WatchIPConfigsRequest)(nil),   // 13: azure.cnsipam.v1.WatchIPConfigsRequest
+	(*IPConfigEvent)(nil),           // 14: azure.cnsipam.v1.IPConfigEvent
+}
+var file_CNSIPAM_proto_depIdxs = []int32{
+	2,  // 0: azure.cnsipam.v1.IPConfigRequest.pod_info:type_name -> azure.cnsipam.v1.PodInfo
+	4,  // 1: azure.cnsipam.v1.IPConfiguration.ip_subnet:type_name -> azure.cnsipam.v1.IPSubnet
+	4,  // 2: azure.cnsipam.v1.PodIPInfo.pod_ip_config:type_name -> azure.cnsipam.v1.IPSubnet
+	5,  // 3: azure.cnsipam.v1.PodIPInfo.network_container_primary_ip_config:type_name -> azure.cnsipam.v1.IPConfiguration
+	6,  // 4: azure.cnsipam.v1.PodIPInfo.host_primary_ip_info:type_name -> azure.cnsipam.v1.HostIPInfo
+	7,  // 5: azure.cnsipam.v1.IPConfigResponse.pod_ip_info:type_name -> azure.cnsipam.v1.PodIPInfo
+	7,  // 6: azure.cnsipam.v1.IPConfigResponse.pod_ip_info_v6:type_name -> azure.cnsipam.v1.PodIPInfo
+	0,  // 7: azure.cnsipam.v1.IPConfigurationStatus.state:type_name -> azure.cnsipam.v1.IPConfigState
+	2,  // 8: azure.cnsipam.v1.IPConfigurationStatus.pod_info:type_name -> azure.cnsipam.v1.PodInfo
+	0,  // 9: azure.cnsipam.v1.GetIPAddressesRequest.state_filter:type_name -> azure.cnsipam.v1.IPConfigState
+	10, // 10: azure.cnsipam.v1.GetIPAddressesResponse.ip_configurations:type_name -> azure.cnsipam.v1.IPConfigurationStatus
+	0,  // 11: azure.cnsipam.v1.WatchIPConfigsRequest.state_filter:type_name -> azure.cnsipam.v1.IPConfigState
+	1,  // 12: azure.cnsipam.v1.IPConfigEvent.type:type_name -> azure.cnsipam.v1.EventType
+	10, // 13: azure.cnsipam.v1.IPConfigEvent.ip_configuration:type_name -> azure.cnsipam.v1.IPConfigurationStatus
+	0,  // 14: azure.cnsipam.v1.IPConfigEvent.previous_state:type_name -> azure.cnsipam.v1.IPConfigState
+	3,  // 15: azure.cnsipam.v1.CNSIPAM.RequestIPConfig:input_type -> azure.cnsipam.v1.IPConfigRequest
+	3,  // 16: azure.cnsipam.v1.CNSIPAM.ReleaseIPConfig:input_type -> azure.cnsipam.v1.IPConfigRequest
+	11, // 17: azure.cnsipam.v1.CNSIPAM.GetIPAddresses:input_type -> azure.cnsipam.v1.GetIPAddressesRequest
+	13, // 18: azure.cnsipam.v1.CNSIPAM.WatchIPConfigs:input_type -> azure.cnsipam.v1.WatchIPConfigsRequest
+	8,  // 19: azure.cnsipam.v1.CNSIPAM.RequestIPConfig:output_type -> azure.cnsipam.v1.IPConfigResponse
+	9,  // 20: azure.cnsipam.v1.CNSIPAM.ReleaseIPConfig:output_type -> azure.cnsipam.v1.ReleaseIPConfigResponse
+	12, // 21: azure.cnsipam.v1.CNSIPAM.GetIPAddresses:output_type -> azure.cnsipam.v1.GetIPAddressesResponse
+	14, // 22: azure.cnsipam.v1.CNSIPAM.WatchIPConfigs:output_type -> azure.cnsipam.v1.IPConfigEvent
+	19, // [19:23] is the sub-list for method output_type
+	15, // [15:19] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_CNSIPAM_proto_init() }
+func file_CNSIPAM_proto_init() {
+	if File_CNSIPAM_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_CNSIPAM_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PodInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfigRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPSubnet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfiguration); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HostIPInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PodIPInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfigResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReleaseIPConfigResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfigurationStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetIPAddressesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetIPAddressesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchIPConfigsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_CNSIPAM_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*IPConfigEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_CNSIPAM_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_CNSIPAM_proto_goTypes,
+		DependencyIndexes: file_CNSIPAM_proto_depIdxs,
+		EnumInfos:         file_CNSIPAM_proto_enumTypes,
+		MessageInfos:      file_CNSIPAM_proto_msgTypes,
+	}.Build()
+	File_CNSIPAM_proto = out.File
+	file_CNSIPAM_proto_rawDesc = nil
+	file_CNSIPAM_proto_goTypes = nil
+	file_CNSIPAM_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// CNSIPAMClient is the client API for CNSIPAM service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CNSIPAMClient interface {
+	RequestIPConfig(ctx context.Context, in *IPConfigRequest, opts ...grpc.CallOption) (*IPConfigResponse, error)
+	ReleaseIPConfig(ctx context.Context, in *IPConfigRequest, opts ...grpc.CallOption) (*ReleaseIPConfigResponse, error)
+	GetIPAddresses(ctx context.Context, in *GetIPAddressesRequest, opts ...grpc.CallOption) (*GetIPAddressesResponse, error)
+	WatchIPConfigs(ctx context.Context, in *WatchIPConfigsRequest, opts ...grpc.CallOption) (CNSIPAM_WatchIPConfigsClient, error)
+}
+
+type cNSIPAMClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCNSIPAMClient(cc grpc.ClientConnInterface) CNSIPAMClient {
+	return &cNSIPAMClient{cc}
+}
+
+func (c *cNSIPAMClient) RequestIPConfig(ctx context.Context, in *IPConfigRequest, opts ...grpc.CallOption) (*IPConfigResponse, error) {
+	out := new(IPConfigResponse)
+	err := c.cc.Invoke(ctx, "/azure.cnsipam.v1.CNSIPAM/RequestIPConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSIPAMClient) ReleaseIPConfig(ctx context.Context, in *IPConfigRequest, opts ...grpc.CallOption) (*ReleaseIPConfigResponse, error) {
+	out := new(ReleaseIPConfigResponse)
+	err := c.cc.Invoke(ctx, "/azure.cnsipam.v1.CNSIPAM/ReleaseIPConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSIPAMClient) GetIPAddresses(ctx context.Context, in *GetIPAddressesRequest, opts ...grpc.CallOption) (*GetIPAddressesResponse, error) {
+	out := new(GetIPAddressesResponse)
+	err := c.cc.Invoke(ctx, "/azure.cnsipam.v1.CNSIPAM/GetIPAddresses", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cNSIPAMClient) WatchIPConfigs(ctx context.Context, in *WatchIPConfigsRequest, opts ...grpc.CallOption) (CNSIPAM_WatchIPConfigsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CNSIPAM_serviceDesc.Streams[0], "/azure.cnsipam.v1.CNSIPAM/WatchIPConfigs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cNSIPAMWatchIPConfigsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CNSIPAM_WatchIPConfigsClient interface {
+	Recv() (*IPConfigEvent, error)
+	grpc.ClientStream
+}
+
+type cNSIPAMWatchIPConfigsClient struct {
+	grpc.ClientStream
+}
+
+func (x *cNSIPAMWatchIPConfigsClient) Recv() (*IPConfigEvent, error) {
+	m := new(IPConfigEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CNSIPAMServer is the server API for CNSIPAM service.
+type CNSIPAMServer interface {
+	RequestIPConfig(context.Context, *IPConfigRequest) (*IPConfigResponse, error)
+	ReleaseIPConfig(context.Context, *IPConfigRequest) (*ReleaseIPConfigResponse, error)
+	GetIPAddresses(context.Context, *GetIPAddressesRequest) (*GetIPAddressesResponse, error)
+	WatchIPConfigs(*WatchIPConfigsRequest, CNSIPAM_WatchIPConfigsServer) error
+}
+
+// UnimplementedCNSIPAMServer can be embedded to have forward compatible implementations.
+type UnimplementedCNSIPAMServer struct {
+}
+
+func (*UnimplementedCNSIPAMServer) RequestIPConfig(context.Context, *IPConfigRequest) (*IPConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestIPConfig not implemented")
+}
+func (*UnimplementedCNSIPAMServer) ReleaseIPConfig(context.Context, *IPConfigRequest) (*ReleaseIPConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseIPConfig not implemented")
+}
+func (*UnimplementedCNSIPAMServer) GetIPAddresses(context.Context, *GetIPAddressesRequest) (*GetIPAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIPAddresses not implemented")
+}
+func (*UnimplementedCNSIPAMServer) WatchIPConfigs(*WatchIPConfigsRequest, CNSIPAM_WatchIPConfigsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchIPConfigs not implemented")
+}
+
+func RegisterCNSIPAMServer(s *grpc.Server, srv CNSIPAMServer) {
+	s.RegisterService(&_CNSIPAM_serviceDesc, srv)
+}
+
+func _CNSIPAM_RequestIPConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IPConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSIPAMServer).RequestIPConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/azure.cnsipam.v1.CNSIPAM/RequestIPConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSIPAMServer).RequestIPConfig(ctx, req.(*IPConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNSIPAM_ReleaseIPConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IPConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSIPAMServer).ReleaseIPConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/azure.cnsipam.v1.CNSIPAM/ReleaseIPConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSIPAMServer).ReleaseIPConfig(ctx, req.(*IPConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNSIPAM_GetIPAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetIPAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CNSIPAMServer).GetIPAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/azure.cnsipam.v1.CNSIPAM/GetIPAddresses",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CNSIPAMServer).GetIPAddresses(ctx, req.(*GetIPAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CNSIPAM_WatchIPConfigs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchIPConfigsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CNSIPAMServer).WatchIPConfigs(m, &cNSIPAMWatchIPConfigsServer{stream})
+}
+
+type CNSIPAM_WatchIPConfigsServer interface {
+	Send(*IPConfigEvent) error
+	grpc.ServerStream
+}
+
+type cNSIPAMWatchIPConfigsServer struct {
+	grpc.ServerStream
+}
+
+func (x *cNSIPAMWatchIPConfigsServer) Send(m *IPConfigEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _CNSIPAM_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "azure.cnsipam.v1.CNSIPAM",
+	HandlerType: (*CNSIPAMServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RequestIPConfig",
+			Handler:    _CNSIPAM_RequestIPConfig_Handler,
+		},
+		{
+			MethodName: "ReleaseIPConfig",
+			Handler:    _CNSIPAM_ReleaseIPConfig_Handler,
+		},
+		{
+			MethodName: "GetIPAddresses",
+			Handler:    _CNSIPAM_GetIPAddresses_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchIPConfigs",
+			Handler:       _CNSIPAM_WatchIPConfigs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "CNSIPAM.proto",
+}
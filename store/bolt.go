@@ -0,0 +1,261 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/Azure/azure-container-networking/platform"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// Default file name for backing bolt store.
+	defaultBoltFileName = "azure-container-networking.db"
+
+	// boltTimeout is how long to wait for another process to close the database.
+	boltTimeout = lockMaxRetries * lockRetryDelay
+)
+
+// boltBucket is the bucket the key value pairs are stored in.
+var boltBucket = []byte("store")
+
+// boltStore is an implementation of KeyValueStore on an embedded bolt database.
+// Each Write is committed in its own transaction and only rewrites the pages of that key.
+//
+// The database is opened on first use and kept open, except when the store is
+// locked, in which case it is closed again on Unlock so that other processes sharing
+// the store can open it.
+type boltStore struct {
+	fileName     string
+	lockFileName string
+	db           *bolt.DB
+	locked       bool
+	sync.Mutex
+}
+
+// boltTransaction is a Transaction on a boltStore.
+type boltTransaction struct {
+	bucket *bolt.Bucket
+}
+
+// NewBoltStore creates a new boltStore object, accessed as a KeyValueStore.
+func NewBoltStore(fileName string) (TransactionalKeyValueStore, error) {
+	if fileName == "" {
+		fileName = defaultBoltFileName
+	}
+
+	if platform.CNILockPath != "" {
+		err := os.MkdirAll(platform.CNILockPath, os.FileMode(0664))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	kvs := &boltStore{
+		fileName:     fileName,
+		lockFileName: platform.CNILockPath + filepath.Base(fileName) + lockExtension,
+	}
+
+	return kvs, nil
+}
+
+// open opens the database if it is not already open.
+func (kvs *boltStore) open() error {
+	if kvs.db != nil {
+		return nil
+	}
+
+	db, err := bolt.Open(kvs.fileName, 0o644, &bolt.Options{Timeout: boltTimeout})
+	if err != nil {
+		return errors.Wrapf(err, "failed to open bolt store %s", kvs.fileName)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return errors.Wrapf(err, "failed to create bucket in bolt store %s", kvs.fileName)
+	}
+
+	kvs.db = db
+	return nil
+}
+
+// close closes the database if it is open.
+func (kvs *boltStore) close() error {
+	if kvs.db == nil {
+		return nil
+	}
+
+	err := kvs.db.Close()
+	kvs.db = nil
+	return err
+}
+
+// Read restores the value for the given key from persistent store.
+func (kvs *boltStore) Read(key string, value interface{}) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if err := kvs.open(); err != nil {
+		return err
+	}
+
+	return kvs.db.View(func(tx *bolt.Tx) error {
+		return boltTransaction{bucket: tx.Bucket(boltBucket)}.Read(key, value)
+	})
+}
+
+// Write saves the given key value pair to persistent store.
+func (kvs *boltStore) Write(key string, value interface{}) error {
+	return kvs.Update(func(tx Transaction) error {
+		return tx.Write(key, value)
+	})
+}
+
+// Update runs fn in a read-write transaction which is committed if fn returns nil
+// and rolled back otherwise.
+func (kvs *boltStore) Update(fn func(Transaction) error) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if err := kvs.open(); err != nil {
+		return err
+	}
+
+	return kvs.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTransaction{bucket: tx.Bucket(boltBucket)})
+	})
+}
+
+// Flush commits in-memory state to persistent store.
+// Every transaction is already synced to disk when it is committed.
+func (kvs *boltStore) Flush() error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if kvs.db == nil {
+		return nil
+	}
+
+	return kvs.db.Sync()
+}
+
+// Lock locks the store for exclusive access.
+func (kvs *boltStore) Lock(block bool) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if kvs.locked {
+		return ErrStoreLocked
+	}
+
+	if err := acquireLockFile(kvs.lockFileName, block); err != nil {
+		return err
+	}
+
+	kvs.locked = true
+
+	return nil
+}
+
+// Unlock unlocks the store.
+func (kvs *boltStore) Unlock(forceUnlock bool) error {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if !forceUnlock && !kvs.locked {
+		return ErrStoreNotLocked
+	}
+
+	if err := kvs.close(); err != nil {
+		log.Errorf("could not close bolt store %s. Error: %v", kvs.fileName, err)
+	}
+
+	err := os.Remove(kvs.lockFileName)
+	if err != nil {
+		return err
+	}
+
+	kvs.locked = false
+
+	return nil
+}
+
+// GetModificationTime returns the modification time of the persistent store.
+func (kvs *boltStore) GetModificationTime() (time.Time, error) {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	info, err := os.Stat(kvs.fileName)
+	if err != nil {
+		log.Printf("os.stat() for file %v failed: %v", kvs.fileName, err)
+		return time.Time{}.UTC(), err
+	}
+
+	return info.ModTime().UTC(), nil
+}
+
+// GetLockFileModificationTime returns the modification time of the lock file of the persistent store.
+func (kvs *boltStore) GetLockFileModificationTime() (time.Time, error) {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	return lockFileModificationTime(kvs.lockFileName)
+}
+
+func (kvs *boltStore) GetLockFileName() string {
+	return kvs.lockFileName
+}
+
+// GetFileName returns the name of the file backing the store.
+func (kvs *boltStore) GetFileName() string {
+	return kvs.fileName
+}
+
+func (kvs *boltStore) Remove() {
+	kvs.Mutex.Lock()
+	defer kvs.Mutex.Unlock()
+
+	if err := kvs.close(); err != nil {
+		log.Errorf("could not close bolt store %s. Error: %v", kvs.fileName, err)
+	}
+
+	if err := os.Remove(kvs.fileName); err != nil {
+		log.Errorf("could not remove file %s. Error: %v", kvs.fileName, err)
+	}
+}
+
+// Read restores the value for the given key within the transaction.
+func (tx boltTransaction) Read(key string, value interface{}) error {
+	raw := tx.bucket.Get([]byte(key))
+	if raw == nil {
+		return ErrKeyNotFound
+	}
+
+	return json.Unmarshal(raw, value)
+}
+
+// Write saves the given key value pair within the transaction.
+func (tx boltTransaction) Write(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return tx.bucket.Put([]byte(key), raw)
+}
+
+// Delete removes the given key within the transaction.
+func (tx boltTransaction) Delete(key string) error {
+	return tx.bucket.Delete([]byte(key))
+}
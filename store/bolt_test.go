@@ -0,0 +1,118 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltStoreReadWrite(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test.db")
+	kvs, err := NewBoltStore(fileName)
+	require.NoError(t, err)
+
+	var value testType1
+	assert.Equal(t, ErrKeyNotFound, kvs.Read(testKey1, &value))
+
+	require.NoError(t, kvs.Lock(true))
+	require.NoError(t, kvs.Write(testKey1, testType1{"test", 42}))
+	require.NoError(t, kvs.Write(testKey2, testType1{"other", 7}))
+	require.NoError(t, kvs.Unlock(false))
+
+	// a new store on the same file sees the persisted writes.
+	kvs, err = NewBoltStore(fileName)
+	require.NoError(t, err)
+	require.NoError(t, kvs.Read(testKey1, &value))
+	assert.Equal(t, testType1{"test", 42}, value)
+	require.NoError(t, kvs.Read(testKey2, &value))
+	assert.Equal(t, testType1{"other", 7}, value)
+
+	kvs.Remove()
+	assert.NoFileExists(t, fileName)
+}
+
+func TestBoltStoreUpdateIsAtomic(t *testing.T) {
+	kvs, err := NewBoltStore(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	require.NoError(t, kvs.Write(testKey1, testType1{"before", 1}))
+
+	errAbort := errors.New("abort")
+	err = kvs.Update(func(tx Transaction) error {
+		if err := tx.Write(testKey1, testType1{"after", 2}); err != nil {
+			return err
+		}
+		if err := tx.Write(testKey2, testType1{"after", 2}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.Equal(t, errAbort, err)
+
+	var value testType1
+	require.NoError(t, kvs.Read(testKey1, &value))
+	assert.Equal(t, testType1{"before", 1}, value)
+	assert.Equal(t, ErrKeyNotFound, kvs.Read(testKey2, &value))
+
+	require.NoError(t, kvs.Update(func(tx Transaction) error {
+		if err := tx.Delete(testKey1); err != nil {
+			return err
+		}
+		return tx.Write(testKey2, testType1{"after", 2})
+	}))
+	assert.Equal(t, ErrKeyNotFound, kvs.Read(testKey1, &value))
+	require.NoError(t, kvs.Read(testKey2, &value))
+	assert.Equal(t, testType1{"after", 2}, value)
+}
+
+func TestBoltStoreLockIsSharedAcrossStores(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test.db")
+	first, err := NewBoltStore(fileName)
+	require.NoError(t, err)
+	second, err := NewBoltStore(fileName)
+	require.NoError(t, err)
+
+	require.NoError(t, first.Lock(false))
+	assert.Equal(t, ErrStoreLocked, first.Lock(false))
+	assert.Equal(t, ErrNonBlockingLockIsAlreadyLocked, second.Lock(false))
+	require.NoError(t, first.Write(testKey1, testType1{"test", 42}))
+	require.NoError(t, first.Unlock(false))
+
+	// the database was closed on Unlock so the second store can open it.
+	require.NoError(t, second.Lock(false))
+	var value testType1
+	require.NoError(t, second.Read(testKey1, &value))
+	assert.Equal(t, testType1{"test", 42}, value)
+	require.NoError(t, second.Unlock(false))
+}
+
+func TestMigrateJSONFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonFileName := filepath.Join(dir, "test.json")
+	require.NoError(t, ioutil.WriteFile(jsonFileName, []byte(`{"key1":{"Field1":"test","Field2":42},"key2":{"Field1":"other","Field2":7}}`), 0o644))
+
+	kvs, err := NewBoltStore(filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+
+	n, err := MigrateJSONFile(jsonFileName, kvs)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	var value testType1
+	require.NoError(t, kvs.Read(testKey1, &value))
+	assert.Equal(t, testType1{"test", 42}, value)
+	require.NoError(t, kvs.Read(testKey2, &value))
+	assert.Equal(t, testType1{"other", 7}, value)
+
+	// the JSON store is left in place and unlocked.
+	jsonStore, err := NewJsonFileStore(jsonFileName)
+	require.NoError(t, err)
+	require.NoError(t, jsonStore.Lock(false))
+	require.NoError(t, jsonStore.Unlock(false))
+}
@@ -4,6 +4,8 @@
 package store
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -29,20 +31,54 @@ const (
 
 	// Delay between lock retries.
 	lockRetryDelay = 100 * time.Millisecond
+
+	// Key of the metadata used to validate the contents of a store file.
+	metadataKey = "$Metadata"
+
+	// Version of the store file format written by this package.
+	storeSchemaVersion = 1
+
+	// Default number of previous good generations of a store file kept for recovery.
+	defaultStoreGenerations = 3
 )
 
+// jsonFileMetadata is stored alongside the key value pairs in a store file.
+type jsonFileMetadata struct {
+	SchemaVersion int
+	Checksum      string
+}
+
 // jsonFileStore is an implementation of KeyValueStore using a local JSON file.
 type jsonFileStore struct {
-	fileName string
+	fileName     string
 	lockFileName string
-	data     map[string]*json.RawMessage
-	inSync   bool
-	locked   bool
+	data         map[string]*json.RawMessage
+	inSync       bool
+	locked       bool
+	// generations is the number of previous good generations kept for recovery.
+	generations int
+	// fileValid is set while the store file is known to be valid, because it was read or written
+	// by this store since the lock was last released, so that it can be kept as a generation without
+	// being read and validated again.
+	fileValid bool
 	sync.Mutex
 }
 
+// JsonFileStoreOption configures a jsonFileStore.
+type JsonFileStoreOption func(*jsonFileStore)
+
+// WithGenerations sets the number of previous good generations of the store file kept for recovery.
+// No generations are kept if n is 0.
+func WithGenerations(n int) JsonFileStoreOption {
+	return func(kvs *jsonFileStore) {
+		if n >= 0 {
+			kvs.generations = n
+		}
+	}
+}
+
 // NewJsonFileStore creates a new jsonFileStore object, accessed as a KeyValueStore.
-func NewJsonFileStore(fileName string) (KeyValueStore, error) {
+func NewJsonFileStore(fileName string, opts ...JsonFileStoreOption) (KeyValueStore, error) {
 	if fileName == "" {
 		fileName = defaultFileName
 	}
@@ -55,10 +91,17 @@ func NewJsonFileStore(fileName string) (KeyValueStore, error) {
 	}
 
 	kvs := &jsonFileStore{
-		fileName: fileName,
+		fileName:     fileName,
 		lockFileName: platform.CNILockPath + filepath.Base(fileName) + lockExtension,
-		data:     make(map[string]*json.RawMessage),
+		data:         make(map[string]*json.RawMessage),
+		generations:  defaultStoreGenerations,
 	}
+	for _, opt := range opts {
+		opt(kvs)
+	}
+
+	// Recover from a crash or power loss which left the store file unusable.
+	kvs.restoreNewestValidGeneration()
 
 	return kvs, nil
 }
@@ -70,32 +113,24 @@ func (kvs *jsonFileStore) Read(key string, value interface{}) error {
 
 	// Read contents from file if memory is not in sync.
 	if !kvs.inSync {
-		// Open and parse the file if it exists.
-		file, err := os.Open(kvs.fileName)
+		data, fileName, err := kvs.readNewestValidGeneration()
 		if err != nil {
 			if os.IsNotExist(err) {
 				return ErrKeyNotFound
 			}
+			if err == ErrStoreEmpty {
+				log.Printf("Unable to read file %s, was empty", kvs.fileName)
+			}
 			return err
 		}
-		defer file.Close()
-
-		b, err := ioutil.ReadAll(file)
-		if err != nil {
-			return err
-		}
-
-		if len(b) == 0 {
-			log.Printf("Unable to read file %s, was empty", kvs.fileName)
-			return ErrStoreEmpty
-		}
 
-		// Decode to raw JSON messages.
-		if err := json.Unmarshal(b, &kvs.data); err != nil {
-			return err
+		if fileName != kvs.fileName {
+			log.Printf("[store] Warning: %s is corrupt, using state from %s", kvs.fileName, fileName)
 		}
 
+		kvs.data = data
 		kvs.inSync = true
+		kvs.fileValid = fileName == kvs.fileName
 	}
 
 	raw, ok := kvs.data[key]
@@ -131,13 +166,188 @@ func (kvs *jsonFileStore) Flush() error {
 }
 
 // Lock-free flush for internal callers.
+// The previous file is kept as the newest generation and the new contents are
+// written to a temp file which is synced to disk before atomically replacing it.
 func (kvs *jsonFileStore) flush() error {
-	buf, err := json.MarshalIndent(&kvs.data, "", "\t")
+	buf, err := encodeJSONFile(kvs.data)
 	if err != nil {
 		return err
 	}
 
-	dir, file := filepath.Split(kvs.fileName)
+	kvs.rotateGenerations()
+
+	if err = writeFileAtomic(kvs.fileName, buf); err != nil {
+		kvs.fileValid = false
+		return err
+	}
+	kvs.fileValid = true
+
+	return nil
+}
+
+// generationFileName returns the name of the file holding the nth previous generation of the store.
+func (kvs *jsonFileStore) generationFileName(n int) string {
+	return kvs.fileName + "." + strconv.Itoa(n)
+}
+
+// rotateGenerations shifts every kept generation back by one and keeps the current
+// file as the newest generation. The current file stays in place throughout.
+// It is only validated if it was not read or written by this store since the lock was released.
+func (kvs *jsonFileStore) rotateGenerations() {
+	if kvs.generations == 0 {
+		return
+	}
+
+	if !kvs.fileValid {
+		if _, err := readJSONFile(kvs.fileName); err != nil {
+			// only keep good generations.
+			return
+		}
+	}
+
+	for n := kvs.generations - 1; n > 0; n-- {
+		if err := os.Rename(kvs.generationFileName(n), kvs.generationFileName(n+1)); err != nil && !os.IsNotExist(err) {
+			log.Printf("[store] Failed to rotate generation %s: %v", kvs.generationFileName(n), err)
+		}
+	}
+
+	// hard link the current file so it is not copied; the link is not replaced by the next write.
+	newest := kvs.generationFileName(1)
+	_ = os.Remove(newest)
+	if err := os.Link(kvs.fileName, newest); err != nil {
+		b, err := ioutil.ReadFile(kvs.fileName)
+		if err == nil {
+			err = writeFileAtomic(newest, b)
+		}
+		if err != nil {
+			log.Printf("[store] Failed to keep generation %s: %v", newest, err)
+		}
+	}
+}
+
+// readNewestValidGeneration reads the store file, falling back to the newest generation
+// which is valid if it is empty or corrupt. It returns the name of the file read.
+// If no file is valid, the error reading the store file is returned.
+func (kvs *jsonFileStore) readNewestValidGeneration() (map[string]*json.RawMessage, string, error) {
+	data, err := readJSONFile(kvs.fileName)
+	if err == nil || os.IsNotExist(err) || err == ErrUnsupportedStoreSchemaVersion {
+		// writes never leave the store file missing, so a missing file was deleted on purpose.
+		// a newer schema was written by a newer binary; falling back would silently discard its writes.
+		return data, kvs.fileName, err
+	}
+
+	for n := 1; n <= kvs.generations; n++ {
+		fileName := kvs.generationFileName(n)
+		if generationData, generationErr := readJSONFile(fileName); generationErr == nil {
+			return generationData, fileName, nil
+		}
+	}
+
+	return nil, kvs.fileName, err
+}
+
+// restoreNewestValidGeneration replaces an empty or corrupt store file with the newest valid generation.
+func (kvs *jsonFileStore) restoreNewestValidGeneration() {
+	data, fileName, err := kvs.readNewestValidGeneration()
+	if err != nil || fileName == kvs.fileName {
+		return
+	}
+
+	log.Printf("[store] Warning: %s is corrupt, restoring it from %s", kvs.fileName, fileName)
+	buf, err := encodeJSONFile(data)
+	if err == nil {
+		err = writeFileAtomic(kvs.fileName, buf)
+	}
+	if err != nil {
+		log.Errorf("[store] Failed to restore %s from %s: %v", kvs.fileName, fileName, err)
+	}
+}
+
+// encodeJSONFile encodes the key value pairs along with the metadata used to validate them.
+func encodeJSONFile(data map[string]*json.RawMessage) ([]byte, error) {
+	sum, err := checksum(data)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := json.Marshal(jsonFileMetadata{SchemaVersion: storeSchemaVersion, Checksum: sum})
+	if err != nil {
+		return nil, err
+	}
+
+	file := make(map[string]*json.RawMessage, len(data)+1)
+	for key, value := range data {
+		file[key] = value
+	}
+	raw := json.RawMessage(metadata)
+	file[metadataKey] = &raw
+
+	return json.MarshalIndent(file, "", "\t")
+}
+
+// readJSONFile reads and validates the key value pairs in a store file.
+// Files written before the metadata was added are accepted without validation.
+func readJSONFile(fileName string) (map[string]*json.RawMessage, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, ErrStoreEmpty
+	}
+
+	// Decode to raw JSON messages.
+	data := make(map[string]*json.RawMessage)
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+
+	raw, ok := data[metadataKey]
+	if !ok {
+		return data, nil
+	}
+	delete(data, metadataKey)
+
+	var metadata jsonFileMetadata
+	if raw == nil {
+		return nil, ErrStoreChecksumMismatch
+	}
+	if err := json.Unmarshal(*raw, &metadata); err != nil {
+		return nil, err
+	}
+
+	if metadata.SchemaVersion > storeSchemaVersion {
+		return nil, ErrUnsupportedStoreSchemaVersion
+	}
+
+	sum, err := checksum(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if sum != metadata.Checksum {
+		return nil, ErrStoreChecksumMismatch
+	}
+
+	return data, nil
+}
+
+// checksum returns the SHA-256 checksum of the compact encoding of the key value pairs.
+func checksum(data map[string]*json.RawMessage) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// writeFileAtomic writes the contents to a temp file next to fileName, syncs it to disk
+// and then renames it over fileName, so that fileName is either the old or the new contents.
+func writeFileAtomic(fileName string, buf []byte) (err error) {
+	dir, file := filepath.Split(fileName)
 	if dir == "" {
 		dir = "."
 	}
@@ -162,15 +372,25 @@ func (kvs *jsonFileStore) flush() error {
 		return fmt.Errorf("Temp file write failed with: %v", err)
 	}
 
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("temp file sync failed with: %v", err)
+	}
+
 	if err = f.Close(); err != nil {
 		return fmt.Errorf("temp file close failed with: %v", err)
 	}
 
 	// atomic replace
-	if err = platform.ReplaceFile(tmpFileName, kvs.fileName); err != nil {
+	if err = platform.ReplaceFile(tmpFileName, fileName); err != nil {
 		return fmt.Errorf("rename temp file to state file failed:%v", err)
 	}
 
+	// persist the rename. Directories can't be synced on every platform, so this is best effort.
+	if d, dirErr := os.Open(dir); dirErr == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+
 	return nil
 }
 
@@ -183,46 +403,7 @@ func (kvs *jsonFileStore) Lock(block bool) error {
 		return ErrStoreLocked
 	}
 
-	var lockFile *os.File
-	var err error
-	lockPerm := os.FileMode(0664) + os.FileMode(os.ModeExclusive)
-
-	// Try to acquire the lock file.
-	var lockRetryCount uint
-	var modTimeCur time.Time
-	var modTimePrev time.Time
-	for lockRetryCount < lockMaxRetries {
-		lockFile, err = os.OpenFile(kvs.lockFileName, os.O_CREATE|os.O_EXCL|os.O_RDWR, lockPerm)
-		if err == nil {
-			break
-		}
-
-		if !block {
-			return ErrNonBlockingLockIsAlreadyLocked
-		}
-
-		// Reset the lock retry count if the timestamp for the lock file changes.
-		if fileInfo, err := os.Stat(kvs.lockFileName); err == nil {
-			modTimeCur = fileInfo.ModTime()
-			if !modTimeCur.Equal(modTimePrev) {
-				lockRetryCount = 0
-			}
-			modTimePrev = modTimeCur
-		}
-
-		time.Sleep(lockRetryDelay)
-
-		lockRetryCount++
-	}
-
-	if lockRetryCount == lockMaxRetries {
-		return ErrTimeoutLockingStore
-	}
-
-	defer lockFile.Close()
-
-	// Write the process ID for easy identification.
-	if _, err = lockFile.WriteString(strconv.Itoa(os.Getpid())); err != nil {
+	if err := acquireLockFile(kvs.lockFileName, block); err != nil {
 		return err
 	}
 
@@ -246,6 +427,7 @@ func (kvs *jsonFileStore) Unlock(forceUnlock bool) error {
 	}
 
 	kvs.inSync = false
+	kvs.fileValid = false
 	kvs.locked = false
 
 	return nil
@@ -270,31 +452,29 @@ func (kvs *jsonFileStore) GetLockFileModificationTime() (time.Time, error) {
 	kvs.Mutex.Lock()
 	defer kvs.Mutex.Unlock()
 
-	// Check if the file exists.
-	file, err := os.Open(kvs.lockFileName)
-	if err != nil {
-		return time.Time{}.UTC(), err
-	}
-
-	defer file.Close()
-
-	info, err := os.Stat(kvs.lockFileName)
-	if err != nil {
-		log.Printf("os.stat() for file %v failed: %v", kvs.lockFileName, err)
-		return time.Time{}.UTC(), err
-	}
-
-	return info.ModTime().UTC(), nil
+	return lockFileModificationTime(kvs.lockFileName)
 }
 
 func (kvs *jsonFileStore) GetLockFileName() string {
 	return kvs.lockFileName
 }
 
+// GetFileName returns the name of the file backing the store.
+func (kvs *jsonFileStore) GetFileName() string {
+	return kvs.fileName
+}
+
 func (kvs *jsonFileStore) Remove() {
 	kvs.Mutex.Lock()
 	if err := os.Remove(kvs.fileName); err != nil {
 		log.Errorf("could not remove file %s. Error: %v", kvs.fileName, err)
 	}
+	// the generations are removed too, else a later corrupt file would be restored from the state removed on purpose.
+	for n := 1; n <= kvs.generations; n++ {
+		if err := os.Remove(kvs.generationFileName(n)); err != nil && !os.IsNotExist(err) {
+			log.Errorf("could not remove file %s. Error: %v", kvs.generationFileName(n), err)
+		}
+	}
+	kvs.fileValid = false
 	kvs.Mutex.Unlock()
 }
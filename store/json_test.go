@@ -4,8 +4,12 @@
 package store
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -19,6 +23,14 @@ const (
 	testKey2 = "key2"
 )
 
+// removeTestStore removes a test store file and its generations.
+func removeTestStore(fileName string) {
+	os.Remove(fileName)
+	for n := 1; n <= defaultStoreGenerations; n++ {
+		os.Remove(fileName + "." + strconv.Itoa(n))
+	}
+}
+
 // Type for testing aggregate encoding.
 type testType1 struct {
 	Field1 string
@@ -43,7 +55,7 @@ func TestKeyValuePairsAreReinstantiatedFromJSONFile(t *testing.T) {
 	}
 
 	file.Close()
-	defer os.Remove(testFileName)
+	defer removeTestStore(testFileName)
 
 	// Create the store, initialized using the JSON file.
 	kvs, err := NewJsonFileStore(testFileName)
@@ -83,25 +95,29 @@ func TestKeyValuePairsArePersistedToJSONFile(t *testing.T) {
 	}
 
 	// Read the persisted file contents.
-	file, err := os.Open(testFileName)
+	b, err := ioutil.ReadFile(testFileName)
 	if err != nil {
-		t.Fatalf("Failed to open file %v", err)
+		t.Fatalf("Failed to read file %v", err)
 	}
 
-	data := make([]byte, 100)
-	n, err := file.Read(data)
-	if err != nil {
-		t.Fatalf("Failed to read from file %v", err)
+	removeTestStore(testFileName)
+
+	// Drop the metadata and remove indentation to normalize the JSON encoding.
+	var file map[string]json.RawMessage
+	if err = json.Unmarshal(b, &file); err != nil {
+		t.Fatalf("Failed to decode file %v", err)
 	}
 
-	file.Close()
-	os.Remove(testFileName)
+	if _, ok := file[metadataKey]; !ok {
+		t.Errorf("Store file is missing its metadata: %s", b)
+	}
+	delete(file, metadataKey)
 
-	// Remove indentation to normalize the JSON encoding.
-	actualPair = string(data[:n])
-	actualPair = strings.Replace(actualPair, " ", "", -1)
-	actualPair = strings.Replace(actualPair, "\t", "", -1)
-	actualPair = strings.Replace(actualPair, "\n", "", -1)
+	b, err = json.Marshal(file)
+	if err != nil {
+		t.Fatalf("Failed to encode file %v", err)
+	}
+	actualPair = string(b)
 
 	// Fail if the contents do not match expected JSON encoding.
 	if actualPair != expectedPair {
@@ -147,7 +163,7 @@ func TestKeyValuePairsAreWrittenAndReadCorrectly(t *testing.T) {
 	}
 
 	// Cleanup.
-	os.Remove(testFileName)
+	removeTestStore(testFileName)
 }
 
 // Tests that locking a store gives the caller exclusive access.
@@ -205,7 +221,7 @@ func TestLockingStoreGivesExclusiveAccess(t *testing.T) {
 	}
 
 	// Cleanup.
-	os.Remove(testFileName)
+	removeTestStore(testFileName)
 }
 
 // test case for testing newjsonfilestore idempotent
@@ -240,3 +256,237 @@ func TestLockFilePath(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a store file truncated by a crash falls back to the newest good generation.
+func TestTruncatedStoreFileIsRestoredFromGeneration(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	kvs, err := NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if err = kvs.Write(testKey1, &testType1{"first", 1}); err != nil {
+		t.Fatalf("Failed to write to store: %v", err)
+	}
+
+	if err = kvs.Write(testKey1, &testType1{"second", 2}); err != nil {
+		t.Fatalf("Failed to write to store: %v", err)
+	}
+
+	// Simulate a power loss leaving a truncated store file.
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read store file: %v", err)
+	}
+
+	if err = ioutil.WriteFile(fileName, b[:len(b)/2], 0o644); err != nil {
+		t.Fatalf("Failed to truncate store file: %v", err)
+	}
+
+	kvs, err = NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store from truncated file: %v", err)
+	}
+
+	var value testType1
+	if err = kvs.Read(testKey1, &value); err != nil {
+		t.Fatalf("Failed to read from restored store: %v", err)
+	}
+
+	if value != (testType1{"first", 1}) {
+		t.Errorf("Expected the newest good generation to be restored, got %+v", value)
+	}
+
+	// The store file itself was repaired.
+	if _, err = readJSONFile(fileName); err != nil {
+		t.Errorf("Expected the store file to be restored, got %v", err)
+	}
+}
+
+// Tests that a store file which doesn't match its checksum is not used.
+func TestStoreFileChecksumIsVerified(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	kvs, err := NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if err = kvs.Write(testKey1, &testType1{"test", 42}); err != nil {
+		t.Fatalf("Failed to write to store: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read store file: %v", err)
+	}
+
+	corrupt := []byte(strings.Replace(string(b), "42", "43", 1))
+	if err = ioutil.WriteFile(fileName, corrupt, 0o644); err != nil {
+		t.Fatalf("Failed to corrupt store file: %v", err)
+	}
+
+	if _, err = readJSONFile(fileName); err != ErrStoreChecksumMismatch {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+
+	// There is no good generation to fall back to.
+	kvs, err = NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	var value testType1
+	if err = kvs.Read(testKey1, &value); err != ErrStoreChecksumMismatch {
+		t.Errorf("Expected checksum mismatch reading corrupt store, got %v", err)
+	}
+}
+
+// Tests that a store file written with a newer schema is refused rather than replaced.
+func TestNewerStoreSchemaVersionIsRefused(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+	contents := `{"$Metadata":{"SchemaVersion":2,"Checksum":""},"key1":{"Field1":"test","Field2":42}}`
+
+	if err := ioutil.WriteFile(fileName, []byte(contents), 0o644); err != nil {
+		t.Fatalf("Failed to write store file: %v", err)
+	}
+
+	kvs, err := NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	var value testType1
+	if err = kvs.Read(testKey1, &value); err != ErrUnsupportedStoreSchemaVersion {
+		t.Errorf("Expected unsupported schema version, got %v", err)
+	}
+}
+
+// Tests that only the configured number of generations are kept.
+func TestStoreGenerationsAreBounded(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	kvs, err := NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for i := 0; i < defaultStoreGenerations+2; i++ {
+		if err = kvs.Write(testKey1, &testType1{"test", i}); err != nil {
+			t.Fatalf("Failed to write to store: %v", err)
+		}
+	}
+
+	for n := 1; n <= defaultStoreGenerations; n++ {
+		var value testType1
+		data, err := readJSONFile(fileName + "." + strconv.Itoa(n))
+		if err != nil {
+			t.Fatalf("Failed to read generation %d: %v", n, err)
+		}
+
+		if err = json.Unmarshal(*data[testKey1], &value); err != nil {
+			t.Fatalf("Failed to decode generation %d: %v", n, err)
+		}
+
+		if value.Field2 != defaultStoreGenerations+1-n {
+			t.Errorf("Generation %d holds write %d", n, value.Field2)
+		}
+	}
+
+	if _, err = os.Stat(fileName + "." + strconv.Itoa(defaultStoreGenerations+1)); !os.IsNotExist(err) {
+		t.Errorf("Expected no more than %d generations, got %v", defaultStoreGenerations, err)
+	}
+}
+
+// Tests that the number of generations kept can be configured.
+func TestStoreGenerationsAreConfigurable(t *testing.T) {
+	for _, generations := range []int{0, 1} {
+		fileName := filepath.Join(t.TempDir(), testFileName)
+
+		kvs, err := NewJsonFileStore(fileName, WithGenerations(generations))
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			if err = kvs.Write(testKey1, &testType1{"test", i}); err != nil {
+				t.Fatalf("Failed to write to store: %v", err)
+			}
+		}
+
+		for n := 1; n <= generations; n++ {
+			if _, err = readJSONFile(fileName + "." + strconv.Itoa(n)); err != nil {
+				t.Errorf("Failed to read generation %d of %d: %v", n, generations, err)
+			}
+		}
+
+		if _, err = os.Stat(fileName + "." + strconv.Itoa(generations+1)); !os.IsNotExist(err) {
+			t.Errorf("Expected no more than %d generations, got %v", generations, err)
+		}
+	}
+}
+
+// Tests that a deleted store file is not brought back from its generations.
+func TestDeletedStoreFileIsNotRestored(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	kvs, err := NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = kvs.Write(testKey1, &testType1{"test", i}); err != nil {
+			t.Fatalf("Failed to write to store: %v", err)
+		}
+	}
+
+	// The store file is cleared on purpose, e.g. after a reboot on windows.
+	if err = os.Remove(fileName); err != nil {
+		t.Fatalf("Failed to remove store file: %v", err)
+	}
+
+	kvs, err = NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	var value testType1
+	if err = kvs.Read(testKey1, &value); err != ErrKeyNotFound {
+		t.Errorf("Expected a deleted store to be empty, got %v and %+v", err, value)
+	}
+}
+
+// Tests that a removed store isn't restored from its generations when the store file is corrupt later.
+func TestRemovedStoreIsNotRestoredFromGenerations(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), testFileName)
+
+	kvs, err := NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = kvs.Write(testKey1, &testType1{"test", i}); err != nil {
+			t.Fatalf("Failed to write to store: %v", err)
+		}
+	}
+
+	kvs.Remove()
+
+	// Simulate a power loss leaving a truncated store file.
+	if err = ioutil.WriteFile(fileName, []byte(`{"key1":`), 0o644); err != nil {
+		t.Fatalf("Failed to write corrupt store file: %v", err)
+	}
+
+	kvs, err = NewJsonFileStore(fileName)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	var value testType1
+	if err = kvs.Read(testKey1, &value); err == nil {
+		t.Errorf("Expected the removed state not to be restored, got %+v", value)
+	}
+}
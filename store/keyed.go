@@ -0,0 +1,126 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// KeyedMap persists the entries of a map under keys of their own in a TransactionalKeyValueStore,
+// so that a write only rewrites the entries which changed since the map was last read or written.
+// The IDs of the entries are kept under the key of the map and every entry under the key of the
+// map followed by a slash and its ID.
+type KeyedMap struct {
+	key string
+	// written is the encoding of every entry as of the last Read or committed Update.
+	written map[string][]byte
+	sync.Mutex
+}
+
+// NewKeyedMap creates a KeyedMap stored under the key.
+func NewKeyedMap(key string) *KeyedMap {
+	return &KeyedMap{
+		key:     key,
+		written: make(map[string][]byte),
+	}
+}
+
+func (m *KeyedMap) entryKey(id string) string {
+	return m.key + "/" + id
+}
+
+// Read calls fn with the ID and encoded value of every entry in the store.
+// It returns nil without calling fn if the map was never written.
+func (m *KeyedMap) Read(kvs KeyValueStore, fn func(id string, raw json.RawMessage) error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var ids []string
+	if err := kvs.Read(m.key, &ids); err != nil {
+		if err == ErrKeyNotFound {
+			m.written = make(map[string][]byte)
+			return nil
+		}
+		return err
+	}
+
+	written := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		var raw json.RawMessage
+		if err := kvs.Read(m.entryKey(id), &raw); err != nil {
+			return errors.Wrapf(err, "failed to read entry %s of %s", id, m.key)
+		}
+		if err := fn(id, raw); err != nil {
+			return err
+		}
+		written[id] = raw
+	}
+	m.written = written
+
+	return nil
+}
+
+// Update writes the entries which changed since the last Read or Update and deletes the ones which were
+// removed, in the same transaction as the other writes of fn.
+func (m *KeyedMap) Update(kvs TransactionalKeyValueStore, entries map[string]interface{}, fn func(Transaction) error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	written := make(map[string][]byte, len(entries))
+	for id, value := range entries {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode entry %s of %s", id, m.key)
+		}
+		written[id] = raw
+	}
+
+	err := kvs.Update(func(tx Transaction) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		for id, raw := range written {
+			if old, ok := m.written[id]; ok && bytes.Equal(old, raw) {
+				continue
+			}
+			if err := tx.Write(m.entryKey(id), json.RawMessage(raw)); err != nil {
+				return errors.Wrapf(err, "failed to write entry %s of %s", id, m.key)
+			}
+		}
+
+		removed := false
+		for id := range m.written {
+			if _, ok := written[id]; ok {
+				continue
+			}
+			removed = true
+			if err := tx.Delete(m.entryKey(id)); err != nil {
+				return errors.Wrapf(err, "failed to delete entry %s of %s", id, m.key)
+			}
+		}
+
+		if !removed && len(written) == len(m.written) {
+			// the IDs are unchanged.
+			return nil
+		}
+		ids := make([]string, 0, len(written))
+		for id := range written {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return tx.Write(m.key, ids)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.written = written
+	return nil
+}
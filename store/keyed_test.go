@@ -0,0 +1,88 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStore records the keys written and deleted by the transactions of a TransactionalKeyValueStore.
+type recordingStore struct {
+	TransactionalKeyValueStore
+	changed []string
+}
+
+type recordingTransaction struct {
+	Transaction
+	s *recordingStore
+}
+
+func (s *recordingStore) Update(fn func(Transaction) error) error {
+	s.changed = nil
+	return s.TransactionalKeyValueStore.Update(func(tx Transaction) error {
+		return fn(recordingTransaction{Transaction: tx, s: s})
+	})
+}
+
+func (tx recordingTransaction) Write(key string, value interface{}) error {
+	tx.s.changed = append(tx.s.changed, key)
+	return tx.Transaction.Write(key, value)
+}
+
+func (tx recordingTransaction) Delete(key string) error {
+	tx.s.changed = append(tx.s.changed, "-"+key)
+	return tx.Transaction.Delete(key)
+}
+
+func readKeyedMap(t *testing.T, m *KeyedMap, kvs KeyValueStore) map[string]testType1 {
+	entries := map[string]testType1{}
+	require.NoError(t, m.Read(kvs, func(id string, raw json.RawMessage) error {
+		var value testType1
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		entries[id] = value
+		return nil
+	}))
+	return entries
+}
+
+func TestKeyedMapOnlyWritesChangedEntries(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test.db")
+	bolt, err := NewBoltStore(fileName)
+	require.NoError(t, err)
+	kvs := &recordingStore{TransactionalKeyValueStore: bolt}
+
+	m := NewKeyedMap("map")
+	assert.Empty(t, readKeyedMap(t, m, kvs))
+
+	writeOther := func(tx Transaction) error { return tx.Write(testKey1, testType1{"other", 1}) }
+	require.NoError(t, m.Update(kvs, map[string]interface{}{"a": testType1{"a", 1}, "b": testType1{"b", 1}}, writeOther))
+	assert.ElementsMatch(t, []string{testKey1, "map/a", "map/b", "map"}, kvs.changed)
+
+	require.NoError(t, m.Update(kvs, map[string]interface{}{"a": testType1{"a", 2}, "b": testType1{"b", 1}}, writeOther))
+	assert.ElementsMatch(t, []string{testKey1, "map/a"}, kvs.changed)
+
+	require.NoError(t, m.Update(kvs, map[string]interface{}{"b": testType1{"b", 1}}, writeOther))
+	assert.ElementsMatch(t, []string{testKey1, "-map/a", "map"}, kvs.changed)
+
+	// a new map reads the committed entries and carries on from them.
+	m = NewKeyedMap("map")
+	assert.Equal(t, map[string]testType1{"b": {"b", 1}}, readKeyedMap(t, m, kvs))
+	require.NoError(t, m.Update(kvs, map[string]interface{}{"b": testType1{"b", 1}}, writeOther))
+	assert.ElementsMatch(t, []string{testKey1}, kvs.changed)
+
+	// entries aren't considered written by a transaction which is rolled back.
+	errAbort := errors.New("abort")
+	assert.Equal(t, errAbort, m.Update(kvs, map[string]interface{}{"c": testType1{"c", 1}}, func(Transaction) error { return errAbort }))
+	require.NoError(t, m.Update(kvs, map[string]interface{}{"c": testType1{"c", 1}}, writeOther))
+	assert.ElementsMatch(t, []string{testKey1, "map/c", "-map/b", "map"}, kvs.changed)
+	assert.Equal(t, map[string]testType1{"c": {"c", 1}}, readKeyedMap(t, NewKeyedMap("map"), kvs))
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// acquireLockFile creates the lock file shared by the processes using a store,
+// retrying while another process holds it if block is set.
+func acquireLockFile(lockFileName string, block bool) error {
+	var lockFile *os.File
+	var err error
+	lockPerm := os.FileMode(0664) + os.FileMode(os.ModeExclusive)
+
+	// Try to acquire the lock file.
+	var lockRetryCount uint
+	var modTimeCur time.Time
+	var modTimePrev time.Time
+	for lockRetryCount < lockMaxRetries {
+		lockFile, err = os.OpenFile(lockFileName, os.O_CREATE|os.O_EXCL|os.O_RDWR, lockPerm)
+		if err == nil {
+			break
+		}
+
+		if !block {
+			return ErrNonBlockingLockIsAlreadyLocked
+		}
+
+		// Reset the lock retry count if the timestamp for the lock file changes.
+		if fileInfo, err := os.Stat(lockFileName); err == nil {
+			modTimeCur = fileInfo.ModTime()
+			if !modTimeCur.Equal(modTimePrev) {
+				lockRetryCount = 0
+			}
+			modTimePrev = modTimeCur
+		}
+
+		time.Sleep(lockRetryDelay)
+
+		lockRetryCount++
+	}
+
+	if lockRetryCount == lockMaxRetries {
+		return ErrTimeoutLockingStore
+	}
+
+	defer lockFile.Close()
+
+	// Write the process ID for easy identification.
+	if _, err = lockFile.WriteString(strconv.Itoa(os.Getpid())); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// lockFileModificationTime returns the modification time of the lock file.
+func lockFileModificationTime(lockFileName string) (time.Time, error) {
+	// Check if the file exists.
+	file, err := os.Open(lockFileName)
+	if err != nil {
+		return time.Time{}.UTC(), err
+	}
+
+	defer file.Close()
+
+	info, err := os.Stat(lockFileName)
+	if err != nil {
+		log.Printf("os.stat() for file %v failed: %v", lockFileName, err)
+		return time.Time{}.UTC(), err
+	}
+
+	return info.ModTime().UTC(), nil
+}
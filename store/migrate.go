@@ -0,0 +1,47 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"github.com/pkg/errors"
+)
+
+// MigrateJSONFile copies every key of the JSON file store at jsonFileName to dst in a
+// single transaction and returns the number of keys copied. Keys already in dst are overwritten.
+// Both stores are locked for the duration of the migration and the JSON file is left in place.
+func MigrateJSONFile(jsonFileName string, dst TransactionalKeyValueStore) (int, error) {
+	src, err := NewJsonFileStore(jsonFileName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = src.Lock(true); err != nil {
+		return 0, errors.Wrapf(err, "failed to lock %s", jsonFileName)
+	}
+	defer src.Unlock(false) //nolint:errcheck // nothing to do if the lock can't be released
+
+	if err = dst.Lock(true); err != nil {
+		return 0, errors.Wrap(err, "failed to lock destination store")
+	}
+	defer dst.Unlock(false) //nolint:errcheck // nothing to do if the lock can't be released
+
+	data, err := readJSONFile(jsonFileName)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read %s", jsonFileName)
+	}
+
+	err = dst.Update(func(tx Transaction) error {
+		for key, value := range data {
+			if err := tx.Write(key, value); err != nil {
+				return errors.Wrapf(err, "failed to write key %s", key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
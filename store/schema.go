@@ -0,0 +1,108 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/log"
+	"github.com/pkg/errors"
+)
+
+// SchemaVersionField is the field of a persisted state object holding its schema version.
+// State written before schema versions were introduced has no such field and is version 0.
+const SchemaVersionField = "SchemaVersion"
+
+// ErrNewerStateSchemaVersion is returned when persisted state was written by a newer binary.
+var ErrNewerStateSchemaVersion = fmt.Errorf("state was written with a newer schema version than this binary supports")
+
+// Migration upgrades the fields of a persisted state object by one schema version.
+type Migration struct {
+	// Version is the schema version of the state after the migration.
+	Version int
+	// Description is logged when the migration runs.
+	Description string
+	// Migrate rewrites the fields of the state object in place.
+	Migrate func(fields map[string]json.RawMessage) error
+}
+
+// Schema is the ordered list of migrations of a persisted state object.
+// The current version of the schema is the version of its last migration.
+type Schema struct {
+	// Name identifies the state in logs and errors.
+	Name       string
+	Migrations []Migration
+}
+
+// Version returns the current version of the schema.
+func (s *Schema) Version() int {
+	if len(s.Migrations) == 0 {
+		return 0
+	}
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+// Read reads the state object for key from the store, runs every migration newer than
+// the version it was written with and decodes the result into value. It returns the
+// version the state was written with. State written with a newer schema version than
+// the current one is refused with ErrNewerStateSchemaVersion and value is left untouched.
+func (s *Schema) Read(kvs KeyValueStore, key string, value interface{}) (int, error) {
+	var fields map[string]json.RawMessage
+	if err := kvs.Read(key, &fields); err != nil {
+		return 0, err
+	}
+
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+
+	version, err := s.Migrate(fields)
+	if err != nil {
+		return version, err
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return version, errors.Wrapf(err, "failed to encode migrated %s state", s.Name)
+	}
+
+	return version, json.Unmarshal(b, value)
+}
+
+// Migrate runs every migration newer than the version of the state object on its fields
+// and sets its version to the current one. It returns the version the state was written with.
+func (s *Schema) Migrate(fields map[string]json.RawMessage) (int, error) {
+	version := 0
+	if raw, ok := fields[SchemaVersionField]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return 0, errors.Wrapf(err, "failed to decode %s state schema version", s.Name)
+		}
+	}
+
+	if version > s.Version() {
+		return version, errors.Wrapf(ErrNewerStateSchemaVersion,
+			"%s state has schema version %d but this binary supports up to %d, upgrade the binary or remove the state",
+			s.Name, version, s.Version())
+	}
+
+	for _, migration := range s.Migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		log.Printf("[store] Migrating %s state to schema version %d: %s", s.Name, migration.Version, migration.Description)
+		if err := migration.Migrate(fields); err != nil {
+			return version, errors.Wrapf(err, "failed to migrate %s state to schema version %d", s.Name, migration.Version)
+		}
+	}
+
+	raw, err := json.Marshal(s.Version())
+	if err != nil {
+		return version, err
+	}
+	fields[SchemaVersionField] = raw
+
+	return version, nil
+}
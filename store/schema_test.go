@@ -0,0 +1,113 @@
+// Copyright 2021 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState struct {
+	SchemaVersion int
+	Name          string
+	Names         []string
+}
+
+// testSchema renames Field1 to Name in version 1 and turns Name into Names in version 2.
+var testSchema = Schema{
+	Name: "test",
+	Migrations: []Migration{
+		{
+			Version:     1,
+			Description: "rename Field1 to Name",
+			Migrate: func(fields map[string]json.RawMessage) error {
+				fields["Name"] = fields["Field1"]
+				delete(fields, "Field1")
+				return nil
+			},
+		},
+		{
+			Version:     2,
+			Description: "turn Name into Names",
+			Migrate: func(fields map[string]json.RawMessage) error {
+				var name string
+				if err := json.Unmarshal(fields["Name"], &name); err != nil {
+					return err
+				}
+				names, err := json.Marshal([]string{name})
+				if err != nil {
+					return err
+				}
+				fields["Names"] = names
+				delete(fields, "Name")
+				return nil
+			},
+		},
+	},
+}
+
+func TestSchemaReadMigratesInOrder(t *testing.T) {
+	tests := []struct {
+		name            string
+		stored          interface{}
+		expectedVersion int
+	}{
+		{
+			name:            "unversioned",
+			stored:          map[string]interface{}{"Field1": "test"},
+			expectedVersion: 0,
+		},
+		{
+			name:            "version 1",
+			stored:          map[string]interface{}{SchemaVersionField: 1, "Name": "test"},
+			expectedVersion: 1,
+		},
+		{
+			name:            "current",
+			stored:          map[string]interface{}{SchemaVersionField: 2, "Names": []string{"test"}},
+			expectedVersion: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			kvs, err := NewJsonFileStore(filepath.Join(t.TempDir(), testFileName))
+			require.NoError(t, err)
+			require.NoError(t, kvs.Write(testKey1, tt.stored))
+
+			var state testState
+			version, err := testSchema.Read(kvs, testKey1, &state)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedVersion, version)
+			assert.Equal(t, testState{SchemaVersion: 2, Names: []string{"test"}}, state)
+		})
+	}
+}
+
+func TestSchemaReadRefusesNewerVersion(t *testing.T) {
+	kvs, err := NewJsonFileStore(filepath.Join(t.TempDir(), testFileName))
+	require.NoError(t, err)
+	require.NoError(t, kvs.Write(testKey1, map[string]interface{}{SchemaVersionField: 3, "Names": []string{"test"}}))
+
+	state := testState{Name: "untouched"}
+	version, err := testSchema.Read(kvs, testKey1, &state)
+	assert.True(t, errors.Is(err, ErrNewerStateSchemaVersion))
+	assert.Equal(t, 3, version)
+	assert.Equal(t, testState{Name: "untouched"}, state)
+}
+
+func TestSchemaReadPassesThroughStoreErrors(t *testing.T) {
+	kvs, err := NewJsonFileStore(filepath.Join(t.TempDir(), testFileName))
+	require.NoError(t, err)
+
+	var state testState
+	_, err = testSchema.Read(kvs, testKey1, &state)
+	assert.Equal(t, ErrKeyNotFound, err)
+}
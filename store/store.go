@@ -5,6 +5,7 @@ package store
 
 import (
 	"fmt"
+	"os"
 	"time"
 )
 
@@ -18,9 +19,64 @@ type KeyValueStore interface {
 	GetModificationTime() (time.Time, error)
 	GetLockFileModificationTime() (time.Time, error)
 	GetLockFileName() string
+	GetFileName() string
 	Remove()
 }
 
+// Transaction is a set of reads and writes applied to a TransactionalKeyValueStore atomically.
+type Transaction interface {
+	Read(key string, value interface{}) error
+	Write(key string, value interface{}) error
+	Delete(key string) error
+}
+
+// TransactionalKeyValueStore is a KeyValueStore which can apply several reads and writes atomically.
+type TransactionalKeyValueStore interface {
+	KeyValueStore
+	Update(fn func(Transaction) error) error
+}
+
+// Type is the backend a KeyValueStore persists to.
+type Type string
+
+const (
+	// JSONFile stores every key in a single JSON file which is rewritten on every write.
+	JSONFile Type = "json"
+	// Bolt stores every key in an embedded bolt database which is updated in place.
+	Bolt Type = "bolt"
+)
+
+// FileExtension returns the extension of the files backing stores of the type.
+func (t Type) FileExtension() string {
+	if t == Bolt {
+		return ".db"
+	}
+	return ".json"
+}
+
+// DetectType returns the type of the store at path, the file name without its extension: Bolt once the
+// JSON state has been migrated to a bolt store next to it, and JSONFile otherwise.
+func DetectType(path string) Type {
+	if _, err := os.Stat(path + Bolt.FileExtension()); err == nil {
+		return Bolt
+	}
+	return JSONFile
+}
+
+// NewKeyValueStore creates a KeyValueStore of the given type backed by the file
+// at path plus the extension of the type. An empty type is a JSONFile store.
+// The opts only apply to JSONFile stores.
+func NewKeyValueStore(storeType Type, path string, opts ...JsonFileStoreOption) (KeyValueStore, error) {
+	switch storeType {
+	case JSONFile, "":
+		return NewJsonFileStore(path+JSONFile.FileExtension(), opts...)
+	case Bolt:
+		return NewBoltStore(path + Bolt.FileExtension())
+	default:
+		return nil, fmt.Errorf("unknown store type %s", storeType)
+	}
+}
+
 var (
 	// Errors returned by KeyValueStore methods.
 	ErrKeyNotFound                    = fmt.Errorf("key not found")
@@ -29,4 +85,6 @@ var (
 	ErrStoreEmpty                     = fmt.Errorf("store is empty")
 	ErrTimeoutLockingStore            = fmt.Errorf("timed out locking store")
 	ErrNonBlockingLockIsAlreadyLocked = fmt.Errorf("attempted to perform non-blocking lock on an already locked store")
+	ErrStoreChecksumMismatch          = fmt.Errorf("store checksum does not match its contents")
+	ErrUnsupportedStoreSchemaVersion  = fmt.Errorf("store was written with a newer schema version")
 )
@@ -53,4 +53,8 @@ func (store *KeyValueStoreMock) GetLockFileName() string {
 	return ""
 }
 
+func (store *KeyValueStoreMock) GetFileName() string {
+	return ""
+}
+
 func (store *KeyValueStoreMock) Remove() {}
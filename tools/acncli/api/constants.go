@@ -26,6 +26,28 @@ const (
 	FlagFollow      = "follow"
 	FlagLogFilePath = "log-file"
 
+	//Store Migrate Flags
+	FlagMigrateFrom = "from"
+	FlagMigrateTo   = "to"
+
+	//CNS Flags
+	FlagCNSURL         = "cns-url"
+	FlagOutput         = "output"
+	FlagState          = "state"
+	FlagNC             = "nc"
+	FlagPod            = "pod"
+	FlagNamespace      = "namespace"
+	FlagCIDR           = "cidr"
+	FlagMinTimeInState = "min-time-in-state"
+	FlagFile           = "file"
+
+	// output flags
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+
+	DefaultCNSURL = "http://localhost:10090"
+
 	// tenancy flags
 	Singletenancy = "singletenancy"
 	Multitenancy  = "multitenancy"
@@ -88,6 +110,8 @@ var (
 		FlagConflistDirectory:        DefaultConflistDirLinux,
 		FlagVersion:                  Packaged,
 		FlagLogFilePath:              DefaultLogFile,
+		FlagCNSURL:                   DefaultCNSURL,
+		FlagOutput:                   OutputTable,
 		EnvCNILogFile:                EnvCNILogFile,
 		EnvCNISourceDir:              DefaultSrcDirLinux,
 		EnvCNIDestinationBinDir:      DefaultBinDirLinux,
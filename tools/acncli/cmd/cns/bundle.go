@@ -0,0 +1,61 @@
+package cns
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/cnsclient"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/spf13/cobra"
+)
+
+// bundleRequestTimeout bounds the download of a debug bundle, which includes the CNS log files.
+const bundleRequestTimeout = 2 * time.Minute
+
+// BundleCmd returns the command to download a debug bundle of the CNS diagnostics
+func BundleCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "bundle",
+		Short: "Download a tar.gz of the CNS diagnostics",
+		Long: "The bundle command downloads a tar.gz of the CNS in-memory state, IPAM pool monitor state, cached NodeNetworkConfig, " +
+			"persisted state file, log files, config with its secrets redacted and goroutine dump.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := cmd.Flags().GetString(c.FlagFile)
+			if err != nil {
+				return err
+			}
+			if file == "" {
+				file = fmt.Sprintf("azure-cns-debug-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+			}
+			url, err := cmd.Flags().GetString(c.FlagCNSURL)
+			if err != nil {
+				return err
+			}
+			client, err := cnsclient.New(cnsclient.Config{URL: url, RequestTimeout: bundleRequestTimeout})
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			if err = client.GetDebugBundle(cmd.Context(), f); err != nil {
+				f.Close()
+				os.Remove(file)
+				return err
+			}
+			if err = f.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "✅ - wrote CNS debug bundle to %s\n", file)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP(c.FlagFile, "f", "", "File to write the bundle to, azure-cns-debug-<time>.tar.gz by default")
+
+	return cmd
+}
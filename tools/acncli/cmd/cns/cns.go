@@ -0,0 +1,66 @@
+package cns
+
+import (
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/cnsclient"
+	"github.com/Azure/azure-container-networking/log"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/spf13/cobra"
+)
+
+const cnsRequestTimeout = 5 * time.Second
+
+// CNSCmd returns the command group for querying and operating on Azure CNS
+func CNSCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "cns",
+		Short: "Collection of functions related to Azure CNS",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// the CNS client logs every request to stderr, which would drown out the output
+			log.SetLevel(log.LevelError)
+		},
+	}
+
+	cmd.PersistentFlags().String(c.FlagCNSURL, c.Defaults[c.FlagCNSURL], "URL of the CNS API, e.g. http://localhost:10090 or unix:///var/run/azure-cns/cns.sock")
+	cmd.PersistentFlags().StringP(c.FlagOutput, "o", c.Defaults[c.FlagOutput], "Output format, one of table, json or yaml")
+
+	cmd.AddCommand(GetCmd())
+	cmd.AddCommand(ReleaseCmd())
+	cmd.AddCommand(BundleCmd())
+	return cmd
+}
+
+// GetCmd returns the command group for getting the state of CNS
+func GetCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "get",
+		Short: "Get the state of Azure CNS",
+	}
+
+	cmd.AddCommand(GetIPsCmd())
+	cmd.AddCommand(GetPodsCmd())
+	cmd.AddCommand(GetNCsCmd())
+	cmd.AddCommand(GetPoolCmd())
+	return cmd
+}
+
+// ReleaseCmd returns the command group for releasing resources held in CNS
+func ReleaseCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "release",
+		Short: "Release resources held in Azure CNS",
+	}
+
+	cmd.AddCommand(ReleaseIPCmd())
+	return cmd
+}
+
+// newClient returns a CNS client for the URL set on the command.
+func newClient(cmd *cobra.Command) (*cnsclient.Client, error) {
+	url, err := cmd.Flags().GetString(c.FlagCNSURL)
+	if err != nil {
+		return nil, err
+	}
+	return cnsclient.New(cnsclient.Config{URL: url, RequestTimeout: cnsRequestTimeout})
+}
@@ -0,0 +1,359 @@
+package cns
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/spf13/cobra"
+)
+
+// ipConfigStates are the states an IP in the CNS pool can be in.
+var ipConfigStates = []cns.IPConfigState{cns.Available, cns.Allocated, cns.PendingRelease, cns.PendingProgramming}
+
+// GetIPsCmd returns the command to get the IPs in the CNS pool
+func GetIPsCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "ips",
+		Short: "Get the IPs in the CNS pool, optionally only those in the given states or matching the given filters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := ipsRequest(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			ips, err := client.GetIPAddresses(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+
+			sortIPConfigs(ips)
+			return printOutput(cmd, ips, ipsTable(ips))
+		},
+	}
+
+	cmd.Flags().StringSlice(c.FlagState, nil, "States of the IPs to get, any of Available, Allocated, PendingRelease and PendingProgramming. All IPs by default")
+	cmd.Flags().String(c.FlagNC, "", "Only get the IPs of the network container with this ID")
+	cmd.Flags().String(c.FlagPod, "", "Only get the IPs of the pods with this name")
+	cmd.Flags().String(c.FlagNamespace, "", "Only get the IPs of the pods in this namespace")
+	cmd.Flags().String(c.FlagCIDR, "", "Only get the IPs in this CIDR, e.g. 10.0.0.0/24")
+	cmd.Flags().Duration(c.FlagMinTimeInState, 0, "Only get the IPs which have been in their state for longer than this, e.g. 1h")
+
+	return cmd
+}
+
+// ipsRequest returns the request for the IPs matching the flags of the command.
+func ipsRequest(cmd *cobra.Command) (cns.GetIPAddressesRequest, error) {
+	var req cns.GetIPAddressesRequest
+	stateNames, err := cmd.Flags().GetStringSlice(c.FlagState)
+	if err != nil {
+		return req, err
+	}
+	if req.IPConfigStateFilter, err = parseStates(stateNames); err != nil {
+		return req, err
+	}
+	if req.NCID, err = cmd.Flags().GetString(c.FlagNC); err != nil {
+		return req, err
+	}
+	if req.PodName, err = cmd.Flags().GetString(c.FlagPod); err != nil {
+		return req, err
+	}
+	if req.PodNamespace, err = cmd.Flags().GetString(c.FlagNamespace); err != nil {
+		return req, err
+	}
+	if req.IPCIDR, err = cmd.Flags().GetString(c.FlagCIDR); err != nil {
+		return req, err
+	}
+	if req.IPCIDR != "" {
+		if _, _, err = net.ParseCIDR(req.IPCIDR); err != nil {
+			return req, fmt.Errorf("invalid CIDR %q: %w", req.IPCIDR, err)
+		}
+	}
+	req.MinTimeInState, err = cmd.Flags().GetDuration(c.FlagMinTimeInState)
+	return req, err
+}
+
+// GetPodsCmd returns the command to get the pods holding IPs in CNS
+func GetPodsCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "pods",
+		Short: "Get the pods holding IPs from the CNS pool",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			podContext, err := client.GetPodOrchestratorContext(cmd.Context())
+			if err != nil {
+				return err
+			}
+			ips, err := client.GetIPAddressesMatchingStates(cmd.Context(), ipConfigStates...)
+			if err != nil {
+				return err
+			}
+
+			pods := podIPs(podContext, ips)
+			return printOutput(cmd, pods, podsTable(pods))
+		},
+	}
+
+	return cmd
+}
+
+// GetNCsCmd returns the command to get the network containers the CNS pool is made of
+func GetNCsCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "ncs",
+		Short: "Get the network containers of the CNS pool with their IP counts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			resp, err := client.GetHTTPServiceData(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			ncs := ncSummaries(resp.HTTPRestServiceData)
+			return printOutput(cmd, ncs, ncsTable(ncs))
+		},
+	}
+
+	return cmd
+}
+
+// GetPoolCmd returns the command to get the state of the CNS pool
+func GetPoolCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "pool",
+		Short: "Get the size, scaling limits and IP counts of the CNS pool",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			resp, err := client.GetHTTPServiceData(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			pool := summarizePool(resp.HTTPRestServiceData)
+			return printOutput(cmd, pool, poolTable(pool))
+		},
+	}
+
+	return cmd
+}
+
+// parseStates returns the IPConfigStates named, ignoring case, or all of them if none are.
+func parseStates(names []string) ([]cns.IPConfigState, error) {
+	if len(names) == 0 {
+		return ipConfigStates, nil
+	}
+
+	states := make([]cns.IPConfigState, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, state := range ipConfigStates {
+			if strings.EqualFold(name, string(state)) {
+				states = append(states, state)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown IP state %q, options are %v", name, ipConfigStates)
+		}
+	}
+	return states, nil
+}
+
+// sortIPConfigs sorts the IPs by address, numerically.
+func sortIPConfigs(ips []cns.IPConfigurationStatus) {
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(ips[i].IPAddress).To16(), net.ParseIP(ips[j].IPAddress).To16()) < 0
+	})
+}
+
+func ipsTable(ips []cns.IPConfigurationStatus) *table {
+	t := &table{header: []string{"IP", "STATE", "NC", "POD", "NAMESPACE", "ID"}}
+	for i := range ips {
+		var name, namespace string
+		if ips[i].PodInfo != nil {
+			name, namespace = ips[i].PodInfo.Name(), ips[i].PodInfo.Namespace()
+		}
+		t.append(ips[i].IPAddress, string(ips[i].State), ips[i].NCID, name, namespace, ips[i].ID)
+	}
+	return t
+}
+
+// PodIP is a pod holding an IP from the CNS pool.
+type PodIP struct {
+	PodInterfaceKey string
+	PodName         string
+	PodNamespace    string
+	IPAddress       string
+	State           cns.IPConfigState
+	NCID            string
+	IPConfigID      string
+}
+
+// podIPs joins the IP config IDs held by each pod interface to the IPs, sorted by pod.
+func podIPs(podContext map[string]string, ips []cns.IPConfigurationStatus) []PodIP {
+	ipsByID := make(map[string]cns.IPConfigurationStatus, len(ips))
+	for i := range ips {
+		ipsByID[ips[i].ID] = ips[i]
+	}
+
+	pods := make([]PodIP, 0, len(podContext))
+	for key, id := range podContext {
+		pod := PodIP{PodInterfaceKey: key, IPConfigID: id}
+		if ip, ok := ipsByID[id]; ok {
+			pod.IPAddress, pod.State, pod.NCID = ip.IPAddress, ip.State, ip.NCID
+			if ip.PodInfo != nil {
+				pod.PodName, pod.PodNamespace = ip.PodInfo.Name(), ip.PodInfo.Namespace()
+			}
+		}
+		pods = append(pods, pod)
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].PodNamespace != pods[j].PodNamespace {
+			return pods[i].PodNamespace < pods[j].PodNamespace
+		}
+		if pods[i].PodName != pods[j].PodName {
+			return pods[i].PodName < pods[j].PodName
+		}
+		return pods[i].PodInterfaceKey < pods[j].PodInterfaceKey
+	})
+	return pods
+}
+
+func podsTable(pods []PodIP) *table {
+	t := &table{header: []string{"NAMESPACE", "POD", "IP", "STATE", "NC", "INTERFACE KEY"}}
+	for i := range pods {
+		t.append(pods[i].PodNamespace, pods[i].PodName, pods[i].IPAddress, string(pods[i].State), pods[i].NCID, pods[i].PodInterfaceKey)
+	}
+	return t
+}
+
+// IPCounts is the IP count in each state.
+type IPCounts struct {
+	Total              int
+	Available          int
+	Allocated          int
+	PendingRelease     int
+	PendingProgramming int
+}
+
+func (counts *IPCounts) add(state cns.IPConfigState) {
+	counts.Total++
+	switch state {
+	case cns.Available:
+		counts.Available++
+	case cns.Allocated:
+		counts.Allocated++
+	case cns.PendingRelease:
+		counts.PendingRelease++
+	case cns.PendingProgramming:
+		counts.PendingProgramming++
+	}
+}
+
+// NCSummary is a network container of the CNS pool with its IP counts.
+type NCSummary struct {
+	ID  string
+	IPs IPCounts
+}
+
+// ncSummaries returns the NCs IPs in the pool are from, sorted by ID.
+func ncSummaries(data restserver.HTTPRestServiceData) []NCSummary {
+	byID := map[string]*NCSummary{}
+	for _, ip := range data.PodIPConfigState {
+		nc, ok := byID[ip.NCID]
+		if !ok {
+			nc = &NCSummary{ID: ip.NCID}
+			byID[ip.NCID] = nc
+		}
+		nc.IPs.add(ip.State)
+	}
+
+	ncs := make([]NCSummary, 0, len(byID))
+	for _, nc := range byID {
+		ncs = append(ncs, *nc)
+	}
+	sort.Slice(ncs, func(i, j int) bool { return ncs[i].ID < ncs[j].ID })
+	return ncs
+}
+
+func ncsTable(ncs []NCSummary) *table {
+	t := &table{header: []string{"NC", "IPS", "AVAILABLE", "ALLOCATED", "PENDING RELEASE", "PENDING PROGRAMMING"}}
+	for i := range ncs {
+		t.append(ncs[i].ID, strconv.Itoa(ncs[i].IPs.Total), strconv.Itoa(ncs[i].IPs.Available), strconv.Itoa(ncs[i].IPs.Allocated),
+			strconv.Itoa(ncs[i].IPs.PendingRelease), strconv.Itoa(ncs[i].IPs.PendingProgramming))
+	}
+	return t
+}
+
+// PoolSummary is the size, scaling limits and IP counts of the CNS pool.
+type PoolSummary struct {
+	RequestedIPCount  int64
+	BatchSize         int64
+	MaxIPCount        int64
+	MinimumFreeIPs    int64
+	MaximumFreeIPs    int64
+	IPsNotInUse       int
+	IPs               IPCounts
+	LastReconcileTime time.Time
+}
+
+func summarizePool(data restserver.HTTPRestServiceData) PoolSummary {
+	nnc := data.IPAMPoolMonitor.CachedNNC
+	pool := PoolSummary{
+		RequestedIPCount:  nnc.Spec.RequestedIPCount,
+		BatchSize:         nnc.Status.Scaler.BatchSize,
+		MaxIPCount:        nnc.Status.Scaler.MaxIPCount,
+		MinimumFreeIPs:    data.IPAMPoolMonitor.MinimumFreeIps,
+		MaximumFreeIPs:    data.IPAMPoolMonitor.MaximumFreeIps,
+		IPsNotInUse:       len(nnc.Spec.IPsNotInUse),
+		LastReconcileTime: data.IPAMPoolMonitor.LastReconcileTime,
+	}
+	for _, ip := range data.PodIPConfigState {
+		pool.IPs.add(ip.State)
+	}
+	return pool
+}
+
+func poolTable(pool PoolSummary) *table {
+	lastReconcile := "never"
+	if !pool.LastReconcileTime.IsZero() {
+		lastReconcile = pool.LastReconcileTime.Format(time.RFC3339)
+	}
+
+	t := &table{header: []string{"FIELD", "VALUE"}}
+	t.append("Requested IPs", strconv.FormatInt(pool.RequestedIPCount, 10))
+	t.append("Batch size", strconv.FormatInt(pool.BatchSize, 10))
+	t.append("Max IPs", strconv.FormatInt(pool.MaxIPCount, 10))
+	t.append("Minimum free IPs", strconv.FormatInt(pool.MinimumFreeIPs, 10))
+	t.append("Maximum free IPs", strconv.FormatInt(pool.MaximumFreeIPs, 10))
+	t.append("IPs not in use", strconv.Itoa(pool.IPsNotInUse))
+	t.append("IPs", strconv.Itoa(pool.IPs.Total))
+	t.append("Available", strconv.Itoa(pool.IPs.Available))
+	t.append("Allocated", strconv.Itoa(pool.IPs.Allocated))
+	t.append("Pending release", strconv.Itoa(pool.IPs.PendingRelease))
+	t.append("Pending programming", strconv.Itoa(pool.IPs.PendingProgramming))
+	t.append("Last reconcile", lastReconcile)
+	return t
+}
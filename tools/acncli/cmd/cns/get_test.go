@@ -0,0 +1,127 @@
+package cns
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testIPs = []cns.IPConfigurationStatus{
+	{ID: "id-10", NCID: "nc2", IPAddress: "10.0.0.10", State: cns.Allocated, PodInfo: cns.NewPodInfo("infra", "web-eth0", "web", "default")},
+	{ID: "id-9", NCID: "nc1", IPAddress: "10.0.0.9", State: cns.Available},
+	{ID: "id-2", NCID: "nc1", IPAddress: "10.0.0.2", State: cns.PendingRelease},
+}
+
+func TestParseStates(t *testing.T) {
+	states, err := parseStates(nil)
+	require.NoError(t, err)
+	assert.Equal(t, ipConfigStates, states)
+
+	states, err = parseStates([]string{"allocated", "PendingRelease"})
+	require.NoError(t, err)
+	assert.Equal(t, []cns.IPConfigState{cns.Allocated, cns.PendingRelease}, states)
+
+	_, err = parseStates([]string{"Leaked"})
+	assert.Error(t, err)
+}
+
+func TestSortIPConfigsNumerically(t *testing.T) {
+	ips := append([]cns.IPConfigurationStatus{}, testIPs...)
+	sortIPConfigs(ips)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.9", "10.0.0.10"}, []string{ips[0].IPAddress, ips[1].IPAddress, ips[2].IPAddress})
+}
+
+func TestPodIPs(t *testing.T) {
+	pods := podIPs(map[string]string{"web-eth0": "id-10", "gone-eth0": "id-missing"}, testIPs)
+	require.Len(t, pods, 2)
+	assert.Equal(t, PodIP{PodInterfaceKey: "gone-eth0", IPConfigID: "id-missing"}, pods[0])
+	assert.Equal(t, PodIP{
+		PodInterfaceKey: "web-eth0",
+		PodName:         "web",
+		PodNamespace:    "default",
+		IPAddress:       "10.0.0.10",
+		State:           cns.Allocated,
+		NCID:            "nc2",
+		IPConfigID:      "id-10",
+	}, pods[1])
+}
+
+func TestNCAndPoolSummaries(t *testing.T) {
+	data := restserver.HTTPRestServiceData{PodIPConfigState: map[string]cns.IPConfigurationStatus{}}
+	for _, ip := range testIPs {
+		data.PodIPConfigState[ip.ID] = ip
+	}
+	data.IPAMPoolMonitor.CachedNNC.Spec.RequestedIPCount = 3
+	data.IPAMPoolMonitor.CachedNNC.Status.Scaler.BatchSize = 10
+
+	ncs := ncSummaries(data)
+	assert.Equal(t, []NCSummary{
+		{ID: "nc1", IPs: IPCounts{Total: 2, Available: 1, PendingRelease: 1}},
+		{ID: "nc2", IPs: IPCounts{Total: 1, Allocated: 1}},
+	}, ncs)
+
+	pool := summarizePool(data)
+	assert.Equal(t, int64(3), pool.RequestedIPCount)
+	assert.Equal(t, int64(10), pool.BatchSize)
+	assert.Equal(t, IPCounts{Total: 3, Available: 1, Allocated: 1, PendingRelease: 1}, pool.IPs)
+}
+
+func TestReleaseRequest(t *testing.T) {
+	req, podInfo, err := releaseRequest("10.0.0.10", testIPs)
+	require.NoError(t, err)
+	assert.Equal(t, "web", podInfo.Name())
+	assert.Equal(t, "web-eth0", req.PodInterfaceID)
+	assert.Equal(t, "infra", req.InfraContainerID)
+	assert.JSONEq(t, `{"PodName":"web","PodNamespace":"default"}`, string(req.OrchestratorContext))
+
+	_, _, err = releaseRequest("10.0.0.11", testIPs)
+	assert.Error(t, err)
+}
+
+func TestWriteOutput(t *testing.T) {
+	ncs := []NCSummary{{ID: "nc1", IPs: IPCounts{Total: 1, Available: 1}}}
+
+	var out bytes.Buffer
+	require.NoError(t, writeOutput(&out, c.OutputTable, ncs, ncsTable(ncs)))
+	assert.Contains(t, out.String(), "PENDING PROGRAMMING")
+	assert.Contains(t, out.String(), "nc1")
+
+	out.Reset()
+	require.NoError(t, writeOutput(&out, c.OutputJSON, ncs, ncsTable(ncs)))
+	var decoded []NCSummary
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, ncs, decoded)
+
+	out.Reset()
+	require.NoError(t, writeOutput(&out, c.OutputYAML, ncs, ncsTable(ncs)))
+	assert.Contains(t, out.String(), "- ID: nc1")
+
+	assert.Error(t, writeOutput(&out, "xml", ncs, ncsTable(ncs)))
+}
+
+func TestIPsRequest(t *testing.T) {
+	cmd := GetIPsCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--state", "allocated", "--nc", "nc1", "--namespace", "default", "--pod", "web", "--cidr", "10.0.0.0/24", "--min-time-in-state", "1h"}))
+	req, err := ipsRequest(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, cns.GetIPAddressesRequest{
+		IPConfigStateFilter: []cns.IPConfigState{cns.Allocated},
+		NCID:                "nc1",
+		PodName:             "web",
+		PodNamespace:        "default",
+		IPCIDR:              "10.0.0.0/24",
+		MinTimeInState:      time.Hour,
+	}, req)
+
+	cmd = GetIPsCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--cidr", "10.0.0.0"}))
+	_, err = ipsRequest(cmd)
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package cns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// table is the header and rows printed for the table output.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) append(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+// print writes the table with aligned columns.
+func (t *table) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0) //nolint:gomnd
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printOutput writes v in the output format set on the command, using the table for the table format.
+func printOutput(cmd *cobra.Command, v interface{}, t *table) error {
+	format, err := cmd.Flags().GetString(c.FlagOutput)
+	if err != nil {
+		return err
+	}
+	return writeOutput(cmd.OutOrStdout(), format, v, t)
+}
+
+func writeOutput(w io.Writer, format string, v interface{}, t *table) error {
+	switch format {
+	case c.OutputTable, "":
+		return t.print(w)
+	case c.OutputJSON:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case c.OutputYAML:
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q, options are %s, %s and %s", format, c.OutputTable, c.OutputJSON, c.OutputYAML)
+	}
+}
@@ -0,0 +1,73 @@
+package cns
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Azure/azure-container-networking/cns"
+	"github.com/spf13/cobra"
+)
+
+// ReleaseIPCmd returns the command to release an allocated IP on behalf of the pod holding it
+func ReleaseIPCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "ip <address>",
+		Short: "Release an allocated IP back to the CNS pool",
+		Long: "The release ip command releases an allocated IP on behalf of the pod holding it, as the CNI would when the pod is deleted. " +
+			"Use it to reclaim IPs leaked by pods which no longer exist.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			address := args[0]
+			if net.ParseIP(address) == nil {
+				return fmt.Errorf("invalid IP address %q", address)
+			}
+
+			client, err := newClient(cmd)
+			if err != nil {
+				return err
+			}
+			allocated, err := client.GetIPAddressesMatchingStates(cmd.Context(), cns.Allocated)
+			if err != nil {
+				return err
+			}
+
+			req, podInfo, err := releaseRequest(address, allocated)
+			if err != nil {
+				return err
+			}
+			if err = client.ReleaseIPAddress(cmd.Context(), req); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "✅ - released %s held by pod %s/%s\n", address, podInfo.Namespace(), podInfo.Name())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// releaseRequest returns the request to release the allocated IP with the address, and the pod holding it.
+func releaseRequest(address string, allocated []cns.IPConfigurationStatus) (*cns.IPConfigRequest, cns.PodInfo, error) {
+	ip := net.ParseIP(address)
+	for i := range allocated {
+		if !ip.Equal(net.ParseIP(allocated[i].IPAddress)) {
+			continue
+		}
+
+		podInfo := allocated[i].PodInfo
+		if podInfo == nil {
+			return nil, nil, fmt.Errorf("IP %s is allocated to no pod", address)
+		}
+		orchestratorContext, err := podInfo.OrchestratorContext()
+		if err != nil {
+			return nil, nil, err
+		}
+		return &cns.IPConfigRequest{
+			PodInterfaceID:      podInfo.InterfaceID(),
+			InfraContainerID:    podInfo.InfraContainerID(),
+			OrchestratorContext: orchestratorContext,
+		}, podInfo, nil
+	}
+	return nil, nil, fmt.Errorf("IP %s is not allocated", address)
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/Azure/azure-container-networking/tools/acncli/cmd/npm"
 
 	"github.com/Azure/azure-container-networking/tools/acncli/cmd/cni"
+	"github.com/Azure/azure-container-networking/tools/acncli/cmd/cns"
+	"github.com/Azure/azure-container-networking/tools/acncli/cmd/store"
 
 	c "github.com/Azure/azure-container-networking/tools/acncli/api"
 	"github.com/spf13/cobra"
@@ -37,7 +39,9 @@ func NewRootCmd(version string) *cobra.Command {
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(cni.CNICmd())
+	rootCmd.AddCommand(cns.CNSCmd())
 	rootCmd.AddCommand(npm.NPMRootCmd())
+	rootCmd.AddCommand(store.StoreCmd())
 	rootCmd.SetVersionTemplate(version)
 	return rootCmd
 }
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/store"
+	c "github.com/Azure/azure-container-networking/tools/acncli/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// StoreCmd returns the command group for the ACN state stores
+func StoreCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "store",
+		Short: "Collection of functions related to the state stores of ACN components",
+	}
+
+	cmd.AddCommand(MigrateCmd())
+	return cmd
+}
+
+// MigrateCmd copies a JSON file store to a bolt store
+func MigrateCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrates a JSON state file to a bolt store",
+		Long: "The migrate command copies every key of a JSON state file to a bolt store, leaving the JSON file in place. " +
+			"Azure CNI uses the bolt store once it exists next to its JSON state file, CNS once its StoreType is set to bolt. " +
+			"Run it while the component is stopped so no state is written to the JSON file after the migration.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src := viper.GetString(c.FlagMigrateFrom)
+			dst := viper.GetString(c.FlagMigrateTo)
+			if src == "" || dst == "" {
+				return fmt.Errorf("both --%s and --%s are required", c.FlagMigrateFrom, c.FlagMigrateTo)
+			}
+
+			kvs, err := store.NewBoltStore(dst)
+			if err != nil {
+				return err
+			}
+
+			n, err := store.MigrateJSONFile(src, kvs)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("✅ - migrated %d keys from %s to %s\n", n, src, dst)
+			return nil
+		},
+	}
+
+	cmd.Flags().String(c.FlagMigrateFrom, "", "Path of the JSON state file to migrate, e.g. /var/run/azure-vnet.json")
+	cmd.Flags().String(c.FlagMigrateTo, "", "Path of the bolt store to migrate to, e.g. /var/run/azure-vnet.db")
+
+	return cmd
+}